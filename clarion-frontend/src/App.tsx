@@ -2,7 +2,7 @@ import { useEffect } from 'react';
 import Header from "./components/Header";
 import Layout from "./components/Layout";
 import { useAppStore } from './store/store';
-import { fetchFilterPreview } from './lib/api';
+import { fetchFilterPreview, socketURL } from './lib/api';
 import { TreeNodeData } from './data/file-tree';
 
 const getAllFilePaths = (nodes: TreeNodeData[]): string[] => {
@@ -29,43 +29,51 @@ function App() {
         return;
     }
 
-    const socket = new WebSocket('ws://localhost:2038/api/v2/fs/watch');
+    let socket: WebSocket | undefined;
+    let closed = false;
 
-    socket.onopen = () => {
-        console.log('File watcher connection established.');
-        socket.send(JSON.stringify({ path: currentProject.path }));
-    };
+    socketURL('/api/v2/fs/watch').then(url => {
+      if (closed) return;
+      const ws = new WebSocket(url);
+      socket = ws;
+
+      ws.onopen = () => {
+          console.log('File watcher connection established.');
+          ws.send(JSON.stringify({ path: currentProject.path }));
+      };
 
-    socket.onmessage = (event) => {
-        try {
-            const data = JSON.parse(event.data);
-            if (data.event === 'change') {
-                console.log(`File change detected: ${data.path}, op: ${data.op}`);
+      ws.onmessage = (event) => {
+          try {
+              const data = JSON.parse(event.data);
+              if (data.event === 'change') {
+                  console.log(`File change detected: ${data.path}, op: ${data.op}`);
                 
-                const { refreshFileTree, openFiles, refreshOpenFileContent } = useAppStore.getState();
+                  const { refreshFileTree, openFiles, refreshOpenFileContent } = useAppStore.getState();
                 
-                refreshFileTree();
-
-                const changedFileIsOpen = openFiles.some(file => file.path === data.path);
-                if (changedFileIsOpen && data.op !== 'REMOVE') {
-                    refreshOpenFileContent(data.path);
-                }
-            }
-        } catch (e) {
-            console.error("Error parsing file watcher message:", e);
-        }
-    };
+                  refreshFileTree();
 
-    socket.onerror = (error) => {
-        console.error('File watcher WebSocket error:', error);
-    };
+                  const changedFileIsOpen = openFiles.some(file => file.path === data.path);
+                  if (changedFileIsOpen && data.op !== 'REMOVE') {
+                      refreshOpenFileContent(data.path);
+                  }
+              }
+          } catch (e) {
+              console.error("Error parsing file watcher message:", e);
+          }
+      };
 
-    socket.onclose = () => {
-        console.log('File watcher connection closed.');
-    };
+      ws.onerror = (error) => {
+          console.error('File watcher WebSocket error:', error);
+      };
+
+      ws.onclose = () => {
+          console.log('File watcher connection closed.');
+      };
+    }).catch(e => console.error('Failed to connect the file watcher:', e));
 
     return () => {
-        socket.close();
+        closed = true;
+        socket?.close();
     };
   }, [currentProject?.path]);
 
@@ -78,7 +86,8 @@ function App() {
         const statuses = await fetchFilterPreview(
           allFilePaths, 
           activeAgent.codebaseFilters.includeGlobs || [], 
-          activeAgent.codebaseFilters.excludeGlobs || []
+          activeAgent.codebaseFilters.excludeGlobs || [],
+          currentProject.path
         );
         const includedPaths = Object.entries(statuses)
           .filter(([, status]) => status === 'included')
@@ -75,6 +75,9 @@ const AIMessage = ({ run }: { run: AgentRun }) => {
                 </TooltipProvider>
               </div>
             )}
+            {run.output.cache?.hit && (
+              <div className="flex justify-end text-xs text-text-secondary">Cached response</div>
+            )}
           </div>
         );
       default:
@@ -3,7 +3,7 @@ import { useAppStore } from '../store/store';
 import Button from './ui/Button';
 import Textarea from './ui/Textarea';
 import { Sparkles, Files } from 'lucide-react';
-import { runAgent as runAgentApi, AgentOutput as ApiAgentOutput, fetchTotalTokenCount } from '../lib/api';
+import { runAgent as runAgentApi, AgentOutput as ApiAgentOutput, ContextSnapshot, fetchTotalTokenCount } from '../lib/api';
 import { useDebounce } from '../hooks/useDebounce';
 import { formatTokenCount } from '../lib/utils';
 
@@ -82,6 +82,8 @@ const PromptZone = () => {
     const runId = startNewRun(activeAgent, localPrompt, pathsToProcess, currentProject.path);
     setLocalPrompt('');
 
+    let contextSnapshot: ContextSnapshot | undefined;
+    let agentRevision: number | undefined;
     try {
       const result: ApiAgentOutput = await runAgentApi({
         system_instruction: activeAgent.systemPrompt,
@@ -90,7 +92,8 @@ const PromptZone = () => {
         codebase_paths: pathsToProcess,
         project_root: currentProject.path,
         llm_config: activeAgent.llmConfig,
-      });
+        agent_id: activeAgent.id,
+      }, snapshot => { contextSnapshot = snapshot; }, revision => { agentRevision = revision; });
 
       if (result.error) {
         throw new Error(result.error);
@@ -98,12 +101,15 @@ const PromptZone = () => {
       
       updateRun(runId, {
         status: 'success',
+        agentRevision,
+        contextSnapshot,
         output: {
           summary: result.summary || 'No summary provided.',
           fileChanges: result.file_changes || [],
           rawOutput: result,
           error: undefined,
           tokenUsage: result.token_usage,
+          cache: result.cache,
         },
       });
 
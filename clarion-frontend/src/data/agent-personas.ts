@@ -36,6 +36,10 @@ export interface AgentPersona {
     required: boolean;
   }[];
   llmConfig: LLMConfig;
+  // Set for agents loaded from a project's .clarion/agents directory.
+  projectPath?: string;
+  // The number of the agent's latest saved revision.
+  revision?: number;
 }
 
 export const agentPersonas: AgentPersona[] = [];
@@ -12,6 +12,31 @@ export interface Project {
   path: string;
   lastOpenedAt: string;
   activeAgentId?: string;
+  settings?: ProjectSettings;
+}
+
+// Settings for every run in a project, saved in the app or in .clarion/project.yml.
+export interface ProjectSettings {
+  include_globs?: string[];
+  exclude_globs?: string[];
+  ignore_dirs?: string[];
+  llm_config_id?: string;
+  verify_commands?: string[];
+  pinned_files?: string[];
+}
+
+export interface ProjectSettingsResponse {
+  settings: ProjectSettings;
+  file: ProjectSettings | null;
+  file_path: string;
+  effective: ProjectSettings;
+}
+
+// Selects codebase paths from git in addition to codebase_paths.
+export interface GitContext {
+  mode: 'modified' | 'staged' | 'untracked' | 'commit_range';
+  range?: string; // for commit_range, e.g. "HEAD~3..HEAD"
+  include_diff?: boolean;
 }
 
 export interface AgentRunRequest {
@@ -21,6 +46,62 @@ export interface AgentRunRequest {
   output_schema: object;
   project_root: string;
   llm_config: LLMConfig;
+  git_context?: GitContext;
+  run_id?: string;
+  // Run the agent in a git worktree of its own; requires run_id.
+  isolate?: boolean;
+  // The saved agent whose required user variables are checked; the run fails with
+  // status 422 and a list of the missing names if any has no value.
+  agent_id?: string;
+  // Values for {{name}} placeholders in the system instruction and prompt. The built-ins
+  // project.name, project.path, git.branch, git.commit, date, time and datetime are always set.
+  variables?: Record<string, string>;
+  // Pins the run to a revision of agent_id, whose prompt, schema and LLM config are used.
+  agent_revision?: number;
+  // A saved prompt template whose messages replace the system instruction and prompt pair.
+  prompt_template_id?: string;
+  // Response cache policy. By default the cache is used when the server enables it and the
+  // temperature is 0; force also caches runs with a higher temperature.
+  cache?: 'off' | 'on' | 'force';
+}
+
+export interface MissingVariablesError {
+  error: string;
+  missing: string[];
+}
+
+export interface FieldError {
+  field: string;
+  message: string;
+}
+
+export interface ValidationErrorResponse {
+  error: string;
+  fields: FieldError[];
+}
+
+export interface RunWorktree {
+  run_id: string;
+  path: string;
+  branch: string;
+  project_dir: string;
+  repo_dir: string;
+  base_branch?: string;
+  base_commit: string;
+  created_at: string;
+}
+
+export interface CommandResult {
+  command: string;
+  exit_code: number;
+  output: string;
+}
+
+export interface WorktreeApplyResult {
+  worktree: RunWorktree;
+  commit: { hash: string; short_hash: string; message: string };
+  verification: CommandResult[] | null;
+  passed: boolean;
 }
 
 export interface FileChange {
@@ -40,12 +121,21 @@ export interface AgentOutput {
     completion: number;
     total: number;
   };
+  // Set when the response cache was consulted; a hit made no provider call.
+  cache?: {
+    hit: boolean;
+    key: string;
+    cached_at?: string;
+  };
   [key: string]: any;
 }
 
 export interface ApplyChangesPayload {
     root_path: string;
     changes: FileChange[];
+    // Commit the changes on a new clarion/<run_id> branch instead of leaving them in the working tree.
+    run_id?: string;
+    create_branch?: boolean;
 }
 
 export interface PreparedPromptResponse {
@@ -63,6 +153,7 @@ export interface TotalTokenCountRequest {
   user_prompt: string;
   codebase_paths: string[];
   project_root: string;
+  git_context?: GitContext;
 }
 
 export const fetchTotalTokenCount = async (payload: TotalTokenCountRequest, signal: AbortSignal): Promise<number> => {
@@ -182,10 +273,13 @@ export const fetchFilesContent = async (paths: string[]): Promise<Record<string,
     }
 };
 
+// fetchFilterPreview reports which files the globs select. With projectRoot, the project's
+// default globs are merged in as they are for runs.
 export const fetchFilterPreview = async (
     filePaths: string[], 
     includeGlobs: string[], 
-    excludeGlobs: string[]
+    excludeGlobs: string[],
+    projectRoot?: string
 ): Promise<Record<string, 'included' | 'excluded'>> => {
     try {
         const response = await fetch(`${API_URL}/api/v2/fs/preview-filter`, {
@@ -193,7 +287,7 @@ export const fetchFilterPreview = async (
             headers: {
                 'Content-Type': 'application/json',
             },
-            body: JSON.stringify({ file_paths: filePaths, include_globs: includeGlobs, exclude_globs: excludeGlobs }),
+            body: JSON.stringify({ file_paths: filePaths, include_globs: includeGlobs, exclude_globs: excludeGlobs, project_root: projectRoot }),
         });
 
         if (!response.ok) {
@@ -232,7 +326,20 @@ export const fetchPreparedPrompt = async (payload: AgentRunRequest): Promise<Pre
     }
 };
 
-export const runAgent = async (request: AgentRunRequest): Promise<AgentOutput> => {
+// ContextSnapshot is the codebase context a run was given, kept with the run so that it
+// can be rerun on the same files.
+export interface ContextSnapshot {
+    files: Record<string, string>;
+    diff?: string;
+}
+
+// runAgent runs an agent and returns its output. onContext receives the context the run
+// was given, and onAgentRevision the revision of agent_id the server ran.
+export const runAgent = async (
+    request: AgentRunRequest,
+    onContext?: (snapshot: ContextSnapshot) => void,
+    onAgentRevision?: (revision: number) => void,
+): Promise<AgentOutput> => {
     try {
         const response = await fetch(`${API_URL}/api/v2/agents/run`, {
             method: 'POST',
@@ -253,6 +360,12 @@ export const runAgent = async (request: AgentRunRequest): Promise<AgentOutput> =
         }
 
         const data = await response.json();
+        if (data.context_snapshot && onContext) {
+            onContext(data.context_snapshot);
+        }
+        if (data.agent_revision && onAgentRevision) {
+            onAgentRevision(data.agent_revision);
+        }
         return data.output || {};
     } catch (error) {
         console.error("Error running agent:", error);
@@ -282,6 +395,70 @@ export const applyFileChanges = async (payload: ApplyChangesPayload): Promise<{
     }
 };
 
+export const rejectRunBranch = async (runId: string, rootPath: string): Promise<{ success: boolean; error?: string }> => {
+    try {
+        const response = await fetch(`${API_URL}/api/v2/runs/${encodeURIComponent(runId)}/reject`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ path: rootPath }),
+        });
+
+        if (!response.ok) {
+            const errorText = await response.text();
+            return { success: false, error: errorText };
+        }
+
+        return { success: true };
+    } catch (error) {
+        console.error("Error rejecting run branch:", error);
+        return { success: false, error: (error as Error).message };
+    }
+};
+
+const worktreeUrl = (runId: string) => `${API_URL}/api/v2/runs/${encodeURIComponent(runId)}/worktree`;
+
+export const applyRunWorktree = async (runId: string, changes: FileChange[], verifyCommands: string[] = []): Promise<WorktreeApplyResult> => {
+    const response = await fetch(`${worktreeUrl(runId)}/apply`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ changes, verify_commands: verifyCommands }),
+    });
+    if (!response.ok) {
+        throw new Error(await response.text());
+    }
+    return response.json();
+};
+
+export const integrateRunWorktree = async (runId: string, mode: 'merge' | 'cherry-pick' = 'merge'): Promise<{ success: boolean; error?: string }> => {
+    try {
+        const response = await fetch(`${worktreeUrl(runId)}/integrate`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ mode }),
+        });
+        if (!response.ok) {
+            return { success: false, error: await response.text() };
+        }
+        return { success: true };
+    } catch (error) {
+        console.error("Error integrating run worktree:", error);
+        return { success: false, error: (error as Error).message };
+    }
+};
+
+export const discardRunWorktree = async (runId: string): Promise<{ success: boolean; error?: string }> => {
+    try {
+        const response = await fetch(worktreeUrl(runId), { method: 'DELETE' });
+        if (!response.ok) {
+            return { success: false, error: await response.text() };
+        }
+        return { success: true };
+    } catch (error) {
+        console.error("Error discarding run worktree:", error);
+        return { success: false, error: (error as Error).message };
+    }
+};
+
 export const createFile = async (rootPath: string, path: string): Promise<{ success: boolean; error?: string }> => {
     try {
         const response = await fetch(`${API_URL}/api/v2/fs/file/create`, {
@@ -333,9 +510,12 @@ export const copyFile = async (rootPath: string, source: string, destination: st
     }
 };
 
-export const fetchAgents = async (): Promise<AgentPersona[]> => {
+// fetchAgents lists the saved agents; with a project root it also includes the agents
+// loaded from the project's .clarion/agents directory.
+export const fetchAgents = async (projectRoot?: string): Promise<AgentPersona[]> => {
     try {
-        const response = await fetch(`${API_URL}/api/v2/agents/list`);
+        const query = projectRoot ? `?project_root=${encodeURIComponent(projectRoot)}` : '';
+        const response = await fetch(`${API_URL}/api/v2/agents/list${query}`);
         if (!response.ok) {
             throw new Error('Failed to fetch agents');
         }
@@ -362,6 +542,8 @@ export const fetchAgents = async (): Promise<AgentPersona[]> => {
                 parameters: agent.llm_config?.parameters || { temperature: 0.7 },
                 configId: agent.llm_config?.configId || '',
             },
+            projectPath: agent.project_path,
+            revision: agent.revision,
         }));
     } catch (error) {
         console.error("Error fetching agents:", error);
@@ -369,7 +551,9 @@ export const fetchAgents = async (): Promise<AgentPersona[]> => {
     }
 };
 
-export const saveAgent = async (agent: AgentPersona): Promise<{ success: boolean; error?: string }> => {
+// saveAgent saves the agent, recording a new revision with the changelog if it changed.
+// New agents get their ID from the server; invalid agents come back with field errors.
+export const saveAgent = async (agent: AgentPersona, changelog?: string): Promise<{ success: boolean; id?: string; error?: string; fields?: FieldError[] }> => {
     const payload = {
         Profile: {
             ID: agent.id,
@@ -394,6 +578,7 @@ export const saveAgent = async (agent: AgentPersona): Promise<{ success: boolean
             parameters: agent.llmConfig.parameters,
             configId: agent.llmConfig.configId,
         },
+        changelog,
     };
 
     try {
@@ -404,17 +589,104 @@ export const saveAgent = async (agent: AgentPersona): Promise<{ success: boolean
             },
             body: JSON.stringify(payload),
         });
+        if (response.status === 422) {
+            const invalid: ValidationErrorResponse = await response.json();
+            const details = invalid.fields.map(f => `${f.field}: ${f.message}`).join('; ');
+            return { success: false, error: `${invalid.error}: ${details}`, fields: invalid.fields };
+        }
         if (!response.ok) {
             const errorText = await response.text();
             return { success: false, error: errorText };
         }
-        return { success: true };
+        const saved = await response.json();
+        return { success: true, id: saved.Profile.ID };
     } catch (error) {
         console.error("Error saving agent:", error);
         return { success: false, error: (error as Error).message };
     }
 };
 
+// exportAgent returns the agent as a YAML bundle, without its LLM config ID.
+export const exportAgent = async (id: string): Promise<string> => {
+    const response = await fetch(`${API_URL}/api/v2/agents/${id}/export`);
+    if (!response.ok) {
+        throw new Error(await response.text());
+    }
+    return response.text();
+};
+
+// importAgent saves the agent in a YAML bundle. Replacing an existing agent requires overwrite.
+export const importAgent = async (bundle: string, overwrite = false): Promise<{ success: boolean; error?: string }> => {
+    try {
+        const response = await fetch(`${API_URL}/api/v2/agents/import${overwrite ? '?overwrite=true' : ''}`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/yaml' },
+            body: bundle,
+        });
+        if (!response.ok) {
+            const errorText = await response.text();
+            return { success: false, error: errorText };
+        }
+        return { success: true };
+    } catch (error) {
+        console.error("Error importing agent:", error);
+        return { success: false, error: (error as Error).message };
+    }
+};
+
+export interface AgentRevision {
+  agent_id: string;
+  revision: number;
+  changelog?: string;
+  agent: any; // the agent as stored, in the same shape as the agents list
+  created_at: string;
+}
+
+export interface AgentRevisionDiff {
+  agent_id: string;
+  from: number;
+  to: number;
+  // Unified diffs of the changed parts: profile, system_prompt, output_schema,
+  // codebase_filters, user_variables and llm_config.
+  changes: { field: string; diff: string }[];
+}
+
+export const fetchAgentRevisions = async (agentId: string): Promise<AgentRevision[]> => {
+    try {
+        const response = await fetch(`${API_URL}/api/v2/agents/${agentId}/revisions`);
+        if (!response.ok) throw new Error('Failed to fetch agent revisions');
+        return await response.json();
+    } catch (error) {
+        console.error("Error fetching agent revisions:", error);
+        return [];
+    }
+};
+
+// diffAgentRevisions compares two revisions; by default the latest one and the one before it.
+export const diffAgentRevisions = async (agentId: string, from?: number, to?: number): Promise<AgentRevisionDiff> => {
+    const params = new URLSearchParams();
+    if (from) params.set('from', String(from));
+    if (to) params.set('to', String(to));
+    const response = await fetch(`${API_URL}/api/v2/agents/${agentId}/revisions/diff?${params}`);
+    if (!response.ok) {
+        throw new Error(await response.text());
+    }
+    return response.json();
+};
+
+// rollbackAgent saves a past revision as the agent's newest revision.
+export const rollbackAgent = async (agentId: string, revision: number, changelog?: string): Promise<AgentRevision> => {
+    const response = await fetch(`${API_URL}/api/v2/agents/${agentId}/revisions/${revision}/rollback`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ changelog }),
+    });
+    if (!response.ok) {
+        throw new Error(await response.text());
+    }
+    return response.json();
+};
+
 export const deleteAgent = async (id: string): Promise<{ success: boolean; error?: string }> => {
     try {
         const response = await fetch(`${API_URL}/api/v2/agents/delete/${id}`, {
@@ -431,6 +703,203 @@ export const deleteAgent = async (id: string): Promise<{ success: boolean; error
     }
 };
 
+export type CanvasNodeType = 'input' | 'prompt' | 'file_selection' | 'agent' | 'command' | 'approval';
+
+export interface CanvasNode {
+  id: string;
+  type: CanvasNodeType;
+  label?: string;
+  position: { x: number; y: number };
+  input?: { name: string; default?: string };
+  prompt?: { template: string };
+  file_selection?: { paths: string[] };
+  agent?: { agent_id: string; prompt?: string };
+  command?: { command: string };
+  approval?: { message?: string };
+}
+
+export interface CanvasEdge {
+  id: string;
+  source: string;
+  source_output: string;
+  target: string;
+  target_input: string;
+  // Follow the edge only when a field of the source's outputs matches.
+  condition?: { field: string; op: 'equals' | 'not_equals' | 'contains' | 'truthy' | 'falsy'; value?: string };
+}
+
+export interface Canvas {
+  id: string;
+  name: string;
+  description?: string;
+  nodes: CanvasNode[];
+  edges: CanvasEdge[];
+  created_at?: string;
+  updated_at?: string;
+}
+
+export const fetchCanvases = async (): Promise<Canvas[]> => {
+    try {
+        const response = await fetch(`${API_URL}/api/v2/canvases/list`);
+        if (!response.ok) throw new Error('Failed to fetch canvases');
+        return await response.json();
+    } catch (error) {
+        console.error("Error fetching canvases:", error);
+        return [];
+    }
+};
+
+// saveCanvas throws with the validation problems when the canvas is rejected.
+export const saveCanvas = async (canvas: Canvas): Promise<Canvas> => {
+    const response = await fetch(`${API_URL}/api/v2/canvases/save`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(canvas),
+    });
+    if (!response.ok) {
+        throw new Error(await response.text());
+    }
+    return response.json();
+};
+
+export const validateCanvas = async (canvas: Canvas): Promise<{ valid: boolean; problems: string[] }> => {
+    const response = await fetch(`${API_URL}/api/v2/canvases/validate`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(canvas),
+    });
+    if (!response.ok) {
+        throw new Error(await response.text());
+    }
+    return response.json();
+};
+
+export type CanvasRunStatus = 'pending' | 'running' | 'waiting_approval' | 'completed' | 'skipped' | 'rejected' | 'failed';
+
+export interface CanvasNodeResult {
+  status: CanvasRunStatus;
+  inputs?: Record<string, unknown>;
+  outputs?: Record<string, unknown>;
+  error?: string;
+  attempts: number;
+  started_at?: string;
+  finished_at?: string;
+}
+
+export interface CanvasRun {
+  id: string;
+  canvas_id: string;
+  canvas: Canvas;
+  project_root: string;
+  inputs: Record<string, string>;
+  status: CanvasRunStatus;
+  error?: string;
+  nodes: Record<string, CanvasNodeResult>;
+  created_at: string;
+  updated_at: string;
+}
+
+const postCanvasRun = async (url: string, body?: object): Promise<CanvasRun> => {
+    const response = await fetch(url, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: body ? JSON.stringify(body) : undefined,
+    });
+    if (!response.ok) {
+        throw new Error(await response.text());
+    }
+    return response.json();
+};
+
+export const runCanvas = (canvasId: string, projectRoot: string, inputs: Record<string, string>): Promise<CanvasRun> =>
+    postCanvasRun(`${API_URL}/api/v2/canvases/${canvasId}/run`, { project_root: projectRoot, inputs });
+
+export const resumeCanvasRun = (runId: string): Promise<CanvasRun> =>
+    postCanvasRun(`${API_URL}/api/v2/canvas-runs/${runId}/resume`);
+
+// approveCanvasNode records a decision on an approval node. changes, if given, replace the
+// file changes the node was given before later nodes use them.
+export const approveCanvasNode = (runId: string, nodeId: string, approved: boolean, comment?: string, changes?: FileChange[]): Promise<CanvasRun> =>
+    postCanvasRun(`${API_URL}/api/v2/canvas-runs/${runId}/nodes/${encodeURIComponent(nodeId)}/approve`, { approved, comment, changes });
+
+export const fetchCanvasRun = async (runId: string): Promise<CanvasRun> => {
+    const response = await fetch(`${API_URL}/api/v2/canvas-runs/${runId}`);
+    if (!response.ok) {
+        throw new Error(await response.text());
+    }
+    return response.json();
+};
+
+export const deleteCanvas = async (id: string): Promise<{ success: boolean; error?: string }> => {
+    try {
+        const response = await fetch(`${API_URL}/api/v2/canvases/delete/${id}`, {
+            method: 'DELETE',
+        });
+        if (!response.ok) {
+            const errorText = await response.text();
+            return { success: false, error: errorText };
+        }
+        return { success: true };
+    } catch (error) {
+        console.error("Error deleting canvas:", error);
+        return { success: false, error: (error as Error).message };
+    }
+};
+
+export type MessageRole = 'system' | 'developer' | 'user';
+
+// Message contents may use {{system_instruction}}, {{prompt}} and {{context}} besides the
+// run's variables; without {{context}} the codebase context is sent as its own user message.
+export interface PromptTemplate {
+  id: string;
+  name: string;
+  description: string;
+  tags: string[];
+  messages: { role: MessageRole; content: string }[];
+  created_at?: string;
+  updated_at?: string;
+}
+
+export const fetchPromptTemplates = async (tag?: string): Promise<PromptTemplate[]> => {
+    try {
+        const query = tag ? `?tag=${encodeURIComponent(tag)}` : '';
+        const response = await fetch(`${API_URL}/api/v2/prompt-templates/list${query}`);
+        if (!response.ok) throw new Error('Failed to fetch prompt templates');
+        return await response.json();
+    } catch (error) {
+        console.error("Error fetching prompt templates:", error);
+        return [];
+    }
+};
+
+export const savePromptTemplate = async (template: PromptTemplate): Promise<PromptTemplate> => {
+    const response = await fetch(`${API_URL}/api/v2/prompt-templates/save`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(template),
+    });
+    if (!response.ok) {
+        throw new Error(await response.text());
+    }
+    return response.json();
+};
+
+export const deletePromptTemplate = async (id: string): Promise<{ success: boolean; error?: string }> => {
+    try {
+        const response = await fetch(`${API_URL}/api/v2/prompt-templates/delete/${id}`, {
+            method: 'DELETE',
+        });
+        if (!response.ok) {
+            const errorText = await response.text();
+            return { success: false, error: errorText };
+        }
+        return { success: true };
+    } catch (error) {
+        console.error("Error deleting prompt template:", error);
+        return { success: false, error: (error as Error).message };
+    }
+};
+
 export const fetchLLMConfigs = async (): Promise<LLMProviderConfig[]> => {
     try {
         const response = await fetch(`${API_URL}/api/v2/llm-configs/list`);
@@ -505,6 +974,50 @@ export const openProject = async (path: string): Promise<Project | null> => {
     }
 };
 
+// syncProjectAgents loads the agents in a project's .clarion/agents directory on the server.
+export const syncProjectAgents = async (projectId: string): Promise<void> => {
+    const response = await fetch(`${API_URL}/api/v2/projects/${projectId}/agents/sync`, { method: 'POST' });
+    if (!response.ok) {
+        const errorText = await response.text();
+        throw new Error(`Failed to sync project agents: ${errorText}`);
+    }
+};
+
+export const fetchProjectSettings = async (projectId: string): Promise<ProjectSettingsResponse> => {
+    const response = await fetch(`${API_URL}/api/v2/projects/${projectId}/settings`);
+    if (!response.ok) {
+        throw new Error(`Failed to fetch project settings: ${await response.text()}`);
+    }
+    return response.json();
+};
+
+// updateProjectSettings saves the settings, and with writeFile also writes them to
+// .clarion/project.yml. Invalid settings come back with field errors.
+export const updateProjectSettings = async (
+    projectId: string,
+    settings: ProjectSettings,
+    writeFile = false
+): Promise<{ success: boolean; data?: ProjectSettingsResponse; error?: string; fields?: FieldError[] }> => {
+    try {
+        const response = await fetch(`${API_URL}/api/v2/projects/${projectId}/settings`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ settings, write_file: writeFile }),
+        });
+        if (response.status === 422 && response.headers.get('Content-Type')?.includes('application/json')) {
+            const invalid: ValidationErrorResponse = await response.json();
+            return { success: false, error: invalid.error, fields: invalid.fields };
+        }
+        if (!response.ok) {
+            return { success: false, error: await response.text() };
+        }
+        return { success: true, data: await response.json() };
+    } catch (error) {
+        console.error("Error saving project settings:", error);
+        return { success: false, error: (error as Error).message };
+    }
+};
+
 export const updateProject = async (project: Project): Promise<{ success: boolean; error?: string }> => {
     try {
         const response = await fetch(`${API_URL}/api/v2/projects/update`, {
@@ -539,11 +1052,63 @@ export const deleteProject = async (id: string): Promise<{ success: boolean; err
     }
 };
 
-export const fetchRunsForProject = async (projectId: string): Promise<AgentRun[]> => {
+export interface RunSummary {
+    id: string;
+    project_id: string;
+    agent_id?: string;
+    agent_name: string;
+    status: string;
+    prompt: string;
+    summary: string;
+    created_at: string;
+}
+
+export interface RunPage {
+    runs: RunSummary[];
+    total: number;
+    limit: number;
+    offset: number;
+}
+
+// RunFilter narrows a run listing. from and to are dates (YYYY-MM-DD, both inclusive) or
+// RFC 3339 times; q searches prompts and summaries.
+export interface RunFilter {
+    agent_id?: string;
+    status?: string;
+    from?: string;
+    to?: string;
+    q?: string;
+    limit?: number;
+    offset?: number;
+}
+
+// fetchRunPage returns a page of a project's runs as summaries, newest first.
+export const fetchRunPage = async (projectId: string, filter: RunFilter = {}): Promise<RunPage> => {
+    const params = new URLSearchParams();
+    Object.entries(filter).forEach(([key, value]) => {
+        if (value !== undefined && value !== '') params.set(key, String(value));
+    });
+    const response = await fetch(`${API_URL}/api/v2/projects/${projectId}/runs?${params}`);
+    if (!response.ok) {
+        throw new Error(`Failed to fetch runs: ${await response.text()}`);
+    }
+    return response.json();
+};
+
+export const fetchRun = async (runId: string): Promise<AgentRun> => {
+    const response = await fetch(`${API_URL}/api/v2/runs/${runId}`);
+    if (!response.ok) {
+        throw new Error(`Failed to fetch run: ${await response.text()}`);
+    }
+    return response.json();
+};
+
+// fetchRunsForProject returns the full records of a project's latest runs, oldest first.
+export const fetchRunsForProject = async (projectId: string, limit = 50): Promise<AgentRun[]> => {
     try {
-        const response = await fetch(`${API_URL}/api/v2/projects/${projectId}/runs`);
-        if (!response.ok) throw new Error('Failed to fetch runs for project');
-        return await response.json();
+        const page = await fetchRunPage(projectId, { limit });
+        const runs = await Promise.all(page.runs.map(summary => fetchRun(summary.id)));
+        return runs.reverse();
     } catch (error) {
         console.error("Error fetching runs:", error);
         return [];
@@ -567,3 +1132,358 @@ export const saveRun = async (projectId: string, run: AgentRun): Promise<{ succe
         return { success: false, error: (error as Error).message };
     }
 };
+
+export interface TokenUsage {
+    prompt: number;
+    completion: number;
+    total: number;
+}
+
+// ComparisonCandidate is the result of one LLM config in a comparison. cost is in US
+// dollars, set when the provider reports token usage and the model's price is known.
+export interface ComparisonCandidate {
+    llm_config: LLMConfig;
+    output?: AgentOutput;
+    error?: string;
+    latency_ms: number;
+    token_usage?: TokenUsage;
+    cost?: number;
+}
+
+export interface Comparison {
+    id: string;
+    project_id?: string;
+    request: AgentRunRequest;
+    candidates: ComparisonCandidate[];
+    created_at: string;
+}
+
+export interface ComparisonFileDiff {
+    path: string;
+    // Empty when the candidate does not change the file.
+    action_a?: string;
+    action_b?: string;
+    // Unified diff from candidate a's content to b's; empty when they agree.
+    diff: string;
+}
+
+// compareModels runs one request against several LLM configs at once and returns the saved
+// comparison. A config may name just a saved config, or a provider and model.
+export const compareModels = async (request: AgentRunRequest, llmConfigs: Partial<LLMConfig>[]): Promise<Comparison> => {
+    const response = await fetch(`${API_URL}/api/v2/compare`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ ...request, llm_configs: llmConfigs }),
+    });
+    if (!response.ok) {
+        throw new Error(`Failed to compare models: ${await response.text()}`);
+    }
+    return response.json();
+};
+
+export const fetchComparison = async (comparisonId: string): Promise<Comparison> => {
+    const response = await fetch(`${API_URL}/api/v2/comparisons/${comparisonId}`);
+    if (!response.ok) {
+        throw new Error(`Failed to fetch comparison: ${await response.text()}`);
+    }
+    return response.json();
+};
+
+export const fetchComparisons = async (projectId: string): Promise<Comparison[]> => {
+    const response = await fetch(`${API_URL}/api/v2/projects/${projectId}/comparisons`);
+    if (!response.ok) {
+        throw new Error(`Failed to fetch comparisons: ${await response.text()}`);
+    }
+    return response.json();
+};
+
+// fetchComparisonDiff diffs the file changes of candidates a and b of a comparison.
+export const fetchComparisonDiff = async (comparisonId: string, a = 0, b = 1): Promise<ComparisonFileDiff[]> => {
+    const response = await fetch(`${API_URL}/api/v2/comparisons/${comparisonId}/diff?a=${a}&b=${b}`);
+    if (!response.ok) {
+        throw new Error(`Failed to fetch comparison diff: ${await response.text()}`);
+    }
+    const data: { files: ComparisonFileDiff[] } = await response.json();
+    return data.files;
+};
+
+// RerunRequest changes a run for a rerun; omitted fields keep the original's values.
+// context picks the files: the run's snapshot (the default when it has one and
+// codebase_paths is not set) or their current contents.
+export interface RerunRequest {
+    agent_revision?: number;
+    prompt?: string;
+    llm_config?: Partial<LLMConfig>;
+    codebase_paths?: string[];
+    variables?: Record<string, string>;
+    context?: 'snapshot' | 'current';
+}
+
+// rerunRun runs a saved run again and returns the new run, saved and linked to the
+// original through rerunOf. A failed agent run still returns a run, with status 'error'.
+export const rerunRun = async (runId: string, request: RerunRequest = {}): Promise<AgentRun> => {
+    const response = await fetch(`${API_URL}/api/v2/runs/${runId}/rerun`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(request),
+    });
+    if (!response.ok) {
+        throw new Error(`Failed to rerun run: ${await response.text()}`);
+    }
+    return response.json();
+};
+
+export const deleteRun = async (runId: string): Promise<{ success: boolean; error?: string }> => {
+    try {
+        const response = await fetch(`${API_URL}/api/v2/runs/${runId}`, {
+            method: 'DELETE',
+        });
+        if (!response.ok) {
+            const errorText = await response.text();
+            return { success: false, error: errorText };
+        }
+        return { success: true };
+    } catch (error) {
+        console.error("Error deleting run:", error);
+        return { success: false, error: (error as Error).message };
+    }
+};
+
+// deleteRuns deletes several runs and returns how many existed.
+export const deleteRuns = async (runIds: string[]): Promise<number> => {
+    const response = await fetch(`${API_URL}/api/v2/runs/delete`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ ids: runIds }),
+    });
+    if (!response.ok) {
+        throw new Error(`Failed to delete runs: ${await response.text()}`);
+    }
+    const result: { deleted: number } = await response.json();
+    return result.deleted;
+};
+
+export interface CompactResult {
+    runs: number;
+    blobs: number;
+    bytes_saved: number;
+}
+
+export interface VacuumResult {
+    size_before: number;
+    size_after: number;
+}
+
+const runMaintenance = async <T>(task: string, body: object = {}): Promise<T> => {
+    const response = await fetch(`${API_URL}/api/v2/maintenance/${task}`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(body),
+    });
+    if (!response.ok) {
+        throw new Error(`Failed to ${task}: ${await response.text()}`);
+    }
+    return response.json();
+};
+
+// pruneRuns deletes the runs outside the retention policy, optionally with other limits,
+// and returns how many it deleted.
+export const pruneRuns = async (limits: { max_age_days?: number; max_runs_per_project?: number } = {}): Promise<number> =>
+    (await runMaintenance<{ deleted: number }>('prune', limits)).deleted;
+
+export const compactRuns = (minSize?: number): Promise<CompactResult> =>
+    runMaintenance<CompactResult>('compact', minSize ? { min_size: minSize } : {});
+
+export const vacuumDatabase = (): Promise<VacuumResult> => runMaintenance<VacuumResult>('vacuum');
+
+export type EvalAssertionType = 'json_path_equals' | 'json_path_matches' | 'touches_path' | 'command_passes' | 'llm_judge';
+
+// EvalAssertion checks a case's output. path is a JSON path such as
+// "file_changes[0].action" for the json_path types and a glob for touches_path.
+export interface EvalAssertion {
+    type: EvalAssertionType;
+    path?: string;
+    value?: unknown;
+    pattern?: string;
+    command?: string;
+    rubric?: string;
+}
+
+export interface EvalCase {
+    name: string;
+    prompt: string;
+    // The fixture project the case runs in, by path.
+    files?: Record<string, string>;
+    variables?: Record<string, string>;
+    assertions: EvalAssertion[];
+    recorded_output?: AgentOutput;
+}
+
+export interface EvalSuite {
+    id: string;
+    agent_id: string;
+    name: string;
+    description?: string;
+    cases: EvalCase[];
+    created_at: string;
+    updated_at: string;
+}
+
+export interface EvalAssertionResult {
+    type: EvalAssertionType;
+    passed: boolean;
+    skipped?: boolean;
+    score: number;
+    message?: string;
+}
+
+export interface EvalCaseResult {
+    name: string;
+    passed: boolean;
+    output?: AgentOutput;
+    error?: string;
+    latency_ms: number;
+    assertions: EvalAssertionResult[];
+}
+
+// EvalReport is a suite's result for one agent revision. score is the share of checked
+// assertions that passed, from 0 to 1.
+export interface EvalReport {
+    id: string;
+    suite_id: string;
+    agent_id: string;
+    agent_revision: number;
+    llm_config: LLMConfig;
+    recorded: boolean;
+    cases: EvalCaseResult[];
+    score: number;
+    passed_cases: number;
+    total_cases: number;
+    created_at: string;
+}
+
+// EvalRunRequest picks what a suite runs against; omitted fields use the agent's latest
+// revision and LLM config. recorded checks the cases' recorded outputs instead of calling
+// the provider, and record saves a live run's outputs as the new recordings.
+export interface EvalRunRequest {
+    agent_revision?: number;
+    llm_config?: Partial<LLMConfig>;
+    recorded?: boolean;
+    record?: boolean;
+    judge_llm_config?: Partial<LLMConfig>;
+}
+
+export const fetchEvalSuites = async (agentId: string): Promise<EvalSuite[]> => {
+    const response = await fetch(`${API_URL}/api/v2/agents/${agentId}/evals`);
+    if (!response.ok) {
+        throw new Error(`Failed to fetch eval suites: ${await response.text()}`);
+    }
+    return response.json();
+};
+
+export const saveEvalSuite = async (agentId: string, suite: Partial<EvalSuite>): Promise<EvalSuite> => {
+    const response = await fetch(`${API_URL}/api/v2/agents/${agentId}/evals`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(suite),
+    });
+    if (!response.ok) {
+        throw new Error(`Failed to save eval suite: ${await response.text()}`);
+    }
+    return response.json();
+};
+
+export const deleteEvalSuite = async (suiteId: string): Promise<void> => {
+    const response = await fetch(`${API_URL}/api/v2/evals/${suiteId}`, { method: 'DELETE' });
+    if (!response.ok) {
+        throw new Error(`Failed to delete eval suite: ${await response.text()}`);
+    }
+};
+
+// runEvalSuite runs a suite and returns its saved report.
+export const runEvalSuite = async (suiteId: string, request: EvalRunRequest = {}): Promise<EvalReport> => {
+    const response = await fetch(`${API_URL}/api/v2/evals/${suiteId}/run`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(request),
+    });
+    if (!response.ok) {
+        throw new Error(`Failed to run eval suite: ${await response.text()}`);
+    }
+    return response.json();
+};
+
+// fetchEvalReports lists a suite's reports, newest first.
+export const fetchEvalReports = async (suiteId: string): Promise<EvalReport[]> => {
+    const response = await fetch(`${API_URL}/api/v2/evals/${suiteId}/reports`);
+    if (!response.ok) {
+        throw new Error(`Failed to fetch eval reports: ${await response.text()}`);
+    }
+    return response.json();
+};
+
+// CachedResponse is a response cache entry; lists leave output out.
+export interface CachedResponse {
+    key: string;
+    provider: string;
+    model: string;
+    output?: AgentOutput;
+    size: number;
+    hits: number;
+    created_at: string;
+    expires_at: string;
+}
+
+export interface ResponseCacheStats {
+    enabled: boolean;
+    ttl_seconds: number;
+    entries: CachedResponse[];
+}
+
+export const fetchResponseCache = async (): Promise<ResponseCacheStats> => {
+    const response = await fetch(`${API_URL}/api/v2/cache`);
+    if (!response.ok) {
+        throw new Error(`Failed to fetch response cache: ${await response.text()}`);
+    }
+    return response.json();
+};
+
+// clearResponseCache deletes every cached response, or only the expired ones, and returns
+// how many it deleted.
+export const clearResponseCache = async (expiredOnly = false): Promise<number> => {
+    const response = await fetch(`${API_URL}/api/v2/cache${expiredOnly ? '?expired=true' : ''}`, { method: 'DELETE' });
+    if (!response.ok) {
+        throw new Error(`Failed to clear response cache: ${await response.text()}`);
+    }
+    const data: { deleted: number } = await response.json();
+    return data.deleted;
+};
+
+export const deleteCachedResponse = async (key: string): Promise<void> => {
+    const response = await fetch(`${API_URL}/api/v2/cache/${key}`, { method: 'DELETE' });
+    if (!response.ok) {
+        throw new Error(`Failed to delete cached response: ${await response.text()}`);
+    }
+};
+
+let socketToken: Promise<string> | undefined;
+
+// socketURL returns the websocket URL of an API path, with the token the backend requires
+// on its sockets. The token is fetched once per page load.
+export const socketURL = async (path: string): Promise<string> => {
+    socketToken ??= fetch(`${API_URL}/api/v2/ws/token`).then(async response => {
+        if (!response.ok) {
+            throw new Error(`Failed to fetch socket token: ${await response.text()}`);
+        }
+        const data: { token: string } = await response.json();
+        return data.token;
+    });
+    let token: string;
+    try {
+        token = await socketToken;
+    } catch (e) {
+        socketToken = undefined;
+        throw e;
+    }
+    return `${API_URL.replace(/^http/, 'ws')}${path}?token=${encodeURIComponent(token)}`;
+};
@@ -4,10 +4,12 @@ import { TreeNodeData } from '../data/file-tree';
 import { AgentPersona } from '../data/agent-personas';
 import { 
     AgentRunRequest, 
+    ContextSnapshot,
     FileChange, 
     fetchDirectoryTree, 
     fetchFileContent, 
     fetchAgents, 
+    syncProjectAgents,
     fetchLLMConfigs,
     createFile as apiCreateFile,
     deleteFile as apiDeleteFile,
@@ -19,6 +21,7 @@ import {
     updateProject,
     fetchRunsForProject,
     saveRun,
+    socketURL,
 } from '../lib/api';
 import { LLMProviderConfig } from "../data/llm-configs";
 import { open } from '@tauri-apps/plugin-dialog';
@@ -53,15 +56,28 @@ export interface AgentOutput {
     completion: number;
     total: number;
   };
+  // Set when the response cache was consulted; hit means no provider call was made.
+  cache?: {
+    hit: boolean;
+    key: string;
+    cached_at?: string;
+  };
 }
 
 export interface AgentRun {
   id: string;
   prompt: string;
   agentName: string;
+  // The agent and the revision of it the run used, so the run can be reproduced.
+  agentId?: string;
+  agentRevision?: number;
   status: AgentStatus;
   output: AgentOutput;
   rawRequest: AgentRunRequest;
+  // The files the run was given, for rerunning it on the same context.
+  contextSnapshot?: ContextSnapshot;
+  // The run this one reruns.
+  rerunOf?: string;
 }
 
 export interface TerminalEntry {
@@ -192,6 +208,10 @@ export const useAppStore = createWithEqualityFn<AppState>((set, get) => ({
         set({ currentProject: project, fileTree: [], openFiles: [], activeFileId: null, contextFilePaths: new Set(), runs: [] });
         if (project.path) {
           get().refreshFileTree();
+          syncProjectAgents(project.id)
+            .catch(error => console.error("Failed to sync project agents:", error))
+            .then(() => fetchAgents(project.path))
+            .then(agents => set({ agents }));
         }
         get().loadRunsForProject(project.id);
     } else {
@@ -386,12 +406,15 @@ export const useAppStore = createWithEqualityFn<AppState>((set, get) => ({
       codebase_paths: codebasePaths,
       project_root: projectRoot,
       llm_config: agent.llmConfig,
+      agent_id: agent.id,
     };
 
+    // agentRevision is set from the run response, which names the revision the server ran.
     const newRun: AgentRun = {
       id: newRunId,
       prompt,
       agentName: agent.name,
+      agentId: agent.id,
       status: 'running',
       output: { summary: '', fileChanges: [], rawOutput: {} },
       rawRequest,
@@ -758,7 +781,19 @@ export const useAppStore = createWithEqualityFn<AppState>((set, get) => ({
       ),
     }));
 
-    const socket = new WebSocket('ws://localhost:2038/api/v2/terminal/ws');
+    let socket: WebSocket;
+    try {
+      socket = new WebSocket(await socketURL('/api/v2/terminal/ws'));
+    } catch (e: any) {
+      set(state => ({
+        terminalSessions: state.terminalSessions.map(session =>
+          session.id === activeTerminalId
+            ? { ...session, history: session.history.map(entry => entry.id === entryId ? { ...entry, isRunning: false, isError: true, output: `[${e.message}]\n` } : entry) }
+            : session
+        ),
+      }));
+      return;
+    }
     terminalSockets.set(activeTerminalId, socket);
 
     socket.onopen = () => {
@@ -0,0 +1,11 @@
+// Package db embeds the SQL migrations into the binary so the server does not
+// depend on the working directory it is started from.
+package db
+
+import "embed"
+
+// Migrations holds the versioned schema migrations. Files are named
+// NNN_description.sql, with an optional NNN_description.down.sql to revert them.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS canvas_runs;
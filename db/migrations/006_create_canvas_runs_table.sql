@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS canvas_runs (
+    id TEXT PRIMARY KEY,
+    canvas_id TEXT NOT NULL,
+    run_data TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (canvas_id) REFERENCES canvases(id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+UPDATE agents SET agent_data = json_remove(agent_data, '$.revision');
+
+DROP TABLE IF EXISTS agent_revisions;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS agent_revisions (
+    agent_id TEXT NOT NULL,
+    revision INTEGER NOT NULL,
+    agent_data TEXT NOT NULL,
+    changelog TEXT NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (agent_id, revision)
+);
+
+INSERT INTO agent_revisions (agent_id, revision, agent_data, created_at)
+SELECT id, 1, agent_data, updated_at FROM agents;
+
+UPDATE agents SET agent_data = json_set(agent_data, '$.revision', 1);
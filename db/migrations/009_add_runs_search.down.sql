@@ -0,0 +1,12 @@
+DROP TRIGGER IF EXISTS runs_fts_update;
+DROP TRIGGER IF EXISTS runs_fts_delete;
+DROP TRIGGER IF EXISTS runs_fts_insert;
+DROP TABLE IF EXISTS runs_fts;
+
+DROP INDEX IF EXISTS idx_runs_project_agent;
+DROP INDEX IF EXISTS idx_runs_project_created;
+
+ALTER TABLE runs DROP COLUMN summary;
+ALTER TABLE runs DROP COLUMN prompt;
+ALTER TABLE runs DROP COLUMN status;
+ALTER TABLE runs DROP COLUMN agent_id;
@@ -0,0 +1,25 @@
+ALTER TABLE runs ADD COLUMN agent_id TEXT GENERATED ALWAYS AS (json_extract(run_data, '$.agentId')) VIRTUAL;
+ALTER TABLE runs ADD COLUMN status TEXT GENERATED ALWAYS AS (json_extract(run_data, '$.status')) VIRTUAL;
+ALTER TABLE runs ADD COLUMN prompt TEXT GENERATED ALWAYS AS (json_extract(run_data, '$.prompt')) VIRTUAL;
+ALTER TABLE runs ADD COLUMN summary TEXT GENERATED ALWAYS AS (json_extract(run_data, '$.output.summary')) VIRTUAL;
+
+CREATE INDEX IF NOT EXISTS idx_runs_project_created ON runs (project_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_runs_project_agent ON runs (project_id, agent_id);
+
+-- runs_fts indexes the prompt and summary of every run, kept in step by the triggers below.
+CREATE VIRTUAL TABLE IF NOT EXISTS runs_fts USING fts5(prompt, summary, content='runs', content_rowid='rowid');
+
+CREATE TRIGGER IF NOT EXISTS runs_fts_insert AFTER INSERT ON runs BEGIN
+    INSERT INTO runs_fts (rowid, prompt, summary) VALUES (new.rowid, new.prompt, new.summary);
+END;
+
+CREATE TRIGGER IF NOT EXISTS runs_fts_delete AFTER DELETE ON runs BEGIN
+    INSERT INTO runs_fts (runs_fts, rowid, prompt, summary) VALUES ('delete', old.rowid, old.prompt, old.summary);
+END;
+
+CREATE TRIGGER IF NOT EXISTS runs_fts_update AFTER UPDATE ON runs BEGIN
+    INSERT INTO runs_fts (runs_fts, rowid, prompt, summary) VALUES ('delete', old.rowid, old.prompt, old.summary);
+    INSERT INTO runs_fts (rowid, prompt, summary) VALUES (new.rowid, new.prompt, new.summary);
+END;
+
+INSERT INTO runs_fts (runs_fts) VALUES ('rebuild');
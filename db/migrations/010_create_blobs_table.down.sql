@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS run_blobs;
+DROP TABLE IF EXISTS blobs;
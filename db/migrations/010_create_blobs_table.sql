@@ -0,0 +1,18 @@
+-- blobs holds large file contents moved out of run records, keyed by their SHA-256 so that
+-- runs sharing a file share one copy. run_blobs records which runs refer to which blobs.
+CREATE TABLE IF NOT EXISTS blobs (
+    hash TEXT PRIMARY KEY,
+    content TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS run_blobs (
+    run_id TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    PRIMARY KEY (run_id, hash),
+    FOREIGN KEY (run_id) REFERENCES runs(id) ON DELETE CASCADE,
+    FOREIGN KEY (hash) REFERENCES blobs(hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_run_blobs_hash ON run_blobs (hash);
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS eval_suites (
+    id TEXT PRIMARY KEY,
+    agent_id TEXT NOT NULL,
+    suite_data TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_eval_suites_agent ON eval_suites (agent_id);
+
+-- eval_reports keeps every run of a suite, so that scores can be compared across agent
+-- revisions.
+CREATE TABLE IF NOT EXISTS eval_reports (
+    id TEXT PRIMARY KEY,
+    suite_id TEXT NOT NULL,
+    agent_revision INTEGER NOT NULL DEFAULT 0,
+    report_data TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (suite_id) REFERENCES eval_suites(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_eval_reports_suite_created ON eval_reports (suite_id, created_at DESC);
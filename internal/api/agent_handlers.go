@@ -1,16 +1,23 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
 
+	"github.com/ClarionDev/clarion/internal/bundle"
 	"github.com/ClarionDev/clarion/internal/llm"
 	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/projectconfig"
+	"github.com/ClarionDev/clarion/internal/runner"
+	"github.com/ClarionDev/clarion/internal/storage"
+	"github.com/ClarionDev/clarion/internal/worktree"
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
 func (s *Server) handleAgentRun(w http.ResponseWriter, r *http.Request) {
@@ -22,39 +29,47 @@ func (s *Server) handleAgentRun(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("Agent run initiated with prompt: '%s' using provider: %s, model: %s", apiReq.Prompt, apiReq.LLMConfig.Provider, apiReq.LLMConfig.Model)
 
-	internalReq := models.AgentRunRequest{
-		SystemInstruction: apiReq.SystemInstruction,
-		Prompt:            apiReq.Prompt,
-		OutputSchema:      apiReq.OutputSchema,
-		LLMConfig:         apiReq.LLMConfig,
-	}
-
-	provider, err := llm.GetProvider(internalReq.LLMConfig.Provider)
+	runReq, revision, err := s.runRequest(r.Context(), apiReq)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get LLM provider: %v", err), http.StatusInternalServerError)
+		writeLookupError(w, err)
 		return
 	}
 
-	codebaseContent, err := s.readCodebaseFiles(apiReq.ProjectRoot, apiReq.CodebasePaths)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to read codebase files: %v", err), http.StatusInternalServerError)
-		return
+	progress := s.runProgress(apiReq.RunID)
+	progress("started", "", nil)
+
+	var wt *worktree.Worktree
+	if apiReq.Isolate {
+		if wt, err = s.runWorktree(r.Context(), apiReq.RunID, apiReq.ProjectRoot); err != nil {
+			progress("failed", "", err)
+			writeWorktreeError(w, "isolate run", err)
+			return
+		}
+		runReq.ProjectRoot = wt.ProjectDir
 	}
 
-	messages, err := llm.BuildChatMessages(internalReq, codebaseContent)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to build chat messages: %v", err), http.StatusInternalServerError)
+	// The context is read here rather than by the runner so that it can be returned with
+	// the output and kept with the run, to replay it later.
+	progress("reading_context", fmt.Sprintf("Reading %d files", len(runReq.CodebasePaths)), nil)
+	if runReq.Context, err = runner.Snapshot(r.Context(), runReq.ProjectRoot, runReq.CodebasePaths, runReq.GitContext); err != nil {
+		progress("failed", "", err)
+		writeRunError(w, "Agent run failed", err)
 		return
 	}
 
-	output, err := provider.Generate(r.Context(), messages, internalReq, s.llmConfigStore)
+	output, err := s.runner.Run(r.Context(), runReq, progress)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("LLM generation failed: %v", err), http.StatusInternalServerError)
+		progress("failed", "", err)
+		writeRunError(w, "Agent run failed", err)
 		return
 	}
+	progress("completed", "", nil)
 
 	resp := AgentRunResponse{
-		Output: output,
+		Output:          output,
+		Worktree:        wt,
+		AgentRevision:   revision,
+		ContextSnapshot: runReq.Context,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -69,20 +84,26 @@ func (s *Server) handlePreparePrompt(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create an internal request model (same as in handleAgentRun)
-	internalReq := models.AgentRunRequest{
-		SystemInstruction: apiReq.SystemInstruction,
-		Prompt:            apiReq.Prompt,
-		OutputSchema:      apiReq.OutputSchema,
-		LLMConfig:         apiReq.LLMConfig,
+	runReq, _, err := s.runRequest(r.Context(), apiReq)
+	if err != nil {
+		writeLookupError(w, err)
+		return
+	}
+
+	// Fill in variables the same way a live run does.
+	internalReq, err := runner.PrepareRequest(r.Context(), runReq)
+	if err != nil {
+		writeRunError(w, "Failed to render prompt", err)
+		return
 	}
 
 	// Read codebase files (same as in handleAgentRun)
-	codebaseContent, err := s.readCodebaseFiles(apiReq.ProjectRoot, apiReq.CodebasePaths)
+	codebaseContent, diff, err := runner.BuildContext(r.Context(), runReq.ProjectRoot, runReq.CodebasePaths, runReq.GitContext)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to read codebase files: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to read codebase files: %v", err), runErrorStatus(err))
 		return
 	}
+	internalReq.Diff = diff
 
 	// 1. Build the chat messages using the SAME function as a live run.
 	messages, err := llm.BuildChatMessages(internalReq, codebaseContent)
@@ -114,42 +135,57 @@ func (s *Server) handlePreparePrompt(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
-func (s *Server) readCodebaseFiles(projectRoot string, codebasePaths []string) (map[string]string, error) {
-	contentMap := make(map[string]string)
-	for _, relPath := range codebasePaths {
-		absPath := filepath.Join(projectRoot, relPath)
-		fileContent, err := os.ReadFile(absPath)
-		if err != nil {
-			log.Printf("Skipping file %s due to read error: %v", relPath, err)
-			continue // Or handle error more gracefully
-		}
-		contentMap[relPath] = string(fileContent)
-	}
-	return contentMap, nil
-}
-
+// handleSaveAgent validates and saves an agent, assigning an ID to new ones, appending a
+// revision if it changed, and returns the saved agent. The body is the agent, with an
+// optional "changelog" field describing the change.
 func (s *Server) handleSaveAgent(w http.ResponseWriter, r *http.Request) {
-	var agentToSave models.Agent
-	if err := json.NewDecoder(r.Body).Decode(&agentToSave); err != nil {
+	var req struct {
+		models.Agent
+		Changelog string `json:"changelog"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
 		return
 	}
+	agentToSave := req.Agent
+	if agentToSave.Profile.ID == "" {
+		agentToSave.Profile.ID = uuid.New().String()
+	}
+	if err := agentToSave.Validate(s.lookupLLMConfig(r.Context())); err != nil {
+		writeValidationError(w, err)
+		return
+	}
 
-	if err := s.agentStore.SaveAgent(r.Context(), &agentToSave); err != nil {
+	// Agents from a project's .clarion/agents directory stay with that project.
+	if existing, err := s.agentStore.GetAgent(r.Context(), agentToSave.Profile.ID); err == nil {
+		agentToSave.ProjectPath = existing.ProjectPath
+	}
+
+	if _, err := s.agentStore.SaveAgentRevision(r.Context(), &agentToSave, req.Changelog); err != nil {
 		http.Error(w, fmt.Sprintf("Failed to save agent: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Agent saved successfully"))
+	writeJSON(w, agentToSave)
 }
 
+// handleListAgents lists the saved agents. With a "project_root" query parameter, the
+// agents loaded from that project's .clarion/agents directory are listed too; agents of
+// other projects are always left out. The directory is loaded when the project is opened
+// or synced, not here.
 func (s *Server) handleListAgents(w http.ResponseWriter, r *http.Request) {
-	agents, err := s.agentStore.ListAgents(r.Context())
+	projectRoot := r.URL.Query().Get("project_root")
+	all, err := s.agentStore.ListAgents(r.Context())
 	if err != nil {
 		http.Error(w, "Failed to list agents", http.StatusInternalServerError)
 		return
 	}
+	agents := make([]*models.Agent, 0, len(all))
+	for _, agent := range all {
+		if agent.ProjectPath == "" || agent.ProjectPath == projectRoot {
+			agents = append(agents, agent)
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -173,3 +209,179 @@ func (s *Server) handleDeleteAgent(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Agent deleted successfully"))
 }
+
+// handleExportAgent returns an agent as a YAML bundle.
+func (s *Server) handleExportAgent(w http.ResponseWriter, r *http.Request) {
+	agentID := chi.URLParam(r, "agentID")
+	agent, err := s.agentStore.GetAgent(r.Context(), agentID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, fmt.Sprintf("Failed to get agent: %v", err), status)
+		return
+	}
+
+	data, err := bundle.Marshal(agent)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to export agent: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", agentID+".yml"))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// handleImportAgent saves the agent in a YAML bundle and returns it. An agent without an ID
+// is given one; an existing agent is only replaced with "overwrite=true", and keeps its LLM
+// config if the provider is unchanged.
+func (s *Server) handleImportAgent(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	agent, err := bundle.Unmarshal(data)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to import agent: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if agent.Profile.ID == "" {
+		agent.Profile.ID = uuid.New().String()
+	} else if existing, err := s.agentStore.GetAgent(r.Context(), agent.Profile.ID); err == nil {
+		if r.URL.Query().Get("overwrite") != "true" {
+			http.Error(w, fmt.Sprintf("An agent with id '%s' already exists", agent.Profile.ID), http.StatusConflict)
+			return
+		}
+		if existing.LLMConfig.Provider == agent.LLMConfig.Provider {
+			agent.LLMConfig.ConfigID = existing.LLMConfig.ConfigID
+		}
+	}
+
+	if err := agent.Validate(s.lookupLLMConfig(r.Context())); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	if err := s.agentStore.SaveAgent(r.Context(), agent); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save agent: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(agent)
+}
+
+// lookupLLMConfig returns the config lookup used to validate agents.
+func (s *Server) lookupLLMConfig(ctx context.Context) func(id string) (string, bool) {
+	return func(id string) (string, bool) {
+		config, err := s.llmConfigStore.GetLLMConfig(ctx, id)
+		if err != nil {
+			return "", false
+		}
+		return config.Provider, true
+	}
+}
+
+// writeValidationError reports an invalid model with its field errors and status 422.
+func writeValidationError(w http.ResponseWriter, err error) {
+	var invalid *models.ValidationError
+	if !errors.As(err, &invalid) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(ValidationErrorResponse{Error: "Invalid " + invalid.Subject, Fields: invalid.Fields})
+}
+
+// runRequest builds the runner request for an API run and returns it with the revision of
+// the named agent it uses. A named agent adds its declared user variables, a pinned agent
+// revision its prompt, schema and LLM config, a named prompt template its messages, and an
+// opened project its name.
+func (s *Server) runRequest(ctx context.Context, apiReq AgentRunRequest) (runner.Request, int, error) {
+	req := runner.Request{
+		SystemInstruction: apiReq.SystemInstruction,
+		Prompt:            apiReq.Prompt,
+		OutputSchema:      apiReq.OutputSchema,
+		LLMConfig:         apiReq.LLMConfig,
+		ProjectRoot:       apiReq.ProjectRoot,
+		CodebasePaths:     apiReq.CodebasePaths,
+		GitContext:        apiReq.GitContext,
+		Variables:         apiReq.Variables,
+		Cache:             apiReq.Cache,
+	}
+	var revision int
+	if apiReq.AgentID != "" {
+		agent, err := s.agentStore.GetAgent(ctx, apiReq.AgentID)
+		if err != nil {
+			return req, 0, err
+		}
+		if apiReq.AgentRevision != 0 && apiReq.AgentRevision != agent.Revision {
+			pinned, err := s.agentStore.GetAgentRevision(ctx, apiReq.AgentID, apiReq.AgentRevision)
+			if err != nil {
+				return req, 0, err
+			}
+			agent = &pinned.Agent
+			req.SystemInstruction = agent.SystemPrompt
+			req.OutputSchema = map[string]any{"schema": agent.OutputSchema.Schema}
+			req.LLMConfig = agent.LLMConfig
+		}
+		req.UserVariables = agent.UserVariables
+		revision = agent.Revision
+	}
+	if apiReq.PromptTemplateID != "" {
+		t, err := s.promptTemplateStore.GetPromptTemplate(ctx, apiReq.PromptTemplateID)
+		if err != nil {
+			return req, 0, err
+		}
+		req.Messages = t.Messages
+	}
+	if apiReq.ProjectRoot != "" {
+		if project, err := s.projectStore.GetProjectByPath(ctx, apiReq.ProjectRoot); err == nil {
+			req.ProjectName = project.Name
+		}
+		settings := s.projectSettings(ctx, apiReq.ProjectRoot)
+		req.CodebasePaths = projectconfig.ContextPaths(settings, req.CodebasePaths)
+		if req.LLMConfig.ConfigID == "" {
+			req.LLMConfig.ConfigID = projectconfig.LLMConfigID(ctx, s.llmConfigStore, settings, req.LLMConfig.Provider)
+		}
+	}
+	return req, revision, nil
+}
+
+// writeLookupError reports a failure to load the agent or template a run refers to.
+func writeLookupError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, storage.ErrNotFound) {
+		status = http.StatusNotFound
+	}
+	http.Error(w, fmt.Sprintf("Failed to prepare run: %v", err), status)
+}
+
+// writeRunError reports an error from preparing or running an agent. Missing required
+// variables are listed in a 422 response.
+func writeRunError(w http.ResponseWriter, prefix string, err error) {
+	var missing *runner.MissingVariablesError
+	if errors.As(err, &missing) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(MissingVariablesResponse{Error: err.Error(), Missing: missing.Names})
+		return
+	}
+	http.Error(w, fmt.Sprintf("%s: %v", prefix, err), runErrorStatus(err))
+}
+
+// runErrorStatus returns the HTTP status for an error from preparing a run's prompt or
+// context.
+func runErrorStatus(err error) int {
+	if errors.Is(err, runner.ErrInvalidContextSelection) || errors.Is(err, runner.ErrInvalidTemplate) || errors.Is(err, runner.ErrInvalidCachePolicy) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
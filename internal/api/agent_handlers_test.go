@@ -0,0 +1,40 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ClarionDev/clarion/internal/models"
+)
+
+func TestRunRequestProjectDefaultConfig(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	root := t.TempDir()
+	if err := s.llmConfigStore.SaveLLMConfig(ctx, &models.LLMProviderConfig{ID: "cfg-openai", Name: "OpenAI", Provider: models.ProviderOpenAI, APIKey: "sk-test"}); err != nil {
+		t.Fatal(err)
+	}
+	project := &models.Project{ID: "p1", Name: "Project", Path: root, Settings: models.ProjectSettings{LLMConfigID: "cfg-openai"}}
+	if err := s.projectStore.SaveProject(ctx, project); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		config models.LLMConfig
+		want   string
+	}{
+		{"same provider", models.LLMConfig{Provider: models.ProviderOpenAI, Model: "gpt-4o"}, "cfg-openai"},
+		{"other provider", models.LLMConfig{Provider: models.ProviderGoogle, Model: "gemini-2.5-pro"}, ""},
+		{"explicit config", models.LLMConfig{Provider: models.ProviderOpenAI, ConfigID: "cfg-other"}, "cfg-other"},
+	}
+	for _, tt := range tests {
+		req, _, err := s.runRequest(ctx, AgentRunRequest{Prompt: "hi", ProjectRoot: root, LLMConfig: tt.config})
+		if err != nil {
+			t.Fatalf("%s: runRequest() error = %v", tt.name, err)
+		}
+		if req.LLMConfig.ConfigID != tt.want {
+			t.Errorf("%s: ConfigID = %q, want %q", tt.name, req.LLMConfig.ConfigID, tt.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/storage"
+	"github.com/ClarionDev/clarion/internal/textdiff"
+	"github.com/go-chi/chi/v5"
+)
+
+func (s *Server) handleListAgentRevisions(w http.ResponseWriter, r *http.Request) {
+	revisions, err := s.agentStore.ListAgentRevisions(r.Context(), chi.URLParam(r, "agentID"))
+	if err != nil {
+		http.Error(w, "Failed to list agent revisions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(revisions); err != nil {
+		log.Printf("Failed to write agent revision list response: %v", err)
+	}
+}
+
+func (s *Server) handleGetAgentRevision(w http.ResponseWriter, r *http.Request) {
+	revision, ok := s.agentRevision(w, r, chi.URLParam(r, "revision"))
+	if !ok {
+		return
+	}
+	writeJSON(w, revision)
+}
+
+// handleDiffAgentRevisions compares two revisions of an agent, given by the "from" and "to"
+// query parameters. "to" defaults to the latest revision and "from" to the one before it.
+func (s *Server) handleDiffAgentRevisions(w http.ResponseWriter, r *http.Request) {
+	agentID := chi.URLParam(r, "agentID")
+	toParam, fromParam := r.URL.Query().Get("to"), r.URL.Query().Get("from")
+	if toParam == "" {
+		agent, err := s.agentStore.GetAgent(r.Context(), agentID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get agent: %v", err), http.StatusNotFound)
+			return
+		}
+		toParam = strconv.Itoa(agent.Revision)
+	}
+	to, ok := s.agentRevision(w, r, toParam)
+	if !ok {
+		return
+	}
+	if fromParam == "" {
+		fromParam = strconv.Itoa(to.Revision - 1)
+	}
+	from, ok := s.agentRevision(w, r, fromParam)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, AgentRevisionDiff{
+		AgentID: agentID,
+		From:    from.Revision,
+		To:      to.Revision,
+		Changes: diffAgents(&from.Agent, &to.Agent, from.Revision, to.Revision),
+	})
+}
+
+// handleRollbackAgent saves a past revision of an agent as its newest revision. The agent
+// stays with its project, if it has one.
+func (s *Server) handleRollbackAgent(w http.ResponseWriter, r *http.Request) {
+	var req RollbackAgentRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	target, ok := s.agentRevision(w, r, chi.URLParam(r, "revision"))
+	if !ok {
+		return
+	}
+	current, err := s.agentStore.GetAgent(r.Context(), target.AgentID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get agent: %v", err), http.StatusNotFound)
+		return
+	}
+
+	agent := target.Agent
+	agent.ProjectPath = current.ProjectPath
+	changelog := req.Changelog
+	if changelog == "" {
+		changelog = fmt.Sprintf("Rolled back to revision %d", target.Revision)
+	}
+	revision, err := s.agentStore.SaveAgentRevision(r.Context(), &agent, changelog)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to roll back agent: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, revision)
+}
+
+// agentRevision loads the named revision of the agent in the URL, writing the error
+// response if it cannot.
+func (s *Server) agentRevision(w http.ResponseWriter, r *http.Request, param string) (*models.AgentRevision, bool) {
+	number, err := strconv.Atoi(param)
+	if err != nil || number < 1 {
+		http.Error(w, fmt.Sprintf("Invalid revision %q", param), http.StatusBadRequest)
+		return nil, false
+	}
+	revision, err := s.agentStore.GetAgentRevision(r.Context(), chi.URLParam(r, "agentID"), number)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, fmt.Sprintf("Failed to get agent revision: %v", err), status)
+		return nil, false
+	}
+	return revision, true
+}
+
+// diffAgents compares the parts of two agents that shape a run. The system prompt is
+// compared as text and the other parts as indented JSON; unchanged parts are left out.
+func diffAgents(from, to *models.Agent, fromRevision, toRevision int) []AgentFieldDiff {
+	fields := []struct {
+		name     string
+		from, to any
+	}{
+		{"profile", from.Profile, to.Profile},
+		{"system_prompt", from.SystemPrompt, to.SystemPrompt},
+		{"output_schema", from.OutputSchema.Schema, to.OutputSchema.Schema},
+		{"codebase_filters", from.CodebaseFilters, to.CodebaseFilters},
+		{"user_variables", from.UserVariables, to.UserVariables},
+		{"llm_config", from.LLMConfig, to.LLMConfig},
+	}
+
+	changes := []AgentFieldDiff{}
+	for _, f := range fields {
+		diff := textdiff.Unified(
+			fmt.Sprintf("revision %d/%s", fromRevision, f.name),
+			fmt.Sprintf("revision %d/%s", toRevision, f.name),
+			diffText(f.from), diffText(f.to))
+		if diff != "" {
+			changes = append(changes, AgentFieldDiff{Field: f.name, Diff: diff})
+		}
+	}
+	return changes
+}
+
+func diffText(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, _ := json.MarshalIndent(v, "", "  ")
+	return string(data)
+}
@@ -1,6 +1,11 @@
 package api
 
-import "github.com/ClarionDev/clarion/internal/models"
+import (
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/runner"
+	"github.com/ClarionDev/clarion/internal/shell"
+	"github.com/ClarionDev/clarion/internal/worktree"
+)
 
 type LoadDirectoryRequest struct {
 	Path string `json:"path"`
@@ -23,25 +28,53 @@ type ReadFilesResponse struct {
 }
 
 type AgentRunRequest struct {
-	SystemInstruction string           `json:"system_instruction"`
-	Prompt              string           `json:"prompt"`
-	CodebasePaths       []string         `json:"codebase_paths"`
-	OutputSchema        map[string]any   `json:"output_schema"`
-	ProjectRoot         string           `json:"project_root"`
-	LLMConfig           models.LLMConfig `json:"llm_config"`
+	RunID             string             `json:"run_id,omitempty"`
+	SystemInstruction string             `json:"system_instruction"`
+	Prompt            string             `json:"prompt"`
+	CodebasePaths     []string           `json:"codebase_paths"`
+	OutputSchema      map[string]any     `json:"output_schema"`
+	ProjectRoot       string             `json:"project_root"`
+	LLMConfig         models.LLMConfig   `json:"llm_config"`
+	GitContext        *runner.GitContext `json:"git_context,omitempty"`
+	// Isolate runs the agent in a git worktree of its own, created from HEAD on first use.
+	// It requires a run ID.
+	Isolate bool `json:"isolate,omitempty"`
+	// AgentID names the saved agent whose user variables are checked and filled from
+	// Variables.
+	AgentID   string            `json:"agent_id,omitempty"`
+	Variables map[string]string `json:"variables,omitempty"`
+	// AgentRevision pins the run to a revision of the agent, whose system prompt, output
+	// schema and LLM config then replace those in the request.
+	AgentRevision int `json:"agent_revision,omitempty"`
+	// PromptTemplateID names a saved prompt template whose messages are sent in place of
+	// the system instruction and prompt pair.
+	PromptTemplateID string `json:"prompt_template_id,omitempty"`
+	// Cache is the run's response cache policy: off, on or force. By default the cache is
+	// used when the server enables it and the temperature is 0.
+	Cache string `json:"cache,omitempty"`
 }
 
 type AgentRunResponse struct {
-	Output map[string]any `json:"output"`
+	Output   map[string]any     `json:"output"`
+	Worktree *worktree.Worktree `json:"worktree,omitempty"`
+	// AgentRevision is the revision of the named agent the run used.
+	AgentRevision int `json:"agent_revision,omitempty"`
+	// ContextSnapshot is the codebase context the run was given. Kept with the run as
+	// contextSnapshot, it lets the run be replayed on the same files.
+	ContextSnapshot *runner.ContextSnapshot `json:"context_snapshot,omitempty"`
 }
 
 type AgentPreparePromptRequest struct {
-	SystemInstruction string           `json:"system_instruction"`
-	Prompt              string           `json:"prompt"`
-	CodebasePaths       []string         `json:"codebase_paths"`
-	OutputSchema        map[string]any   `json:"output_schema"`
-	ProjectRoot         string           `json:"project_root"`
-	LLMConfig           models.LLMConfig `json:"llm_config"`
+	SystemInstruction string             `json:"system_instruction"`
+	Prompt            string             `json:"prompt"`
+	CodebasePaths     []string           `json:"codebase_paths"`
+	OutputSchema      map[string]any     `json:"output_schema"`
+	ProjectRoot       string             `json:"project_root"`
+	LLMConfig         models.LLMConfig   `json:"llm_config"`
+	GitContext        *runner.GitContext `json:"git_context,omitempty"`
+	AgentID           string             `json:"agent_id,omitempty"`
+	Variables         map[string]string  `json:"variables,omitempty"`
+	PromptTemplateID  string             `json:"prompt_template_id,omitempty"`
 }
 
 type AgentPreparePromptResponse struct {
@@ -49,14 +82,63 @@ type AgentPreparePromptResponse struct {
 	JSONPrompt     string `json:"jsonPrompt"`
 }
 
+// MissingVariablesResponse is returned with status 422 when required user variables have
+// no value.
+type MissingVariablesResponse struct {
+	Error   string   `json:"error"`
+	Missing []string `json:"missing"`
+}
+
+// ValidationErrorResponse is returned with status 422 when an agent fails validation.
+type ValidationErrorResponse struct {
+	Error  string              `json:"error"`
+	Fields []models.FieldError `json:"fields"`
+}
+
+// ProjectSettingsResponse describes a project's settings. Settings are the ones saved in the
+// app, File the ones in .clarion/project.yml (nil when there is no file), and Effective the
+// merged settings runs use, where fields set in the file win.
+type ProjectSettingsResponse struct {
+	Settings  models.ProjectSettings  `json:"settings"`
+	File      *models.ProjectSettings `json:"file"`
+	FilePath  string                  `json:"file_path"`
+	Effective models.ProjectSettings  `json:"effective"`
+}
+
+type UpdateProjectSettingsRequest struct {
+	Settings models.ProjectSettings `json:"settings"`
+	// WriteFile also writes the settings to .clarion/project.yml, to share them through
+	// the repository.
+	WriteFile bool `json:"write_file,omitempty"`
+}
+
+type RollbackAgentRequest struct {
+	Changelog string `json:"changelog,omitempty"`
+}
+
+type AgentFieldDiff struct {
+	Field string `json:"field"`
+	Diff  string `json:"diff"`
+}
+
+type AgentRevisionDiff struct {
+	AgentID string           `json:"agent_id"`
+	From    int              `json:"from"`
+	To      int              `json:"to"`
+	Changes []AgentFieldDiff `json:"changes"`
+}
+
 type SaveAgentRequest struct {
 	Agent models.Agent `json:"agent"`
 }
 
+// PreviewFilterRequest previews an agent's filters. With ProjectRoot set, the project's
+// default globs are merged into them as they are for runs.
 type PreviewFilterRequest struct {
 	FilePaths    []string `json:"file_paths"`
 	IncludeGlobs []string `json:"include_globs"`
 	ExcludeGlobs []string `json:"exclude_globs"`
+	ProjectRoot  string   `json:"project_root,omitempty"`
 }
 
 type PreviewFilterResponse struct {
@@ -81,12 +163,59 @@ type WriteFileRequest struct {
 }
 
 type TokenCountRequest struct {
-	AgentID       string   `json:"agent_id"`
-	UserPrompt    string   `json:"user_prompt"`
-	CodebasePaths []string `json:"codebase_paths"`
-	ProjectRoot   string   `json:"project_root"`
+	AgentID       string             `json:"agent_id"`
+	UserPrompt    string             `json:"user_prompt"`
+	CodebasePaths []string           `json:"codebase_paths"`
+	ProjectRoot   string             `json:"project_root"`
+	GitContext    *runner.GitContext `json:"git_context,omitempty"`
 }
 
 type TokenCountResponse struct {
 	TokenCount int `json:"token_count"`
 }
+
+type LLMConfigTestResponse struct {
+	OK        bool   `json:"ok"`
+	Status    string `json:"status"` // "ok", "invalid_key", "error"
+	Message   string `json:"message"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+type CreateWorktreeRequest struct {
+	ProjectRoot string `json:"project_root"`
+}
+
+// WorktreeApplyRequest commits a run's changes in its worktree and then runs the
+// verification commands there, in order, stopping at the first failure.
+type WorktreeApplyRequest struct {
+	Changes        []FileChange `json:"changes"`
+	VerifyCommands []string     `json:"verify_commands,omitempty"`
+}
+
+type WorktreeApplyResponse struct {
+	Worktree     *worktree.Worktree     `json:"worktree"`
+	Commit       *GitCommit             `json:"commit"`
+	Verification []*shell.CommandResult `json:"verification"`
+	Passed       bool                   `json:"passed"`
+}
+
+type WorktreeIntegrateRequest struct {
+	Mode string `json:"mode"` // "merge" (default) or "cherry-pick"
+}
+
+type ValidateCanvasResponse struct {
+	Valid    bool     `json:"valid"`
+	Problems []string `json:"problems"`
+}
+
+type RunCanvasRequest struct {
+	ProjectRoot string            `json:"project_root"`
+	Inputs      map[string]string `json:"inputs"`
+}
+
+type ApproveCanvasNodeRequest struct {
+	Approved bool   `json:"approved"`
+	Comment  string `json:"comment,omitempty"`
+	// Changes, if set, replace the file changes the approval node was given.
+	Changes []map[string]any `json:"changes,omitempty"`
+}
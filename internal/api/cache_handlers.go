@@ -0,0 +1,58 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+type ResponseCacheResponse struct {
+	// Enabled tells whether runs use the cache unless they opt out.
+	Enabled    bool                     `json:"enabled"`
+	TTLSeconds int64                    `json:"ttl_seconds"`
+	Entries    []*models.CachedResponse `json:"entries"`
+}
+
+type ClearCacheResponse struct {
+	Deleted int `json:"deleted"`
+}
+
+// handleListCache lists the cached responses, without their outputs, and the cache
+// settings.
+func (s *Server) handleListCache(w http.ResponseWriter, r *http.Request) {
+	entries, err := s.responseCacheStore.ListCachedResponses(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list cached responses: %v", err), http.StatusInternalServerError)
+		return
+	}
+	enabled, ttl := s.settings.ResponseCache()
+	writeJSON(w, ResponseCacheResponse{Enabled: enabled, TTLSeconds: int64(ttl.Seconds()), Entries: entries})
+}
+
+// handleClearCache deletes every cached response, or only the expired ones with
+// ?expired=true.
+func (s *Server) handleClearCache(w http.ResponseWriter, r *http.Request) {
+	expiredOnly := r.URL.Query().Get("expired") == "true"
+	deleted, err := s.responseCacheStore.ClearResponseCache(r.Context(), expiredOnly)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to clear response cache: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, ClearCacheResponse{Deleted: deleted})
+}
+
+func (s *Server) handleDeleteCachedResponse(w http.ResponseWriter, r *http.Request) {
+	if err := s.responseCacheStore.DeleteCachedResponse(r.Context(), chi.URLParam(r, "key")); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, fmt.Sprintf("Failed to delete cached response: %v", err), status)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,229 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/ClarionDev/clarion/internal/canvas"
+	"github.com/ClarionDev/clarion/internal/storage"
+	"github.com/ClarionDev/clarion/internal/workflow"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func (s *Server) handleListCanvases(w http.ResponseWriter, r *http.Request) {
+	canvases, err := s.canvasStore.ListCanvases(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to list canvases", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(canvases); err != nil {
+		log.Printf("Failed to write canvas list response: %v", err)
+	}
+}
+
+func (s *Server) handleGetCanvas(w http.ResponseWriter, r *http.Request) {
+	c, err := s.canvasStore.GetCanvas(r.Context(), chi.URLParam(r, "canvasID"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, fmt.Sprintf("Failed to get canvas: %v", err), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(c)
+}
+
+// handleSaveCanvas validates and stores a canvas, assigning an ID to new ones, and returns
+// the saved canvas.
+func (s *Server) handleSaveCanvas(w http.ResponseWriter, r *http.Request) {
+	var c canvas.Canvas
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := s.validateCanvas(r.Context(), &c); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
+	if err := s.canvasStore.SaveCanvas(r.Context(), &c); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save canvas: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(c)
+}
+
+// handleValidateCanvas reports the problems of a canvas without saving it.
+func (s *Server) handleValidateCanvas(w http.ResponseWriter, r *http.Request) {
+	var c canvas.Canvas
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp := ValidateCanvasResponse{Valid: true, Problems: []string{}}
+	if err := s.validateCanvas(r.Context(), &c); err != nil {
+		var verr *canvas.ValidationError
+		if !errors.As(err, &verr) {
+			http.Error(w, fmt.Sprintf("Failed to validate canvas: %v", err), http.StatusInternalServerError)
+			return
+		}
+		resp = ValidateCanvasResponse{Valid: false, Problems: verr.Problems}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// validateCanvas checks the graph and that the agents its nodes refer to exist.
+func (s *Server) validateCanvas(ctx context.Context, c *canvas.Canvas) error {
+	var problems []string
+	var verr *canvas.ValidationError
+	if err := c.Validate(); errors.As(err, &verr) {
+		problems = verr.Problems
+	} else if err != nil {
+		return err
+	}
+
+	for _, n := range c.Nodes {
+		if n.Type != canvas.NodeAgent || n.Agent == nil || n.Agent.AgentID == "" {
+			continue
+		}
+		if _, err := s.agentStore.GetAgent(ctx, n.Agent.AgentID); err != nil {
+			problems = append(problems, fmt.Sprintf("node %q: agent %q does not exist", n.ID, n.Agent.AgentID))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &canvas.ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func (s *Server) handleDeleteCanvas(w http.ResponseWriter, r *http.Request) {
+	canvasID := chi.URLParam(r, "canvasID")
+	if canvasID == "" {
+		http.Error(w, "Canvas ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.canvasStore.DeleteCanvas(r.Context(), canvasID); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete canvas: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Canvas deleted successfully"))
+}
+
+// writeWorkflowError maps workflow and store errors to HTTP status codes.
+func writeWorkflowError(w http.ResponseWriter, action string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, canvas.ErrInvalid), errors.Is(err, workflow.ErrNoChanges):
+		status = http.StatusBadRequest
+	case errors.Is(err, workflow.ErrRunActive), errors.Is(err, workflow.ErrRunFinished), errors.Is(err, workflow.ErrNotWaiting):
+		status = http.StatusConflict
+	}
+	http.Error(w, fmt.Sprintf("Failed to %s: %v", action, err), status)
+}
+
+// handleRunCanvas starts a run of a saved canvas and returns it right away. Progress is
+// published on the run's channel and the run can be fetched from /canvas-runs/{runID}.
+func (s *Server) handleRunCanvas(w http.ResponseWriter, r *http.Request) {
+	var req RunCanvasRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.ProjectRoot == "" {
+		http.Error(w, "Project root cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	c, err := s.canvasStore.GetCanvas(r.Context(), chi.URLParam(r, "canvasID"))
+	if err != nil {
+		writeWorkflowError(w, "get canvas", err)
+		return
+	}
+	if err := s.validateCanvas(r.Context(), c); err != nil {
+		writeWorkflowError(w, "run canvas", err)
+		return
+	}
+
+	run, err := s.workflows.Start(r.Context(), c, req.ProjectRoot, req.Inputs)
+	if err != nil {
+		writeWorkflowError(w, "run canvas", err)
+		return
+	}
+	writeJSON(w, run)
+}
+
+func (s *Server) handleListCanvasRuns(w http.ResponseWriter, r *http.Request) {
+	runs, err := s.canvasRunStore.ListCanvasRuns(r.Context(), chi.URLParam(r, "canvasID"))
+	if err != nil {
+		writeWorkflowError(w, "list canvas runs", err)
+		return
+	}
+	writeJSON(w, runs)
+}
+
+func (s *Server) handleGetCanvasRun(w http.ResponseWriter, r *http.Request) {
+	run, err := s.canvasRunStore.GetCanvasRun(r.Context(), chi.URLParam(r, "runID"))
+	if err != nil {
+		writeWorkflowError(w, "get canvas run", err)
+		return
+	}
+	writeJSON(w, run)
+}
+
+// handleResumeCanvasRun runs a failed or interrupted canvas run again from the nodes that
+// did not complete.
+func (s *Server) handleResumeCanvasRun(w http.ResponseWriter, r *http.Request) {
+	run, err := s.workflows.Resume(r.Context(), chi.URLParam(r, "runID"))
+	if err != nil {
+		writeWorkflowError(w, "resume canvas run", err)
+		return
+	}
+	writeJSON(w, run)
+}
+
+func (s *Server) handleApproveCanvasNode(w http.ResponseWriter, r *http.Request) {
+	var req ApproveCanvasNodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	run, err := s.workflows.Approve(r.Context(), chi.URLParam(r, "runID"), chi.URLParam(r, "nodeID"), workflow.Decision{
+		Approved: req.Approved,
+		Comment:  req.Comment,
+		Changes:  req.Changes,
+	})
+	if err != nil {
+		writeWorkflowError(w, "record approval", err)
+		return
+	}
+	writeJSON(w, run)
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ClarionDev/clarion/internal/compare"
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// CompareRequest runs one request against several LLM configs. The request's own LLM
+// config is ignored.
+type CompareRequest struct {
+	AgentRunRequest
+	LLMConfigs []models.LLMConfig `json:"llm_configs"`
+}
+
+// handleCompare runs a prompt and context against several LLM configs concurrently and
+// saves the outputs, latencies, token usage and costs as a comparison. Candidates that fail
+// keep their error; the comparison is saved either way.
+func (s *Server) handleCompare(w http.ResponseWriter, r *http.Request) {
+	var req CompareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.LLMConfigs) == 0 || len(req.LLMConfigs) > compare.MaxCandidates {
+		http.Error(w, fmt.Sprintf("Between 1 and %d LLM configs are required", compare.MaxCandidates), http.StatusBadRequest)
+		return
+	}
+	req.Isolate = false
+	req.AgentRevision = 0
+
+	configs, err := s.candidateConfigs(r.Context(), req.LLMConfigs)
+	if err != nil {
+		writeLLMConfigsError(w, err)
+		return
+	}
+	runReq, _, err := s.runRequest(r.Context(), req.AgentRunRequest)
+	if err != nil {
+		writeLookupError(w, err)
+		return
+	}
+	candidates, err := compare.Run(r.Context(), s.runner, runReq, configs)
+	if err != nil {
+		writeRunError(w, "Comparison failed", err)
+		return
+	}
+
+	req.LLMConfig = models.LLMConfig{}
+	request, err := json.Marshal(req.AgentRunRequest)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save comparison: %v", err), http.StatusInternalServerError)
+		return
+	}
+	comparison := &models.Comparison{
+		ID:         uuid.New().String(),
+		Request:    request,
+		Candidates: candidates,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if req.ProjectRoot != "" {
+		if project, err := s.projectStore.GetProjectByPath(r.Context(), req.ProjectRoot); err == nil {
+			comparison.ProjectID = project.ID
+		}
+	}
+	if err := s.comparisonStore.SaveComparison(r.Context(), comparison); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save comparison: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(comparison)
+}
+
+// errInvalidLLMConfig is wrapped by candidateConfigs errors about the configs themselves.
+var errInvalidLLMConfig = errors.New("invalid LLM config")
+
+// candidateConfigs completes the LLM configs of a comparison: one that names only a saved
+// config takes its provider, and one without a saved config uses the first saved config
+// of its provider. A saved config that does not exist or is for another provider is an
+// error wrapping errInvalidLLMConfig.
+func (s *Server) candidateConfigs(ctx context.Context, configs []models.LLMConfig) ([]models.LLMConfig, error) {
+	saved, err := s.llmConfigStore.ListLLMConfigs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	completed := make([]models.LLMConfig, len(configs))
+	for i, c := range configs {
+		if c.ConfigID != "" {
+			var match *models.LLMProviderConfig
+			for _, sc := range saved {
+				if sc.ID == c.ConfigID {
+					match = sc
+					break
+				}
+			}
+			switch {
+			case match == nil:
+				return nil, fmt.Errorf("%w %d: no saved config with id '%s'", errInvalidLLMConfig, i, c.ConfigID)
+			case c.Provider == "":
+				c.Provider = match.Provider
+			case c.Provider != match.Provider:
+				return nil, fmt.Errorf("%w %d: saved config '%s' is for %s, not %s", errInvalidLLMConfig, i, c.ConfigID, match.Provider, c.Provider)
+			}
+		} else {
+			for _, sc := range saved {
+				if sc.Provider == c.Provider {
+					c.ConfigID = sc.ID
+					break
+				}
+			}
+		}
+		if c.Provider == "" || c.Model == "" {
+			return nil, fmt.Errorf("%w %d: a provider and a model are required", errInvalidLLMConfig, i)
+		}
+		completed[i] = c
+	}
+	return completed, nil
+}
+
+// writeLLMConfigsError writes a candidateConfigs error: 400 for invalid configs, 500 otherwise.
+func writeLLMConfigsError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, errInvalidLLMConfig) {
+		status = http.StatusBadRequest
+	}
+	http.Error(w, fmt.Sprintf("Invalid LLM configs: %v", err), status)
+}
+
+func (s *Server) handleGetComparison(w http.ResponseWriter, r *http.Request) {
+	comparison, ok := s.comparison(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, comparison)
+}
+
+func (s *Server) handleListComparisons(w http.ResponseWriter, r *http.Request) {
+	comparisons, err := s.comparisonStore.ListComparisons(r.Context(), chi.URLParam(r, "projectID"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list comparisons: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, comparisons)
+}
+
+type ComparisonDiffResponse struct {
+	A     int                `json:"a"`
+	B     int                `json:"b"`
+	Files []compare.FileDiff `json:"files"`
+}
+
+// handleComparisonDiff diffs the file changes of two candidates of a comparison, chosen by
+// their index with the a and b query parameters, 0 and 1 by default.
+func (s *Server) handleComparisonDiff(w http.ResponseWriter, r *http.Request) {
+	comparison, ok := s.comparison(w, r)
+	if !ok {
+		return
+	}
+	index := func(name string, fallback int) (int, error) {
+		v := r.URL.Query().Get(name)
+		if v == "" {
+			return fallback, nil
+		}
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 0 || i >= len(comparison.Candidates) {
+			return 0, fmt.Errorf("%s must be a candidate index from 0 to %d", name, len(comparison.Candidates)-1)
+		}
+		return i, nil
+	}
+	a, err := index("a", 0)
+	if err == nil && len(comparison.Candidates) < 2 {
+		err = errors.New("the comparison has a single candidate")
+	}
+	b := 0
+	if err == nil {
+		b, err = index("b", 1)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	candidateA, candidateB := comparison.Candidates[a], comparison.Candidates[b]
+	files := compare.Diff(candidateA, candidateB, candidateName(candidateA, a), candidateName(candidateB, b))
+	writeJSON(w, ComparisonDiffResponse{A: a, B: b, Files: files})
+}
+
+// candidateName names a candidate in diff headers by its model, or its index when it has none.
+func candidateName(c models.ComparisonCandidate, index int) string {
+	if c.LLMConfig.Model != "" {
+		return c.LLMConfig.Model
+	}
+	return strconv.Itoa(index)
+}
+
+func (s *Server) comparison(w http.ResponseWriter, r *http.Request) (*models.Comparison, bool) {
+	comparison, err := s.comparisonStore.GetComparison(r.Context(), chi.URLParam(r, "comparisonID"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, fmt.Sprintf("Failed to get comparison: %v", err), status)
+		return nil, false
+	}
+	return comparison, true
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ClarionDev/clarion/internal/models"
+)
+
+func TestHandleCompareLLMConfigs(t *testing.T) {
+	s := newTestServer(t)
+	root, _ := rerunFixture(t, s)
+	compareWith := func(configs ...models.LLMConfig) *httptest.ResponseRecorder {
+		return serve(t, s, http.MethodPost, "/api/v2/compare", CompareRequest{
+			AgentRunRequest: AgentRunRequest{Prompt: "Do it", ProjectRoot: root},
+			LLMConfigs:      configs,
+		})
+	}
+
+	tests := []struct {
+		name    string
+		configs []models.LLMConfig
+		want    string
+	}{
+		{"unknown config", []models.LLMConfig{
+			{Provider: echoProviderName, Model: "a"},
+			{Model: "b", ConfigID: "cfg-missing"},
+		}, "no saved config with id 'cfg-missing'"},
+		{"provider mismatch", []models.LLMConfig{
+			{Provider: echoProviderName, Model: "a"},
+			{Provider: echoProviderName, Model: "b", ConfigID: "cfg-openai"},
+		}, "is for OpenAI"},
+	}
+	for _, tt := range tests {
+		rec := compareWith(tt.configs...)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%s: compare = %d %q, want 400 mentioning %q", tt.name, rec.Code, rec.Body, tt.want)
+		}
+	}
+	// Rejected comparisons start no candidate, so nothing is saved.
+	if comparisons, err := s.comparisonStore.ListComparisons(context.Background(), "p1"); err != nil || len(comparisons) != 0 {
+		t.Errorf("comparisons after rejected requests = %v, %v, want none", comparisons, err)
+	}
+
+	rec := compareWith(
+		models.LLMConfig{Provider: echoProviderName, Model: "a"},
+		models.LLMConfig{Model: "b", ConfigID: "cfg-echo"},
+	)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("compare with valid configs = %d %s, want 201", rec.Code, rec.Body)
+	}
+	var comparison models.Comparison
+	if err := json.NewDecoder(rec.Body).Decode(&comparison); err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range comparison.Candidates {
+		if c.LLMConfig.ConfigID != "cfg-echo" || c.LLMConfig.Provider != echoProviderName {
+			t.Errorf("candidate %d config = %+v, want the echo config", i, c.LLMConfig)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ClarionDev/clarion/internal/eval"
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// EvalRunRequest selects what an eval suite runs against. Omitted fields use the agent's
+// latest revision and its LLM config.
+type EvalRunRequest struct {
+	AgentRevision int               `json:"agent_revision,omitempty"`
+	LLMConfig     *models.LLMConfig `json:"llm_config,omitempty"`
+	// Recorded checks the cases' recorded outputs instead of calling the provider.
+	Recorded bool `json:"recorded,omitempty"`
+	// Record saves the outputs of a live run as the cases' recorded outputs.
+	Record         bool              `json:"record,omitempty"`
+	JudgeLLMConfig *models.LLMConfig `json:"judge_llm_config,omitempty"`
+}
+
+func (s *Server) handleListEvalSuites(w http.ResponseWriter, r *http.Request) {
+	suites, err := s.evalStore.ListEvalSuites(r.Context(), chi.URLParam(r, "agentID"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list eval suites: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, suites)
+}
+
+// handleSaveEvalSuite creates or replaces a suite of the agent in the URL.
+func (s *Server) handleSaveEvalSuite(w http.ResponseWriter, r *http.Request) {
+	var suite models.EvalSuite
+	if err := json.NewDecoder(r.Body).Decode(&suite); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	suite.AgentID = chi.URLParam(r, "agentID")
+	if err := suite.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if _, err := s.agentStore.GetAgent(r.Context(), suite.AgentID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, fmt.Sprintf("Failed to get agent: %v", err), status)
+		return
+	}
+
+	if suite.ID == "" {
+		suite.ID = uuid.New().String()
+	} else if existing, err := s.evalStore.GetEvalSuite(r.Context(), suite.ID); err == nil && existing.AgentID != suite.AgentID {
+		http.Error(w, "The eval suite belongs to another agent", http.StatusConflict)
+		return
+	}
+	if err := s.evalStore.SaveEvalSuite(r.Context(), &suite); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save eval suite: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, suite)
+}
+
+func (s *Server) handleGetEvalSuite(w http.ResponseWriter, r *http.Request) {
+	suite, ok := s.evalSuite(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, suite)
+}
+
+func (s *Server) handleDeleteEvalSuite(w http.ResponseWriter, r *http.Request) {
+	suite, ok := s.evalSuite(w, r)
+	if !ok {
+		return
+	}
+	if err := s.evalStore.DeleteEvalSuite(r.Context(), suite.ID); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete eval suite: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleRunEvalSuite runs a suite against a revision of its agent and saves the report.
+// Case failures are part of the report; the response is 201 whenever the suite ran.
+func (s *Server) handleRunEvalSuite(w http.ResponseWriter, r *http.Request) {
+	var req EvalRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Recorded && req.Record {
+		http.Error(w, "A recorded run cannot record outputs", http.StatusBadRequest)
+		return
+	}
+	suite, ok := s.evalSuite(w, r)
+	if !ok {
+		return
+	}
+
+	agent, err := s.evalAgent(r.Context(), suite.AgentID, req.AgentRevision)
+	if err != nil {
+		writeLookupError(w, err)
+		return
+	}
+	opts := eval.Options{LLMConfig: agent.LLMConfig, Recorded: req.Recorded, Record: req.Record}
+	if req.LLMConfig != nil {
+		opts.LLMConfig = *req.LLMConfig
+	}
+	configs := []models.LLMConfig{opts.LLMConfig}
+	if req.JudgeLLMConfig != nil {
+		configs = append(configs, *req.JudgeLLMConfig)
+	}
+	if !req.Recorded || req.JudgeLLMConfig != nil {
+		completed, err := s.candidateConfigs(r.Context(), configs)
+		if err != nil {
+			writeLLMConfigsError(w, err)
+			return
+		}
+		opts.LLMConfig = completed[0]
+		if len(completed) > 1 {
+			opts.Judge = &completed[1]
+		}
+	}
+
+	report := eval.Run(r.Context(), s.runner, suite, agent, opts)
+	report.ID = uuid.New().String()
+	if err := s.evalStore.SaveEvalReport(r.Context(), report); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save eval report: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if req.Record {
+		if err := s.evalStore.SaveEvalSuite(r.Context(), suite); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to save recorded outputs: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(report)
+}
+
+// evalAgent returns the agent a suite runs against: a saved revision, or the latest one
+// when revision is 0.
+func (s *Server) evalAgent(ctx context.Context, agentID string, revision int) (*models.Agent, error) {
+	agent, err := s.agentStore.GetAgent(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+	if revision == 0 || revision == agent.Revision {
+		return agent, nil
+	}
+	pinned, err := s.agentStore.GetAgentRevision(ctx, agentID, revision)
+	if err != nil {
+		return nil, err
+	}
+	pinned.Agent.Revision = pinned.Revision
+	return &pinned.Agent, nil
+}
+
+// handleListEvalReports lists the reports of a suite, newest first, to compare the scores
+// of the agent's revisions.
+func (s *Server) handleListEvalReports(w http.ResponseWriter, r *http.Request) {
+	suite, ok := s.evalSuite(w, r)
+	if !ok {
+		return
+	}
+	reports, err := s.evalStore.ListEvalReports(r.Context(), suite.ID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list eval reports: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, reports)
+}
+
+func (s *Server) handleGetEvalReport(w http.ResponseWriter, r *http.Request) {
+	report, err := s.evalStore.GetEvalReport(r.Context(), chi.URLParam(r, "reportID"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, fmt.Sprintf("Failed to get eval report: %v", err), status)
+		return
+	}
+	writeJSON(w, report)
+}
+
+func (s *Server) evalSuite(w http.ResponseWriter, r *http.Request) (*models.EvalSuite, bool) {
+	suite, err := s.evalStore.GetEvalSuite(r.Context(), chi.URLParam(r, "suiteID"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, fmt.Sprintf("Failed to get eval suite: %v", err), status)
+		return nil, false
+	}
+	return suite, true
+}
@@ -10,7 +10,18 @@ type FileChange struct {
 }
 
 // ApplyChangesRequest is the payload from the frontend to apply a batch of file changes.
+// With CreateBranch set, the changes are committed on a new clarion/<run-id> branch instead
+// of being left in the working tree.
 type ApplyChangesRequest struct {
-	RootPath string       `json:"root_path"`
-	Changes  []FileChange `json:"changes"`
+	RootPath     string       `json:"root_path"`
+	Changes      []FileChange `json:"changes"`
+	RunID        string       `json:"run_id,omitempty"`
+	CreateBranch bool         `json:"create_branch,omitempty"`
+}
+
+// ApplyChangesBranchResponse describes the commit made when changes are applied on a branch.
+type ApplyChangesBranchResponse struct {
+	Branch     string     `json:"branch"`
+	BaseBranch string     `json:"base_branch"`
+	Commit     *GitCommit `json:"commit"`
 }
@@ -1,23 +1,20 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 
+	"github.com/ClarionDev/clarion/internal/storage"
+	"github.com/ClarionDev/clarion/internal/ws"
 	"github.com/fsnotify/fsnotify"
 	"github.com/gorilla/websocket"
 )
 
-var fsUpgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
-}
-
 type fsWatchRequest struct {
 	Path string `json:"path"`
 }
@@ -43,36 +40,61 @@ var defaultIgnoreDirs = map[string]struct{}{
 	"markdown":     {},
 }
 
-func (s *Server) handleFSWatchWS(w http.ResponseWriter, r *http.Request) {
-	conn, err := fsUpgrader.Upgrade(w, r, nil)
-	if err != nil {
-		log.Printf("Failed to upgrade fs watch connection: %v", err)
-		return
-	}
-	defer conn.Close()
+const fsTopicName = "fs"
 
-	var req fsWatchRequest
-	if err := conn.ReadJSON(&req); err != nil {
-		log.Printf("Failed to read initial fs watch message: %v", err)
-		return
+var errCreateWatcher = errors.New("failed to create file watcher")
+
+// fsTopic watches a project directory for "fs:<project>" channels. The key is a project
+// ID, or an absolute directory path for clients that only know the path.
+type fsTopic struct {
+	projectStore storage.ProjectStore
+}
+
+func (t *fsTopic) resolveRoot(ctx context.Context, key string) (string, error) {
+	if project, err := t.projectStore.GetProject(ctx, key); err == nil && project.Path != "" {
+		return project.Path, nil
+	}
+	if filepath.IsAbs(key) {
+		if info, err := os.Stat(key); err == nil && info.IsDir() {
+			return key, nil
+		}
 	}
+	return "", fmt.Errorf("unknown project or directory: %s", key)
+}
 
-	if req.Path == "" {
-		log.Println("Invalid initial fs watch message: path is required")
-		_ = conn.WriteJSON(fsWatchResponse{Event: "error_path_required"})
-		return
+func (t *fsTopic) Activate(ctx context.Context, key string, publish ws.PublishFunc) error {
+	root, err := t.resolveRoot(ctx, key)
+	if err != nil {
+		return err
 	}
 
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
-		log.Printf("Failed to create file watcher: %v", err)
-		_ = conn.WriteJSON(fsWatchResponse{Event: "error_create_watcher"})
-		return
+		return fmt.Errorf("%w: %v", errCreateWatcher, err)
+	}
+
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			dirName := info.Name()
+			if _, ignored := defaultIgnoreDirs[dirName]; ignored && dirName != filepath.Base(root) {
+				return filepath.SkipDir
+			}
+			return watcher.Add(path)
+		}
+		return nil
+	})
+	if err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to add paths to watcher: %w", err)
 	}
-	defer watcher.Close()
+
+	log.Printf("Watching %s for file changes.", root)
 
 	go func() {
-		defer conn.Close()
+		defer watcher.Close()
 		for {
 			select {
 			case event, ok := <-watcher.Events:
@@ -80,55 +102,99 @@ func (s *Server) handleFSWatchWS(w http.ResponseWriter, r *http.Request) {
 					return
 				}
 				if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) || event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
-					relPath, err := filepath.Rel(req.Path, event.Name)
+					relPath, err := filepath.Rel(root, event.Name)
 					if err != nil {
 						log.Printf("Could not get relative path for changed file %s: %v", event.Name, err)
 						continue
 					}
 					log.Printf("File change detected: %s op: %s", relPath, event.Op)
-					if err := conn.WriteJSON(fsWatchResponse{Event: "change", Path: relPath, Op: event.Op.String()}); err != nil {
-						log.Printf("Error sending fs watch event: %v", err)
-						return
-					}
+					publish("change", fsWatchResponse{Event: "change", Path: relPath, Op: event.Op.String()})
 				}
 			case err, ok := <-watcher.Errors:
 				if !ok {
 					return
 				}
 				log.Printf("File watcher error: %v", err)
-			case <-r.Context().Done():
+			case <-ctx.Done():
+				log.Printf("Stopped watching %s.", root)
 				return
 			}
 		}
 	}()
 
-	err = filepath.Walk(req.Path, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() {
-			dirName := info.Name()
-			if _, ignored := defaultIgnoreDirs[dirName]; ignored && dirName != filepath.Base(req.Path) {
-				return filepath.SkipDir
-			}
-			return watcher.Add(path)
-		}
-		return nil
-	})
+	return nil
+}
+
+// handleFSWatchWS is the single-directory watch socket. It subscribes to the directory's
+// fs channel and relays change events in the legacy message format.
+func (s *Server) handleFSWatchWS(w http.ResponseWriter, r *http.Request) {
+	if !s.wsAuthorized(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := s.wsUpgrader().Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Failed to upgrade fs watch connection: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	var req fsWatchRequest
+	if err := conn.ReadJSON(&req); err != nil {
+		log.Printf("Failed to read initial fs watch message: %v", err)
+		return
+	}
 
+	if req.Path == "" {
+		log.Println("Invalid initial fs watch message: path is required")
+		_ = conn.WriteJSON(fsWatchResponse{Event: "error_path_required"})
+		return
+	}
+
+	absPath, err := filepath.Abs(req.Path)
 	if err != nil {
-		log.Printf("Failed to add paths to watcher: %v", err)
 		_ = conn.WriteJSON(fsWatchResponse{Event: "error_add_watch_path"})
 		return
 	}
 
-	log.Printf("Watching %s for file changes.", req.Path)
+	sub, err := s.hub.Subscribe(ws.Channel(fsTopicName, absPath), 0)
+	if err != nil {
+		log.Printf("Failed to watch %s: %v", absPath, err)
+		if errors.Is(err, errCreateWatcher) {
+			_ = conn.WriteJSON(fsWatchResponse{Event: "error_create_watcher"})
+		} else {
+			_ = conn.WriteJSON(fsWatchResponse{Event: "error_add_watch_path"})
+		}
+		return
+	}
+	defer sub.Close()
+
+	disconnected := make(chan struct{})
+	go func() {
+		defer close(disconnected)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				log.Printf("FS Watch client disconnected: %v", err)
+				return
+			}
+		}
+	}()
 
 	for {
-		if _, _, err := conn.NextReader(); err != nil {
-			log.Printf("FS Watch client disconnected: %v", err)
-			break
+		select {
+		case env := <-sub.C:
+			if env.Type != ws.TypeEvent {
+				continue
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, env.Data); err != nil {
+				log.Printf("Error sending fs watch event: %v", err)
+				return
+			}
+		case <-sub.Done():
+			return
+		case <-disconnected:
+			return
 		}
 	}
-	log.Printf("Stopped watching %s.", req.Path)
 }
@@ -0,0 +1,245 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ClarionDev/clarion/internal/git"
+)
+
+// openGitRepo opens the repository at path, writing the error response itself when it fails.
+func openGitRepo(w http.ResponseWriter, r *http.Request, path string) (*git.Repo, bool) {
+	if path == "" {
+		http.Error(w, "Path cannot be empty", http.StatusBadRequest)
+		return nil, false
+	}
+	repo, err := git.Open(r.Context(), path)
+	if err != nil {
+		writeGitError(w, "open repository", err)
+		return nil, false
+	}
+	return repo, true
+}
+
+// writeGitError maps git package errors to HTTP status codes.
+func writeGitError(w http.ResponseWriter, action string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, git.ErrNotRepository), errors.Is(err, git.ErrInvalidRef), errors.Is(err, git.ErrNothingToCommit):
+		status = http.StatusBadRequest
+	case errors.Is(err, git.ErrDirtyTree), errors.Is(err, git.ErrConflict):
+		status = http.StatusConflict
+	}
+	http.Error(w, fmt.Sprintf("Failed to %s: %v", action, err), status)
+}
+
+func (s *Server) handleGitStatus(w http.ResponseWriter, r *http.Request) {
+	var req GitRepoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	repo, ok := openGitRepo(w, r, req.Path)
+	if !ok {
+		return
+	}
+
+	status, err := repo.Status(r.Context())
+	if err != nil {
+		writeGitError(w, "get git status", err)
+		return
+	}
+	writeJSON(w, status)
+}
+
+func (s *Server) handleGitLog(w http.ResponseWriter, r *http.Request) {
+	var req GetGitLogRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	repo, ok := openGitRepo(w, r, req.Path)
+	if !ok {
+		return
+	}
+
+	commits, hasMore, err := repo.Log(r.Context(), git.LogOptions{
+		Ref:   req.Ref,
+		Path:  req.FilePath,
+		Skip:  req.Skip,
+		Limit: req.Limit,
+	})
+	if err != nil {
+		writeGitError(w, "get git log", err)
+		return
+	}
+
+	resp := GetGitLogResponse{Commits: make([]*GitCommit, 0, len(commits)), HasMore: hasMore}
+	for _, c := range commits {
+		resp.Commits = append(resp.Commits, newGitCommit(c))
+	}
+	writeJSON(w, resp)
+}
+
+func (s *Server) handleGitCommitDiff(w http.ResponseWriter, r *http.Request) {
+	var req GetCommitDiffRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	repo, ok := openGitRepo(w, r, req.Path)
+	if !ok {
+		return
+	}
+
+	diff, err := repo.CommitDiff(r.Context(), req.Hash)
+	if err != nil {
+		writeGitError(w, "get commit diff", err)
+		return
+	}
+	writeJSON(w, GetCommitDiffResponse{Diff: diff})
+}
+
+func (s *Server) handleGitWorkingDiff(w http.ResponseWriter, r *http.Request) {
+	var req GetWorkingDiffRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	repo, ok := openGitRepo(w, r, req.Path)
+	if !ok {
+		return
+	}
+
+	diff, err := repo.Diff(r.Context(), req.Staged, req.Files...)
+	if err != nil {
+		writeGitError(w, "get working tree diff", err)
+		return
+	}
+	writeJSON(w, GetCommitDiffResponse{Diff: diff})
+}
+
+func (s *Server) handleGitStage(w http.ResponseWriter, r *http.Request) {
+	var req GitPathsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Files) == 0 {
+		http.Error(w, "Files cannot be empty", http.StatusBadRequest)
+		return
+	}
+	repo, ok := openGitRepo(w, r, req.Path)
+	if !ok {
+		return
+	}
+
+	if err := repo.Stage(r.Context(), req.Files...); err != nil {
+		writeGitError(w, "stage files", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Files staged successfully"))
+}
+
+func (s *Server) handleGitUnstage(w http.ResponseWriter, r *http.Request) {
+	var req GitPathsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Files) == 0 {
+		http.Error(w, "Files cannot be empty", http.StatusBadRequest)
+		return
+	}
+	repo, ok := openGitRepo(w, r, req.Path)
+	if !ok {
+		return
+	}
+
+	if err := repo.Unstage(r.Context(), req.Files...); err != nil {
+		writeGitError(w, "unstage files", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Files unstaged successfully"))
+}
+
+func (s *Server) handleGitCommit(w http.ResponseWriter, r *http.Request) {
+	var req GitCommitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	repo, ok := openGitRepo(w, r, req.Path)
+	if !ok {
+		return
+	}
+
+	commit, err := repo.Commit(r.Context(), req.Message)
+	if err != nil {
+		writeGitError(w, "commit", err)
+		return
+	}
+	writeJSON(w, newGitCommit(*commit))
+}
+
+func (s *Server) handleGitBranches(w http.ResponseWriter, r *http.Request) {
+	var req GitRepoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	repo, ok := openGitRepo(w, r, req.Path)
+	if !ok {
+		return
+	}
+
+	branches, err := repo.Branches(r.Context())
+	if err != nil {
+		writeGitError(w, "list branches", err)
+		return
+	}
+	writeJSON(w, GitBranchesResponse{Branches: branches})
+}
+
+func (s *Server) handleGitCreateBranch(w http.ResponseWriter, r *http.Request) {
+	var req CreateBranchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	repo, ok := openGitRepo(w, r, req.Path)
+	if !ok {
+		return
+	}
+
+	if err := repo.CreateBranch(r.Context(), req.Name, req.StartPoint, req.Checkout); err != nil {
+		writeGitError(w, "create branch", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Branch created successfully"))
+}
+
+// handleGitCheckout refuses with 409 Conflict when the working tree has uncommitted changes.
+func (s *Server) handleGitCheckout(w http.ResponseWriter, r *http.Request) {
+	var req CheckoutCommitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	repo, ok := openGitRepo(w, r, req.Path)
+	if !ok {
+		return
+	}
+
+	if err := repo.Checkout(r.Context(), req.Hash); err != nil {
+		writeGitError(w, "checkout", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Checked out successfully"))
+}
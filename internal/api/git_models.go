@@ -1,12 +1,19 @@
 package api
 
-import "time"
+import (
+	"time"
+
+	"github.com/ClarionDev/clarion/internal/git"
+)
 
 type GitCommit struct {
-	Hash    string    `json:"hash"`
-	Message string    `json:"message"`
-	Author  string    `json:"author"`
-	Date    time.Time `json:"date"`
+	Hash        string    `json:"hash"`
+	ShortHash   string    `json:"short_hash"`
+	Message     string    `json:"message"`
+	Body        string    `json:"body,omitempty"`
+	Author      string    `json:"author"`
+	AuthorEmail string    `json:"author_email"`
+	Date        time.Time `json:"date"`
 }
 
 type FileDiff struct {
@@ -14,12 +21,21 @@ type FileDiff struct {
 	Patch string `json:"patch"`
 }
 
-type GetGitLogRequest struct {
+type GitRepoRequest struct {
 	Path string `json:"path"`
 }
 
+type GetGitLogRequest struct {
+	Path     string `json:"path"`
+	Ref      string `json:"ref,omitempty"`
+	FilePath string `json:"file_path,omitempty"`
+	Skip     int    `json:"skip"`
+	Limit    int    `json:"limit"`
+}
+
 type GetGitLogResponse struct {
 	Commits []*GitCommit `json:"commits"`
+	HasMore bool         `json:"has_more"`
 }
 
 type GetCommitDiffRequest struct {
@@ -31,7 +47,47 @@ type GetCommitDiffResponse struct {
 	Diff string `json:"diff"`
 }
 
+type GetWorkingDiffRequest struct {
+	Path   string   `json:"path"`
+	Staged bool     `json:"staged"`
+	Files  []string `json:"files,omitempty"`
+}
+
+type GitPathsRequest struct {
+	Path  string   `json:"path"`
+	Files []string `json:"files"`
+}
+
+type GitCommitRequest struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+type GitBranchesResponse struct {
+	Branches []git.Branch `json:"branches"`
+}
+
+type CreateBranchRequest struct {
+	Path       string `json:"path"`
+	Name       string `json:"name"`
+	StartPoint string `json:"start_point,omitempty"`
+	Checkout   bool   `json:"checkout"`
+}
+
+// CheckoutCommitRequest checks out a branch by name, or detaches HEAD at any other commit.
 type CheckoutCommitRequest struct {
 	Path string `json:"path"`
 	Hash string `json:"hash"`
 }
+
+func newGitCommit(c git.Commit) *GitCommit {
+	return &GitCommit{
+		Hash:        c.Hash,
+		ShortHash:   c.ShortHash,
+		Message:     c.Subject,
+		Body:        c.Body,
+		Author:      c.Author,
+		AuthorEmail: c.AuthorEmail,
+		Date:        c.Date,
+	}
+}
@@ -1,11 +1,15 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
+	"github.com/ClarionDev/clarion/internal/llm"
 	"github.com/ClarionDev/clarion/internal/models"
 	"github.com/go-chi/chi/v5"
 )
@@ -17,6 +21,11 @@ func (s *Server) handleListLLMConfigs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Keys never leave the backend; clients get the masked hint in their place.
+	for _, config := range configs {
+		config.APIKey = config.APIKeyHint
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(configs); err != nil {
@@ -55,3 +64,83 @@ func (s *Server) handleDeleteLLMConfig(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("LLM config deleted successfully"))
 }
+
+// handleTestLLMConfig checks a saved config's API key with a cheap authenticated call to
+// its provider. A rejected key is a successful test with a negative result, so it is
+// reported in the body rather than as an HTTP error.
+func (s *Server) handleTestLLMConfig(w http.ResponseWriter, r *http.Request) {
+	config, provider, ok := s.loadLLMConfigProvider(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	err := llm.CheckAPIKey(ctx, provider, config, s.llmConfigStore)
+	resp := LLMConfigTestResponse{OK: err == nil, LatencyMS: time.Since(start).Milliseconds()}
+	switch {
+	case err == nil:
+		resp.Status = "ok"
+		resp.Message = fmt.Sprintf("Connected to %s successfully.", config.Provider)
+	case errors.Is(err, llm.ErrInvalidAPIKey):
+		resp.Status = "invalid_key"
+		resp.Message = fmt.Sprintf("%s rejected the API key. Check that it is correct and has not been revoked.", config.Provider)
+	default:
+		resp.Status = "error"
+		resp.Message = err.Error()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleListLLMConfigModels lists the models available to a saved config's API key.
+func (s *Server) handleListLLMConfigModels(w http.ResponseWriter, r *http.Request) {
+	config, provider, ok := s.loadLLMConfigProvider(w, r)
+	if !ok {
+		return
+	}
+
+	lister, ok := provider.(llm.ModelLister)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Provider '%s' does not support listing models", config.Provider), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+
+	modelList, err := lister.ListModels(ctx, config, s.llmConfigStore)
+	if errors.Is(err, llm.ErrInvalidAPIKey) {
+		http.Error(w, fmt.Sprintf("Failed to list models: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list models: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(modelList)
+}
+
+// loadLLMConfigProvider resolves the {configID} URL parameter to a stored config and its
+// provider, writing the error response itself when it fails.
+func (s *Server) loadLLMConfigProvider(w http.ResponseWriter, r *http.Request) (*models.LLMProviderConfig, llm.Provider, bool) {
+	configID := chi.URLParam(r, "configID")
+	config, err := s.llmConfigStore.GetLLMConfig(r.Context(), configID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get LLM config: %v", err), http.StatusNotFound)
+		return nil, nil, false
+	}
+	provider, err := llm.GetProvider(config.Provider)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, nil, false
+	}
+	return config, provider, true
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ClarionDev/clarion/internal/storage"
+)
+
+// PruneRunsRequest overrides the configured retention policy for one pass. Omitted fields
+// use the configured limits.
+type PruneRunsRequest struct {
+	MaxAgeDays        *int `json:"max_age_days,omitempty"`
+	MaxRunsPerProject *int `json:"max_runs_per_project,omitempty"`
+}
+
+type PruneRunsResponse struct {
+	Deleted int `json:"deleted"`
+}
+
+// handlePruneRuns deletes the runs outside the retention policy now, instead of waiting
+// for the background pass.
+func (s *Server) handlePruneRuns(w http.ResponseWriter, r *http.Request) {
+	var req PruneRunsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	maxAge, maxCount := s.settings.RunRetention()
+	policy := storage.RetentionPolicy{MaxAge: maxAge, MaxRunsPerProject: maxCount}
+	if req.MaxAgeDays != nil {
+		policy.MaxAge = time.Duration(*req.MaxAgeDays) * 24 * time.Hour
+	}
+	if req.MaxRunsPerProject != nil {
+		policy.MaxRunsPerProject = *req.MaxRunsPerProject
+	}
+	if policy.MaxAge < 0 || policy.MaxRunsPerProject < 0 {
+		http.Error(w, "Retention limits cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := s.runStore.PruneRuns(r.Context(), policy)
+	s.worktrees.RemoveRuns(r.Context(), deleted)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to prune runs: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, PruneRunsResponse{Deleted: len(deleted)})
+}
+
+type CompactRunsRequest struct {
+	// MinSize is the size in bytes from which strings are moved out of run records,
+	// storage.DefaultCompactMinSize when zero.
+	MinSize int `json:"min_size,omitempty"`
+}
+
+// handleCompactRuns moves large file contents out of saved runs into shared blobs.
+func (s *Server) handleCompactRuns(w http.ResponseWriter, r *http.Request) {
+	var req CompactRunsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.MinSize < 0 {
+		http.Error(w, "Minimum size cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.runStore.CompactRuns(r.Context(), req.MinSize)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to compact runs: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, result)
+}
+
+// handleVacuum rebuilds the database file to give the space of deleted runs back.
+func (s *Server) handleVacuum(w http.ResponseWriter, r *http.Request) {
+	result, err := s.runStore.Vacuum(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to vacuum database: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, result)
+}
@@ -1,14 +1,19 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"path/filepath"
 	"time"
 
+	"github.com/ClarionDev/clarion/internal/bundle"
 	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/projectconfig"
+	"github.com/ClarionDev/clarion/internal/storage"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
@@ -39,7 +44,7 @@ func (s *Server) handleOpenProject(w http.ResponseWriter, r *http.Request) {
 
 	// Check if project already exists
 	project, err := s.projectStore.GetProjectByPath(r.Context(), req.Path)
-	if err != nil && err.Error() != "sql: no rows in result set" {
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
 		// handle other errors, but continue if it's just not found
 		http.Error(w, fmt.Sprintf("Error checking for existing project: %v", err), http.StatusInternalServerError)
 		return
@@ -60,11 +65,32 @@ func (s *Server) handleOpenProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := bundle.SyncProject(r.Context(), s.agentStore, project.Path); err != nil {
+		log.Printf("Failed to load agents of project %s: %v", project.Path, err)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(project)
 }
 
+// handleSyncProjectAgents loads the agents in a project's .clarion/agents directory into
+// the store, so changes to the files show up without reopening the project.
+func (s *Server) handleSyncProjectAgents(w http.ResponseWriter, r *http.Request) {
+	project, err := s.projectStore.GetProject(r.Context(), chi.URLParam(r, "projectID"))
+	if err != nil {
+		writeProjectError(w, err)
+		return
+	}
+	if err := bundle.SyncProject(r.Context(), s.agentStore, project.Path); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to load project agents: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Project agents synced successfully"))
+}
+
 func (s *Server) handleDeleteProject(w http.ResponseWriter, r *http.Request) {
 	projectID := chi.URLParam(r, "projectID")
 	if projectID == "" {
@@ -81,12 +107,17 @@ func (s *Server) handleDeleteProject(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("Project deleted successfully"))
 }
 
+// handleUpdateProject saves a project. Its settings are kept; they change only through the
+// settings endpoint.
 func (s *Server) handleUpdateProject(w http.ResponseWriter, r *http.Request) {
 	var projectToUpdate models.Project
 	if err := json.NewDecoder(r.Body).Decode(&projectToUpdate); err != nil {
 		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
 		return
 	}
+	if existing, err := s.projectStore.GetProject(r.Context(), projectToUpdate.ID); err == nil {
+		projectToUpdate.Settings = existing.Settings
+	}
 
 	if err := s.projectStore.SaveProject(r.Context(), &projectToUpdate); err != nil {
 		http.Error(w, fmt.Sprintf("Failed to update project: %v", err), http.StatusInternalServerError)
@@ -96,3 +127,88 @@ func (s *Server) handleUpdateProject(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Project updated successfully"))
 }
+
+// handleGetProjectSettings returns a project's saved settings, its .clarion/project.yml
+// settings if it has them, and the merged settings that runs use.
+func (s *Server) handleGetProjectSettings(w http.ResponseWriter, r *http.Request) {
+	project, err := s.projectStore.GetProject(r.Context(), chi.URLParam(r, "projectID"))
+	if err != nil {
+		writeProjectError(w, err)
+		return
+	}
+	resp, err := projectSettingsResponse(project)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read project settings: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+	writeJSON(w, resp)
+}
+
+// handleUpdateProjectSettings saves a project's settings and, with write_file set, also
+// writes them to .clarion/project.yml.
+func (s *Server) handleUpdateProjectSettings(w http.ResponseWriter, r *http.Request) {
+	var req UpdateProjectSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	project, err := s.projectStore.GetProject(r.Context(), chi.URLParam(r, "projectID"))
+	if err != nil {
+		writeProjectError(w, err)
+		return
+	}
+	if err := req.Settings.Validate(s.lookupLLMConfig(r.Context())); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	project.Settings = req.Settings
+	if err := s.projectStore.SaveProject(r.Context(), project); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save project settings: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if req.WriteFile {
+		if err := projectconfig.WriteFile(project.Path, &req.Settings); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to write %s: %v", projectconfig.FileName, err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	resp, err := projectSettingsResponse(project)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read project settings: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+	writeJSON(w, resp)
+}
+
+func projectSettingsResponse(project *models.Project) (*ProjectSettingsResponse, error) {
+	file, err := projectconfig.LoadFile(project.Path)
+	if err != nil {
+		return nil, err
+	}
+	return &ProjectSettingsResponse{
+		Settings:  project.Settings,
+		File:      file,
+		FilePath:  filepath.Join(project.Path, projectconfig.FileName),
+		Effective: projectconfig.Merge(project.Settings, file),
+	}, nil
+}
+
+// projectSettings returns the settings in effect for the project at projectRoot. A settings
+// file that cannot be read is logged and ignored.
+func (s *Server) projectSettings(ctx context.Context, projectRoot string) models.ProjectSettings {
+	settings, err := projectconfig.Resolve(ctx, s.projectStore, projectRoot)
+	if err != nil {
+		log.Printf("Failed to load settings of project %s: %v", projectRoot, err)
+	}
+	return settings
+}
+
+func writeProjectError(w http.ResponseWriter, err error) {
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return
+	}
+	http.Error(w, fmt.Sprintf("Failed to get project: %v", err), http.StatusInternalServerError)
+}
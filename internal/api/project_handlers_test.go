@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ClarionDev/clarion/internal/agent"
+	"github.com/ClarionDev/clarion/internal/bundle"
+	"github.com/ClarionDev/clarion/internal/models"
+)
+
+func TestHandleOpenProject(t *testing.T) {
+	s := newTestServer(t)
+	root := t.TempDir()
+	open := func() *models.Project {
+		t.Helper()
+		rec := serve(t, s, http.MethodPost, "/api/v2/projects/open", map[string]string{"path": root})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("open project = %d %s, want 200", rec.Code, rec.Body)
+		}
+		var project models.Project
+		if err := json.NewDecoder(rec.Body).Decode(&project); err != nil {
+			t.Fatal(err)
+		}
+		return &project
+	}
+
+	// The first open creates the project, later ones find it by its path.
+	first := open()
+	if first.ID == "" || first.Path != root {
+		t.Fatalf("opened project = %+v, want a new project at %s", first, root)
+	}
+	if again := open(); again.ID != first.ID {
+		t.Errorf("reopened project ID = %q, want %q", again.ID, first.ID)
+	}
+}
+
+func TestHandleSyncProjectAgents(t *testing.T) {
+	s := newTestServer(t)
+	root := t.TempDir()
+	project := &models.Project{ID: "p1", Name: "Project", Path: root}
+	if err := s.projectStore.SaveProject(context.Background(), project); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(root, bundle.ProjectDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	data, err := bundle.Marshal(&models.Agent{
+		Profile:      agent.AgentProfile{ID: "reviewer", Name: "Reviewer"},
+		SystemPrompt: "Review the code",
+		OutputSchema: models.OutputSchema{Schema: map[string]any{"type": "object"}},
+		LLMConfig:    models.LLMConfig{Provider: models.ProviderOpenAI, Model: "gpt-4o"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "reviewer.yml"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	listIDs := func() []string {
+		t.Helper()
+		rec := serve(t, s, http.MethodGet, "/api/v2/agents/list?project_root="+url.QueryEscape(root), nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("list agents = %d %s, want 200", rec.Code, rec.Body)
+		}
+		var agents []*models.Agent
+		if err := json.NewDecoder(rec.Body).Decode(&agents); err != nil {
+			t.Fatal(err)
+		}
+		ids := make([]string, len(agents))
+		for i, a := range agents {
+			ids[i] = a.Profile.ID
+		}
+		return ids
+	}
+
+	// Listing only reads the store; the bundle is loaded by the sync.
+	if ids := listIDs(); len(ids) != 0 {
+		t.Fatalf("agents before sync = %v, want none", ids)
+	}
+	if rec := serve(t, s, http.MethodPost, "/api/v2/projects/p1/agents/sync", nil); rec.Code != http.StatusOK {
+		t.Fatalf("sync = %d %s, want 200", rec.Code, rec.Body)
+	}
+	if ids := listIDs(); len(ids) != 1 || ids[0] != "reviewer" {
+		t.Errorf("agents after sync = %v, want [reviewer]", ids)
+	}
+	if rec := serve(t, s, http.MethodPost, "/api/v2/projects/missing/agents/sync", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("sync of an unknown project = %d, want 404", rec.Code)
+	}
+}
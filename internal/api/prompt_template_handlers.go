@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/storage"
+	"github.com/ClarionDev/clarion/internal/templating"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// handleListPromptTemplates lists the saved templates, optionally only those with the tag
+// given in the "tag" query parameter.
+func (s *Server) handleListPromptTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := s.promptTemplateStore.ListPromptTemplates(r.Context(), r.URL.Query().Get("tag"))
+	if err != nil {
+		http.Error(w, "Failed to list prompt templates", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(templates); err != nil {
+		log.Printf("Failed to write prompt template list response: %v", err)
+	}
+}
+
+func (s *Server) handleGetPromptTemplate(w http.ResponseWriter, r *http.Request) {
+	t, err := s.promptTemplateStore.GetPromptTemplate(r.Context(), chi.URLParam(r, "templateID"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, fmt.Sprintf("Failed to get prompt template: %v", err), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(t)
+}
+
+// handleSavePromptTemplate validates and stores a template, assigning an ID to new ones,
+// and returns the saved template.
+func (s *Server) handleSavePromptTemplate(w http.ResponseWriter, r *http.Request) {
+	var t models.PromptTemplate
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validatePromptTemplate(&t); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid prompt template: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+	if err := s.promptTemplateStore.SavePromptTemplate(r.Context(), &t); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save prompt template: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(t)
+}
+
+func (s *Server) handleDeletePromptTemplate(w http.ResponseWriter, r *http.Request) {
+	templateID := chi.URLParam(r, "templateID")
+	if templateID == "" {
+		http.Error(w, "Prompt template ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.promptTemplateStore.DeletePromptTemplate(r.Context(), templateID); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete prompt template: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Prompt template deleted successfully"))
+}
+
+// validatePromptTemplate checks that a template has a name and at least one message, that
+// every message has a known role, and that every message parses. Tags are trimmed and
+// empty ones dropped.
+func validatePromptTemplate(t *models.PromptTemplate) error {
+	if strings.TrimSpace(t.Name) == "" {
+		return errors.New("name is required")
+	}
+	if len(t.Messages) == 0 {
+		return errors.New("at least one message is required")
+	}
+	for i, m := range t.Messages {
+		if !m.Role.Valid() {
+			return fmt.Errorf("message %d has unknown role %q", i+1, m.Role)
+		}
+		if _, err := templating.Parse(m.Content); err != nil {
+			return fmt.Errorf("message %d: %v", i+1, err)
+		}
+	}
+
+	tags := t.Tags[:0]
+	for _, tag := range t.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	t.Tags = tags
+	return nil
+}
@@ -0,0 +1,221 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/ClarionDev/clarion/internal/database"
+	"github.com/ClarionDev/clarion/internal/git"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	runBranchPrefix = "clarion/"
+
+	// Trailers recorded on commits made for agent runs.
+	runIDTrailer      = "Clarion-Run-Id"
+	baseBranchTrailer = "Clarion-Base-Branch"
+)
+
+func runBranchName(runID string) string {
+	return runBranchPrefix + runID
+}
+
+// handleApplyChangesOnBranch applies a run's changes on a new clarion/<run-id> branch and
+// commits them with the run summary as the message. The working tree must be clean so the
+// commit contains only the agent's changes, and no change may target an untracked file,
+// since undoing or rejecting the run would delete it. On failure the branch is removed
+// again.
+func (s *Server) handleApplyChangesOnBranch(w http.ResponseWriter, r *http.Request, req ApplyChangesRequest) {
+	if req.RunID == "" {
+		http.Error(w, "Run ID is required to apply changes on a branch", http.StatusBadRequest)
+		return
+	}
+	repo, ok := openGitRepo(w, r, req.RootPath)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	branch := runBranchName(req.RunID)
+	base, err := repo.CurrentBranch(ctx)
+	if err != nil {
+		writeGitError(w, "read current branch", err)
+		return
+	}
+	if base == "" {
+		// Detached HEAD: the base is the commit itself.
+		if base, err = repo.ResolveCommit(ctx, "HEAD"); err != nil {
+			writeGitError(w, "resolve HEAD", err)
+			return
+		}
+	}
+
+	paths, err := repoPaths(repo, req.RootPath, req.Changes, "")
+	if err != nil {
+		writeGitError(w, "resolve changed paths", err)
+		return
+	}
+	untracked, err := repo.UntrackedPaths(ctx, paths...)
+	if err != nil {
+		writeGitError(w, "check for untracked files", err)
+		return
+	}
+	if len(untracked) > 0 {
+		err := fmt.Errorf("%w: the run would overwrite untracked files: %s", git.ErrDirtyTree, strings.Join(untracked, ", "))
+		writeGitError(w, "apply changes on branch "+branch, err)
+		return
+	}
+
+	if err := repo.CreateBranch(ctx, branch, "", true); err != nil {
+		writeGitError(w, "create branch "+branch, err)
+		return
+	}
+
+	commit, err := s.commitRunChanges(ctx, repo, req.RootPath, req.RunID, base, req.Changes)
+	if err != nil {
+		if rbErr := abandonRunBranch(ctx, repo, req.RootPath, branch, base, req.Changes); rbErr != nil {
+			log.Printf("Failed to roll back branch %s: %v", branch, rbErr)
+		}
+		writeGitError(w, "apply changes on branch "+branch, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ApplyChangesBranchResponse{
+		Branch:     branch,
+		BaseBranch: base,
+		Commit:     newGitCommit(*commit),
+	})
+}
+
+// commitRunChanges applies a run's changes in projectDir and commits them with the run
+// summary as the message and the run ID and base branch as trailers.
+func (s *Server) commitRunChanges(ctx context.Context, repo *git.Repo, projectDir, runID, base string, changes []FileChange) (*git.Commit, error) {
+	if err := applyFileChanges(projectDir, changes); err != nil {
+		return nil, fmt.Errorf("failed to apply change for %w", err)
+	}
+
+	paths, err := repoPaths(repo, projectDir, changes, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := repo.Stage(ctx, paths...); err != nil {
+		return nil, err
+	}
+
+	message := fmt.Sprintf("%s\n\n%s: %s\n%s: %s\n", s.runSummary(ctx, runID), runIDTrailer, runID, baseBranchTrailer, base)
+	return repo.Commit(ctx, message)
+}
+
+// repoPaths converts the paths of changes, which are relative to the project directory,
+// to paths relative to the repository root. A non-empty action keeps only those changes.
+func repoPaths(repo *git.Repo, projectDir string, changes []FileChange, action string) ([]string, error) {
+	prefix, err := repo.Prefix(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, change := range changes {
+		if action == "" || change.Action == action {
+			paths = append(paths, prefix+filepath.ToSlash(change.Path))
+		}
+	}
+	return paths, nil
+}
+
+// runSummary returns the summary of a saved run, or a generic message if the run has not
+// been saved or has no summary.
+func (s *Server) runSummary(ctx context.Context, runID string) string {
+	fallback := fmt.Sprintf("Apply changes from Clarion run %s", runID)
+
+	run, err := s.runStore.GetRun(ctx, runID)
+	if err != nil {
+		return fallback
+	}
+	var runData database.AgentRunData
+	if err := json.Unmarshal([]byte(run.RunData), &runData); err != nil {
+		return fallback
+	}
+	if summary := strings.TrimSpace(runData.Output.Summary); summary != "" {
+		return summary
+	}
+	return fallback
+}
+
+// abandonRunBranch undoes a partially applied run: it discards the changes, returns to the
+// base branch and deletes the run branch.
+func abandonRunBranch(ctx context.Context, repo *git.Repo, projectDir, branch, base string, changes []FileChange) error {
+	created, err := repoPaths(repo, projectDir, changes, "create")
+	if err != nil {
+		return err
+	}
+	if err := repo.ResetHard(ctx); err != nil {
+		return err
+	}
+	if err := repo.RemoveUntracked(ctx, created...); err != nil {
+		return err
+	}
+	if err := repo.Checkout(ctx, base); err != nil {
+		return err
+	}
+	return repo.DeleteBranch(ctx, branch)
+}
+
+// handleRejectRunBranch throws away the clarion/<run-id> branch of a run. If it is checked
+// out, the base branch recorded on its commit is checked out first, which requires a clean
+// working tree.
+func (s *Server) handleRejectRunBranch(w http.ResponseWriter, r *http.Request) {
+	var req GitRepoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	repo, ok := openGitRepo(w, r, req.Path)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	branch := runBranchName(chi.URLParam(r, "runID"))
+
+	if _, err := repo.ResolveCommit(ctx, "refs/heads/"+branch); err != nil {
+		http.Error(w, fmt.Sprintf("Branch %s does not exist", branch), http.StatusNotFound)
+		return
+	}
+
+	current, err := repo.CurrentBranch(ctx)
+	if err != nil {
+		writeGitError(w, "read current branch", err)
+		return
+	}
+	if current == branch {
+		base, err := repo.Trailer(ctx, branch, baseBranchTrailer)
+		if err != nil {
+			writeGitError(w, "read base branch", err)
+			return
+		}
+		if base == "" {
+			writeGitError(w, "reject run", errors.New("the run branch is checked out and records no base branch to return to"))
+			return
+		}
+		if err := repo.Checkout(ctx, base); err != nil {
+			writeGitError(w, "check out base branch "+base, err)
+			return
+		}
+	}
+
+	if err := repo.DeleteBranch(ctx, branch); err != nil {
+		writeGitError(w, "delete branch "+branch, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Run branch deleted successfully"))
+}
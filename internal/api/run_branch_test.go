@@ -0,0 +1,167 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newGitProject creates a repository on main with one committed file, README.md.
+func newGitProject(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# Test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch=main"},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "commit.gpgsign", "false"},
+		{"add", "README.md"},
+		{"commit", "--quiet", "-m", "Initial"},
+	} {
+		gitOutput(t, dir, args...)
+	}
+	return dir
+}
+
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestApplyOnBranchAndReject(t *testing.T) {
+	s := newTestServer(t)
+	dir := newGitProject(t)
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := serve(t, s, http.MethodPost, "/api/v2/fs/files/apply", ApplyChangesRequest{
+		RootPath: dir, RunID: "run-1", CreateBranch: true,
+		Changes: []FileChange{
+			{Action: "create", Path: "src/new.txt", NewContent: "new\n"},
+			{Action: "modify", Path: "README.md", NewContent: "# Changed\n"},
+		},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("apply = %d %s, want 200", rec.Code, rec.Body)
+	}
+	if branch := gitOutput(t, dir, "branch", "--show-current"); branch != "clarion/run-1" {
+		t.Fatalf("current branch = %q, want clarion/run-1", branch)
+	}
+
+	rec = serve(t, s, http.MethodPost, "/api/v2/runs/run-1/reject", GitRepoRequest{Path: dir})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reject = %d %s, want 200", rec.Code, rec.Body)
+	}
+	if branch := gitOutput(t, dir, "branch", "--show-current"); branch != "main" {
+		t.Errorf("current branch after reject = %q, want main", branch)
+	}
+	if branches := gitOutput(t, dir, "branch", "--list", "clarion/*"); branches != "" {
+		t.Errorf("run branch still exists: %s", branches)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "src/new.txt")); !os.IsNotExist(err) {
+		t.Errorf("created file still exists after reject: %v", err)
+	}
+	if got := readFile(t, filepath.Join(dir, "notes.txt")); got != "mine\n" {
+		t.Errorf("untracked notes.txt = %q, want it kept", got)
+	}
+}
+
+func TestApplyOnBranchRefusesUntrackedTargets(t *testing.T) {
+	s := newTestServer(t)
+	dir := newGitProject(t)
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := serve(t, s, http.MethodPost, "/api/v2/fs/files/apply", ApplyChangesRequest{
+		RootPath: dir, RunID: "run-1", CreateBranch: true,
+		Changes: []FileChange{{Action: "create", Path: "notes.txt", NewContent: "agent\n"}},
+	})
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "notes.txt") {
+		t.Fatalf("apply over an untracked file = %d %s, want 409 naming it", rec.Code, rec.Body)
+	}
+	if got := readFile(t, filepath.Join(dir, "notes.txt")); got != "mine\n" {
+		t.Errorf("untracked notes.txt = %q, want it unchanged", got)
+	}
+	if branches := gitOutput(t, dir, "branch", "--list", "clarion/*"); branches != "" {
+		t.Errorf("run branch was created: %s", branches)
+	}
+}
+
+func TestApplyOnBranchRollsBackFailure(t *testing.T) {
+	s := newTestServer(t)
+	dir := newGitProject(t)
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := serve(t, s, http.MethodPost, "/api/v2/fs/files/apply", ApplyChangesRequest{
+		RootPath: dir, RunID: "run-1", CreateBranch: true,
+		Changes: []FileChange{
+			{Action: "create", Path: "new.txt", NewContent: "new\n"},
+			{Action: "modify", Path: "README.md", NewContent: "# Changed\n"},
+			{Action: "delete", Path: "missing.txt"},
+		},
+	})
+	if rec.Code == http.StatusOK {
+		t.Fatal("apply with a failing change succeeded, want an error")
+	}
+	if branch := gitOutput(t, dir, "branch", "--show-current"); branch != "main" {
+		t.Errorf("current branch = %q, want main", branch)
+	}
+	if branches := gitOutput(t, dir, "branch", "--list", "clarion/*"); branches != "" {
+		t.Errorf("run branch still exists: %s", branches)
+	}
+	if status := gitOutput(t, dir, "status", "--porcelain"); status != "?? notes.txt" {
+		t.Errorf("status after rollback = %q, want only the untracked notes.txt", status)
+	}
+	if got := readFile(t, filepath.Join(dir, "notes.txt")); got != "mine\n" {
+		t.Errorf("untracked notes.txt = %q, want it kept", got)
+	}
+}
+
+func TestWorktreeAndRunBranchDoNotCollide(t *testing.T) {
+	s := newTestServer(t)
+	dir := newGitProject(t)
+
+	rec := serve(t, s, http.MethodPost, "/api/v2/runs/run-1/worktree/", CreateWorktreeRequest{ProjectRoot: dir})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create worktree = %d %s, want 200", rec.Code, rec.Body)
+	}
+	rec = serve(t, s, http.MethodPost, "/api/v2/fs/files/apply", ApplyChangesRequest{
+		RootPath: dir, RunID: "run-1", CreateBranch: true,
+		Changes: []FileChange{{Action: "create", Path: "new.txt", NewContent: "new\n"}},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("apply on branch with a worktree for the run = %d %s, want 200", rec.Code, rec.Body)
+	}
+	branches := gitOutput(t, dir, "branch", "--list", "--format=%(refname:short)", "clarion/*")
+	if branches != "clarion/run-1\nclarion/wt/run-1" {
+		t.Errorf("branches = %q, want the run branch and the worktree branch", branches)
+	}
+}
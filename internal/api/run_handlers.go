@@ -2,12 +2,16 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/storage"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -57,6 +61,10 @@ func (s *Server) handleSaveRun(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("Run saved successfully"))
 }
 
+// handleListRuns returns a page of a project's runs as summaries, newest first. The query
+// parameters agent_id, status, from, to (dates or RFC 3339 times, "to" inclusive for a
+// date) and q (free text over prompts and summaries) filter the runs; limit and offset page
+// through them.
 func (s *Server) handleListRuns(w http.ResponseWriter, r *http.Request) {
 	projectID := chi.URLParam(r, "projectID")
 	if projectID == "" {
@@ -64,20 +72,125 @@ func (s *Server) handleListRuns(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	runs, err := s.runStore.ListRunsByProject(r.Context(), projectID)
+	filter, err := parseRunFilter(r.URL.Query())
 	if err != nil {
-		http.Error(w, "Failed to list runs", http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Invalid run filter: %v", err), http.StatusBadRequest)
 		return
 	}
+	filter.ProjectID = projectID
 
-	runDataList := make([]json.RawMessage, 0)
-	for _, run := range runs {
-		runDataList = append(runDataList, []byte(run.RunData))
+	page, err := s.runStore.ListRuns(r.Context(), filter)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to list runs: %v", err), http.StatusInternalServerError)
+		return
 	}
+	writeJSON(w, page)
+}
 
+// handleGetRun returns the full record of a saved run.
+func (s *Server) handleGetRun(w http.ResponseWriter, r *http.Request) {
+	run, err := s.runStore.GetRun(r.Context(), chi.URLParam(r, "runID"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, fmt.Sprintf("Failed to get run: %v", err), status)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(run.RunData))
+}
+
+// handleDeleteRun deletes a saved run. A worktree the run still has is removed; its
+// branch is kept.
+func (s *Server) handleDeleteRun(w http.ResponseWriter, r *http.Request) {
+	runID := chi.URLParam(r, "runID")
+	if err := s.runStore.DeleteRun(r.Context(), runID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, fmt.Sprintf("Failed to delete run: %v", err), status)
+		return
+	}
+	s.worktrees.RemoveRuns(r.Context(), []string{runID})
+
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(runDataList); err != nil {
-		log.Printf("Failed to write run list response: %v", err)
+	w.Write([]byte("Run deleted successfully"))
+}
+
+type DeleteRunsRequest struct {
+	IDs []string `json:"ids"`
+}
+
+type DeleteRunsResponse struct {
+	Deleted int `json:"deleted"`
+}
+
+// handleDeleteRuns deletes several saved runs at once. IDs without a run are ignored.
+func (s *Server) handleDeleteRuns(w http.ResponseWriter, r *http.Request) {
+	var req DeleteRunsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.IDs) == 0 {
+		http.Error(w, "At least one run ID is required", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := s.runStore.DeleteRuns(r.Context(), req.IDs)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete runs: %v", err), http.StatusInternalServerError)
+		return
+	}
+	s.worktrees.RemoveRuns(r.Context(), req.IDs)
+	writeJSON(w, DeleteRunsResponse{Deleted: deleted})
+}
+
+func parseRunFilter(q url.Values) (storage.RunFilter, error) {
+	filter := storage.RunFilter{
+		AgentID: q.Get("agent_id"),
+		Status:  q.Get("status"),
+		Query:   q.Get("q"),
+	}
+	var err error
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit < 1 || filter.Limit > storage.MaxRunPageSize {
+			return filter, fmt.Errorf("limit must be between 1 and %d", storage.MaxRunPageSize)
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if filter.Offset, err = strconv.Atoi(v); err != nil || filter.Offset < 0 {
+			return filter, errors.New("offset must be a non-negative number")
+		}
+	}
+	if v := q.Get("from"); v != "" {
+		if filter.Since, _, err = parseRunTime(v); err != nil {
+			return filter, fmt.Errorf("from: %w", err)
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		var dateOnly bool
+		if filter.Until, dateOnly, err = parseRunTime(v); err != nil {
+			return filter, fmt.Errorf("to: %w", err)
+		}
+		if dateOnly {
+			filter.Until = filter.Until.AddDate(0, 0, 1)
+		}
+	}
+	return filter, nil
+}
+
+// parseRunTime parses an RFC 3339 time or a date, and reports whether it was a date.
+func parseRunTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%q is neither a date (YYYY-MM-DD) nor an RFC 3339 time", value)
 	}
+	return t, false, nil
 }
@@ -0,0 +1,279 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/projectconfig"
+	"github.com/ClarionDev/clarion/internal/runner"
+	"github.com/ClarionDev/clarion/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// Context sources for a rerun.
+const (
+	RerunContextSnapshot = "snapshot" // the files as the original run saw them
+	RerunContextCurrent  = "current"  // the files as they are now
+)
+
+// RerunRequest lists what a rerun changes; omitted fields keep the original run's values.
+type RerunRequest struct {
+	// AgentRevision runs a revision of the original run's agent, using its system prompt,
+	// output schema and LLM config.
+	AgentRevision int    `json:"agent_revision,omitempty"`
+	Prompt        string `json:"prompt,omitempty"`
+	// LLMConfig fields that are set replace the provider, model, parameters or config.
+	LLMConfig     *models.LLMConfig `json:"llm_config,omitempty"`
+	CodebasePaths []string          `json:"codebase_paths,omitempty"`
+	Variables     map[string]string `json:"variables,omitempty"`
+	// Context is RerunContextSnapshot or RerunContextCurrent. It defaults to the snapshot
+	// when the run has one and the file set is not changed.
+	Context string `json:"context,omitempty"`
+}
+
+// runRecord is a saved run as the app writes it. Its fields use the app's camelCase
+// names; the request keeps the API's.
+type runRecord struct {
+	ID              string                  `json:"id"`
+	Prompt          string                  `json:"prompt"`
+	AgentName       string                  `json:"agentName"`
+	AgentID         string                  `json:"agentId,omitempty"`
+	AgentRevision   int                     `json:"agentRevision,omitempty"`
+	Status          string                  `json:"status"`
+	Output          runRecordOutput         `json:"output"`
+	RawRequest      AgentRunRequest         `json:"rawRequest"`
+	ContextSnapshot *runner.ContextSnapshot `json:"contextSnapshot,omitempty"`
+	// RerunOf is the ID of the run this one replays.
+	RerunOf string `json:"rerunOf,omitempty"`
+}
+
+type runRecordOutput struct {
+	Summary     string           `json:"summary"`
+	FileChanges []map[string]any `json:"fileChanges"`
+	RawOutput   map[string]any   `json:"rawOutput"`
+	Error       string           `json:"error,omitempty"`
+	TokenUsage  any              `json:"tokenUsage,omitempty"`
+	Cache       any              `json:"cache,omitempty"`
+}
+
+// handleRerunRun runs a saved run again with the changes in the request and saves the
+// result as a new run of the same project, linked to the original. The new run's record
+// is returned whether the agent succeeded or not; its status tells which.
+func (s *Server) handleRerunRun(w http.ResponseWriter, r *http.Request) {
+	var req RerunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	original, err := s.runStore.GetRun(r.Context(), chi.URLParam(r, "runID"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, fmt.Sprintf("Failed to get run: %v", err), status)
+		return
+	}
+	var stored runRecord
+	if err := json.Unmarshal([]byte(original.RunData), &stored); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read run: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	rerun, runReq, err := s.rerunRequest(r.Context(), &stored, req)
+	if err != nil {
+		var invalid rerunError
+		switch {
+		case errors.As(err, &invalid):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, storage.ErrNotFound):
+			writeLookupError(w, err)
+		default:
+			writeRunError(w, "Failed to prepare run", err)
+		}
+		return
+	}
+
+	progress := s.runProgress(rerun.ID)
+	progress("started", "", nil)
+	output, err := s.runner.Run(r.Context(), runReq, progress)
+	if err != nil {
+		var missing *runner.MissingVariablesError
+		if errors.As(err, &missing) || runErrorStatus(err) == http.StatusBadRequest {
+			progress("failed", "", err)
+			writeRunError(w, "Rerun failed", err)
+			return
+		}
+		progress("failed", "", err)
+		rerun.Status = "error"
+		rerun.Output = runRecordOutput{Summary: "An error occurred.", FileChanges: []map[string]any{}, RawOutput: map[string]any{"error": err.Error()}, Error: err.Error()}
+	} else {
+		progress("completed", "", nil)
+		rerun.Status = "success"
+		rerun.Output = recordOutput(rerun.ID, output)
+	}
+
+	data, err := json.Marshal(rerun)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save run: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if err := s.runStore.SaveRun(r.Context(), &models.Run{ID: rerun.ID, ProjectID: original.ProjectID, RunData: string(data)}); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save run: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(data)
+}
+
+// rerunError is a rerun request that does not fit the original run.
+type rerunError string
+
+func (e rerunError) Error() string { return string(e) }
+
+// rerunRequest applies a rerun's changes to a stored run and returns the record of the new
+// run, without its status and output, and the request to run it with.
+func (s *Server) rerunRequest(ctx context.Context, stored *runRecord, req RerunRequest) (*runRecord, runner.Request, error) {
+	apiReq := stored.RawRequest
+	apiReq.AgentID = stored.AgentID
+	apiReq.AgentRevision = 0
+	apiReq.Isolate = false
+	revision := stored.AgentRevision
+
+	var pinned *models.AgentRevision
+	if req.AgentRevision != 0 {
+		if stored.AgentID == "" {
+			return nil, runner.Request{}, rerunError("the run has no agent to take a revision of")
+		}
+		var err error
+		if pinned, err = s.agentStore.GetAgentRevision(ctx, stored.AgentID, req.AgentRevision); err != nil {
+			return nil, runner.Request{}, err
+		}
+		apiReq.SystemInstruction = pinned.Agent.SystemPrompt
+		apiReq.OutputSchema = map[string]any{"schema": pinned.Agent.OutputSchema.Schema}
+		apiReq.LLMConfig = pinned.Agent.LLMConfig
+		revision = req.AgentRevision
+	}
+	if apiReq.AgentID != "" {
+		// The agent is only needed for its variables; a deleted agent does not stop a rerun.
+		if _, err := s.agentStore.GetAgent(ctx, apiReq.AgentID); errors.Is(err, storage.ErrNotFound) {
+			apiReq.AgentID = ""
+		} else if err != nil {
+			return nil, runner.Request{}, err
+		}
+	}
+	if req.Prompt != "" {
+		apiReq.Prompt = req.Prompt
+	}
+	if c := req.LLMConfig; c != nil {
+		if c.Provider != "" {
+			apiReq.LLMConfig.Provider = c.Provider
+			apiReq.LLMConfig.ConfigID = ""
+		}
+		if c.Model != "" {
+			apiReq.LLMConfig.Model = c.Model
+		}
+		if c.Parameters != nil {
+			apiReq.LLMConfig.Parameters = c.Parameters
+		}
+		if c.ConfigID != "" {
+			apiReq.LLMConfig.ConfigID = c.ConfigID
+			if c.Provider == "" {
+				// A saved config alone brings its own provider.
+				apiReq.LLMConfig.Provider = ""
+			}
+		}
+		if apiReq.LLMConfig.ConfigID == "" {
+			// A new provider takes the project's default config when it is for that
+			// provider, or else the first saved config of the provider.
+			settings := s.projectSettings(ctx, apiReq.ProjectRoot)
+			apiReq.LLMConfig.ConfigID = projectconfig.LLMConfigID(ctx, s.llmConfigStore, settings, apiReq.LLMConfig.Provider)
+		}
+		if c.ConfigID != "" || apiReq.LLMConfig.ConfigID == "" {
+			configs, err := s.candidateConfigs(ctx, []models.LLMConfig{apiReq.LLMConfig})
+			if errors.Is(err, errInvalidLLMConfig) {
+				return nil, runner.Request{}, rerunError(err.Error())
+			} else if err != nil {
+				return nil, runner.Request{}, err
+			}
+			apiReq.LLMConfig = configs[0]
+		}
+	}
+	if req.Variables != nil {
+		apiReq.Variables = req.Variables
+	}
+
+	source := req.Context
+	switch {
+	case source == "" && req.CodebasePaths == nil && stored.ContextSnapshot != nil:
+		source = RerunContextSnapshot
+	case source == "":
+		source = RerunContextCurrent
+	case source != RerunContextSnapshot && source != RerunContextCurrent:
+		return nil, runner.Request{}, rerunError(fmt.Sprintf("context must be %q or %q", RerunContextSnapshot, RerunContextCurrent))
+	}
+	if source == RerunContextSnapshot {
+		if stored.ContextSnapshot == nil {
+			return nil, runner.Request{}, rerunError("the run has no context snapshot; use the current files")
+		}
+		if req.CodebasePaths != nil {
+			return nil, runner.Request{}, rerunError("a changed file set needs the current files")
+		}
+	}
+	if req.CodebasePaths != nil {
+		apiReq.CodebasePaths = req.CodebasePaths
+	}
+
+	runReq, _, err := s.runRequest(ctx, apiReq)
+	if err != nil {
+		return nil, runReq, err
+	}
+	if pinned != nil {
+		// The variables the pinned revision declares, not the ones of the current agent.
+		runReq.UserVariables = pinned.Agent.UserVariables
+	}
+	if source == RerunContextSnapshot {
+		runReq.Context = stored.ContextSnapshot
+	} else if runReq.Context, err = runner.Snapshot(ctx, runReq.ProjectRoot, runReq.CodebasePaths, runReq.GitContext); err != nil {
+		return nil, runReq, err
+	}
+
+	apiReq.RunID = ""
+	rerun := &runRecord{
+		ID:              "run-" + uuid.New().String(),
+		Prompt:          apiReq.Prompt,
+		AgentName:       stored.AgentName,
+		AgentID:         stored.AgentID,
+		AgentRevision:   revision,
+		RawRequest:      apiReq,
+		ContextSnapshot: runReq.Context,
+		RerunOf:         stored.ID,
+	}
+	return rerun, runReq, nil
+}
+
+// recordOutput turns a provider's output into the output of a run record.
+func recordOutput(runID string, output map[string]any) runRecordOutput {
+	out := runRecordOutput{RawOutput: output, FileChanges: []map[string]any{}, TokenUsage: output["token_usage"], Cache: output["cache"]}
+	out.Summary, _ = output["summary"].(string)
+	if out.Summary == "" {
+		out.Summary = "No summary provided."
+	}
+	changes, _ := output["file_changes"].([]any)
+	for i, c := range changes {
+		if change, ok := c.(map[string]any); ok {
+			change = maps.Clone(change)
+			change["id"] = fmt.Sprintf("%s-change-%d", runID, i)
+			out.FileChanges = append(out.FileChanges, change)
+		}
+	}
+	return out
+}
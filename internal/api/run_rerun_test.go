@@ -0,0 +1,185 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/ClarionDev/clarion/internal/agent"
+	"github.com/ClarionDev/clarion/internal/llm"
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/storage"
+)
+
+// echoProvider answers with the model and config it was called with.
+type echoProvider struct{}
+
+func (echoProvider) Generate(ctx context.Context, messages []llm.ChatMessage, request models.AgentRunRequest, store storage.LLMConfigStore) (map[string]any, error) {
+	return map[string]any{"summary": request.LLMConfig.Model + " via " + request.LLMConfig.ConfigID, "file_changes": []any{}}, nil
+}
+
+const echoProviderName = "API Test"
+
+func init() {
+	llm.RegisterProvider(echoProviderName, echoProvider{})
+}
+
+// rerunFixture saves a project, two LLM configs of different providers, an agent with two
+// revisions that declare different variables, and a run of the agent's first revision.
+func rerunFixture(t *testing.T, s *Server) (root string, stored *runRecord) {
+	t.Helper()
+	ctx := context.Background()
+	root = t.TempDir()
+	if err := s.projectStore.SaveProject(ctx, &models.Project{ID: "p1", Name: "Project", Path: root}); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []*models.LLMProviderConfig{
+		{ID: "cfg-echo", Name: "Echo", Provider: echoProviderName, APIKey: "key"},
+		{ID: "cfg-openai", Name: "OpenAI", Provider: models.ProviderOpenAI, APIKey: "sk-test"},
+	} {
+		if err := s.llmConfigStore.SaveLLMConfig(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a := &models.Agent{
+		Profile:       agent.AgentProfile{ID: "agent-1", Name: "Agent"},
+		SystemPrompt:  "First prompt",
+		UserVariables: []models.UserVariableDef{{Name: "ticket", Required: true}},
+		LLMConfig:     models.LLMConfig{Provider: echoProviderName, Model: "first", ConfigID: "cfg-echo"},
+	}
+	if _, err := s.agentStore.SaveAgentRevision(ctx, a, "First"); err != nil {
+		t.Fatal(err)
+	}
+	a.SystemPrompt = "Second prompt"
+	a.UserVariables = []models.UserVariableDef{{Name: "branch"}}
+	a.LLMConfig.Model = "second"
+	if _, err := s.agentStore.SaveAgentRevision(ctx, a, "Second"); err != nil {
+		t.Fatal(err)
+	}
+
+	stored = &runRecord{
+		ID: "run-1", Prompt: "Do it", AgentName: "Agent", AgentID: "agent-1", AgentRevision: 1, Status: "success",
+		RawRequest: AgentRunRequest{
+			SystemInstruction: "First prompt",
+			Prompt:            "Do it",
+			ProjectRoot:       root,
+			LLMConfig:         models.LLMConfig{Provider: echoProviderName, Model: "first", ConfigID: "cfg-echo"},
+			Variables:         map[string]string{"ticket": "T-1"},
+		},
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.runStore.SaveRun(ctx, &models.Run{ID: stored.ID, ProjectID: "p1", RunData: string(data)}); err != nil {
+		t.Fatal(err)
+	}
+	return root, stored
+}
+
+func TestRerunRequestLLMConfigOverrides(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	root, stored := rerunFixture(t, s)
+
+	tests := []struct {
+		name     string
+		override models.LLMConfig
+		want     models.LLMConfig
+	}{
+		{"model", models.LLMConfig{Model: "other"}, models.LLMConfig{Provider: echoProviderName, Model: "other", ConfigID: "cfg-echo"}},
+		{"provider", models.LLMConfig{Provider: models.ProviderOpenAI}, models.LLMConfig{Provider: models.ProviderOpenAI, Model: "first", ConfigID: "cfg-openai"}},
+		{"provider and config", models.LLMConfig{Provider: models.ProviderOpenAI, Model: "gpt-4o", ConfigID: "cfg-openai"}, models.LLMConfig{Provider: models.ProviderOpenAI, Model: "gpt-4o", ConfigID: "cfg-openai"}},
+		{"config only", models.LLMConfig{ConfigID: "cfg-openai"}, models.LLMConfig{Provider: models.ProviderOpenAI, Model: "first", ConfigID: "cfg-openai"}},
+	}
+	for _, tt := range tests {
+		override := tt.override
+		_, req, err := s.rerunRequest(ctx, stored, RerunRequest{LLMConfig: &override})
+		if err != nil {
+			t.Fatalf("%s: rerunRequest() error = %v", tt.name, err)
+		}
+		got := req.LLMConfig
+		if got.Provider != tt.want.Provider || got.Model != tt.want.Model || got.ConfigID != tt.want.ConfigID {
+			t.Errorf("%s: LLMConfig = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	for name, override := range map[string]models.LLMConfig{
+		"unknown config":    {ConfigID: "cfg-missing"},
+		"provider mismatch": {Provider: models.ProviderOpenAI, ConfigID: "cfg-echo"},
+	} {
+		var invalid rerunError
+		if _, _, err := s.rerunRequest(ctx, stored, RerunRequest{LLMConfig: &override}); !errors.As(err, &invalid) {
+			t.Errorf("%s: rerunRequest() error = %v, want a rerun error", name, err)
+		}
+	}
+
+	// The project's default config wins over the first saved one of the provider.
+	if err := s.llmConfigStore.SaveLLMConfig(ctx, &models.LLMProviderConfig{ID: "cfg-openai-2", Name: "OpenAI 2", Provider: models.ProviderOpenAI, APIKey: "sk-2"}); err != nil {
+		t.Fatal(err)
+	}
+	project := &models.Project{ID: "p1", Name: "Project", Path: root, Settings: models.ProjectSettings{LLMConfigID: "cfg-openai-2"}}
+	if err := s.projectStore.SaveProject(ctx, project); err != nil {
+		t.Fatal(err)
+	}
+	_, req, err := s.rerunRequest(ctx, stored, RerunRequest{LLMConfig: &models.LLMConfig{Provider: models.ProviderOpenAI}})
+	if err != nil || req.LLMConfig.ConfigID != "cfg-openai-2" {
+		t.Errorf("provider with a project default: ConfigID = %q, %v; want cfg-openai-2", req.LLMConfig.ConfigID, err)
+	}
+}
+
+func TestRerunRequestPinnedRevision(t *testing.T) {
+	s := newTestServer(t)
+	_, stored := rerunFixture(t, s)
+
+	rerun, req, err := s.rerunRequest(context.Background(), stored, RerunRequest{AgentRevision: 1})
+	if err != nil {
+		t.Fatalf("rerunRequest() error = %v", err)
+	}
+	if req.SystemInstruction != "First prompt" || req.LLMConfig.Model != "first" || rerun.AgentRevision != 1 {
+		t.Errorf("pinned rerun = %q with model %q at revision %d, want revision 1's", req.SystemInstruction, req.LLMConfig.Model, rerun.AgentRevision)
+	}
+	if len(req.UserVariables) != 1 || req.UserVariables[0].Name != "ticket" {
+		t.Errorf("UserVariables = %+v, want revision 1's ticket", req.UserVariables)
+	}
+
+	if _, _, err := s.rerunRequest(context.Background(), stored, RerunRequest{AgentRevision: 9}); err == nil {
+		t.Error("rerunRequest() with a missing revision succeeded, want an error")
+	}
+}
+
+func TestHandleRerunRun(t *testing.T) {
+	s := newTestServer(t)
+	rerunFixture(t, s)
+
+	rec := serve(t, s, http.MethodPost, "/api/v2/runs/run-1/rerun", RerunRequest{AgentRevision: 1})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("rerun = %d %s, want 201", rec.Code, rec.Body)
+	}
+	var rerun runRecord
+	if err := json.Unmarshal(rec.Body.Bytes(), &rerun); err != nil {
+		t.Fatal(err)
+	}
+	if rerun.RerunOf != "run-1" || rerun.AgentRevision != 1 || rerun.Status != "success" || rerun.Output.Summary != "first via cfg-echo" {
+		t.Errorf("rerun = %+v, want a successful rerun of run-1 at revision 1", rerun)
+	}
+	if _, err := s.runStore.GetRun(context.Background(), rerun.ID); err != nil {
+		t.Errorf("rerun was not saved: %v", err)
+	}
+
+	// Revision 1 requires the ticket variable.
+	rec = serve(t, s, http.MethodPost, "/api/v2/runs/run-1/rerun", RerunRequest{AgentRevision: 1, Variables: map[string]string{"branch": "main"}})
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("rerun without the pinned revision's variable = %d %s, want 422", rec.Code, rec.Body)
+	}
+	rec = serve(t, s, http.MethodPost, "/api/v2/runs/run-1/rerun", RerunRequest{LLMConfig: &models.LLMConfig{ConfigID: "cfg-missing"}})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("rerun with an unknown config = %d %s, want 400", rec.Code, rec.Body)
+	}
+	if rec := serve(t, s, http.MethodPost, "/api/v2/runs/missing/rerun", RerunRequest{}); rec.Code != http.StatusNotFound {
+		t.Errorf("rerun of a missing run = %d, want 404", rec.Code)
+	}
+}
@@ -10,32 +10,75 @@ import (
 	"path/filepath"
 
 	"github.com/ClarionDev/clarion/internal/codebase"
+	"github.com/ClarionDev/clarion/internal/config"
 	"github.com/ClarionDev/clarion/internal/fs"
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/projectconfig"
+	"github.com/ClarionDev/clarion/internal/runner"
 	"github.com/ClarionDev/clarion/internal/storage"
+	"github.com/ClarionDev/clarion/internal/workflow"
+	"github.com/ClarionDev/clarion/internal/worktree"
+	"github.com/ClarionDev/clarion/internal/ws"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 )
 
 type Server struct {
-	router         *chi.Mux
-	agentStore     storage.AgentStore
-	llmConfigStore storage.LLMConfigStore
-	projectStore   storage.ProjectStore
-	runStore       storage.RunStore
+	router              *chi.Mux
+	settings            *config.Settings
+	agentStore          storage.AgentStore
+	llmConfigStore      storage.LLMConfigStore
+	projectStore        storage.ProjectStore
+	runStore            storage.RunStore
+	canvasStore         storage.CanvasStore
+	canvasRunStore      storage.CanvasRunStore
+	promptTemplateStore storage.PromptTemplateStore
+	comparisonStore     storage.ComparisonStore
+	evalStore           storage.EvalStore
+	responseCacheStore  storage.ResponseCacheStore
+	runner              *runner.Runner
+	workflows           *workflow.Executor
+	worktrees           *worktree.Manager
+	hub                 *ws.Hub
+	terminal            *terminalTopic
+	wsToken             string
 }
 
-func NewServer(agentStore storage.AgentStore, llmConfigStore storage.LLMConfigStore, projectStore storage.ProjectStore, runStore storage.RunStore) *Server {
+func NewServer(settings *config.Settings, agentStore storage.AgentStore, llmConfigStore storage.LLMConfigStore, projectStore storage.ProjectStore, runStore storage.RunStore, canvasStore storage.CanvasStore, canvasRunStore storage.CanvasRunStore, promptTemplateStore storage.PromptTemplateStore, comparisonStore storage.ComparisonStore, evalStore storage.EvalStore, responseCacheStore storage.ResponseCacheStore, worktrees *worktree.Manager) *Server {
 	r := chi.NewRouter()
 
 	s := &Server{
-		router:         r,
-		agentStore:     agentStore,
-		llmConfigStore: llmConfigStore,
-		projectStore:   projectStore,
-		runStore:       runStore,
+		router:              r,
+		settings:            settings,
+		agentStore:          agentStore,
+		llmConfigStore:      llmConfigStore,
+		projectStore:        projectStore,
+		runStore:            runStore,
+		canvasStore:         canvasStore,
+		canvasRunStore:      canvasRunStore,
+		promptTemplateStore: promptTemplateStore,
+		comparisonStore:     comparisonStore,
+		evalStore:           evalStore,
+		responseCacheStore:  responseCacheStore,
+		runner:              runner.New(llmConfigStore),
+		worktrees:           worktrees,
+		hub:                 ws.NewHub(),
+		terminal:            newTerminalTopic(),
+		wsToken:             newWSToken(),
 	}
 
+	cacheEnabled, cacheTTL := settings.ResponseCache()
+	s.runner.Cache = &runner.ResponseCache{Store: responseCacheStore, TTL: cacheTTL, Enabled: cacheEnabled}
+
+	s.workflows = workflow.NewExecutor(agentStore, canvasRunStore, s.runner, workflow.DefaultConcurrency, s.publishWorkflowProgress)
+	s.workflows.ProjectSettings = s.projectSettings
+	s.workflows.LLMConfigStore = llmConfigStore
+
+	s.hub.RegisterTopic(fsTopicName, &fsTopic{projectStore: projectStore})
+	s.hub.RegisterTopic(terminalTopicName, s.terminal)
+	s.hub.RegisterTopic(runTopicName, runTopic{})
+
 	s.setupMiddleware()
 	s.setupRoutes()
 
@@ -49,7 +92,7 @@ func (s *Server) setupMiddleware() {
 	s.router.Use(middleware.Recoverer)
 
 	cors := cors.New(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:*", "tauri://localhost"},
+		AllowedOrigins:   s.settings.CORSOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
@@ -80,12 +123,54 @@ func (s *Server) setupRoutes() {
 			r.Post("/save", s.handleSaveAgent)
 			r.Post("/run", s.handleAgentRun)
 			r.Post("/prepare-prompt", s.handlePreparePrompt)
+			r.Post("/import", s.handleImportAgent)
+			r.Get("/{agentID}/export", s.handleExportAgent)
+			r.Get("/{agentID}/revisions", s.handleListAgentRevisions)
+			r.Get("/{agentID}/revisions/diff", s.handleDiffAgentRevisions)
+			r.Get("/{agentID}/revisions/{revision}", s.handleGetAgentRevision)
+			r.Post("/{agentID}/revisions/{revision}/rollback", s.handleRollbackAgent)
+			r.Get("/{agentID}/evals", s.handleListEvalSuites)
+			r.Post("/{agentID}/evals", s.handleSaveEvalSuite)
 			r.Delete("/delete/{agentID}", s.handleDeleteAgent)
 		})
+		r.Route("/prompt-templates", func(r chi.Router) {
+			r.Get("/list", s.handleListPromptTemplates)
+			r.Post("/save", s.handleSavePromptTemplate)
+			r.Get("/{templateID}", s.handleGetPromptTemplate)
+			r.Delete("/delete/{templateID}", s.handleDeletePromptTemplate)
+		})
+		r.Route("/canvases", func(r chi.Router) {
+			r.Get("/list", s.handleListCanvases)
+			r.Post("/save", s.handleSaveCanvas)
+			r.Post("/validate", s.handleValidateCanvas)
+			r.Get("/{canvasID}", s.handleGetCanvas)
+			r.Post("/{canvasID}/run", s.handleRunCanvas)
+			r.Get("/{canvasID}/runs", s.handleListCanvasRuns)
+			r.Delete("/delete/{canvasID}", s.handleDeleteCanvas)
+		})
+		r.Route("/canvas-runs/{runID}", func(r chi.Router) {
+			r.Get("/", s.handleGetCanvasRun)
+			r.Post("/resume", s.handleResumeCanvasRun)
+			r.Post("/nodes/{nodeID}/approve", s.handleApproveCanvasNode)
+		})
 		r.Route("/llm-configs", func(r chi.Router) {
 			r.Get("/list", s.handleListLLMConfigs)
 			r.Post("/save", s.handleSaveLLMConfig)
 			r.Delete("/delete/{configID}", s.handleDeleteLLMConfig)
+			r.Post("/{configID}/test", s.handleTestLLMConfig)
+			r.Get("/{configID}/models", s.handleListLLMConfigModels)
+		})
+		r.Route("/git", func(r chi.Router) {
+			r.Post("/status", s.handleGitStatus)
+			r.Post("/log", s.handleGitLog)
+			r.Post("/commit-diff", s.handleGitCommitDiff)
+			r.Post("/diff", s.handleGitWorkingDiff)
+			r.Post("/stage", s.handleGitStage)
+			r.Post("/unstage", s.handleGitUnstage)
+			r.Post("/commit", s.handleGitCommit)
+			r.Post("/branches", s.handleGitBranches)
+			r.Post("/branches/create", s.handleGitCreateBranch)
+			r.Post("/checkout", s.handleGitCheckout)
 		})
 		r.Route("/terminal", func(r chi.Router) {
 			r.Get("/ws", s.handleTerminalWS)
@@ -96,9 +181,49 @@ func (s *Server) setupRoutes() {
 			r.Post("/update", s.handleUpdateProject)
 			r.Delete("/delete/{projectID}", s.handleDeleteProject)
 			r.Get("/{projectID}/runs", s.handleListRuns)
+			r.Get("/{projectID}/comparisons", s.handleListComparisons)
+			r.Get("/{projectID}/settings", s.handleGetProjectSettings)
+			r.Post("/{projectID}/settings", s.handleUpdateProjectSettings)
+			r.Post("/{projectID}/agents/sync", s.handleSyncProjectAgents)
 		})
+		r.Get("/ws", s.handleWS)
+		r.Get("/ws/token", s.handleWSToken)
 		r.Post("/runs/save", s.handleSaveRun)
+		r.Post("/runs/delete", s.handleDeleteRuns)
+		r.Get("/runs/{runID}", s.handleGetRun)
+		r.Delete("/runs/{runID}", s.handleDeleteRun)
+		r.Post("/runs/{runID}/rerun", s.handleRerunRun)
+		r.Post("/runs/{runID}/reject", s.handleRejectRunBranch)
+		r.Route("/runs/{runID}/worktree", func(r chi.Router) {
+			r.Post("/", s.handleCreateWorktree)
+			r.Get("/", s.handleGetWorktree)
+			r.Post("/apply", s.handleWorktreeApply)
+			r.Post("/integrate", s.handleWorktreeIntegrate)
+			r.Delete("/", s.handleDiscardWorktree)
+		})
 		r.Post("/tokenizer/count", s.handleTokenCount)
+		r.Post("/compare", s.handleCompare)
+		r.Route("/comparisons/{comparisonID}", func(r chi.Router) {
+			r.Get("/", s.handleGetComparison)
+			r.Get("/diff", s.handleComparisonDiff)
+		})
+		r.Route("/evals/{suiteID}", func(r chi.Router) {
+			r.Get("/", s.handleGetEvalSuite)
+			r.Delete("/", s.handleDeleteEvalSuite)
+			r.Post("/run", s.handleRunEvalSuite)
+			r.Get("/reports", s.handleListEvalReports)
+		})
+		r.Get("/eval-reports/{reportID}", s.handleGetEvalReport)
+		r.Route("/cache", func(r chi.Router) {
+			r.Get("/", s.handleListCache)
+			r.Delete("/", s.handleClearCache)
+			r.Delete("/{key}", s.handleDeleteCachedResponse)
+		})
+		r.Route("/maintenance", func(r chi.Router) {
+			r.Post("/prune", s.handlePruneRuns)
+			r.Post("/compact", s.handleCompactRuns)
+			r.Post("/vacuum", s.handleVacuum)
+		})
 	})
 }
 
@@ -125,6 +250,7 @@ func (s *Server) handleLoadDirectory(w http.ResponseWriter, r *http.Request) {
 	}
 
 	loader := codebase.NewLocalFSLoader()
+	loader.IgnoreDirs = s.projectSettings(r.Context(), req.Path).IgnoreDirs
 	cb, err := loader.LoadCodebaseStructure(req.Path)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to load codebase: %v", err), http.StatusInternalServerError)
@@ -213,8 +339,23 @@ func (s *Server) handleApplyChanges(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	for _, change := range req.Changes {
-		absolutePath := filepath.Join(req.RootPath, change.Path)
+	if req.CreateBranch {
+		s.handleApplyChangesOnBranch(w, r, req)
+		return
+	}
+
+	if err := applyFileChanges(req.RootPath, req.Changes); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to apply change for %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Changes applied successfully"))
+}
+
+func applyFileChanges(rootPath string, changes []FileChange) error {
+	for _, change := range changes {
+		absolutePath := filepath.Join(rootPath, change.Path)
 
 		var err error
 		switch change.Action {
@@ -229,13 +370,10 @@ func (s *Server) handleApplyChanges(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to apply change for %s: %v", change.Path, err), http.StatusInternalServerError)
-			return
+			return fmt.Errorf("%s: %w", change.Path, err)
 		}
 	}
-
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Changes applied successfully"))
+	return nil
 }
 
 func (s *Server) handlePreviewFilter(w http.ResponseWriter, r *http.Request) {
@@ -245,7 +383,11 @@ func (s *Server) handlePreviewFilter(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	statuses := codebase.GetFileStatuses(req.FilePaths, req.IncludeGlobs, req.ExcludeGlobs)
+	filters := models.FilterSet{IncludeGlobs: req.IncludeGlobs, ExcludeGlobs: req.ExcludeGlobs}
+	if req.ProjectRoot != "" {
+		filters = projectconfig.Filters(s.projectSettings(r.Context(), req.ProjectRoot), filters)
+	}
+	statuses := codebase.GetFileStatuses(req.FilePaths, filters.IncludeGlobs, filters.ExcludeGlobs)
 
 	resp := PreviewFilterResponse{
 		Status: statuses,
@@ -312,3 +454,12 @@ func (s *Server) handleWriteFile(w http.ResponseWriter, r *http.Request) {
 	}
 	w.WriteHeader(http.StatusOK)
 }
+
+// writeJSON writes v as a 200 OK JSON response.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ClarionDev/clarion/internal/config"
+	"github.com/ClarionDev/clarion/internal/database"
+	"github.com/ClarionDev/clarion/internal/secrets"
+	"github.com/ClarionDev/clarion/internal/storage"
+	"github.com/ClarionDev/clarion/internal/worktree"
+)
+
+// newTestServer returns a server backed by a migrated database in a temporary directory.
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := database.New(ctx, "sqlite", filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatalf("database.New: %v", err)
+	}
+	t.Cleanup(db.Close)
+	if err := db.RunMigrations(ctx); err != nil {
+		t.Fatalf("RunMigrations: %v", err)
+	}
+	vault, err := secrets.NewVault(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktrees, err := worktree.NewManager(filepath.Join(dir, "worktrees"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(worktrees.Close)
+
+	sqlDB := db.Handle().(*sql.DB)
+	settings := &config.Settings{DataDir: dir}
+	return NewServer(settings, storage.NewSQLiteAgentStore(sqlDB), storage.NewSQLiteLLMConfigStore(sqlDB, vault),
+		storage.NewSQLiteProjectStore(sqlDB), storage.NewSQLiteRunStore(sqlDB), storage.NewSQLiteCanvasStore(sqlDB),
+		storage.NewSQLiteCanvasRunStore(sqlDB), storage.NewSQLitePromptTemplateStore(sqlDB), storage.NewSQLiteComparisonStore(sqlDB),
+		storage.NewSQLiteEvalStore(sqlDB), storage.NewSQLiteResponseCacheStore(sqlDB), worktrees)
+}
+
+// serve sends a request with a JSON body to the server and returns the recorded response.
+func serve(t *testing.T, s *Server, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
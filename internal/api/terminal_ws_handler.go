@@ -1,24 +1,105 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
-	"github.com/ClarionDev/clarion/internal/shell"
+	"sync"
 
-	"github.com/gorilla/websocket"
+	"github.com/ClarionDev/clarion/internal/shell"
+	"github.com/ClarionDev/clarion/internal/ws"
+	"github.com/google/uuid"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		// Allow all connections for development
-		return true
-	},
+const terminalTopicName = "terminal"
+
+// terminalTopic runs shell commands for "terminal:<session>" channels. Commands keep
+// running when the subscriber disconnects, so a client can resume their output.
+type terminalTopic struct {
+	mu       sync.Mutex
+	sessions map[string]context.CancelFunc
+}
+
+func newTerminalTopic() *terminalTopic {
+	return &terminalTopic{sessions: make(map[string]context.CancelFunc)}
+}
+
+func (t *terminalTopic) Activate(ctx context.Context, session string, publish ws.PublishFunc) error {
+	return nil
+}
+
+// HandleCommand supports "exec" with a WsInitMessage payload and "kill".
+func (t *terminalTopic) HandleCommand(ctx context.Context, session, command string, data json.RawMessage, publish ws.PublishFunc) error {
+	switch command {
+	case "exec":
+		var msg WsInitMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return fmt.Errorf("invalid exec payload: %w", err)
+		}
+		return t.exec(session, msg, publish)
+	case "kill":
+		t.kill(session)
+		return nil
+	default:
+		return fmt.Errorf("unknown terminal command: %s", command)
+	}
+}
+
+func (t *terminalTopic) exec(session string, msg WsInitMessage, publish ws.PublishFunc) error {
+	if msg.Command == "" || msg.ProjectRoot == "" {
+		return fmt.Errorf("command and project_root are required")
+	}
+
+	t.mu.Lock()
+	if _, running := t.sessions[session]; running {
+		t.mu.Unlock()
+		return fmt.Errorf("terminal session %s is already running a command", session)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.sessions[session] = cancel
+	t.mu.Unlock()
+
+	go func() {
+		defer func() {
+			t.mu.Lock()
+			delete(t.sessions, session)
+			t.mu.Unlock()
+			cancel()
+		}()
+
+		exited := false
+		log.Printf("Starting command '%s' in '%s'", msg.Command, msg.ProjectRoot)
+		shell.StreamCommand(ctx, msg.Command, msg.ProjectRoot, func(msgType string, data string) {
+			exited = exited || msgType == "exit"
+			publish(msgType, data)
+		})
+		if !exited {
+			publish("exit", "Command failed to run.")
+		}
+		log.Printf("Command streaming finished for '%s'", msg.Command)
+	}()
+	return nil
+}
+
+func (t *terminalTopic) kill(session string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if cancel, ok := t.sessions[session]; ok {
+		cancel()
+	}
 }
 
+// handleTerminalWS is the single-command terminal socket. It runs the command in a
+// one-off terminal session and relays that session's channel in the legacy message format.
 func (s *Server) handleTerminalWS(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	if !s.wsAuthorized(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := s.wsUpgrader().Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("Failed to upgrade connection: %v", err)
 		return
@@ -36,7 +117,48 @@ func (s *Server) handleTerminalWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("Starting command '%s' in '%s'", initMsg.Command, initMsg.ProjectRoot)
-	shell.StreamCommand(conn, initMsg.Command, initMsg.ProjectRoot)
-	log.Printf("Command streaming finished for '%s'", initMsg.Command)
+	session := uuid.New().String()
+	channel := ws.Channel(terminalTopicName, session)
+	sub, err := s.hub.Subscribe(channel, 0)
+	if err != nil {
+		log.Printf("Failed to subscribe to %s: %v", channel, err)
+		return
+	}
+	defer sub.Close()
+
+	if err := s.terminal.exec(session, initMsg, s.hub.Publisher(channel)); err != nil {
+		_ = conn.WriteJSON(WsResponseMessage{Type: "error", Data: err.Error()})
+		return
+	}
+
+	// The legacy protocol has no kill message; a disconnect stops the command.
+	go func() {
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				s.terminal.kill(session)
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case env := <-sub.C:
+			if env.Type != ws.TypeEvent {
+				continue
+			}
+			var data string
+			_ = json.Unmarshal(env.Data, &data)
+			if err := conn.WriteJSON(WsResponseMessage{Type: env.Event, Data: data}); err != nil {
+				log.Printf("WebSocket write error: %v", err)
+				s.terminal.kill(session)
+				return
+			}
+			if env.Event == "exit" {
+				return
+			}
+		case <-sub.Done():
+			return
+		}
+	}
 }
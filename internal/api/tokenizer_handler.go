@@ -7,6 +7,8 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/ClarionDev/clarion/internal/projectconfig"
+	"github.com/ClarionDev/clarion/internal/runner"
 	"github.com/ClarionDev/clarion/internal/tokencounter"
 )
 
@@ -28,9 +30,13 @@ func (s *Server) handleTokenCount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	codebaseContents, err := s.readCodebaseFiles(req.ProjectRoot, req.CodebasePaths)
+	paths := req.CodebasePaths
+	if req.ProjectRoot != "" {
+		paths = projectconfig.ContextPaths(s.projectSettings(r.Context(), req.ProjectRoot), paths)
+	}
+	codebaseContents, diff, err := runner.BuildContext(r.Context(), req.ProjectRoot, paths, req.GitContext)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to read codebase files: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to read codebase files: %v", err), runErrorStatus(err))
 		return
 	}
 
@@ -43,6 +49,9 @@ func (s *Server) handleTokenCount(w http.ResponseWriter, r *http.Request) {
 	for _, path := range keys {
 		contentBuilder.WriteString(fmt.Sprintf("File: %s\n```\n%s\n```\n\n", path, codebaseContents[path]))
 	}
+	if diff != "" {
+		contentBuilder.WriteString(fmt.Sprintf("## Changes\n```diff\n%s\n```\n", diff))
+	}
 	codebaseString := strings.TrimSpace(contentBuilder.String())
 
 	count, err := tokencounter.Count(r.Context(), agent, req.UserPrompt, codebaseString)
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+
+	"github.com/ClarionDev/clarion/internal/git"
+	"github.com/ClarionDev/clarion/internal/worktree"
+	"github.com/go-chi/chi/v5"
+)
+
+var errRunIDRequired = errors.New("a run ID is required to isolate a run")
+
+// worktreeBranchPrefix keeps worktree branches apart from the clarion/<run-id> branches
+// that runs are applied on.
+const worktreeBranchPrefix = "clarion/wt/"
+
+func worktreeBranchName(runID string) string {
+	return worktreeBranchPrefix + runID
+}
+
+// runWorktree returns the worktree of a run, creating it from the HEAD of projectRoot's
+// repository on a clarion/wt/<run-id> branch if the run has none yet.
+func (s *Server) runWorktree(ctx context.Context, runID, projectRoot string) (*worktree.Worktree, error) {
+	if runID == "" {
+		return nil, errRunIDRequired
+	}
+	return s.worktrees.GetOrCreate(ctx, projectRoot, runID, worktreeBranchName(runID))
+}
+
+// writeWorktreeError maps worktree errors to HTTP status codes, falling back to the git
+// error mapping.
+func writeWorktreeError(w http.ResponseWriter, action string, err error) {
+	switch {
+	case errors.Is(err, worktree.ErrNotFound):
+		http.Error(w, fmt.Sprintf("Failed to %s: %v", action, err), http.StatusNotFound)
+	case errors.Is(err, worktree.ErrNoChanges), errors.Is(err, errRunIDRequired):
+		http.Error(w, fmt.Sprintf("Failed to %s: %v", action, err), http.StatusBadRequest)
+	default:
+		writeGitError(w, action, err)
+	}
+}
+
+func (s *Server) handleCreateWorktree(w http.ResponseWriter, r *http.Request) {
+	var req CreateWorktreeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.ProjectRoot == "" {
+		http.Error(w, "Project root cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	wt, err := s.runWorktree(r.Context(), chi.URLParam(r, "runID"), req.ProjectRoot)
+	if err != nil {
+		writeWorktreeError(w, "create worktree", err)
+		return
+	}
+	writeJSON(w, wt)
+}
+
+func (s *Server) handleGetWorktree(w http.ResponseWriter, r *http.Request) {
+	wt, err := s.worktrees.Get(chi.URLParam(r, "runID"))
+	if err != nil {
+		writeWorktreeError(w, "get worktree", err)
+		return
+	}
+	writeJSON(w, wt)
+}
+
+// handleWorktreeApply commits a run's changes in its worktree and runs the verification
+// commands there. A failed verification is reported in the response, not as an error, so
+// the user can still decide whether to integrate the changes.
+func (s *Server) handleWorktreeApply(w http.ResponseWriter, r *http.Request) {
+	var req WorktreeApplyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Changes) == 0 {
+		http.Error(w, "Changes cannot be empty", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	runID := chi.URLParam(r, "runID")
+
+	resp := WorktreeApplyResponse{Passed: true}
+	err := s.worktrees.Use(ctx, runID, func(wt *worktree.Worktree, repo *git.Repo) error {
+		base := wt.BaseBranch
+		if base == "" {
+			base = wt.BaseCommit
+		}
+		commit, err := s.commitRunChanges(ctx, repo, wt.ProjectDir, runID, base, req.Changes)
+		if err != nil {
+			// Leave the worktree as it was so the changes can be applied again.
+			if rbErr := resetWorktree(ctx, repo, wt.ProjectDir, req.Changes); rbErr != nil {
+				log.Printf("Failed to reset worktree of run %s: %v", runID, rbErr)
+			}
+			return err
+		}
+		resp.Worktree = wt
+		resp.Commit = newGitCommit(*commit)
+		return nil
+	})
+	if err != nil {
+		writeWorktreeError(w, "apply changes in worktree", err)
+		return
+	}
+
+	if req.VerifyCommands == nil && resp.Worktree != nil {
+		req.VerifyCommands = s.projectSettings(ctx, worktreeProjectRoot(resp.Worktree)).VerifyCommands
+	}
+	if len(req.VerifyCommands) > 0 {
+		s.runProgress(runID)("verifying", fmt.Sprintf("Running %d verification commands", len(req.VerifyCommands)), nil)
+		results, err := s.worktrees.Verify(ctx, runID, req.VerifyCommands)
+		if err != nil {
+			writeWorktreeError(w, "verify changes", err)
+			return
+		}
+		resp.Verification = results
+		resp.Passed = len(results) == len(req.VerifyCommands) && results[len(results)-1].ExitCode == 0
+	}
+	writeJSON(w, resp)
+}
+
+// worktreeProjectRoot returns the project directory a worktree was created for.
+func worktreeProjectRoot(wt *worktree.Worktree) string {
+	rel, err := filepath.Rel(wt.Path, wt.ProjectDir)
+	if err != nil {
+		return wt.RepoDir
+	}
+	return filepath.Join(wt.RepoDir, rel)
+}
+
+// resetWorktree discards uncommitted changes, including files the changes created.
+func resetWorktree(ctx context.Context, repo *git.Repo, projectDir string, changes []FileChange) error {
+	created, err := repoPaths(repo, projectDir, changes, "create")
+	if err != nil {
+		return err
+	}
+	if err := repo.ResetHard(ctx); err != nil {
+		return err
+	}
+	return repo.RemoveUntracked(ctx, created...)
+}
+
+// handleWorktreeIntegrate merges or cherry-picks a run's worktree commits into the main
+// working tree and removes the worktree. The main working tree must be clean and on the
+// branch the worktree was created from; conflicts are aborted and reported with 409.
+func (s *Server) handleWorktreeIntegrate(w http.ResponseWriter, r *http.Request) {
+	var req WorktreeIntegrateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = worktree.ModeMerge
+	}
+	if req.Mode != worktree.ModeMerge && req.Mode != worktree.ModeCherryPick {
+		http.Error(w, fmt.Sprintf("Unknown mode %q, expected %s or %s", req.Mode, worktree.ModeMerge, worktree.ModeCherryPick), http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	runID := chi.URLParam(r, "runID")
+
+	message := fmt.Sprintf("Merge %s: %s", worktreeBranchName(runID), s.runSummary(ctx, runID))
+	if err := s.worktrees.Integrate(ctx, runID, req.Mode, message); err != nil {
+		writeWorktreeError(w, "integrate worktree", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Worktree integrated successfully"))
+}
+
+// handleDiscardWorktree removes a run's worktree together with its branch.
+func (s *Server) handleDiscardWorktree(w http.ResponseWriter, r *http.Request) {
+	if err := s.worktrees.Remove(r.Context(), chi.URLParam(r, "runID"), true); err != nil {
+		writeWorktreeError(w, "discard worktree", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Worktree discarded successfully"))
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/ClarionDev/clarion/internal/canvas"
+	"github.com/ClarionDev/clarion/internal/ws"
+	"github.com/gorilla/websocket"
+)
+
+const runTopicName = "run"
+
+// runTopic carries agent run progress on "run:<id>" channels. Events are published by
+// the run handlers; the topic itself has nothing to start.
+type runTopic struct{}
+
+func (runTopic) Activate(ctx context.Context, runID string, publish ws.PublishFunc) error {
+	return nil
+}
+
+// RunProgress is the payload of "status" events on a run channel. Canvas runs report their
+// own statuses: "running", "waiting_approval", "completed" and "failed".
+type RunProgress struct {
+	Status  string `json:"status"` // "started", "reading_context", "generating", "completed", "failed"
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// WorkflowNodeProgress is the payload of "node" events on the run channel of a canvas run.
+type WorkflowNodeProgress struct {
+	NodeID string `json:"node_id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// publishWorkflowProgress publishes canvas run changes on the run's channel: "status"
+// events for the run and "node" events for its nodes.
+func (s *Server) publishWorkflowProgress(run *canvas.Run, nodeID string) {
+	publish := s.hub.Publisher(ws.Channel(runTopicName, run.ID))
+	if nodeID == "" {
+		publish("status", RunProgress{Status: string(run.Status), Error: run.Error})
+		return
+	}
+	result := run.Nodes[nodeID]
+	publish("node", WorkflowNodeProgress{NodeID: nodeID, Status: string(result.Status), Error: result.Error})
+}
+
+// runProgress returns a publisher for a run channel, or a no-op if the run has no ID.
+func (s *Server) runProgress(runID string) func(status, message string, err error) {
+	if runID == "" {
+		return func(string, string, error) {}
+	}
+	publish := s.hub.Publisher(ws.Channel(runTopicName, runID))
+	return func(status, message string, err error) {
+		progress := RunProgress{Status: status, Message: message}
+		if err != nil {
+			progress.Error = err.Error()
+		}
+		publish("status", progress)
+	}
+}
+
+// originAllowed matches an Origin header against the configured CORS origins, where a
+// trailing "*" matches any port.
+func (s *Server) originAllowed(origin string) bool {
+	for _, allowed := range s.settings.CORSOrigins {
+		if prefix, ok := strings.CutSuffix(allowed, "*"); ok {
+			if strings.HasPrefix(origin, prefix) {
+				return true
+			}
+		} else if origin == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// newWSToken returns the token clients must present on the workspace socket.
+// CLARION_WS_TOKEN pins it, otherwise a random token is generated per process.
+func newWSToken() string {
+	if token := os.Getenv("CLARION_WS_TOKEN"); token != "" {
+		return token
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		log.Fatalf("Failed to generate websocket token: %v", err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+func (s *Server) wsAuthorized(r *http.Request) bool {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		token, _ = strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.wsToken)) == 1
+}
+
+// wsUpgrader returns the upgrader of the backend's sockets, which accepts requests without
+// an Origin header and from the configured CORS origins.
+func (s *Server) wsUpgrader() *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			return origin == "" || s.originAllowed(origin)
+		},
+	}
+}
+
+// handleWSToken hands the socket token to the local frontend. Cross-origin pages cannot
+// read it because of the CORS policy.
+func (s *Server) handleWSToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"token": s.wsToken})
+}
+
+// handleWS serves the multiplexed workspace socket. Clients subscribe to "fs:<project>",
+// "terminal:<session>" and "run:<id>" channels over a single connection.
+func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
+	if !s.wsAuthorized(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := s.wsUpgrader().Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Failed to upgrade workspace connection: %v", err)
+		return
+	}
+
+	s.hub.ServeConn(context.Background(), conn)
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSocketsRequireToken(t *testing.T) {
+	s := newTestServer(t)
+	srv := httptest.NewServer(s.router)
+	defer srv.Close()
+	base := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	for _, path := range []string{"/api/v2/ws", "/api/v2/fs/watch", "/api/v2/terminal/ws"} {
+		_, resp, err := websocket.DefaultDialer.Dial(base+path, nil)
+		if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s without a token: err = %v, want 401", path, err)
+		}
+		_, resp, err = websocket.DefaultDialer.Dial(base+path+"?token=wrong", nil)
+		if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s with a wrong token: err = %v, want 401", path, err)
+		}
+		conn, _, err := websocket.DefaultDialer.Dial(base+path+"?token="+s.wsToken, nil)
+		if err != nil {
+			t.Errorf("%s with the token: %v", path, err)
+			continue
+		}
+		conn.Close()
+	}
+
+	header := http.Header{"Origin": {"https://evil.example"}}
+	if _, resp, err := websocket.DefaultDialer.Dial(base+"/api/v2/fs/watch?token="+s.wsToken, header); err == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("fs watch from another origin: err = %v, want 403", err)
+	}
+}
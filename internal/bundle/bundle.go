@@ -0,0 +1,167 @@
+// Package bundle reads and writes agents as versioned YAML bundles, the format used to
+// export and import agents and to ship them with a repository in .clarion/agents.
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/storage"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the bundle format version written by Marshal and the newest one Unmarshal
+// reads.
+const Version = 1
+
+// ProjectDir is where a project keeps its agent bundles, relative to the project root.
+const ProjectDir = ".clarion/agents"
+
+// ErrInvalid is wrapped by errors for bundles that cannot be read.
+var ErrInvalid = errors.New("invalid agent bundle")
+
+// Bundle is the document stored in a bundle file.
+type Bundle struct {
+	Version int          `yaml:"version"`
+	Agent   models.Agent `yaml:"agent"`
+}
+
+// Marshal writes an agent as a bundle. The LLM config ID is left out, since it names
+// provider credentials that only exist on this machine.
+func Marshal(agent *models.Agent) ([]byte, error) {
+	b := Bundle{Version: Version, Agent: *agent}
+	b.Agent.LLMConfig.ConfigID = ""
+	b.Agent.ProjectPath = ""
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(b); err != nil {
+		return nil, fmt.Errorf("failed to marshal agent bundle: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal reads a bundle. Unknown fields, a missing or newer version and a missing name
+// are errors. An LLM config ID in the bundle is dropped.
+func Unmarshal(data []byte) (*models.Agent, error) {
+	var b Bundle
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&b); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	switch {
+	case b.Version == 0:
+		return nil, fmt.Errorf("%w: version is required", ErrInvalid)
+	case b.Version > Version:
+		return nil, fmt.Errorf("%w: version %d is newer than the supported version %d", ErrInvalid, b.Version, Version)
+	}
+	if strings.TrimSpace(b.Agent.Profile.Name) == "" {
+		return nil, fmt.Errorf("%w: agent name is required", ErrInvalid)
+	}
+	b.Agent.LLMConfig.ConfigID = ""
+	b.Agent.ProjectPath = ""
+	return &b.Agent, nil
+}
+
+// LoadDir reads every *.yml and *.yaml bundle in dir, in name order. An agent without an
+// ID takes the file name without its extension. A missing directory holds no agents. Files
+// that cannot be read are reported together in the error, after the agents that could.
+func LoadDir(dir string) ([]*models.Agent, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var agents []*models.Agent
+	var errs []error
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		agent, err := Unmarshal(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", entry.Name(), err))
+			continue
+		}
+		if agent.Profile.ID == "" {
+			agent.Profile.ID = strings.TrimSuffix(entry.Name(), ext)
+		}
+		agents = append(agents, agent)
+	}
+	return agents, errors.Join(errs...)
+}
+
+// SyncProject loads the bundles in a project's .clarion/agents directory into the store,
+// marked with the project's path, and deletes stored agents of the project whose files are
+// gone. Invalid bundles, and bundles whose ID is taken by an agent that does not belong to
+// the project, are skipped. When some bundles cannot be read, the error is logged and
+// nothing is deleted.
+func SyncProject(ctx context.Context, store storage.AgentStore, projectRoot string) error {
+	dir := filepath.Join(projectRoot, ProjectDir)
+	loaded, loadErr := LoadDir(dir)
+	if loadErr != nil {
+		log.Printf("Some agents in %s could not be loaded: %v", dir, loadErr)
+	}
+
+	stored, err := store.ListAgents(ctx)
+	if err != nil {
+		return err
+	}
+	owner := make(map[string]string, len(stored))
+	for _, a := range stored {
+		owner[a.Profile.ID] = a.ProjectPath
+	}
+
+	keep := make(map[string]bool, len(loaded))
+	for _, a := range loaded {
+		if err := a.Validate(nil); err != nil {
+			log.Printf("Skipping agent %q in %s: %v", a.Profile.ID, dir, err)
+			continue
+		}
+		if path, ok := owner[a.Profile.ID]; ok && path != projectRoot {
+			log.Printf("Skipping agent %q in %s: the ID is used by another agent", a.Profile.ID, dir)
+			continue
+		}
+		a.ProjectPath = projectRoot
+		if err := store.SaveAgent(ctx, a); err != nil {
+			return err
+		}
+		keep[a.Profile.ID] = true
+	}
+
+	if loadErr != nil {
+		return nil
+	}
+	for _, a := range stored {
+		if a.ProjectPath == projectRoot && !keep[a.Profile.ID] {
+			if err := store.DeleteAgent(ctx, a.Profile.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
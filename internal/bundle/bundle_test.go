@@ -0,0 +1,93 @@
+package bundle
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ClarionDev/clarion/internal/agent"
+	"github.com/ClarionDev/clarion/internal/models"
+)
+
+func TestRoundTrip(t *testing.T) {
+	in := &models.Agent{
+		Profile:      agent.AgentProfile{ID: "reviewer", Name: "Reviewer", Version: "1.0.0"},
+		SystemPrompt: "Review {{focus}}",
+		CodebaseFilters: models.FilterSet{
+			IncludeGlobs: []string{"**/*.go"},
+			ExcludeGlobs: []string{"vendor/**"},
+		},
+		OutputSchema: models.OutputSchema{Schema: map[string]any{"type": "object"}},
+		UserVariables: []models.UserVariableDef{
+			{Name: "focus", Description: "What to review", Required: true},
+		},
+		LLMConfig:   models.LLMConfig{Provider: "OpenAI", Model: "gpt-4o", ConfigID: "local-key"},
+		ProjectPath: "/src/project",
+	}
+
+	data, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "local-key") || strings.Contains(string(data), "/src/project") {
+		t.Fatalf("bundle contains machine-specific settings:\n%s", data)
+	}
+
+	out, err := Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Profile != in.Profile || out.SystemPrompt != in.SystemPrompt || out.LLMConfig.Model != "gpt-4o" {
+		t.Errorf("got %+v", out)
+	}
+	if out.LLMConfig.ConfigID != "" || out.ProjectPath != "" {
+		t.Errorf("config ID %q and project path %q should be empty", out.LLMConfig.ConfigID, out.ProjectPath)
+	}
+	if len(out.UserVariables) != 1 || !out.UserVariables[0].Required {
+		t.Errorf("user variables = %+v", out.UserVariables)
+	}
+	if out.OutputSchema.Schema["type"] != "object" || out.CodebaseFilters.ExcludeGlobs[0] != "vendor/**" {
+		t.Errorf("schema or filters lost: %+v %+v", out.OutputSchema, out.CodebaseFilters)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	for name, src := range map[string]string{
+		"no version":    "agent: {profile: {name: A}}",
+		"newer version": "version: 2\nagent: {profile: {name: A}}",
+		"no name":       "version: 1\nagent: {system_prompt: x}",
+		"unknown field": "version: 1\nagent: {profile: {name: A}, prompt: x}",
+	} {
+		if _, err := Unmarshal([]byte(src)); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: got %v, want ErrInvalid", name, err)
+		}
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("b.yaml", "version: 1\nagent: {profile: {id: custom, name: B}}")
+	write("a.yml", "version: 1\nagent: {profile: {name: A}}")
+	write("broken.yml", "version: 1\nagent: [")
+	write("notes.txt", "not an agent")
+
+	agents, err := LoadDir(dir)
+	if err == nil || !strings.Contains(err.Error(), "broken.yml") {
+		t.Errorf("err = %v, want an error naming broken.yml", err)
+	}
+	if len(agents) != 2 || agents[0].Profile.ID != "a" || agents[1].Profile.ID != "custom" {
+		t.Fatalf("agents = %+v", agents)
+	}
+
+	agents, err = LoadDir(filepath.Join(dir, "missing"))
+	if err != nil || agents != nil {
+		t.Errorf("missing dir: got %v, %v", agents, err)
+	}
+}
@@ -1,15 +1,150 @@
+// Package canvas defines agent workflows: graphs of nodes whose outputs are carried along
+// edges into the variables of the nodes that follow them.
 package canvas
 
-type node struct {
+import "time"
 
+// NodeType identifies what a node does.
+type NodeType string
+
+const (
+	// NodeInput is a value supplied when the workflow is started.
+	NodeInput NodeType = "input"
+	// NodePrompt renders a text template from its variables.
+	NodePrompt NodeType = "prompt"
+	// NodeFileSelection selects codebase files to give to agents.
+	NodeFileSelection NodeType = "file_selection"
+	// NodeAgent runs a saved agent.
+	NodeAgent NodeType = "agent"
+	// NodeCommand runs a shell command in the project directory.
+	NodeCommand NodeType = "command"
+	// NodeApproval pauses the workflow until a person approves or rejects it.
+	NodeApproval NodeType = "approval"
+)
+
+// ValueType is the type of a value passed along an edge.
+type ValueType string
+
+const (
+	ValueText    ValueType = "text"
+	ValueJSON    ValueType = "json"
+	ValueFiles   ValueType = "files"
+	ValueChanges ValueType = "changes"
+)
+
+// Canvas is a saved workflow.
+type Canvas struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Nodes       []Node    `json:"nodes"`
+	Edges       []Edge    `json:"edges"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Position is where a node is drawn on the canvas.
+type Position struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Node is a step of a workflow. Exactly the config matching its type is set.
+type Node struct {
+	ID       string   `json:"id"`
+	Type     NodeType `json:"type"`
+	Label    string   `json:"label,omitempty"`
+	Position Position `json:"position"`
+
+	Input         *InputConfig         `json:"input,omitempty"`
+	Prompt        *PromptConfig        `json:"prompt,omitempty"`
+	FileSelection *FileSelectionConfig `json:"file_selection,omitempty"`
+	Agent         *AgentConfig         `json:"agent,omitempty"`
+	Command       *CommandConfig       `json:"command,omitempty"`
+	Approval      *ApprovalConfig      `json:"approval,omitempty"`
+}
+
+type InputConfig struct {
+	Name    string `json:"name"`
+	Default string `json:"default,omitempty"`
+}
+
+// PromptConfig is a template whose {{variables}} are filled from incoming edges.
+type PromptConfig struct {
+	Template string `json:"template"`
+}
+
+type FileSelectionConfig struct {
+	Paths []string `json:"paths"`
 }
 
-type edge struct {
+type AgentConfig struct {
+	AgentID string `json:"agent_id"`
+	// Prompt is the user prompt template. Without one, the "prompt" variable is used.
+	Prompt string `json:"prompt,omitempty"`
+}
 
+type CommandConfig struct {
+	// Command may contain {{variables}}, filled from incoming edges.
+	Command string `json:"command"`
+}
 
+// ApprovalConfig is shown to the person asked to approve. The node's inputs are shown with
+// it, so they can review what earlier nodes produced. File changes given to its "changes"
+// input are passed on from its "changes" output, with the approver's edits if they made any.
+type ApprovalConfig struct {
+	Message string `json:"message,omitempty"`
 }
 
+// Edge carries the output Source.SourceOutput into the variable TargetInput of Target.
+type Edge struct {
+	ID           string `json:"id"`
+	Source       string `json:"source"`
+	SourceOutput string `json:"source_output"`
+	Target       string `json:"target"`
+	TargetInput  string `json:"target_input"`
+	// Condition, if set, must hold for the source's outputs for the edge to be followed.
+	Condition *Condition `json:"condition,omitempty"`
+}
 
-type canvas struct {
+// Outputs returns the outputs of a node by name.
+func (n *Node) Outputs() map[string]ValueType {
+	switch n.Type {
+	case NodeInput:
+		return map[string]ValueType{"value": ValueText}
+	case NodePrompt:
+		return map[string]ValueType{"text": ValueText}
+	case NodeFileSelection:
+		return map[string]ValueType{"files": ValueFiles}
+	case NodeAgent:
+		return map[string]ValueType{"output": ValueJSON, "summary": ValueText, "changes": ValueChanges}
+	case NodeCommand:
+		return map[string]ValueType{"output": ValueText, "exit_code": ValueText}
+	case NodeApproval:
+		return map[string]ValueType{"approved": ValueText, "comment": ValueText, "changes": ValueChanges}
+	}
+	return nil
+}
 
-}
\ No newline at end of file
+// Accepts returns the value types the named input of a node accepts, or nil if the node
+// has no such input. Named variables take text, and JSON, which is rendered as text.
+func (n *Node) Accepts(input string) []ValueType {
+	if input == "" {
+		return nil
+	}
+	switch n.Type {
+	case NodePrompt, NodeCommand:
+		return []ValueType{ValueText, ValueJSON}
+	case NodeAgent:
+		switch input {
+		case "files":
+			return []ValueType{ValueFiles}
+		case "changes":
+			return []ValueType{ValueChanges}
+		}
+		return []ValueType{ValueText, ValueJSON}
+	case NodeApproval:
+		return []ValueType{ValueText, ValueJSON, ValueFiles, ValueChanges}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package canvas
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Condition operators.
+const (
+	OpEquals    = "equals"
+	OpNotEquals = "not_equals"
+	OpContains  = "contains"
+	OpTruthy    = "truthy"
+	OpFalsy     = "falsy"
+)
+
+// Condition tests a field of a node's outputs.
+type Condition struct {
+	// Field is a dot-separated path into the outputs, e.g. "exit_code" or "output.approved".
+	Field string `json:"field"`
+	Op    string `json:"op"`
+	Value string `json:"value,omitempty"`
+}
+
+func (c *Condition) validate() error {
+	if c.Field == "" {
+		return errors.New("condition needs a field")
+	}
+	switch c.Op {
+	case OpEquals, OpNotEquals, OpContains, OpTruthy, OpFalsy:
+		return nil
+	}
+	return fmt.Errorf("unknown condition op %q", c.Op)
+}
+
+// Holds reports whether the condition is true for outputs. Values are compared as text, so
+// the number 0, the boolean false and the string "false" are all falsy.
+func (c *Condition) Holds(outputs map[string]any) bool {
+	value, found := lookup(outputs, c.Field)
+	text := ValueString(value)
+	switch c.Op {
+	case OpEquals:
+		return found && text == c.Value
+	case OpNotEquals:
+		return !found || text != c.Value
+	case OpContains:
+		return found && strings.Contains(text, c.Value)
+	case OpTruthy:
+		return found && truthy(value, text)
+	case OpFalsy:
+		return !found || !truthy(value, text)
+	}
+	return false
+}
+
+func lookup(outputs map[string]any, path string) (any, bool) {
+	var current any = outputs
+	for _, key := range strings.Split(path, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func truthy(value any, text string) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case []any:
+		return len(v) > 0
+	case map[string]any:
+		return len(v) > 0
+	}
+	switch text {
+	case "", "0", "false":
+		return false
+	}
+	return true
+}
+
+// ValueString renders an output value as text: strings as they are, everything else as JSON.
+func ValueString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
@@ -0,0 +1,50 @@
+package canvas
+
+import "time"
+
+// Status is the state of a workflow run or of one of its nodes.
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusWaiting   Status = "waiting_approval"
+	StatusCompleted Status = "completed"
+	StatusSkipped   Status = "skipped"  // nodes only: no incoming edge was followed
+	StatusRejected  Status = "rejected" // approval nodes only
+	StatusFailed    Status = "failed"
+)
+
+// Done reports whether a node in this state will not run again without being resumed.
+func (s Status) Done() bool {
+	return s == StatusCompleted || s == StatusSkipped || s == StatusRejected || s == StatusFailed
+}
+
+// Run is an execution of a canvas. It keeps a copy of the canvas so that editing the canvas
+// does not change a run that is paused or being resumed.
+type Run struct {
+	ID          string                 `json:"id"`
+	CanvasID    string                 `json:"canvas_id"`
+	Canvas      Canvas                 `json:"canvas"`
+	ProjectRoot string                 `json:"project_root"`
+	Inputs      map[string]string      `json:"inputs"`
+	Status      Status                 `json:"status"`
+	Error       string                 `json:"error,omitempty"`
+	Nodes       map[string]*NodeResult `json:"nodes"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+// NodeResult is the state and outputs of one node in a run.
+type NodeResult struct {
+	Status Status `json:"status"`
+	// Inputs are the variables the node ran with, kept so approvers can see what they approve.
+	Inputs   map[string]any `json:"inputs,omitempty"`
+	Outputs  map[string]any `json:"outputs,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	Attempts int            `json:"attempts"`
+	// AgentRevision is the revision of the agent an agent node last ran.
+	AgentRevision int        `json:"agent_revision,omitempty"`
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+}
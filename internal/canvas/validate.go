@@ -0,0 +1,191 @@
+package canvas
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrInvalid is wrapped by every error returned from Validate.
+var ErrInvalid = errors.New("invalid workflow")
+
+// ValidationError lists every problem found in a canvas.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrInvalid, strings.Join(e.Problems, "; "))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalid
+}
+
+// Validate checks that a canvas forms a runnable workflow: every node is configured for its
+// type, every edge joins an existing output to an input that accepts its type, no input is
+// fed twice, and the graph has no cycles.
+func (c *Canvas) Validate() error {
+	var problems []string
+	addf := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if strings.TrimSpace(c.Name) == "" {
+		addf("name is required")
+	}
+
+	nodes := make(map[string]*Node, len(c.Nodes))
+	for i := range c.Nodes {
+		n := &c.Nodes[i]
+		if n.ID == "" {
+			addf("node %d has no id", i)
+			continue
+		}
+		if _, dup := nodes[n.ID]; dup {
+			addf("node id %q is used more than once", n.ID)
+			continue
+		}
+		nodes[n.ID] = n
+		if err := n.validateConfig(); err != nil {
+			addf("node %q: %v", n.ID, err)
+		}
+	}
+
+	fed := make(map[string]string)
+	for i, e := range c.Edges {
+		name := e.ID
+		if name == "" {
+			name = fmt.Sprintf("%d", i)
+		}
+		source, target := nodes[e.Source], nodes[e.Target]
+		if source == nil || target == nil {
+			addf("edge %s joins unknown nodes %q and %q", name, e.Source, e.Target)
+			continue
+		}
+		if e.Source == e.Target {
+			addf("edge %s joins node %q to itself", name, e.Source)
+			continue
+		}
+		outType, ok := source.Outputs()[e.SourceOutput]
+		if !ok {
+			addf("edge %s: %s node %q has no output %q", name, source.Type, source.ID, e.SourceOutput)
+			continue
+		}
+		accepts := target.Accepts(e.TargetInput)
+		if accepts == nil {
+			addf("edge %s: %s node %q has no input %q", name, target.Type, target.ID, e.TargetInput)
+			continue
+		}
+		if !slices.Contains(accepts, outType) {
+			addf("edge %s: output %q of %q is %s, but input %q of %q accepts %s",
+				name, e.SourceOutput, source.ID, outType, e.TargetInput, target.ID, joinTypes(accepts))
+			continue
+		}
+		if e.Condition != nil {
+			if err := e.Condition.validate(); err != nil {
+				addf("edge %s: %v", name, err)
+				continue
+			}
+		}
+		key := e.Target + "\x00" + e.TargetInput
+		if other, dup := fed[key]; dup {
+			addf("input %q of %q is fed by both %q and %q", e.TargetInput, e.Target, other, e.Source)
+			continue
+		}
+		fed[key] = e.Source
+	}
+
+	if len(problems) == 0 {
+		if _, err := c.Order(); err != nil {
+			addf("%v", err)
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func (n *Node) validateConfig() error {
+	switch n.Type {
+	case NodeInput:
+		if n.Input == nil || n.Input.Name == "" {
+			return errors.New("input nodes need a name")
+		}
+	case NodePrompt:
+		if n.Prompt == nil || strings.TrimSpace(n.Prompt.Template) == "" {
+			return errors.New("prompt nodes need a template")
+		}
+	case NodeFileSelection:
+		if n.FileSelection == nil || len(n.FileSelection.Paths) == 0 {
+			return errors.New("file selection nodes need at least one path")
+		}
+	case NodeAgent:
+		if n.Agent == nil || n.Agent.AgentID == "" {
+			return errors.New("agent nodes need an agent_id")
+		}
+	case NodeCommand:
+		if n.Command == nil || strings.TrimSpace(n.Command.Command) == "" {
+			return errors.New("command nodes need a command")
+		}
+	case NodeApproval:
+		// The message is optional.
+	default:
+		return fmt.Errorf("unknown node type %q", n.Type)
+	}
+	return nil
+}
+
+// Order returns the node IDs in an order where every node comes after the nodes it depends
+// on. Nodes without dependencies keep their order in the canvas. It fails if the edges form
+// a cycle.
+func (c *Canvas) Order() ([]string, error) {
+	indegree := make(map[string]int, len(c.Nodes))
+	next := make(map[string][]string)
+	for _, n := range c.Nodes {
+		indegree[n.ID] = 0
+	}
+	for _, e := range c.Edges {
+		next[e.Source] = append(next[e.Source], e.Target)
+		indegree[e.Target]++
+	}
+
+	var queue, order []string
+	for _, n := range c.Nodes {
+		if indegree[n.ID] == 0 {
+			queue = append(queue, n.ID)
+		}
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		order = append(order, id)
+		for _, target := range next[id] {
+			indegree[target]--
+			if indegree[target] == 0 {
+				queue = append(queue, target)
+			}
+		}
+	}
+
+	if len(order) < len(indegree) {
+		var cyclic []string
+		for _, n := range c.Nodes {
+			if indegree[n.ID] > 0 {
+				cyclic = append(cyclic, n.ID)
+			}
+		}
+		return nil, fmt.Errorf("the edges between %s form a cycle", strings.Join(cyclic, ", "))
+	}
+	return order, nil
+}
+
+func joinTypes(types []ValueType) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = string(t)
+	}
+	return strings.Join(names, " or ")
+}
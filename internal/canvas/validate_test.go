@@ -0,0 +1,80 @@
+package canvas
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func testCanvas() *Canvas {
+	return &Canvas{
+		Name: "review",
+		Nodes: []Node{
+			{ID: "task", Type: NodeInput, Input: &InputConfig{Name: "task"}},
+			{ID: "files", Type: NodeFileSelection, FileSelection: &FileSelectionConfig{Paths: []string{"main.go"}}},
+			{ID: "coder", Type: NodeAgent, Agent: &AgentConfig{AgentID: "a1"}},
+			{ID: "reviewer", Type: NodeAgent, Agent: &AgentConfig{AgentID: "a2", Prompt: "Review: {{summary}}"}},
+			{ID: "test", Type: NodeCommand, Command: &CommandConfig{Command: "go test ./..."}},
+		},
+		Edges: []Edge{
+			{ID: "e1", Source: "task", SourceOutput: "value", Target: "coder", TargetInput: "prompt"},
+			{ID: "e2", Source: "files", SourceOutput: "files", Target: "coder", TargetInput: "files"},
+			{ID: "e3", Source: "coder", SourceOutput: "summary", Target: "reviewer", TargetInput: "summary"},
+			{ID: "e4", Source: "coder", SourceOutput: "changes", Target: "reviewer", TargetInput: "changes"},
+			{ID: "e5", Source: "reviewer", SourceOutput: "summary", Target: "test", TargetInput: "note"},
+		},
+	}
+}
+
+func TestValidateAndOrder(t *testing.T) {
+	c := testCanvas()
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	order, err := c.Order()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"task", "files", "coder", "reviewer", "test"}
+	if !slices.Equal(order, want) {
+		t.Fatalf("Order() = %v, want %v", order, want)
+	}
+}
+
+func TestValidateRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Canvas)
+		want   string
+	}{
+		{"cycle", func(c *Canvas) {
+			c.Edges = append(c.Edges, Edge{Source: "test", SourceOutput: "output", Target: "coder", TargetInput: "extra"})
+		}, "form a cycle"},
+		{"type mismatch", func(c *Canvas) {
+			c.Edges[1].SourceOutput, c.Edges[1].Source = "summary", "reviewer"
+			c.Edges = c.Edges[:2]
+		}, "accepts files"},
+		{"unknown output", func(c *Canvas) { c.Edges[0].SourceOutput = "nope" }, `has no output "nope"`},
+		{"input without inputs", func(c *Canvas) { c.Edges[0].Target, c.Edges[0].TargetInput = "files", "x" }, "has no input"},
+		{"input fed twice", func(c *Canvas) {
+			c.Edges = append(c.Edges, Edge{Source: "reviewer", SourceOutput: "output", Target: "coder", TargetInput: "prompt"})
+		}, "fed by both"},
+		{"duplicate node", func(c *Canvas) { c.Nodes[1].ID = "task" }, "more than once"},
+		{"missing config", func(c *Canvas) { c.Nodes[4].Command = nil }, "need a command"},
+		{"unknown type", func(c *Canvas) { c.Nodes[0].Type = "webhook" }, "unknown node type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testCanvas()
+			tt.modify(c)
+			err := c.Validate()
+			if !errors.Is(err, ErrInvalid) {
+				t.Fatalf("Validate() = %v, want ErrInvalid", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Validate() = %q, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/ClarionDev/clarion/internal/bundle"
+	"github.com/ClarionDev/clarion/internal/models"
+)
+
+func agentsListCommand(ctx context.Context, e *env, args []string) error {
+	var project string
+	var asJSON bool
+	fs, loadSettings := e.newFlagSet("agents list", "[--project <dir>] [--json]")
+	fs.StringVar(&project, "project", "", "also list the agents in the project's .clarion/agents directory, and no other project's")
+	fs.BoolVar(&asJSON, "json", false, "print the agents as JSON")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	settings, err := loadSettings()
+	if err != nil {
+		return usagef("%v", err)
+	}
+
+	s, err := openStores(ctx, settings)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	var projectRoot string
+	if project != "" {
+		if projectRoot, err = filepath.Abs(project); err != nil {
+			return err
+		}
+		if err := bundle.SyncProject(ctx, s.agents, projectRoot); err != nil {
+			return fmt.Errorf("failed to load project agents: %w", err)
+		}
+	}
+	all, err := s.agents.ListAgents(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list agents: %w", err)
+	}
+	agents := make([]*models.Agent, 0, len(all))
+	for _, a := range all {
+		if a.ProjectPath == "" || a.ProjectPath == projectRoot {
+			agents = append(agents, a)
+		}
+	}
+
+	if asJSON {
+		out, err := json.MarshalIndent(agents, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(e.stdout, string(out))
+		return nil
+	}
+	tw := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tPROVIDER\tMODEL\tREVISION\tSOURCE")
+	for _, a := range agents {
+		source := "saved"
+		if a.ProjectPath != "" {
+			source = bundle.ProjectDir
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n", a.Profile.ID, a.Profile.Name, a.LLMConfig.Provider, a.LLMConfig.Model, a.Revision, source)
+	}
+	return tw.Flush()
+}
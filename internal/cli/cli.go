@@ -0,0 +1,184 @@
+// Package cli implements the headless commands of the clarion binary, which run agents
+// from scripts, git hooks and CI jobs without the API server.
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/ClarionDev/clarion/internal/config"
+	"github.com/ClarionDev/clarion/internal/database"
+	"github.com/ClarionDev/clarion/internal/llm"
+	"github.com/ClarionDev/clarion/internal/runner"
+	"github.com/ClarionDev/clarion/internal/secrets"
+	"github.com/ClarionDev/clarion/internal/storage"
+)
+
+// Exit codes returned by Main.
+const (
+	ExitOK      = 0
+	ExitFailure = 1
+	ExitUsage   = 2
+)
+
+// usageError is an error in a command's arguments. An empty message means the error has
+// already been reported, as the flag package does.
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string { return e.msg }
+
+func usagef(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, env *env, args []string) error
+}
+
+var commands = []command{
+	{"run", "run an agent and print its structured output as JSON", runCommand},
+	{"prompt", "print the request a run would send, without calling the provider", promptCommand},
+	{"agents list", "list the saved agents", agentsListCommand},
+}
+
+// env is what commands read from and write to.
+type env struct {
+	stdout, stderr io.Writer
+}
+
+// IsCommand reports whether name is the first word of a command handled by Main.
+func IsCommand(name string) bool {
+	for _, c := range commands {
+		if strings.Fields(c.name)[0] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Main runs the command named by the leading words of args and returns the process exit
+// code: ExitOK, ExitFailure when the command failed, or ExitUsage for invalid arguments,
+// including missing required variables.
+func Main(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	e := &env{stdout: stdout, stderr: stderr}
+	for _, c := range commands {
+		words := strings.Fields(c.name)
+		if len(args) < len(words) || strings.Join(args[:len(words)], " ") != c.name {
+			continue
+		}
+		err := c.run(ctx, e, args[len(words):])
+		var usage *usageError
+		var missing *runner.MissingVariablesError
+		switch {
+		case err == nil, errors.Is(err, flag.ErrHelp):
+			return ExitOK
+		case errors.As(err, &usage):
+			if usage.msg != "" {
+				fmt.Fprintf(stderr, "clarion %s: %v\n", c.name, err)
+			}
+			return ExitUsage
+		case errors.As(err, &missing):
+			fmt.Fprintf(stderr, "clarion %s: %v; set them with --var name=value\n", c.name, err)
+			return ExitUsage
+		default:
+			fmt.Fprintf(stderr, "clarion %s: %v\n", c.name, err)
+			return ExitFailure
+		}
+	}
+	fmt.Fprintf(stderr, "Unknown command: %s\n\n", strings.Join(args, " "))
+	Usage(stderr)
+	return ExitUsage
+}
+
+// Usage lists the commands.
+func Usage(w io.Writer) {
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-12s %s\n", c.name, c.summary)
+	}
+}
+
+// newFlagSet returns a flag set for the named command that also takes the settings flags.
+func (e *env) newFlagSet(name, usage string) (*flag.FlagSet, func() (*config.Settings, error)) {
+	fs, settings := config.FlagSet("clarion " + name)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(e.stderr, "Usage: clarion %s %s\n\nFlags:\n", name, usage)
+		fs.PrintDefaults()
+	}
+	return fs, settings
+}
+
+// parse parses a command's flags. It rejects positional arguments, which no command takes.
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		// The flag set has printed the error and the usage.
+		return &usageError{}
+	}
+	if fs.NArg() > 0 {
+		return usagef("unexpected argument %q", fs.Arg(0))
+	}
+	return nil
+}
+
+// stores are the stores the commands use, opened on the database the server uses.
+type stores struct {
+	db         database.DB
+	agents     storage.AgentStore
+	llmConfigs storage.LLMConfigStore
+	projects   storage.ProjectStore
+	// cache is the response cache runs use, as configured for the server.
+	cache *runner.ResponseCache
+}
+
+func openStores(ctx context.Context, settings *config.Settings) (*stores, error) {
+	if err := settings.EnsureDataDir(); err != nil {
+		return nil, err
+	}
+	llm.SetMockFixtureDir(filepath.Join(settings.DataDir, "mock-fixtures"))
+	db, err := database.New(ctx, "sqlite", settings.DBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	if err := db.RunMigrations(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to run database migrations: %w", err)
+	}
+
+	masterKey, err := secrets.LoadMasterKey(settings.DataDir, settings.KeyStore)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to load master key: %w", err)
+	}
+	vault, err := secrets.NewVault(masterKey)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize secrets vault: %w", err)
+	}
+
+	sqlDB := db.Handle().(*sql.DB)
+	cacheEnabled, cacheTTL := settings.ResponseCache()
+	return &stores{
+		db:         db,
+		agents:     storage.NewSQLiteAgentStore(sqlDB),
+		llmConfigs: storage.NewSQLiteLLMConfigStore(sqlDB, vault),
+		projects:   storage.NewSQLiteProjectStore(sqlDB),
+		cache:      &runner.ResponseCache{Store: storage.NewSQLiteResponseCacheStore(sqlDB), TTL: cacheTTL, Enabled: cacheEnabled},
+	}, nil
+}
+
+func (s *stores) Close() {
+	s.db.Close()
+}
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestMainExitCodes(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := Main(context.Background(), []string{"agents", "bogus"}, &stdout, &stderr); code != ExitUsage {
+		t.Errorf("unknown command: exit %d, want %d", code, ExitUsage)
+	}
+	stderr.Reset()
+	if code := Main(context.Background(), []string{"run", "--prompt", "x"}, &stdout, &stderr); code != ExitUsage {
+		t.Errorf("run without --agent: exit %d, want %d", code, ExitUsage)
+	}
+	if !strings.Contains(stderr.String(), "--agent is required") {
+		t.Errorf("stderr = %q", stderr.String())
+	}
+}
+
+func TestVarsFlag(t *testing.T) {
+	var v varsFlag
+	for _, arg := range []string{"lang=go", "empty=", "eq=a=b"} {
+		if err := v.Set(arg); err != nil {
+			t.Fatalf("Set(%q): %v", arg, err)
+		}
+	}
+	if v["lang"] != "go" || v["empty"] != "" || v["eq"] != "a=b" {
+		t.Errorf("vars = %v", v)
+	}
+	if err := v.Set("novalue"); err == nil {
+		t.Error("Set accepted an argument without =")
+	}
+}
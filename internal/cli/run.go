@@ -0,0 +1,363 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ClarionDev/clarion/internal/bundle"
+	"github.com/ClarionDev/clarion/internal/codebase"
+	"github.com/ClarionDev/clarion/internal/config"
+	"github.com/ClarionDev/clarion/internal/llm"
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/projectconfig"
+	"github.com/ClarionDev/clarion/internal/runner"
+	"github.com/ClarionDev/clarion/internal/storage"
+)
+
+// runFlags are the flags shared by run and prompt.
+type runFlags struct {
+	agent      string
+	project    string
+	prompt     string
+	promptFile string
+	files      listFlag
+	vars       varsFlag
+	git        string
+	gitRange   string
+	diff       bool
+	llmConfig  string
+	cache      string
+}
+
+func (f *runFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.agent, "agent", "", "ID of a saved agent, or path of an agent bundle (.yml)")
+	fs.StringVar(&f.project, "project", ".", "project directory the run reads and changes")
+	fs.StringVar(&f.prompt, "prompt", "", "the task for the agent")
+	fs.StringVar(&f.promptFile, "prompt-file", "", "read the task from a file, or from stdin with -")
+	fs.Var(&f.files, "files", "glob of project files to add to the context; repeatable or comma-separated (default: the agent's filters)")
+	fs.Var(&f.vars, "var", "set a prompt variable, as name=value; repeatable")
+	fs.StringVar(&f.git, "git", "", "add files from git: modified, staged, untracked or commit_range")
+	fs.StringVar(&f.gitRange, "range", "", "commit range for --git commit_range, e.g. HEAD~3..HEAD")
+	fs.BoolVar(&f.diff, "diff", false, "add the diff of the --git selection to the prompt")
+	fs.StringVar(&f.llmConfig, "llm-config", "", "ID of the saved provider config to use (default: the agent's, or the first for its provider)")
+	fs.StringVar(&f.cache, "cache", "", "response cache policy: off, on or force (default: on when --cache-responses is set and the temperature is 0)")
+}
+
+// listFlag collects repeatable, comma-separated values.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// varsFlag collects repeatable name=value pairs.
+type varsFlag map[string]string
+
+func (v *varsFlag) String() string { return "" }
+
+func (v *varsFlag) Set(value string) error {
+	name, val, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected name=value, got %q", value)
+	}
+	if *v == nil {
+		*v = make(varsFlag)
+	}
+	(*v)[name] = val
+	return nil
+}
+
+func runCommand(ctx context.Context, e *env, args []string) error {
+	var f runFlags
+	var apply, dryRun bool
+	fs, loadSettings := e.newFlagSet("run", "--agent <id|file.yml> [--prompt <task>] [flags]")
+	f.register(fs)
+	fs.BoolVar(&apply, "apply", false, "apply the file_changes of the output to the project")
+	fs.BoolVar(&dryRun, "dry-run", false, "print the request instead of running it, like clarion prompt")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if dryRun {
+		return printPrompt(ctx, e, &f, loadSettings)
+	}
+
+	req, s, err := f.request(ctx, loadSettings)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	progress := func(status, message string, err error) {
+		fmt.Fprintf(e.stderr, "%s: %s\n", status, message)
+	}
+	r := runner.New(s.llmConfigs)
+	r.Cache = s.cache
+	output, err := r.Run(ctx, req, progress)
+	if err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	fmt.Fprintln(e.stdout, string(out))
+
+	if apply {
+		n, err := runner.ApplyOutput(req.ProjectRoot, output)
+		if err != nil {
+			return fmt.Errorf("failed to apply changes: %w", err)
+		}
+		fmt.Fprintf(e.stderr, "Applied %d file changes\n", n)
+	}
+	return nil
+}
+
+func promptCommand(ctx context.Context, e *env, args []string) error {
+	var f runFlags
+	fs, loadSettings := e.newFlagSet("prompt", "--agent <id|file.yml> [--prompt <task>] [flags]")
+	f.register(fs)
+	// The prompt command never calls the provider; --dry-run is accepted so that it reads
+	// the same as run --dry-run in scripts.
+	fs.Bool("dry-run", true, "accepted for symmetry with run --dry-run; prompt never calls the provider")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	return printPrompt(ctx, e, &f, loadSettings)
+}
+
+// printPrompt prints the provider request a run would send, as the prepare-prompt endpoint
+// returns it.
+func printPrompt(ctx context.Context, e *env, f *runFlags, loadSettings func() (*config.Settings, error)) error {
+	req, s, err := f.request(ctx, loadSettings)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	internalReq, err := runner.PrepareRequest(ctx, req)
+	if err != nil {
+		return err
+	}
+	codebaseContent, diff, err := runner.BuildContext(ctx, req.ProjectRoot, req.CodebasePaths, req.GitContext)
+	if err != nil {
+		return fmt.Errorf("failed to read codebase files: %w", err)
+	}
+	internalReq.Diff = diff
+	messages, err := llm.BuildChatMessages(internalReq, codebaseContent)
+	if err != nil {
+		return fmt.Errorf("failed to build chat messages: %w", err)
+	}
+
+	out, err := json.MarshalIndent(llm.CreateRequestPayload(internalReq, messages), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	fmt.Fprintln(e.stdout, string(out))
+	return nil
+}
+
+// request resolves the flags into a run request. The caller closes the returned stores.
+func (f *runFlags) request(ctx context.Context, loadSettings func() (*config.Settings, error)) (runner.Request, *stores, error) {
+	if f.agent == "" {
+		return runner.Request{}, nil, usagef("--agent is required")
+	}
+	if f.prompt != "" && f.promptFile != "" {
+		return runner.Request{}, nil, usagef("--prompt and --prompt-file cannot be combined")
+	}
+	prompt := f.prompt
+	if f.promptFile != "" {
+		data, err := readPromptFile(f.promptFile)
+		if err != nil {
+			return runner.Request{}, nil, err
+		}
+		prompt = string(data)
+	}
+	projectRoot, err := filepath.Abs(f.project)
+	if err != nil {
+		return runner.Request{}, nil, err
+	}
+	if info, err := os.Stat(projectRoot); err != nil || !info.IsDir() {
+		return runner.Request{}, nil, usagef("project %s is not a directory", f.project)
+	}
+
+	settings, err := loadSettings()
+	if err != nil {
+		return runner.Request{}, nil, usagef("%v", err)
+	}
+	llm.RegisterProviders()
+	s, err := openStores(ctx, settings)
+	if err != nil {
+		return runner.Request{}, nil, err
+	}
+	req, err := f.buildRequest(ctx, s, projectRoot, prompt)
+	if err != nil {
+		s.Close()
+		return runner.Request{}, nil, err
+	}
+	return req, s, nil
+}
+
+func (f *runFlags) buildRequest(ctx context.Context, s *stores, projectRoot, prompt string) (runner.Request, error) {
+	agent, err := loadAgent(ctx, s, f.agent, projectRoot)
+	if err != nil {
+		return runner.Request{}, err
+	}
+	settings, err := projectconfig.Resolve(ctx, s.projects, projectRoot)
+	if err != nil {
+		log.Printf("Ignoring %s: %v", projectconfig.FileName, err)
+	}
+
+	if f.llmConfig != "" {
+		agent.LLMConfig.ConfigID = f.llmConfig
+	}
+	if agent.LLMConfig.ConfigID == "" {
+		agent.LLMConfig.ConfigID = projectconfig.LLMConfigID(ctx, s.llmConfigs, settings, agent.LLMConfig.Provider)
+	}
+	if agent.LLMConfig.ConfigID == "" {
+		if agent.LLMConfig.ConfigID, err = defaultLLMConfig(ctx, s, agent.LLMConfig.Provider); err != nil {
+			return runner.Request{}, err
+		}
+	}
+	lookup := func(id string) (string, bool) {
+		config, err := s.llmConfigs.GetLLMConfig(ctx, id)
+		if err != nil {
+			return "", false
+		}
+		return config.Provider, true
+	}
+	if err := agent.Validate(lookup); err != nil {
+		return runner.Request{}, err
+	}
+
+	paths, err := f.contextPaths(projectRoot, settings, agent.CodebaseFilters)
+	if err != nil {
+		return runner.Request{}, err
+	}
+	paths = projectconfig.ContextPaths(settings, paths)
+	var gitContext *runner.GitContext
+	if f.git != "" {
+		gitContext = &runner.GitContext{Mode: f.git, Range: f.gitRange, IncludeDiff: f.diff}
+	}
+
+	req := runner.Request{
+		SystemInstruction: agent.SystemPrompt,
+		Prompt:            prompt,
+		OutputSchema:      map[string]any{"schema": agent.OutputSchema.Schema},
+		LLMConfig:         agent.LLMConfig,
+		ProjectRoot:       projectRoot,
+		CodebasePaths:     paths,
+		GitContext:        gitContext,
+		Variables:         f.vars,
+		UserVariables:     agent.UserVariables,
+		Cache:             f.cache,
+	}
+	if project, err := s.projects.GetProjectByPath(ctx, projectRoot); err == nil {
+		req.ProjectName = project.Name
+	}
+	return req, nil
+}
+
+func readPromptFile(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// loadAgent reads the agent from a bundle file, or loads a saved agent by ID after loading
+// the project's own agents from its .clarion/agents directory.
+func loadAgent(ctx context.Context, s *stores, ref, projectRoot string) (*models.Agent, error) {
+	if ext := filepath.Ext(ref); ext == ".yml" || ext == ".yaml" {
+		data, err := os.ReadFile(ref)
+		if err != nil {
+			return nil, err
+		}
+		agent, err := bundle.Unmarshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ref, err)
+		}
+		if agent.Profile.ID == "" {
+			agent.Profile.ID = strings.TrimSuffix(filepath.Base(ref), ext)
+		}
+		return agent, nil
+	}
+
+	if err := bundle.SyncProject(ctx, s.agents, projectRoot); err != nil {
+		return nil, fmt.Errorf("failed to load project agents: %w", err)
+	}
+	agent, err := s.agents.GetAgent(ctx, ref)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, usagef("no agent with id %q", ref)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load agent: %w", err)
+	}
+	if agent.ProjectPath != "" && agent.ProjectPath != projectRoot {
+		return nil, usagef("agent %q belongs to project %s", ref, agent.ProjectPath)
+	}
+	return agent, nil
+}
+
+// defaultLLMConfig returns the ID of the first saved provider config for provider.
+func defaultLLMConfig(ctx context.Context, s *stores, provider string) (string, error) {
+	configs, err := s.llmConfigs.ListLLMConfigs(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list LLM configs: %w", err)
+	}
+	for _, c := range configs {
+		if c.Provider == provider {
+			return c.ID, nil
+		}
+	}
+	return "", fmt.Errorf("no saved LLM config for %s; add one in the app or pass --llm-config", provider)
+}
+
+// contextPaths returns the project files matching --files, or the agent's filters merged
+// with the project's defaults when no --files are given. The exclude globs and the agent's
+// file limit apply either way. When no include globs are given anywhere, only --git and the
+// project's pinned files select files.
+func (f *runFlags) contextPaths(projectRoot string, settings models.ProjectSettings, agentFilters models.FilterSet) ([]string, error) {
+	filters := projectconfig.Filters(settings, agentFilters)
+	include := filters.IncludeGlobs
+	if len(f.files) > 0 {
+		include = f.files
+	}
+	if len(include) == 0 {
+		return nil, nil
+	}
+
+	loader := codebase.NewLocalFSLoader()
+	loader.IgnoreDirs = settings.IgnoreDirs
+	cb, err := loader.LoadCodebaseStructure(projectRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list project files: %w", err)
+	}
+	filtered := cb.ApplyFilter(&models.FilterSet{IncludeGlobs: include, ExcludeGlobs: filters.ExcludeGlobs})
+	paths := make([]string, 0, len(filtered.Files))
+	for _, file := range filtered.Files {
+		paths = append(paths, filepath.ToSlash(file.Path))
+	}
+	if len(paths) == 0 && len(f.files) > 0 {
+		return nil, usagef("--files matched no files in %s", projectRoot)
+	}
+	if filters.MaxTotalFiles > 0 && len(paths) > filters.MaxTotalFiles {
+		return nil, usagef("%d files selected, the agent allows at most %d", len(paths), filters.MaxTotalFiles)
+	}
+	return paths, nil
+}
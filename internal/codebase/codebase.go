@@ -33,13 +33,39 @@ type CodebaseLoader interface {
 }
 
 // LocalFSLoader implements the CodebaseLoader interface to load source code files from the local filesystem.
-type LocalFSLoader struct{}
+type LocalFSLoader struct {
+	// IgnoreDirs are skipped in addition to the default ones. A name matches directories
+	// anywhere in the tree; a path with a slash matches one directory relative to the root.
+	IgnoreDirs []string
+}
 
 // NewLocalFSLoader creates a new loader capable of reading files from the local disk.
 func NewLocalFSLoader() *LocalFSLoader {
 	return &LocalFSLoader{}
 }
 
+// ignored reports whether the directory at path, named name, is skipped.
+func (l *LocalFSLoader) ignored(absRoot, path, name string) bool {
+	if _, ignored := defaultIgnoreDirs[name]; ignored {
+		return true
+	}
+	if len(l.IgnoreDirs) == 0 {
+		return false
+	}
+	rel, err := filepath.Rel(absRoot, path)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	for _, dir := range l.IgnoreDirs {
+		dir = strings.Trim(filepath.ToSlash(dir), "/")
+		if dir == rel || (!strings.Contains(dir, "/") && dir == name) {
+			return true
+		}
+	}
+	return false
+}
+
 // LoadCodebase recursively walks the filesystem at the given root path and constructs a complete
 // Codebase object containing all discovered files and their content.
 func (l *LocalFSLoader) LoadCodebase(rootPath string) (*Codebase, error) {
@@ -59,8 +85,8 @@ func (l *LocalFSLoader) LoadCodebase(rootPath string) (*Codebase, error) {
 		}
 
 		if d.IsDir() {
-			// If the directory name is in our ignore list, skip it completely.
-			if _, ignored := defaultIgnoreDirs[d.Name()]; ignored {
+			// If the directory is in our ignore list, skip it completely.
+			if l.ignored(absRoot, path, d.Name()) {
 				return filepath.SkipDir
 			}
 			return nil
@@ -114,8 +140,8 @@ func (l *LocalFSLoader) LoadCodebaseStructure(rootPath string) (*Codebase, error
 		}
 
 		if d.IsDir() {
-			// If the directory name is in our ignore list, skip it completely.
-			if _, ignored := defaultIgnoreDirs[d.Name()]; ignored {
+			// If the directory is in our ignore list, skip it completely.
+			if l.ignored(absRoot, path, d.Name()) {
 				return filepath.SkipDir
 			}
 			return nil
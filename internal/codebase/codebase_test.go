@@ -71,4 +71,32 @@ func TestLocalFSLoader_LoadCodebase(t *testing.T) {
 			}
 		}
 	}
-}
\ No newline at end of file
+}
+func TestLocalFSLoader_IgnoreDirs(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, path := range []string{"main.go", "vendor/lib.go", "pkg/vendor/lib.go", "docs/gen/api.md", "docs/guide.md"} {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loader := NewLocalFSLoader()
+	loader.IgnoreDirs = []string{"vendor", "docs/gen"}
+	codebase, err := loader.LoadCodebaseStructure(tempDir)
+	if err != nil {
+		t.Fatalf("LoadCodebaseStructure() returned an unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, f := range codebase.Files {
+		got = append(got, filepath.ToSlash(f.Path))
+	}
+	want := []string{"docs/guide.md", "main.go"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Expected files %v, but got %v", want, got)
+	}
+}
@@ -0,0 +1,158 @@
+// Package compare runs one prompt and context against several LLM configs at once and
+// diffs the file changes the candidates propose.
+package compare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ClarionDev/clarion/internal/llm"
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/runner"
+	"github.com/ClarionDev/clarion/internal/textdiff"
+)
+
+// MaxCandidates bounds the number of LLM configs compared at once.
+const MaxCandidates = 8
+
+// Run runs req once for every config, concurrently, and returns a candidate for each in
+// the order of configs. The context is read once, so every candidate sees the same files.
+// Errors of single candidates are kept in their results; an error is returned only when
+// the request itself cannot be run.
+func Run(ctx context.Context, r *runner.Runner, req runner.Request, configs []models.LLMConfig) ([]models.ComparisonCandidate, error) {
+	if len(configs) == 0 || len(configs) > MaxCandidates {
+		return nil, fmt.Errorf("a comparison needs between 1 and %d LLM configs, got %d", MaxCandidates, len(configs))
+	}
+	if req.Context == nil {
+		snapshot, err := runner.Snapshot(ctx, req.ProjectRoot, req.CodebasePaths, req.GitContext)
+		if err != nil {
+			return nil, err
+		}
+		req.Context = snapshot
+	}
+	// Missing variables and broken templates fail every candidate alike.
+	if _, err := runner.PrepareRequest(ctx, req); err != nil {
+		return nil, err
+	}
+
+	candidates := make([]models.ComparisonCandidate, len(configs))
+	var wg sync.WaitGroup
+	for i, config := range configs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			candidates[i] = runCandidate(ctx, r, req, config)
+		}()
+	}
+	wg.Wait()
+	return candidates, nil
+}
+
+func runCandidate(ctx context.Context, r *runner.Runner, req runner.Request, config models.LLMConfig) models.ComparisonCandidate {
+	req.LLMConfig = config
+	// Providers adjust the schema in place, so each candidate needs its own.
+	req.OutputSchema = copySchema(req.OutputSchema)
+	candidate := models.ComparisonCandidate{LLMConfig: config}
+
+	start := time.Now()
+	output, err := r.Run(ctx, req, nil)
+	candidate.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		candidate.Error = err.Error()
+		return candidate
+	}
+
+	candidate.Output = output
+	if usage, ok := llm.Usage(output); ok {
+		candidate.TokenUsage = usage
+		if cost, ok := llm.Cost(config.Model, *usage); ok {
+			candidate.Cost = &cost
+		}
+	}
+	return candidate
+}
+
+// copySchema deep-copies an output schema through JSON.
+func copySchema(schema map[string]any) map[string]any {
+	if schema == nil {
+		return nil
+	}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return schema
+	}
+	var c map[string]any
+	if err := json.Unmarshal(data, &c); err != nil {
+		return schema
+	}
+	return c
+}
+
+// FileDiff compares what two candidates propose for one file. An action is empty when the
+// candidate does not change the file.
+type FileDiff struct {
+	Path    string `json:"path"`
+	ActionA string `json:"action_a,omitempty"`
+	ActionB string `json:"action_b,omitempty"`
+	// Diff is the unified diff from candidate A's content to candidate B's, empty when
+	// both propose the same content.
+	Diff string `json:"diff"`
+}
+
+// Diff compares the file_changes of two candidates, file by file, in path order.
+func Diff(a, b models.ComparisonCandidate, nameA, nameB string) []FileDiff {
+	changesA, changesB := fileChanges(a.Output), fileChanges(b.Output)
+	paths := make([]string, 0, len(changesA)+len(changesB))
+	for path := range changesA {
+		paths = append(paths, path)
+	}
+	for path := range changesB {
+		if _, ok := changesA[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	diffs := make([]FileDiff, 0, len(paths))
+	for _, path := range paths {
+		ca, cb := changesA[path], changesB[path]
+		diff := textdiff.Unified(nameA+"/"+path, nameB+"/"+path, ca.content, cb.content)
+		diffs = append(diffs, FileDiff{Path: path, ActionA: ca.action, ActionB: cb.action, Diff: diff})
+	}
+	return diffs
+}
+
+type fileChange struct {
+	action  string
+	content string
+}
+
+// fileChanges reads the file_changes of an output by path. Agents name the content field
+// new_content or content.
+func fileChanges(output map[string]any) map[string]fileChange {
+	changes := map[string]fileChange{}
+	list, _ := output["file_changes"].([]any)
+	for _, item := range list {
+		c, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		path, _ := c["path"].(string)
+		if path == "" {
+			continue
+		}
+		change := fileChange{}
+		change.action, _ = c["action"].(string)
+		if content, ok := c["new_content"].(string); ok {
+			change.content = content
+		} else {
+			change.content, _ = c["content"].(string)
+		}
+		changes[path] = change
+	}
+	return changes
+}
@@ -0,0 +1,127 @@
+package compare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ClarionDev/clarion/internal/llm"
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/runner"
+	"github.com/ClarionDev/clarion/internal/storage"
+)
+
+// fakeProvider answers with a file change naming the model, or fails for "broken".
+type fakeProvider struct{}
+
+func (fakeProvider) Generate(ctx context.Context, messages []llm.ChatMessage, request models.AgentRunRequest, store storage.LLMConfigStore) (map[string]any, error) {
+	if request.LLMConfig.Model == "broken" {
+		return nil, errors.New("model unavailable")
+	}
+	return map[string]any{
+		"summary": "done",
+		"file_changes": []any{
+			map[string]any{"action": "update", "path": "main.go", "new_content": "package main\n// " + request.LLMConfig.Model + "\n"},
+		},
+		"token_usage": map[string]int{"prompt": 1000, "completion": 500, "total": 1500},
+	}, nil
+}
+
+// schemaProvider adjusts the output schema in place, as the OpenAI providers do.
+type schemaProvider struct{}
+
+func (schemaProvider) Generate(ctx context.Context, messages []llm.ChatMessage, request models.AgentRunRequest, store storage.LLMConfigStore) (map[string]any, error) {
+	schema := request.OutputSchema["schema"].(map[string]any)
+	schema["additionalProperties"] = false
+	schema["required"] = []string{"summary", request.LLMConfig.Model}
+	return map[string]any{"summary": "done"}, nil
+}
+
+func init() {
+	llm.RegisterProvider("Compare Test", fakeProvider{})
+	llm.RegisterProvider("Compare Schema Test", schemaProvider{})
+}
+
+func TestRun(t *testing.T) {
+	configs := []models.LLMConfig{
+		{Provider: "Compare Test", Model: "gpt-4o"},
+		{Provider: "Compare Test", Model: "broken"},
+		{Provider: "Compare Test", Model: "unpriced"},
+	}
+	req := runner.Request{Prompt: "Add a comment", ProjectRoot: t.TempDir()}
+	candidates, err := Run(context.Background(), runner.New(nil), req, configs)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(candidates) != 3 {
+		t.Fatalf("Run() returned %d candidates, want 3", len(candidates))
+	}
+
+	first := candidates[0]
+	if first.Error != "" || first.TokenUsage == nil || first.TokenUsage.Total != 1500 {
+		t.Errorf("candidate 0 = %+v, want output with token usage", first)
+	}
+	// 1000 prompt tokens at $2.50 and 500 completion tokens at $10 per million.
+	if first.Cost == nil || *first.Cost != 0.0075 {
+		t.Errorf("candidate 0 cost = %v, want 0.0075", first.Cost)
+	}
+	if !strings.Contains(candidates[1].Error, "model unavailable") || candidates[1].Output != nil {
+		t.Errorf("candidate 1 = %+v, want the provider error", candidates[1])
+	}
+	if candidates[2].Cost != nil {
+		t.Errorf("candidate 2 cost = %v, want none for an unknown model", *candidates[2].Cost)
+	}
+
+	if _, err := Run(context.Background(), runner.New(nil), req, nil); err == nil {
+		t.Error("Run() without configs succeeded, want an error")
+	}
+}
+
+func TestRunCopiesSchema(t *testing.T) {
+	schema := map[string]any{"schema": map[string]any{"type": "object", "properties": map[string]any{}}}
+	configs := make([]models.LLMConfig, MaxCandidates)
+	for i := range configs {
+		configs[i] = models.LLMConfig{Provider: "Compare Schema Test", Model: fmt.Sprintf("m%d", i)}
+	}
+	req := runner.Request{Prompt: "Add a comment", ProjectRoot: t.TempDir(), OutputSchema: schema}
+	candidates, err := Run(context.Background(), runner.New(nil), req, configs)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	for i, c := range candidates {
+		if c.Error != "" {
+			t.Errorf("candidate %d error = %s", i, c.Error)
+		}
+	}
+	if inner := schema["schema"].(map[string]any); len(inner) != 2 {
+		t.Errorf("schema after Run() = %v, want it unchanged", inner)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := models.ComparisonCandidate{Output: map[string]any{"file_changes": []any{
+		map[string]any{"action": "update", "path": "main.go", "new_content": "a\nb\n"},
+		map[string]any{"action": "create", "path": "same.go", "new_content": "x\n"},
+		map[string]any{"action": "delete", "path": "old.go"},
+	}}}
+	b := models.ComparisonCandidate{Output: map[string]any{"file_changes": []any{
+		map[string]any{"action": "update", "path": "main.go", "content": "a\nc\n"},
+		map[string]any{"action": "create", "path": "same.go", "new_content": "x\n"},
+	}}}
+
+	diffs := Diff(a, b, "gpt", "claude")
+	if len(diffs) != 3 {
+		t.Fatalf("Diff() = %+v, want 3 files", diffs)
+	}
+	if diffs[0].Path != "main.go" || !strings.Contains(diffs[0].Diff, "-b\n+c\n") || !strings.HasPrefix(diffs[0].Diff, "--- gpt/main.go\n+++ claude/main.go\n") {
+		t.Errorf("main.go diff = %q", diffs[0].Diff)
+	}
+	if diffs[1].Path != "old.go" || diffs[1].ActionA != "delete" || diffs[1].ActionB != "" {
+		t.Errorf("old.go = %+v, want deleted by A only", diffs[1])
+	}
+	if diffs[2].Path != "same.go" || diffs[2].Diff != "" {
+		t.Errorf("same.go = %+v, want no diff", diffs[2])
+	}
+}
@@ -0,0 +1,324 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	defaultPort     = "2077"
+	defaultLogLevel = "info"
+	defaultKeyStore = "auto"
+	defaultCacheTTL = "24h"
+	dbFileName      = "clarion.db"
+)
+
+var defaultCORSOrigins = []string{"http://localhost:*", "tauri://localhost"}
+
+// Settings are the effective runtime settings of the backend. Each value is resolved from
+// its default, then the environment, then command-line flags, later sources winning.
+// LogLevel filters slog records only; the standard logger always writes to stderr.
+type Settings struct {
+	DataDir     string   `json:"data_dir" yaml:"data_dir"`
+	DBPath      string   `json:"db_path" yaml:"db_path"`
+	Port        string   `json:"port" yaml:"port"`
+	LogLevel    string   `json:"log_level" yaml:"log_level"`
+	CORSOrigins []string `json:"cors_origins" yaml:"cors_origins"`
+	KeyStore    string   `json:"key_store" yaml:"key_store"`
+	// RunMaxAge and RunMaxCount are the run retention policy: runs older than the age, and
+	// runs beyond the newest count of each project, are deleted in the background. Empty
+	// means no limit. CompactRuns moves large file contents of runs into shared blobs.
+	RunMaxAge   string `json:"run_max_age" yaml:"run_max_age"`
+	RunMaxCount string `json:"run_max_count" yaml:"run_max_count"`
+	CompactRuns string `json:"compact_runs" yaml:"compact_runs"`
+	// CacheResponses makes runs use the response cache unless they opt out; CacheTTL is how
+	// long a cached response is reused.
+	CacheResponses string `json:"cache_responses" yaml:"cache_responses"`
+	CacheTTL       string `json:"cache_ttl" yaml:"cache_ttl"`
+
+	// Sources records where each setting came from, e.g. "default", "env CLARION_PORT" or "flag --port".
+	Sources map[string]string `json:"-" yaml:"-"`
+}
+
+// DefaultDataDir returns $XDG_DATA_HOME/clarion, falling back to ~/.local/share/clarion.
+func DefaultDataDir() (string, error) {
+	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" && filepath.IsAbs(xdg) {
+		return filepath.Join(xdg, "clarion"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "share", "clarion"), nil
+}
+
+// settingFlags holds the raw flag values; empty means "not given".
+type settingFlags struct {
+	dataDir        string
+	dbPath         string
+	port           string
+	logLevel       string
+	corsOrigins    string
+	keyStore       string
+	runMaxAge      string
+	runMaxCount    string
+	compactRuns    string
+	cacheResponses string
+	cacheTTL       string
+}
+
+func (f *settingFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.dataDir, "data-dir", "", "directory for the database and other local data (env CLARION_DATA_DIR)")
+	fs.StringVar(&f.dbPath, "db", "", "path of the SQLite database (env CLARION_DB_PATH, default <data-dir>/clarion.db)")
+	fs.StringVar(&f.port, "port", "", "port the API server listens on (env CLARION_PORT)")
+	fs.StringVar(&f.logLevel, "log-level", "", "log level: debug, info, warn or error (env CLARION_LOG_LEVEL)")
+	fs.StringVar(&f.corsOrigins, "cors-origins", "", "comma-separated allowed CORS origins (env CLARION_CORS_ORIGINS)")
+	fs.StringVar(&f.keyStore, "key-store", "", "where the master key for stored API keys lives: auto, keyring or file (env CLARION_KEY_STORE)")
+	fs.StringVar(&f.runMaxAge, "run-max-age", "", "delete runs older than this, e.g. 30d or 72h (env CLARION_RUN_MAX_AGE, default: keep)")
+	fs.StringVar(&f.runMaxCount, "run-max-count", "", "keep at most this many runs per project (env CLARION_RUN_MAX_COUNT, default: all)")
+	fs.StringVar(&f.compactRuns, "compact-runs", "", "move large file contents of runs into deduplicated blobs: true or false (env CLARION_COMPACT_RUNS)")
+	fs.StringVar(&f.cacheResponses, "cache-responses", "", "reuse provider responses for identical requests: true or false (env CLARION_CACHE_RESPONSES)")
+	fs.StringVar(&f.cacheTTL, "cache-ttl", "", "how long cached responses are reused, e.g. 24h or 7d (env CLARION_CACHE_TTL)")
+}
+
+// LoadSettings resolves the settings from defaults, the environment and the flags in args.
+// Flags may appear before or after subcommands; the non-flag arguments are returned in order.
+func LoadSettings(args []string) (*Settings, []string, error) {
+	var flags settingFlags
+	fs := flag.NewFlagSet("clarion", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	flags.register(fs)
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+
+	s, err := flags.resolve()
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, positional, nil
+}
+
+// FlagSet returns a flag set for a command that takes the settings flags next to its own,
+// and a function that resolves the settings once the flag set has parsed its arguments.
+func FlagSet(name string) (*flag.FlagSet, func() (*Settings, error)) {
+	var flags settingFlags
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.register(fs)
+	return fs, flags.resolve
+}
+
+// resolve resolves the settings from defaults, the environment and the parsed flags.
+func (flags *settingFlags) resolve() (*Settings, error) {
+	s := &Settings{Sources: make(map[string]string)}
+
+	dataDir, err := DefaultDataDir()
+	if err != nil {
+		return nil, err
+	}
+	s.set("data_dir", &s.DataDir, dataDir, []string{"CLARION_DATA_DIR"}, "data-dir", flags.dataDir)
+	s.set("db_path", &s.DBPath, filepath.Join(s.DataDir, dbFileName), []string{"CLARION_DB_PATH"}, "db", flags.dbPath)
+	// BACKEND_PORT is the variable used before CLARION_PORT existed.
+	s.set("port", &s.Port, defaultPort, []string{"CLARION_PORT", "BACKEND_PORT"}, "port", flags.port)
+	s.set("log_level", &s.LogLevel, defaultLogLevel, []string{"CLARION_LOG_LEVEL"}, "log-level", flags.logLevel)
+
+	var origins string
+	s.set("cors_origins", &origins, strings.Join(defaultCORSOrigins, ","), []string{"CLARION_CORS_ORIGINS"}, "cors-origins", flags.corsOrigins)
+	s.CORSOrigins = splitList(origins)
+	s.set("key_store", &s.KeyStore, defaultKeyStore, []string{"CLARION_KEY_STORE"}, "key-store", flags.keyStore)
+	s.set("run_max_age", &s.RunMaxAge, "", []string{"CLARION_RUN_MAX_AGE"}, "run-max-age", flags.runMaxAge)
+	s.set("run_max_count", &s.RunMaxCount, "", []string{"CLARION_RUN_MAX_COUNT"}, "run-max-count", flags.runMaxCount)
+	s.set("compact_runs", &s.CompactRuns, "false", []string{"CLARION_COMPACT_RUNS"}, "compact-runs", flags.compactRuns)
+	s.set("cache_responses", &s.CacheResponses, "false", []string{"CLARION_CACHE_RESPONSES"}, "cache-responses", flags.cacheResponses)
+	s.set("cache_ttl", &s.CacheTTL, defaultCacheTTL, []string{"CLARION_CACHE_TTL"}, "cache-ttl", flags.cacheTTL)
+
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// set resolves one setting from its default, the first non-empty environment variable
+// in envNames, and its flag value.
+func (s *Settings) set(name string, dst *string, def string, envNames []string, flagName, flagValue string) {
+	*dst = def
+	s.Sources[name] = "default"
+	for _, envName := range envNames {
+		if v := os.Getenv(envName); v != "" {
+			*dst = v
+			s.Sources[name] = "env " + envName
+			break
+		}
+	}
+	if flagValue != "" {
+		*dst = flagValue
+		s.Sources[name] = "flag --" + flagName
+	}
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (s *Settings) validate() error {
+	var errs []error
+	if port, err := strconv.Atoi(s.Port); err != nil || port <= 0 || port > 65535 {
+		errs = append(errs, fmt.Errorf("invalid port %q (%s)", s.Port, s.Sources["port"]))
+	}
+	if _, err := parseLogLevel(s.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("%w (%s)", err, s.Sources["log_level"]))
+	}
+	switch s.KeyStore {
+	case "auto", "keyring", "file":
+	default:
+		errs = append(errs, fmt.Errorf("invalid key store %q, expected auto, keyring or file (%s)", s.KeyStore, s.Sources["key_store"]))
+	}
+	if s.DBPath == "" {
+		errs = append(errs, errors.New("database path cannot be empty"))
+	}
+	if _, err := parseAge(s.RunMaxAge); err != nil {
+		errs = append(errs, fmt.Errorf("invalid run max age: %w (%s)", err, s.Sources["run_max_age"]))
+	}
+	if n, err := strconv.Atoi(s.RunMaxCount); s.RunMaxCount != "" && (err != nil || n < 0) {
+		errs = append(errs, fmt.Errorf("invalid run max count %q (%s)", s.RunMaxCount, s.Sources["run_max_count"]))
+	}
+	if _, err := strconv.ParseBool(s.CompactRuns); err != nil {
+		errs = append(errs, fmt.Errorf("invalid compact runs %q, expected true or false (%s)", s.CompactRuns, s.Sources["compact_runs"]))
+	}
+	if _, err := strconv.ParseBool(s.CacheResponses); err != nil {
+		errs = append(errs, fmt.Errorf("invalid cache responses %q, expected true or false (%s)", s.CacheResponses, s.Sources["cache_responses"]))
+	}
+	if ttl, err := parseAge(s.CacheTTL); err != nil || ttl == 0 {
+		errs = append(errs, fmt.Errorf("invalid cache TTL %q, expected a duration such as 24h or 7d (%s)", s.CacheTTL, s.Sources["cache_ttl"]))
+	}
+	return errors.Join(errs...)
+}
+
+func parseLogLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("invalid log level %q, expected debug, info, warn or error", level)
+	}
+}
+
+// parseAge parses a duration that may also be given in days, such as "30d". Empty means 0.
+func parseAge(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	} else if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("%q is not a duration such as 30d or 72h", value)
+}
+
+// RunRetention returns the run retention limits; zero means no limit.
+func (s *Settings) RunRetention() (maxAge time.Duration, maxCount int) {
+	maxAge, _ = parseAge(s.RunMaxAge)
+	maxCount, _ = strconv.Atoi(s.RunMaxCount)
+	return maxAge, maxCount
+}
+
+// CompactRunsEnabled reports whether runs are compacted in the background.
+func (s *Settings) CompactRunsEnabled() bool {
+	enabled, _ := strconv.ParseBool(s.CompactRuns)
+	return enabled
+}
+
+// ResponseCache reports whether runs use the response cache by default, and how long
+// cached responses are reused.
+func (s *Settings) ResponseCache() (enabled bool, ttl time.Duration) {
+	enabled, _ = strconv.ParseBool(s.CacheResponses)
+	ttl, _ = parseAge(s.CacheTTL)
+	return enabled, ttl
+}
+
+// SlogLevel returns the configured log level for log/slog.
+func (s *Settings) SlogLevel() slog.Level {
+	level, _ := parseLogLevel(s.LogLevel)
+	return level
+}
+
+// EnsureDataDir creates the data directory and the database's parent directory.
+func (s *Settings) EnsureDataDir() error {
+	if err := os.MkdirAll(s.DataDir, 0700); err != nil {
+		return fmt.Errorf("failed to create data directory %s: %w", s.DataDir, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.DBPath), 0700); err != nil {
+		return fmt.Errorf("failed to create database directory: %w", err)
+	}
+	return nil
+}
+
+// Addr returns the listen address for the API server.
+func (s *Settings) Addr() string {
+	return ":" + s.Port
+}
+
+// Print writes the effective settings and their sources in a human-readable table.
+func (s *Settings) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	rows := []struct{ name, value string }{
+		{"data_dir", s.DataDir},
+		{"db_path", s.DBPath},
+		{"port", s.Port},
+		{"log_level", s.LogLevel},
+		{"cors_origins", strings.Join(s.CORSOrigins, ",")},
+		{"key_store", s.KeyStore},
+		{"run_max_age", s.RunMaxAge},
+		{"run_max_count", s.RunMaxCount},
+		{"compact_runs", s.CompactRuns},
+		{"cache_responses", s.CacheResponses},
+		{"cache_ttl", s.CacheTTL},
+	}
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t(%s)\n", row.name, row.value, s.Sources[row.name])
+	}
+	return tw.Flush()
+}
+
+// Usage writes the flag documentation.
+func Usage(w io.Writer) {
+	var flags settingFlags
+	fs := flag.NewFlagSet("clarion", flag.ContinueOnError)
+	flags.register(fs)
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+}
@@ -0,0 +1,117 @@
+package config_test
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ClarionDev/clarion/internal/config"
+)
+
+// clearEnv unsets the environment variables the settings read.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{
+		"CLARION_DATA_DIR", "CLARION_DB_PATH", "CLARION_PORT", "BACKEND_PORT", "CLARION_LOG_LEVEL",
+		"CLARION_CORS_ORIGINS", "CLARION_KEY_STORE", "CLARION_RUN_MAX_AGE", "CLARION_RUN_MAX_COUNT",
+		"CLARION_COMPACT_RUNS", "CLARION_CACHE_RESPONSES", "CLARION_CACHE_TTL",
+	} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+}
+
+func TestLoadSettingsPrecedence(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		args       []string
+		wantPort   string
+		wantSource string
+	}{
+		{"default", nil, nil, "2077", "default"},
+		{"legacy env", map[string]string{"BACKEND_PORT": "3000"}, nil, "3000", "env BACKEND_PORT"},
+		{"env", map[string]string{"CLARION_PORT": "4000", "BACKEND_PORT": "3000"}, nil, "4000", "env CLARION_PORT"},
+		{"flag", map[string]string{"CLARION_PORT": "4000"}, []string{"--port", "5000"}, "5000", "flag --port"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			s, _, err := config.LoadSettings(tt.args)
+			if err != nil {
+				t.Fatalf("LoadSettings() error = %v", err)
+			}
+			if s.Port != tt.wantPort || s.Sources["port"] != tt.wantSource {
+				t.Errorf("port = %q (%s), want %q (%s)", s.Port, s.Sources["port"], tt.wantPort, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestLoadSettingsDerivedAndPositional(t *testing.T) {
+	clearEnv(t)
+	dir := t.TempDir()
+	t.Setenv("CLARION_DATA_DIR", dir)
+	t.Setenv("CLARION_CORS_ORIGINS", " http://a , ,http://b")
+
+	s, args, err := config.LoadSettings([]string{"config", "--log-level", "debug", "show"})
+	if err != nil {
+		t.Fatalf("LoadSettings() error = %v", err)
+	}
+	if !slices.Equal(args, []string{"config", "show"}) {
+		t.Errorf("positional args = %v, want [config show]", args)
+	}
+	if s.DBPath != filepath.Join(dir, "clarion.db") || s.Sources["db_path"] != "default" {
+		t.Errorf("db path = %q (%s), want it under the data dir", s.DBPath, s.Sources["db_path"])
+	}
+	if s.LogLevel != "debug" || s.Sources["log_level"] != "flag --log-level" {
+		t.Errorf("log level = %q (%s)", s.LogLevel, s.Sources["log_level"])
+	}
+	if !slices.Equal(s.CORSOrigins, []string{"http://a", "http://b"}) {
+		t.Errorf("CORS origins = %v", s.CORSOrigins)
+	}
+}
+
+func TestLoadSettingsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"port", []string{"--port", "99999"}},
+		{"log level", []string{"--log-level", "loud"}},
+		{"key store", []string{"--key-store", "cloud"}},
+		{"run max age", []string{"--run-max-age", "soon"}},
+		{"run max count", []string{"--run-max-count", "-1"}},
+		{"cache ttl", []string{"--cache-ttl", "0"}},
+	}
+	for _, tt := range tests {
+		clearEnv(t)
+		if _, _, err := config.LoadSettings(tt.args); err == nil {
+			t.Errorf("%s: LoadSettings(%v) succeeded, want an error", tt.name, tt.args)
+		}
+	}
+}
+
+func TestFlagSet(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("CLARION_RUN_MAX_AGE", "30d")
+	fs, resolve := config.FlagSet("run")
+	verbose := fs.Bool("verbose", false, "")
+	if err := fs.Parse([]string{"--verbose", "--run-max-count", "10"}); err != nil {
+		t.Fatal(err)
+	}
+	s, err := resolve()
+	if err != nil {
+		t.Fatalf("resolve() error = %v", err)
+	}
+	maxAge, maxCount := s.RunRetention()
+	if !*verbose || maxAge.Hours() != 30*24 || maxCount != 10 {
+		t.Errorf("verbose = %v, retention = %v, %d", *verbose, maxAge, maxCount)
+	}
+	if s.Sources["run_max_age"] != "env CLARION_RUN_MAX_AGE" || s.Sources["run_max_count"] != "flag --run-max-count" {
+		t.Errorf("sources = %v", s.Sources)
+	}
+}
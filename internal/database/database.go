@@ -2,14 +2,19 @@ package database
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"io/fs"
+
+	"github.com/ClarionDev/clarion/db"
+	"github.com/ClarionDev/clarion/internal/storage"
 )
 
-var ErrNotFound = errors.New("record not found")
+// ErrNotFound is storage.ErrNotFound, so errors.Is matches either name.
+var ErrNotFound = storage.ErrNotFound
 
 type DB interface {
 	RunMigrations(ctx context.Context) error
+	MigrateDown(ctx context.Context, steps int) error
 	Close()
 	Handle() any
 }
@@ -17,8 +22,11 @@ type DB interface {
 func New(ctx context.Context, driver, connString string) (DB, error) {
 	switch driver {
 	case "sqlite":
-		migrationsDir := "./db/migrations"
-		return NewSQLite(ctx, connString, migrationsDir)
+		migrations, err := fs.Sub(db.Migrations, "migrations")
+		if err != nil {
+			return nil, fmt.Errorf("failed to open embedded migrations: %w", err)
+		}
+		return NewSQLite(ctx, connString, migrations)
 	default:
 		return nil, fmt.Errorf("unsupported database driver: %s", driver)
 	}
@@ -0,0 +1,229 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Migration is a single versioned schema change loaded from a migrations directory.
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// migrationFilePattern matches "001_create_agents_table.sql", "001_create_agents_table.up.sql"
+// and "001_create_agents_table.down.sql".
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+?)(\.up|\.down)?\.sql$`)
+
+const createSchemaMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    checksum TEXT NOT NULL,
+    applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);`
+
+// LoadMigrations reads all migrations from the root of fsys, ordered by version.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("could not read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration file %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %q and %q", version, m.Name, match[2])
+		}
+
+		if match[3] == ".down" {
+			m.Down = string(content)
+		} else {
+			if m.Up != "" {
+				return nil, fmt.Errorf("migration %d has more than one up file", version)
+			}
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %03d_%s has a down file but no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+type appliedMigration struct {
+	Version  int
+	Name     string
+	Checksum string
+}
+
+func loadAppliedMigrations(ctx context.Context, db *sql.DB) ([]appliedMigration, error) {
+	if _, err := db.ExecContext(ctx, createSchemaMigrationsTable); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT version, name, checksum FROM schema_migrations ORDER BY version;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var applied []appliedMigration
+	for rows.Next() {
+		var a appliedMigration
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum); err != nil {
+			return nil, err
+		}
+		applied = append(applied, a)
+	}
+	return applied, rows.Err()
+}
+
+// migrateUp applies every pending migration in version order, each in its own transaction.
+// Already applied migrations are verified against their recorded checksum.
+func migrateUp(ctx context.Context, db *sql.DB, migrations []Migration) error {
+	applied, err := loadAppliedMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+
+	appliedVersions := make(map[int]struct{}, len(applied))
+	latest := 0
+	for _, a := range applied {
+		appliedVersions[a.Version] = struct{}{}
+		if a.Version > latest {
+			latest = a.Version
+		}
+
+		m, ok := known[a.Version]
+		if !ok {
+			log.Printf("Warning: database has migration %03d_%s applied, but this build does not know it.", a.Version, a.Name)
+			continue
+		}
+		if m.Checksum != a.Checksum {
+			return fmt.Errorf("migration %03d_%s was modified after it was applied (checksum %s, recorded %s); add a new migration instead", m.Version, m.Name, m.Checksum, a.Checksum)
+		}
+	}
+
+	for _, m := range migrations {
+		if _, done := appliedVersions[m.Version]; done {
+			continue
+		}
+		if m.Version < latest {
+			return fmt.Errorf("migration %03d_%s is older than the latest applied migration %03d and cannot be applied out of order", m.Version, m.Name, latest)
+		}
+		if err := applyMigration(ctx, db, m); err != nil {
+			return err
+		}
+		log.Printf("Applied migration %03d_%s", m.Version, m.Name)
+		latest = m.Version
+	}
+
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, m Migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for migration %03d_%s: %w", m.Version, m.Name, err)
+	}
+	defer tx.Rollback() // Rollback is a no-op if Commit succeeds
+
+	if _, err := tx.ExecContext(ctx, m.Up); err != nil {
+		return fmt.Errorf("failed to run migration %03d_%s: %w", m.Version, m.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, checksum) VALUES (?, ?, ?);`, m.Version, m.Name, m.Checksum); err != nil {
+		return fmt.Errorf("failed to record migration %03d_%s: %w", m.Version, m.Name, err)
+	}
+
+	return tx.Commit()
+}
+
+// migrateDown reverts the latest steps applied migrations, newest first. It stops with an
+// error at the first migration that has no down file.
+func migrateDown(ctx context.Context, db *sql.DB, migrations []Migration, steps int) error {
+	applied, err := loadAppliedMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+
+	for i := len(applied) - 1; i >= 0 && steps > 0; i, steps = i-1, steps-1 {
+		a := applied[i]
+		m, ok := known[a.Version]
+		if !ok {
+			return fmt.Errorf("cannot revert migration %03d_%s: it is unknown to this build", a.Version, a.Name)
+		}
+		if m.Down == "" {
+			return fmt.Errorf("cannot revert migration %03d_%s: it has no down migration", m.Version, m.Name)
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction for reverting %03d_%s: %w", m.Version, m.Name, err)
+		}
+		if _, err := tx.ExecContext(ctx, m.Down); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to revert migration %03d_%s: %w", m.Version, m.Name, err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = ?;`, m.Version); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to unrecord migration %03d_%s: %w", m.Version, m.Name, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		log.Printf("Reverted migration %03d_%s", m.Version, m.Name)
+	}
+
+	return nil
+}
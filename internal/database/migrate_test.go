@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func newTestSQLite(t *testing.T, migrations fstest.MapFS) *SQLiteDatabase {
+	t.Helper()
+	db, err := NewSQLite(context.Background(), filepath.Join(t.TempDir(), "test.db"), migrations)
+	if err != nil {
+		t.Fatalf("NewSQLite() returned an unexpected error: %v", err)
+	}
+	t.Cleanup(db.Close)
+	return db
+}
+
+func countApplied(t *testing.T, db *SQLiteDatabase) int {
+	t.Helper()
+	var count int
+	if err := db.db.QueryRow(`SELECT COUNT(*) FROM schema_migrations;`).Scan(&count); err != nil {
+		t.Fatalf("Failed to count applied migrations: %v", err)
+	}
+	return count
+}
+
+func TestRunMigrations_AppliesOnceInOrder(t *testing.T) {
+	ctx := context.Background()
+	migrations := fstest.MapFS{
+		"001_create_items.sql":      {Data: []byte("CREATE TABLE items (id TEXT PRIMARY KEY);")},
+		"001_create_items.down.sql": {Data: []byte("DROP TABLE items;")},
+		"002_add_name.sql":          {Data: []byte("ALTER TABLE items ADD COLUMN name TEXT;")},
+		"README.md":                 {Data: []byte("not a migration")},
+	}
+	db := newTestSQLite(t, migrations)
+
+	// The ALTER TABLE would fail on a second run, so running twice proves each migration is applied once.
+	for i := 0; i < 2; i++ {
+		if err := db.RunMigrations(ctx); err != nil {
+			t.Fatalf("RunMigrations() run %d returned an unexpected error: %v", i+1, err)
+		}
+	}
+
+	if got := countApplied(t, db); got != 2 {
+		t.Errorf("Expected 2 applied migrations, got %d", got)
+	}
+	if _, err := db.db.Exec(`INSERT INTO items (id, name) VALUES ('a', 'b');`); err != nil {
+		t.Errorf("Expected migrated schema to have a name column: %v", err)
+	}
+}
+
+func TestRunMigrations_DetectsEditedMigration(t *testing.T) {
+	ctx := context.Background()
+	migrations := fstest.MapFS{
+		"001_create_items.sql": {Data: []byte("CREATE TABLE items (id TEXT PRIMARY KEY);")},
+	}
+	db := newTestSQLite(t, migrations)
+	if err := db.RunMigrations(ctx); err != nil {
+		t.Fatalf("RunMigrations() returned an unexpected error: %v", err)
+	}
+
+	migrations["001_create_items.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE items (id INTEGER PRIMARY KEY);")}
+	err := db.RunMigrations(ctx)
+	if err == nil || !strings.Contains(err.Error(), "modified after it was applied") {
+		t.Fatalf("Expected a checksum mismatch error, got %v", err)
+	}
+}
+
+func TestRunMigrations_FailedMigrationIsRolledBack(t *testing.T) {
+	ctx := context.Background()
+	migrations := fstest.MapFS{
+		"001_create_items.sql": {Data: []byte("CREATE TABLE items (id TEXT PRIMARY KEY);")},
+		"002_broken.sql":       {Data: []byte("CREATE TABLE other (id TEXT); NOT VALID SQL;")},
+	}
+	db := newTestSQLite(t, migrations)
+
+	if err := db.RunMigrations(ctx); err == nil {
+		t.Fatal("Expected RunMigrations() to fail on an invalid migration")
+	}
+	if got := countApplied(t, db); got != 1 {
+		t.Errorf("Expected only the first migration to be recorded, got %d", got)
+	}
+	if _, err := db.db.Exec(`SELECT * FROM other;`); err == nil {
+		t.Error("Expected the partially applied migration to be rolled back")
+	}
+}
+
+func TestMigrateDown(t *testing.T) {
+	ctx := context.Background()
+	migrations := fstest.MapFS{
+		"001_create_items.sql":     {Data: []byte("CREATE TABLE items (id TEXT PRIMARY KEY);")},
+		"002_create_tags.up.sql":   {Data: []byte("CREATE TABLE tags (id TEXT PRIMARY KEY);")},
+		"002_create_tags.down.sql": {Data: []byte("DROP TABLE tags;")},
+	}
+	db := newTestSQLite(t, migrations)
+	if err := db.RunMigrations(ctx); err != nil {
+		t.Fatalf("RunMigrations() returned an unexpected error: %v", err)
+	}
+
+	if err := db.MigrateDown(ctx, 1); err != nil {
+		t.Fatalf("MigrateDown(1) returned an unexpected error: %v", err)
+	}
+	if got := countApplied(t, db); got != 1 {
+		t.Errorf("Expected 1 applied migration after reverting, got %d", got)
+	}
+	if _, err := db.db.Exec(`SELECT * FROM tags;`); err == nil {
+		t.Error("Expected the tags table to be dropped")
+	}
+
+	// 001 has no down file, so it cannot be reverted.
+	if err := db.MigrateDown(ctx, 1); err == nil {
+		t.Error("Expected MigrateDown() to fail for a migration without a down file")
+	}
+}
@@ -5,10 +5,9 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
-	"sort"
-	"strings"
 
 	"github.com/georgysavva/scany/v2/sqlscan"
 	_ "modernc.org/sqlite"
@@ -22,11 +21,11 @@ type SQLiteDBTX interface {
 }
 
 type SQLiteDatabase struct {
-	db            *sql.DB
-	migrationsDir string
+	db         *sql.DB
+	migrations fs.FS
 }
 
-func NewSQLite(ctx context.Context, connString, migrationsDir string) (*SQLiteDatabase, error) {
+func NewSQLite(ctx context.Context, connString string, migrations fs.FS) (*SQLiteDatabase, error) {
 	if connString == "" {
 		return nil, errors.New("database connection string (file path) is empty")
 	}
@@ -50,7 +49,7 @@ func NewSQLite(ctx context.Context, connString, migrationsDir string) (*SQLiteDa
 
 	db.SetMaxOpenConns(1)
 
-	return &SQLiteDatabase{db: db, migrationsDir: migrationsDir}, nil
+	return &SQLiteDatabase{db: db, migrations: migrations}, nil
 }
 
 func (db *SQLiteDatabase) Close() {
@@ -61,37 +60,20 @@ func (db *SQLiteDatabase) Handle() any {
 	return db.db
 }
 
+// RunMigrations applies all pending migrations and records them in schema_migrations.
 func (db *SQLiteDatabase) RunMigrations(ctx context.Context) error {
-	files, err := os.ReadDir(db.migrationsDir)
+	migrations, err := LoadMigrations(db.migrations)
 	if err != nil {
-		return fmt.Errorf("could not read migrations directory %s: %w", db.migrationsDir, err)
+		return err
 	}
+	return migrateUp(ctx, db.db, migrations)
+}
 
-	var migrationFiles []string
-	for _, file := range files {
-		if !file.IsDir() && strings.HasSuffix(file.Name(), ".sql") {
-			migrationFiles = append(migrationFiles, file.Name())
-		}
-	}
-	sort.Strings(migrationFiles)
-
-	tx, err := db.db.BeginTx(ctx, nil)
+// MigrateDown reverts the latest steps applied migrations using their down files.
+func (db *SQLiteDatabase) MigrateDown(ctx context.Context, steps int) error {
+	migrations, err := LoadMigrations(db.migrations)
 	if err != nil {
-		return fmt.Errorf("failed to begin migration transaction: %w", err)
-	}
-	defer tx.Rollback() // Rollback is a no-op if Commit succeeds
-
-	for _, fileName := range migrationFiles {
-		filePath := filepath.Join(db.migrationsDir, fileName)
-		sqlBytes, err := os.ReadFile(filePath)
-		if err != nil {
-			return fmt.Errorf("failed to read migration file %s: %w", filePath, err)
-		}
-
-		if _, err = tx.ExecContext(ctx, string(sqlBytes)); err != nil {
-			return fmt.Errorf("failed to run migration %s: %w", fileName, err)
-		}
+		return err
 	}
-
-	return tx.Commit()
+	return migrateDown(ctx, db.db, migrations, steps)
 }
@@ -0,0 +1,406 @@
+// Package eval runs an agent's evaluation suites: every case runs in a fixture project of
+// its own, against a provider or on its recorded output, and its assertions are checked
+// to score the agent.
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/runner"
+	"github.com/ClarionDev/clarion/internal/shell"
+)
+
+// commandTimeout bounds each command_passes assertion.
+const commandTimeout = 5 * time.Minute
+
+// maxMessage bounds the command output kept in an assertion's message.
+const maxMessage = 2000
+
+// Options select how a suite runs.
+type Options struct {
+	// LLMConfig is the provider config the agent runs with.
+	LLMConfig models.LLMConfig
+	// Recorded runs the cases on their recorded outputs instead of calling the provider.
+	Recorded bool
+	// Record keeps the outputs of a live run as the cases' recorded outputs.
+	Record bool
+	// Judge grades llm_judge assertions. Live runs fall back to LLMConfig; recorded runs
+	// without a judge skip them.
+	Judge *models.LLMConfig
+}
+
+// Run runs every case of a suite with an agent and returns the report. Case failures are
+// part of the report. With opts.Record, the outputs of the cases that ran are stored in
+// the suite's cases; saving the suite is up to the caller.
+func Run(ctx context.Context, r *runner.Runner, suite *models.EvalSuite, agent *models.Agent, opts Options) *models.EvalReport {
+	report := &models.EvalReport{
+		SuiteID:       suite.ID,
+		AgentID:       agent.Profile.ID,
+		AgentRevision: agent.Revision,
+		LLMConfig:     opts.LLMConfig,
+		Recorded:      opts.Recorded,
+		Cases:         make([]models.EvalCaseResult, 0, len(suite.Cases)),
+		TotalCases:    len(suite.Cases),
+	}
+	if opts.Judge == nil && !opts.Recorded {
+		opts.Judge = &opts.LLMConfig
+	}
+
+	var score float64
+	var counted int
+	for i := range suite.Cases {
+		c := &suite.Cases[i]
+		result := runCase(ctx, r, agent, c, opts)
+		if opts.Record && !opts.Recorded && result.Error == "" {
+			c.RecordedOutput = result.Output
+		}
+		for _, a := range result.Assertions {
+			if !a.Skipped {
+				score += a.Score
+				counted++
+			}
+		}
+		if result.Passed {
+			report.PassedCases++
+		}
+		report.Cases = append(report.Cases, result)
+	}
+	if counted > 0 {
+		report.Score = score / float64(counted)
+	}
+	return report
+}
+
+func runCase(ctx context.Context, r *runner.Runner, agent *models.Agent, c *models.EvalCase, opts Options) models.EvalCaseResult {
+	result := models.EvalCaseResult{Name: c.Name}
+	fail := func(err error) models.EvalCaseResult {
+		result.Error = err.Error()
+		for _, a := range c.Assertions {
+			result.Assertions = append(result.Assertions, models.EvalAssertionResult{Type: a.Type, Message: "not checked: the case did not run"})
+		}
+		return result
+	}
+
+	dir, err := os.MkdirTemp("", "clarion-eval-")
+	if err != nil {
+		return fail(err)
+	}
+	defer os.RemoveAll(dir)
+	paths, err := writeFixture(dir, c.Files)
+	if err != nil {
+		return fail(err)
+	}
+
+	var output map[string]any
+	start := time.Now()
+	if opts.Recorded {
+		if c.RecordedOutput == nil {
+			return fail(errors.New("the case has no recorded output"))
+		}
+		output = cloneOutput(c.RecordedOutput)
+	} else {
+		output, err = r.Run(ctx, runner.Request{
+			SystemInstruction: agent.SystemPrompt,
+			Prompt:            c.Prompt,
+			OutputSchema:      map[string]any{"schema": agent.OutputSchema.Schema},
+			LLMConfig:         opts.LLMConfig,
+			ProjectRoot:       dir,
+			CodebasePaths:     paths,
+			Variables:         c.Variables,
+			UserVariables:     agent.UserVariables,
+		}, nil)
+		if err != nil {
+			return fail(err)
+		}
+	}
+	result.LatencyMS = time.Since(start).Milliseconds()
+	result.Output = output
+
+	check := &checker{runner: r, dir: dir, c: c, output: output, judge: opts.Judge}
+	result.Passed = true
+	for _, a := range c.Assertions {
+		res := check.assert(ctx, a)
+		res.Type = a.Type
+		if res.Passed && a.Type != models.AssertLLMJudge {
+			res.Score = 1
+		}
+		if !res.Passed && !res.Skipped {
+			result.Passed = false
+		}
+		result.Assertions = append(result.Assertions, res)
+	}
+	return result
+}
+
+// writeFixture writes a case's files under dir and returns their paths in order.
+func writeFixture(dir string, files map[string]string) ([]string, error) {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		if !filepath.IsLocal(filepath.FromSlash(p)) {
+			return nil, fmt.Errorf("fixture path %q is outside the project", p)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(target, []byte(files[p]), 0644); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+// cloneOutput deep-copies an output through JSON, so that checks cannot change the
+// recording.
+func cloneOutput(output map[string]any) map[string]any {
+	data, err := json.Marshal(output)
+	if err != nil {
+		return output
+	}
+	var clone map[string]any
+	if err := json.Unmarshal(data, &clone); err != nil {
+		return output
+	}
+	return clone
+}
+
+// checker checks the assertions of one case against its output.
+type checker struct {
+	runner *runner.Runner
+	dir    string
+	c      *models.EvalCase
+	output map[string]any
+	judge  *models.LLMConfig
+
+	applied  bool
+	applyErr error
+}
+
+func (k *checker) assert(ctx context.Context, a models.EvalAssertion) models.EvalAssertionResult {
+	switch a.Type {
+	case models.AssertJSONPathEquals:
+		got, err := lookup(k.output, a.Path)
+		if err != nil {
+			return models.EvalAssertionResult{Message: err.Error()}
+		}
+		if !reflect.DeepEqual(normalize(got), normalize(a.Value)) {
+			return models.EvalAssertionResult{Message: fmt.Sprintf("%s is %s, want %s", a.Path, describe(got), describe(a.Value))}
+		}
+		return models.EvalAssertionResult{Passed: true}
+
+	case models.AssertJSONPathMatches:
+		got, err := lookup(k.output, a.Path)
+		if err != nil {
+			return models.EvalAssertionResult{Message: err.Error()}
+		}
+		pattern, err := regexp.Compile(a.Pattern)
+		if err != nil {
+			return models.EvalAssertionResult{Message: err.Error()}
+		}
+		text, ok := got.(string)
+		if !ok {
+			text = describe(got)
+		}
+		if !pattern.MatchString(text) {
+			return models.EvalAssertionResult{Message: fmt.Sprintf("%s does not match %q", a.Path, a.Pattern)}
+		}
+		return models.EvalAssertionResult{Passed: true}
+
+	case models.AssertTouchesPath:
+		touched := changedPaths(k.output)
+		for _, p := range touched {
+			if matchGlob(a.Path, p) {
+				return models.EvalAssertionResult{Passed: true}
+			}
+		}
+		return models.EvalAssertionResult{Message: fmt.Sprintf("no change matches %q; changed: %s", a.Path, strings.Join(touched, ", "))}
+
+	case models.AssertCommandPasses:
+		if !k.applied {
+			k.applied = true
+			_, k.applyErr = runner.ApplyOutput(k.dir, k.output)
+		}
+		if k.applyErr != nil {
+			return models.EvalAssertionResult{Message: "failed to apply the changes: " + k.applyErr.Error()}
+		}
+		ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+		defer cancel()
+		res, err := shell.RunCommand(ctx, a.Command, k.dir)
+		if err != nil {
+			return models.EvalAssertionResult{Message: err.Error()}
+		}
+		if res.ExitCode != 0 {
+			return models.EvalAssertionResult{Message: fmt.Sprintf("exit code %d: %s", res.ExitCode, tail(res.Output))}
+		}
+		return models.EvalAssertionResult{Passed: true}
+
+	case models.AssertLLMJudge:
+		if k.judge == nil {
+			return models.EvalAssertionResult{Skipped: true, Message: "no judge model for a recorded run"}
+		}
+		return k.judgeOutput(ctx, a.Rubric)
+	}
+	return models.EvalAssertionResult{Message: fmt.Sprintf("unknown assertion type %q", a.Type)}
+}
+
+const judgeInstruction = `You grade the output of an AI coding agent. The task the agent was given is in task.md and its JSON output is in output.json. Grade the output strictly against the rubric in the user message: give a score from 0 to 1, whether the output passes, and a short reasoning.`
+
+// judgeSchema returns the schema of a verdict. Providers adjust schemas in place, so
+// every call builds a new one.
+func judgeSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"score":     map[string]any{"type": "number", "description": "How well the output meets the rubric, from 0 to 1."},
+			"passed":    map[string]any{"type": "boolean", "description": "Whether the output meets the rubric."},
+			"reasoning": map[string]any{"type": "string", "description": "Why the output got this grade."},
+		},
+		"required":             []any{"score", "passed", "reasoning"},
+		"additionalProperties": false,
+	}
+}
+
+// judgeOutput asks the judge model to grade the output against a rubric. The task and
+// output go into the context rather than the prompt, so that braces in them are not read
+// as template placeholders.
+func (k *checker) judgeOutput(ctx context.Context, rubric string) models.EvalAssertionResult {
+	outputJSON, err := json.MarshalIndent(k.output, "", "  ")
+	if err != nil {
+		return models.EvalAssertionResult{Message: err.Error()}
+	}
+	verdict, err := k.runner.Run(ctx, runner.Request{
+		SystemInstruction: judgeInstruction,
+		Prompt:            "Rubric:\n" + rubric,
+		OutputSchema:      map[string]any{"schema": judgeSchema()},
+		LLMConfig:         *k.judge,
+		ProjectRoot:       k.dir,
+		Context: &runner.ContextSnapshot{Files: map[string]string{
+			"task.md":     k.c.Prompt,
+			"output.json": string(outputJSON),
+		}},
+	}, nil)
+	if err != nil {
+		return models.EvalAssertionResult{Message: "judge failed: " + err.Error()}
+	}
+	score, _ := verdict["score"].(float64)
+	passed, _ := verdict["passed"].(bool)
+	reasoning, _ := verdict["reasoning"].(string)
+	return models.EvalAssertionResult{Passed: passed, Score: min(max(score, 0), 1), Message: reasoning}
+}
+
+// lookup returns the value at a JSON path such as "file_changes[0].path" or
+// "$.summary".
+func lookup(output map[string]any, jsonPath string) (any, error) {
+	p := strings.TrimPrefix(strings.TrimPrefix(jsonPath, "$"), ".")
+	p = strings.NewReplacer("[", ".", "]", "").Replace(p)
+	var current any = output
+	for _, key := range strings.Split(p, ".") {
+		if key == "" {
+			continue
+		}
+		switch v := current.(type) {
+		case map[string]any:
+			next, ok := v[key]
+			if !ok {
+				return nil, fmt.Errorf("%s: no field %q", jsonPath, key)
+			}
+			current = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("%s: no element %q in a list of %d", jsonPath, key, len(v))
+			}
+			current = v[i]
+		default:
+			return nil, fmt.Errorf("%s: %q is not inside an object or list", jsonPath, key)
+		}
+	}
+	return current, nil
+}
+
+// normalize turns a value into its JSON form, so that e.g. 1 and 1.0 compare equal.
+func normalize(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+func describe(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// matchGlob reports whether a slash-separated path matches a glob. Besides the syntax of
+// path.Match, a "**" segment matches any number of directories, so "src/**/*.go" matches
+// "src/main.go" and "src/a/b/main.go".
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// changedPaths returns the paths of the output's file_changes.
+func changedPaths(output map[string]any) []string {
+	var paths []string
+	changes, _ := output["file_changes"].([]any)
+	for _, c := range changes {
+		if change, ok := c.(map[string]any); ok {
+			if p, ok := change["path"].(string); ok {
+				paths = append(paths, p)
+			}
+		}
+	}
+	return paths
+}
+
+func tail(s string) string {
+	if len(s) <= maxMessage {
+		return s
+	}
+	return "..." + s[len(s)-maxMessage:]
+}
@@ -0,0 +1,180 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ClarionDev/clarion/internal/agent"
+	"github.com/ClarionDev/clarion/internal/llm"
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/runner"
+	"github.com/ClarionDev/clarion/internal/storage"
+)
+
+// fakeProvider creates hello.txt, or judges with a score of 0.8 when asked for a grade.
+type fakeProvider struct{}
+
+func (fakeProvider) Generate(ctx context.Context, messages []llm.ChatMessage, request models.AgentRunRequest, store storage.LLMConfigStore) (map[string]any, error) {
+	if request.LLMConfig.Model == "broken" {
+		return nil, errors.New("model unavailable")
+	}
+	if request.SystemInstruction == judgeInstruction {
+		// Adjust the schema in place, as the OpenAI providers do.
+		request.OutputSchema["schema"].(map[string]any)["required"] = []string{"score"}
+		return map[string]any{"score": 0.8, "passed": true, "reasoning": "close enough"}, nil
+	}
+	return map[string]any{
+		"summary": "Added hello.txt",
+		"file_changes": []any{
+			map[string]any{"action": "create", "path": "hello.txt", "new_content": "hello\n"},
+		},
+	}, nil
+}
+
+func init() {
+	llm.RegisterProvider("Eval Test", fakeProvider{})
+}
+
+func testSuite() *models.EvalSuite {
+	return &models.EvalSuite{ID: "suite-1", AgentID: "agent-1", Name: "Hello", Cases: []models.EvalCase{{
+		Name:   "creates hello",
+		Prompt: "Create hello.txt",
+		Files:  map[string]string{"README.md": "# Test\n"},
+		Assertions: []models.EvalAssertion{
+			{Type: models.AssertJSONPathEquals, Path: "$.file_changes[0].action", Value: "create"},
+			{Type: models.AssertJSONPathMatches, Path: "summary", Pattern: "(?i)hello"},
+			{Type: models.AssertTouchesPath, Path: "*.txt"},
+			{Type: models.AssertCommandPasses, Command: "cat hello.txt README.md"},
+			{Type: models.AssertLLMJudge, Rubric: "The file says hello."},
+		},
+	}}}
+}
+
+func testAgent() *models.Agent {
+	return &models.Agent{Profile: agent.AgentProfile{ID: "agent-1"}, Revision: 3}
+}
+
+func TestRunLive(t *testing.T) {
+	suite := testSuite()
+	opts := Options{LLMConfig: models.LLMConfig{Provider: "Eval Test", Model: "fake"}, Record: true}
+	report := Run(context.Background(), runner.New(nil), suite, testAgent(), opts)
+
+	if report.AgentRevision != 3 || report.PassedCases != 1 || report.TotalCases != 1 {
+		t.Fatalf("Run() = %+v, want 1 of 1 cases passed at revision 3", report)
+	}
+	for _, a := range report.Cases[0].Assertions {
+		if !a.Passed {
+			t.Errorf("assertion %s failed: %s", a.Type, a.Message)
+		}
+	}
+	// Four assertions with 1 and the judge with 0.8.
+	if want := 4.8 / 5; report.Score != want {
+		t.Errorf("Score = %v, want %v", report.Score, want)
+	}
+	if suite.Cases[0].RecordedOutput == nil {
+		t.Error("Run() with Record did not store the output")
+	}
+}
+
+func TestRunRecorded(t *testing.T) {
+	suite := testSuite()
+	suite.Cases[0].RecordedOutput = map[string]any{
+		"summary":      "Added goodbye.txt",
+		"file_changes": []any{map[string]any{"action": "create", "path": "goodbye.txt", "new_content": "bye\n"}},
+	}
+	suite.Cases = append(suite.Cases, models.EvalCase{
+		Name:       "not recorded",
+		Prompt:     "Anything",
+		Assertions: []models.EvalAssertion{{Type: models.AssertTouchesPath, Path: "a.go"}},
+	})
+	report := Run(context.Background(), runner.New(nil), suite, testAgent(), Options{Recorded: true})
+
+	if report.PassedCases != 0 || len(report.Cases) != 2 {
+		t.Fatalf("Run() = %+v, want 0 of 2 cases passed", report)
+	}
+	results := report.Cases[0].Assertions
+	want := []struct{ passed, skipped bool }{{true, false}, {false, false}, {true, false}, {false, false}, {false, true}}
+	for i, w := range want {
+		if results[i].Passed != w.passed || results[i].Skipped != w.skipped {
+			t.Errorf("assertion %d (%s) = %+v, want passed %v, skipped %v", i, results[i].Type, results[i], w.passed, w.skipped)
+		}
+	}
+	if report.Cases[1].Error == "" {
+		t.Error("case without a recording ran, want an error")
+	}
+	// Two of the four checked assertions of the first case and none of the second passed.
+	if want := 2.0 / 5; report.Score != want {
+		t.Errorf("Score = %v, want %v", report.Score, want)
+	}
+}
+
+func TestRunProviderError(t *testing.T) {
+	report := Run(context.Background(), runner.New(nil), testSuite(), testAgent(), Options{LLMConfig: models.LLMConfig{Provider: "Eval Test", Model: "broken"}})
+	result := report.Cases[0]
+	if result.Passed || result.Error == "" || len(result.Assertions) != 5 {
+		t.Errorf("case = %+v, want a failed case with unchecked assertions", result)
+	}
+	if report.Score != 0 {
+		t.Errorf("Score = %v, want 0", report.Score)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	output := map[string]any{"a": map[string]any{"b": []any{"x", map[string]any{"c": 1.0}}}}
+	tests := []struct {
+		path    string
+		want    any
+		wantErr bool
+	}{
+		{"a.b[0]", "x", false},
+		{"$.a.b[1].c", 1.0, false},
+		{"a.b[2]", nil, true},
+		{"a.missing", nil, true},
+		{"a.b[0].c", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := lookup(output, tt.path)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && got != tt.want) {
+			t.Errorf("lookup(%q) = %v, %v; want %v, error %v", tt.path, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestJudgeSchemaIsNotShared(t *testing.T) {
+	opts := Options{LLMConfig: models.LLMConfig{Provider: "Eval Test", Model: "fake"}}
+	Run(context.Background(), runner.New(nil), testSuite(), testAgent(), opts)
+	if required := judgeSchema()["required"].([]any); len(required) != 3 {
+		t.Errorf("judgeSchema() required = %v after a run, want the 3 verdict fields", required)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.txt", "hello.txt", true},
+		{"*.txt", "docs/hello.txt", false},
+		{"src/main.go", "src/main.go", true},
+		{"src/*.go", "src/a/main.go", false},
+		{"src/**/*.go", "src/main.go", true},
+		{"src/**/*.go", "src/a/b/main.go", true},
+		{"src/**/*.go", "lib/main.go", false},
+		{"**/*.txt", "hello.txt", true},
+		{"**/*.txt", "a/b/hello.txt", true},
+		{"src/**", "src/a/b", true},
+		{"src/**", "lib/a", false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWriteFixtureRejectsEscapes(t *testing.T) {
+	if _, err := writeFixture(t.TempDir(), map[string]string{"../x": ""}); err == nil {
+		t.Error("writeFixture() wrote outside the project, want an error")
+	}
+}
@@ -0,0 +1,130 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Branch is a local branch.
+type Branch struct {
+	Name     string `json:"name"`
+	Hash     string `json:"hash"`
+	Current  bool   `json:"current"`
+	Upstream string `json:"upstream,omitempty"`
+}
+
+// Branches lists the local branches.
+func (r *Repo) Branches(ctx context.Context) ([]Branch, error) {
+	out, err := r.run(ctx, "for-each-ref", "--format=%(refname:short)%1f%(objectname)%1f%(HEAD)%1f%(upstream:short)", "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+
+	branches := []Branch{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, fieldSep)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected git for-each-ref line: %q", line)
+		}
+		branches = append(branches, Branch{
+			Name:     fields[0],
+			Hash:     fields[1],
+			Current:  fields[2] == "*",
+			Upstream: fields[3],
+		})
+	}
+	return branches, nil
+}
+
+// CurrentBranch returns the checked-out branch, or "" when HEAD is detached.
+func (r *Repo) CurrentBranch(ctx context.Context) (string, error) {
+	out, err := r.run(ctx, "symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		if exitCode(err) == 1 {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// ValidateBranchName checks that name is a valid new branch name.
+func (r *Repo) ValidateBranchName(ctx context.Context, name string) error {
+	if name == "" || strings.HasPrefix(name, "-") {
+		return fmt.Errorf("%w: invalid branch name %q", ErrInvalidRef, name)
+	}
+	if _, err := r.run(ctx, "check-ref-format", "--branch", name); err != nil {
+		return fmt.Errorf("%w: invalid branch name %q", ErrInvalidRef, name)
+	}
+	return nil
+}
+
+// CreateBranch creates a branch at startPoint, or at HEAD when it is empty, and optionally
+// checks it out. Checking out follows the same rules as Checkout.
+func (r *Repo) CreateBranch(ctx context.Context, name, startPoint string, checkout bool) error {
+	if err := r.ValidateBranchName(ctx, name); err != nil {
+		return err
+	}
+	if startPoint == "" {
+		startPoint = "HEAD"
+	}
+	hash, err := r.ResolveCommit(ctx, startPoint)
+	if err != nil {
+		return err
+	}
+	if checkout {
+		if err := r.requireClean(ctx); err != nil {
+			return err
+		}
+	}
+
+	if _, err := r.run(ctx, "branch", "--", name, hash); err != nil {
+		return err
+	}
+	if checkout {
+		_, err = r.run(ctx, "checkout", "--quiet", name, "--")
+	}
+	return err
+}
+
+// DeleteBranch force-deletes a local branch. It refuses to delete the current branch.
+func (r *Repo) DeleteBranch(ctx context.Context, name string) error {
+	if err := r.ValidateBranchName(ctx, name); err != nil {
+		return err
+	}
+	_, err := r.run(ctx, "branch", "--quiet", "-D", "--", name)
+	return err
+}
+
+// Checkout switches to a branch, or detaches HEAD at any other commit-ish. It refuses to
+// run when there are staged or unstaged changes, so nothing in the working tree is lost.
+func (r *Repo) Checkout(ctx context.Context, ref string) error {
+	if _, err := r.ResolveCommit(ctx, ref); err != nil {
+		return err
+	}
+	if err := r.requireClean(ctx); err != nil {
+		return err
+	}
+
+	if _, err := r.run(ctx, "show-ref", "--verify", "--quiet", "refs/heads/"+ref); err == nil {
+		_, err := r.run(ctx, "checkout", "--quiet", ref, "--")
+		return err
+	}
+	_, err := r.run(ctx, "checkout", "--quiet", "--detach", ref, "--")
+	return err
+}
+
+func (r *Repo) requireClean(ctx context.Context) error {
+	status, err := r.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if !status.Clean() {
+		return ErrDirtyTree
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Diff returns the unstaged changes in the working tree, or the staged changes when staged
+// is true. paths optionally limits the diff.
+func (r *Repo) Diff(ctx context.Context, staged bool, paths ...string) (string, error) {
+	args := []string{"diff", "--no-color", "--no-ext-diff"}
+	if staged {
+		args = append(args, "--cached")
+	}
+	args = append(args, "--")
+	args = append(args, paths...)
+	return r.run(ctx, args...)
+}
+
+// Stage adds the given paths, including deletions, to the index.
+func (r *Repo) Stage(ctx context.Context, paths ...string) error {
+	if len(paths) == 0 {
+		return errors.New("no paths to stage")
+	}
+	_, err := r.run(ctx, append([]string{"add", "--all", "--"}, paths...)...)
+	return err
+}
+
+// Unstage removes the given paths from the index, keeping the working tree changes.
+func (r *Repo) Unstage(ctx context.Context, paths ...string) error {
+	if len(paths) == 0 {
+		return errors.New("no paths to unstage")
+	}
+	if !r.hasHead(ctx) {
+		// Before the first commit there is no HEAD to reset to.
+		_, err := r.run(ctx, append([]string{"rm", "--cached", "-r", "--quiet", "--"}, paths...)...)
+		return err
+	}
+	_, err := r.run(ctx, append([]string{"reset", "--quiet", "HEAD", "--"}, paths...)...)
+	return err
+}
+
+// Commit records the staged changes with message and returns the new commit.
+func (r *Repo) Commit(ctx context.Context, message string) (*Commit, error) {
+	if strings.TrimSpace(message) == "" {
+		return nil, errors.New("commit message cannot be empty")
+	}
+
+	// diff --cached --quiet exits 1 when something is staged.
+	if _, err := r.run(ctx, "diff", "--cached", "--quiet"); err == nil {
+		return nil, ErrNothingToCommit
+	} else if exitCode(err) != 1 {
+		return nil, err
+	}
+
+	if _, err := r.runInput(ctx, []byte(message), "commit", "--quiet", "--file=-"); err != nil {
+		return nil, err
+	}
+
+	commits, _, err := r.Log(ctx, LogOptions{Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("commit succeeded but HEAD could not be read")
+	}
+	return &commits[0], nil
+}
+
+// ResetHard discards all staged and unstaged changes to tracked files. Untracked files are kept.
+func (r *Repo) ResetHard(ctx context.Context) error {
+	_, err := r.run(ctx, "reset", "--hard", "--quiet")
+	return err
+}
+
+// RemoveUntracked deletes the given paths if they are untracked. Other untracked files are kept.
+func (r *Repo) RemoveUntracked(ctx context.Context, paths ...string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	_, err := r.run(ctx, append([]string{"clean", "--force", "--quiet", "--"}, paths...)...)
+	return err
+}
+
+// DiffHead returns all uncommitted changes to tracked files, staged or not, relative to HEAD.
+// Before the first commit it returns the staged changes.
+func (r *Repo) DiffHead(ctx context.Context, paths ...string) (string, error) {
+	if !r.hasHead(ctx) {
+		return r.Diff(ctx, true, paths...)
+	}
+	args := append([]string{"diff", "--no-color", "--no-ext-diff", "HEAD", "--"}, paths...)
+	return r.run(ctx, args...)
+}
@@ -0,0 +1,139 @@
+// Package git reads and modifies project repositories by running the git command-line tool.
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrNotRepository is returned when a directory is not inside a git work tree.
+	ErrNotRepository = errors.New("not a git repository")
+	// ErrDirtyTree is returned by operations that refuse to run with uncommitted changes.
+	ErrDirtyTree = errors.New("the working tree has uncommitted changes")
+	// ErrNothingToCommit is returned by Commit when no changes are staged.
+	ErrNothingToCommit = errors.New("nothing to commit")
+	// ErrInvalidRef is returned when a branch name or revision is malformed or does not exist.
+	ErrInvalidRef = errors.New("invalid reference")
+)
+
+// Error is a failed git invocation with the command's error output.
+type Error struct {
+	Args   []string
+	Stderr string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	msg := strings.TrimSpace(e.Stderr)
+	if msg == "" {
+		msg = e.Err.Error()
+	}
+	return fmt.Sprintf("git %s: %s", strings.Join(e.Args, " "), msg)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Repo is a git work tree on disk.
+type Repo struct {
+	// Dir is the top-level directory of the work tree.
+	Dir string
+}
+
+// Open returns the repository containing dir.
+func Open(ctx context.Context, dir string) (*Repo, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, errors.New("git is not installed or not on PATH")
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("%w: %s is not a directory", ErrNotRepository, dir)
+	}
+
+	out, err := (&Repo{Dir: dir}).run(ctx, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotRepository, dir)
+	}
+	return &Repo{Dir: strings.TrimSpace(out)}, nil
+}
+
+// run executes git in the repository and returns its standard output.
+func (r *Repo) run(ctx context.Context, args ...string) (string, error) {
+	return r.runInput(ctx, nil, args...)
+}
+
+func (r *Repo) runInput(ctx context.Context, stdin []byte, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = r.Dir
+	// Never block on a credential prompt, don't take optional locks that would contend with
+	// the user's own git commands, and keep output parseable regardless of locale.
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_OPTIONAL_LOCKS=0", "LC_ALL=C")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	if err := cmd.Run(); err != nil {
+		return stdout.String(), &Error{Args: args, Stderr: stderr.String(), Err: err}
+	}
+	return stdout.String(), nil
+}
+
+// exitCode returns the exit status of a failed git invocation, or -1.
+func exitCode(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// hasHead reports whether the repository has at least one commit.
+func (r *Repo) hasHead(ctx context.Context) bool {
+	_, err := r.run(ctx, "rev-parse", "--verify", "--quiet", "HEAD")
+	return err == nil
+}
+
+// ResolveCommit returns the full hash of a revision that names a commit.
+func (r *Repo) ResolveCommit(ctx context.Context, rev string) (string, error) {
+	if rev == "" || strings.HasPrefix(rev, "-") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidRef, rev)
+	}
+	out, err := r.run(ctx, "rev-parse", "--verify", "--quiet", "--end-of-options", rev+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("%w: %q does not name a commit", ErrInvalidRef, rev)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Prefix returns dir relative to the top of the work tree, with a trailing slash, or ""
+// when dir is the top itself.
+func (r *Repo) Prefix(dir string) (string, error) {
+	top, err := filepath.EvalSymlinks(r.Dir)
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(top, abs)
+	if err != nil {
+		return "", err
+	}
+	if rel == "." {
+		return "", nil
+	}
+	if strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("%s is outside the work tree %s", dir, r.Dir)
+	}
+	return filepath.ToSlash(rel) + "/", nil
+}
@@ -0,0 +1,274 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestRepo creates an empty repository with a fixed identity in a temporary directory.
+func newTestRepo(t *testing.T) *Repo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch=main"},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "commit.gpgsign", "false"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	repo, err := Open(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func writeFile(t *testing.T, repo *Repo, name, content string) {
+	t.Helper()
+	path := filepath.Join(repo.Dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func commitFile(t *testing.T, repo *Repo, name, content, message string) *Commit {
+	t.Helper()
+	ctx := context.Background()
+	writeFile(t, repo, name, content)
+	if err := repo.Stage(ctx, name); err != nil {
+		t.Fatal(err)
+	}
+	commit, err := repo.Commit(ctx, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return commit
+}
+
+func TestOpenNotRepository(t *testing.T) {
+	if _, err := Open(context.Background(), t.TempDir()); !errors.Is(err, ErrNotRepository) {
+		t.Fatalf("Open = %v, want ErrNotRepository", err)
+	}
+}
+
+func TestStatusStageAndCommit(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+
+	status, err := repo.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Branch != "main" || status.Head != "" || !status.Clean() {
+		t.Fatalf("unexpected status of empty repo: %+v", status)
+	}
+	if _, err := repo.Commit(ctx, "empty"); !errors.Is(err, ErrNothingToCommit) {
+		t.Fatalf("Commit with nothing staged = %v, want ErrNothingToCommit", err)
+	}
+
+	writeFile(t, repo, "a.txt", "one\n")
+	writeFile(t, repo, "dir/b file.txt", "two\n")
+	if err := repo.Stage(ctx, "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	status, _ = repo.Status(ctx)
+	if len(status.Staged) != 1 || status.Staged[0].Path != "a.txt" || status.Staged[0].Status != "added" {
+		t.Fatalf("staged = %+v", status.Staged)
+	}
+	if len(status.Untracked) != 1 || status.Untracked[0] != "dir/b file.txt" {
+		t.Fatalf("untracked = %+v", status.Untracked)
+	}
+
+	if err := repo.Unstage(ctx, "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	status, _ = repo.Status(ctx)
+	if len(status.Staged) != 0 || len(status.Untracked) != 2 {
+		t.Fatalf("after unstage before first commit: %+v", status)
+	}
+
+	commit := commitFile(t, repo, "a.txt", "one\n", "Add a\n\nWith a body.")
+	if commit.Subject != "Add a" || commit.Body != "With a body." || commit.Author != "Test" {
+		t.Fatalf("commit = %+v", commit)
+	}
+
+	writeFile(t, repo, "a.txt", "one\nmore\n")
+	status, _ = repo.Status(ctx)
+	if status.Head != commit.Hash || len(status.Unstaged) != 1 || status.Unstaged[0].Status != "modified" {
+		t.Fatalf("status after edit: %+v", status)
+	}
+
+	diff, err := repo.Diff(ctx, false)
+	if err != nil || !strings.Contains(diff, "+more") {
+		t.Fatalf("Diff = %q, %v", diff, err)
+	}
+	if staged, _ := repo.Diff(ctx, true); staged != "" {
+		t.Fatalf("staged diff should be empty, got %q", staged)
+	}
+
+	if err := repo.Stage(ctx, "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Unstage(ctx, "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	status, _ = repo.Status(ctx)
+	if len(status.Staged) != 0 || len(status.Unstaged) != 1 {
+		t.Fatalf("after unstage: %+v", status)
+	}
+}
+
+func TestLogPaginationAndCommitDiff(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+
+	commits, hasMore, err := repo.Log(ctx, LogOptions{})
+	if err != nil || len(commits) != 0 || hasMore {
+		t.Fatalf("Log of empty repo = %v, %v, %v", commits, hasMore, err)
+	}
+
+	for i, content := range []string{"1\n", "2\n", "3\n", "4\n", "5\n"} {
+		commitFile(t, repo, "n.txt", content, "Commit "+string(rune('1'+i)))
+	}
+
+	page, hasMore, err := repo.Log(ctx, LogOptions{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || !hasMore || page[0].Subject != "Commit 5" || page[1].Subject != "Commit 4" {
+		t.Fatalf("first page = %+v, hasMore %v", page, hasMore)
+	}
+	page, hasMore, _ = repo.Log(ctx, LogOptions{Skip: 4, Limit: 2})
+	if len(page) != 1 || hasMore || page[0].Subject != "Commit 1" || len(page[0].Parents) != 0 {
+		t.Fatalf("last page = %+v, hasMore %v", page, hasMore)
+	}
+
+	diff, err := repo.CommitDiff(ctx, page[0].ShortHash)
+	if err != nil || !strings.Contains(diff, "+1") {
+		t.Fatalf("CommitDiff = %q, %v", diff, err)
+	}
+	if _, err := repo.CommitDiff(ctx, "--output=/tmp/x"); !errors.Is(err, ErrInvalidRef) {
+		t.Fatalf("CommitDiff with option-like ref = %v, want ErrInvalidRef", err)
+	}
+}
+
+func TestBranchesAndCheckout(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	first := commitFile(t, repo, "a.txt", "one\n", "First")
+	commitFile(t, repo, "a.txt", "two\n", "Second")
+
+	if err := repo.CreateBranch(ctx, "feature/x", first.Hash, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateBranch(ctx, "bad..name", "", false); !errors.Is(err, ErrInvalidRef) {
+		t.Fatalf("CreateBranch with bad name = %v, want ErrInvalidRef", err)
+	}
+
+	branches, err := repo.Branches(ctx)
+	if err != nil || len(branches) != 2 {
+		t.Fatalf("Branches = %+v, %v", branches, err)
+	}
+	for _, b := range branches {
+		if (b.Name == "main") != b.Current {
+			t.Fatalf("unexpected current flag: %+v", branches)
+		}
+	}
+
+	writeFile(t, repo, "a.txt", "dirty\n")
+	if err := repo.Checkout(ctx, "feature/x"); !errors.Is(err, ErrDirtyTree) {
+		t.Fatalf("Checkout with dirty tree = %v, want ErrDirtyTree", err)
+	}
+	if err := exec.Command("git", "-C", repo.Dir, "checkout", "--", "a.txt").Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Untracked files don't block a checkout.
+	writeFile(t, repo, "notes.txt", "scratch\n")
+	if err := repo.Checkout(ctx, "feature/x"); err != nil {
+		t.Fatal(err)
+	}
+	if branch, _ := repo.CurrentBranch(ctx); branch != "feature/x" {
+		t.Fatalf("current branch = %q, want feature/x", branch)
+	}
+
+	if err := repo.Checkout(ctx, "main~1"); err != nil {
+		t.Fatal(err)
+	}
+	status, _ := repo.Status(ctx)
+	if status.Branch != "" || status.Head != first.Hash {
+		t.Fatalf("status after detached checkout: %+v", status)
+	}
+}
+
+func TestRangeFilesAndDiff(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	commitFile(t, repo, "a.txt", "one\n", "First")
+	commitFile(t, repo, "b.txt", "two\n", "Second")
+	commitFile(t, repo, "c.txt", "three\n", "Third")
+
+	files, err := repo.RangeFiles(ctx, "HEAD~2..HEAD")
+	if err != nil || strings.Join(files, ",") != "b.txt,c.txt" {
+		t.Fatalf("RangeFiles(HEAD~2..HEAD) = %v, %v", files, err)
+	}
+	files, err = repo.RangeFiles(ctx, "HEAD~2")
+	if err != nil || strings.Join(files, ",") != "a.txt" {
+		t.Fatalf("RangeFiles(HEAD~2) = %v, %v", files, err)
+	}
+	diff, err := repo.RangeDiff(ctx, "HEAD~1..")
+	if err != nil || !strings.Contains(diff, "+three") || strings.Contains(diff, "+two") {
+		t.Fatalf("RangeDiff(HEAD~1..) = %q, %v", diff, err)
+	}
+	if _, err := repo.RangeFiles(ctx, "--output=/tmp/x..HEAD"); !errors.Is(err, ErrInvalidRef) {
+		t.Fatalf("RangeFiles with option-like range = %v, want ErrInvalidRef", err)
+	}
+
+	writeFile(t, repo, "a.txt", "changed\n")
+	writeFile(t, repo, "b.txt", "staged\n")
+	if err := repo.Stage(ctx, "b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	diff, err = repo.DiffHead(ctx)
+	if err != nil || !strings.Contains(diff, "+changed") || !strings.Contains(diff, "+staged") {
+		t.Fatalf("DiffHead = %q, %v", diff, err)
+	}
+}
+
+func TestUntrackedPaths(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	commitFile(t, repo, "tracked.txt", "one\n", "First")
+	commitFile(t, repo, ".gitignore", "*.log\n", "Ignore logs")
+	writeFile(t, repo, "notes.txt", "scratch\n")
+	writeFile(t, repo, "debug.log", "trace\n")
+	writeFile(t, repo, "[x].txt", "literal\n")
+
+	got, err := repo.UntrackedPaths(ctx, "tracked.txt", "notes.txt", "debug.log", "missing.txt", "[x].txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"[x].txt", "debug.log", "notes.txt"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("UntrackedPaths = %v, want %v", got, want)
+	}
+	if got, err := repo.UntrackedPaths(ctx); err != nil || got != nil {
+		t.Errorf("UntrackedPaths() = %v, %v; want nil", got, err)
+	}
+}
@@ -0,0 +1,206 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Commit is a commit as shown in the history.
+type Commit struct {
+	Hash        string    `json:"hash"`
+	ShortHash   string    `json:"short_hash"`
+	Author      string    `json:"author"`
+	AuthorEmail string    `json:"author_email"`
+	Date        time.Time `json:"date"`
+	Subject     string    `json:"subject"`
+	Body        string    `json:"body,omitempty"`
+	Parents     []string  `json:"parents"`
+}
+
+// LogOptions selects a page of history.
+type LogOptions struct {
+	// Ref is the revision to start from; empty means HEAD.
+	Ref string
+	// Path limits the history to commits touching this path.
+	Path  string
+	Skip  int
+	Limit int
+}
+
+const (
+	defaultLogLimit = 50
+	maxLogLimit     = 500
+
+	fieldSep  = "\x1f"
+	recordSep = "\x1e"
+)
+
+// Log returns a page of commits, newest first, and whether older commits remain.
+func (r *Repo) Log(ctx context.Context, opts LogOptions) ([]Commit, bool, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = defaultLogLimit
+	}
+	if opts.Limit > maxLogLimit {
+		opts.Limit = maxLogLimit
+	}
+	if opts.Skip < 0 {
+		opts.Skip = 0
+	}
+
+	ref := opts.Ref
+	if ref == "" {
+		if !r.hasHead(ctx) {
+			return []Commit{}, false, nil
+		}
+		ref = "HEAD"
+	} else if _, err := r.ResolveCommit(ctx, ref); err != nil {
+		return nil, false, err
+	}
+
+	args := []string{
+		"log",
+		"--format=%H%x1f%h%x1f%an%x1f%ae%x1f%aI%x1f%P%x1f%s%x1f%b%x1e",
+		"--skip=" + strconv.Itoa(opts.Skip),
+		// One extra commit tells us whether there is another page.
+		"--max-count=" + strconv.Itoa(opts.Limit+1),
+		"--end-of-options", ref, "--",
+	}
+	if opts.Path != "" {
+		args = append(args, opts.Path)
+	}
+
+	out, err := r.run(ctx, args...)
+	if err != nil {
+		return nil, false, err
+	}
+
+	commits, err := parseLog(out)
+	if err != nil {
+		return nil, false, err
+	}
+	hasMore := len(commits) > opts.Limit
+	if hasMore {
+		commits = commits[:opts.Limit]
+	}
+	return commits, hasMore, nil
+}
+
+func parseLog(out string) ([]Commit, error) {
+	commits := []Commit{}
+	for _, record := range strings.Split(out, recordSep) {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+		fields := strings.SplitN(record, fieldSep, 8)
+		if len(fields) != 8 {
+			return nil, fmt.Errorf("unexpected git log record: %q", record)
+		}
+		date, err := time.Parse(time.RFC3339, fields[4])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected commit date %q: %w", fields[4], err)
+		}
+		parents := strings.Fields(fields[5])
+		if parents == nil {
+			parents = []string{}
+		}
+		commits = append(commits, Commit{
+			Hash:        fields[0],
+			ShortHash:   fields[1],
+			Author:      fields[2],
+			AuthorEmail: fields[3],
+			Date:        date,
+			Parents:     parents,
+			Subject:     fields[6],
+			Body:        strings.TrimSpace(fields[7]),
+		})
+	}
+	return commits, nil
+}
+
+// CommitDiff returns the patch introduced by a commit.
+func (r *Repo) CommitDiff(ctx context.Context, rev string) (string, error) {
+	hash, err := r.ResolveCommit(ctx, rev)
+	if err != nil {
+		return "", err
+	}
+	return r.run(ctx, "show", "--format=", "--patch", "--no-color", "--no-ext-diff", hash)
+}
+
+// Trailer returns the value of a trailer such as "Clarion-Run-Id" in a commit's message,
+// or "" if the commit has none.
+func (r *Repo) Trailer(ctx context.Context, rev, key string) (string, error) {
+	hash, err := r.ResolveCommit(ctx, rev)
+	if err != nil {
+		return "", err
+	}
+	out, err := r.run(ctx, "log", "-1", "--format=%(trailers:key="+key+",valueonly,separator=%x0a)", hash)
+	if err != nil {
+		return "", err
+	}
+	value, _, _ := strings.Cut(strings.TrimSpace(out), "\n")
+	return value, nil
+}
+
+// rangeArgs validates a commit range such as "HEAD~3..HEAD" or "main...feature". A single
+// revision stands for the changes made by that commit.
+func (r *Repo) rangeArgs(ctx context.Context, spec string) ([]string, error) {
+	spec = strings.TrimSpace(spec)
+	from, to, isRange := strings.Cut(spec, "..")
+	if !isRange {
+		hash, err := r.ResolveCommit(ctx, spec)
+		if err != nil {
+			return nil, err
+		}
+		return []string{"diff-tree", "--root", "-r", "--no-commit-id", hash}, nil
+	}
+
+	to = strings.TrimPrefix(to, ".")
+	for _, rev := range []string{from, to} {
+		if rev == "" {
+			continue // an omitted side means HEAD
+		}
+		if _, err := r.ResolveCommit(ctx, rev); err != nil {
+			return nil, err
+		}
+	}
+	return []string{"diff", spec}, nil
+}
+
+// RangeFiles lists the files added or modified by a commit range or a single commit,
+// optionally limited to paths. Deleted files are left out.
+func (r *Repo) RangeFiles(ctx context.Context, spec string, paths ...string) ([]string, error) {
+	args, err := r.rangeArgs(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+	rev := args[len(args)-1]
+	args = append(args[:len(args)-1], "--name-only", "-z", "--diff-filter=d", "--end-of-options", rev, "--")
+	out, err := r.run(ctx, append(args, paths...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, path := range strings.Split(out, "\x00") {
+		if path != "" {
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
+
+// RangeDiff returns the unified diff of a commit range or a single commit, optionally
+// limited to paths.
+func (r *Repo) RangeDiff(ctx context.Context, spec string, paths ...string) (string, error) {
+	args, err := r.rangeArgs(ctx, spec)
+	if err != nil {
+		return "", err
+	}
+	rev := args[len(args)-1]
+	args = append(args[:len(args)-1], "--patch", "--no-color", "--no-ext-diff", "--end-of-options", rev, "--")
+	return r.run(ctx, append(args, paths...)...)
+}
@@ -0,0 +1,158 @@
+package git
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// FileChange is a changed path in the index or the working tree.
+type FileChange struct {
+	Path string `json:"path"`
+	// OrigPath is the previous path of a renamed or copied file.
+	OrigPath string `json:"orig_path,omitempty"`
+	// Status is one of "added", "modified", "deleted", "renamed", "copied" or "type_changed".
+	Status string `json:"status"`
+}
+
+// Status describes the current branch and the changes in a work tree.
+type Status struct {
+	Branch    string       `json:"branch"` // empty when HEAD is detached
+	Head      string       `json:"head"`   // empty before the first commit
+	Upstream  string       `json:"upstream,omitempty"`
+	Ahead     int          `json:"ahead"`
+	Behind    int          `json:"behind"`
+	Staged    []FileChange `json:"staged"`
+	Unstaged  []FileChange `json:"unstaged"`
+	Untracked []string     `json:"untracked"`
+	Conflicts []string     `json:"conflicts"`
+}
+
+// Clean reports whether there are no staged, unstaged or conflicted changes.
+// Untracked files do not count.
+func (s *Status) Clean() bool {
+	return len(s.Staged) == 0 && len(s.Unstaged) == 0 && len(s.Conflicts) == 0
+}
+
+var changeNames = map[byte]string{
+	'A': "added",
+	'M': "modified",
+	'D': "deleted",
+	'R': "renamed",
+	'C': "copied",
+	'T': "type_changed",
+}
+
+// Status returns the branch and the staged, unstaged and untracked changes.
+func (r *Repo) Status(ctx context.Context) (*Status, error) {
+	out, err := r.run(ctx, "status", "--porcelain=v2", "--branch", "--untracked-files=all", "-z")
+	if err != nil {
+		return nil, err
+	}
+	return parseStatus(out), nil
+}
+
+// UntrackedPaths returns those of the given paths that exist in the work tree without
+// being tracked, ignored files included. Paths are relative to the top of the work tree.
+func (r *Repo) UntrackedPaths(ctx context.Context, paths ...string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	args := append([]string{"--literal-pathspecs", "ls-files", "--others", "-z", "--"}, paths...)
+	out, err := r.run(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	var untracked []string
+	for _, p := range strings.Split(out, "\x00") {
+		if p != "" {
+			untracked = append(untracked, p)
+		}
+	}
+	return untracked, nil
+}
+
+// parseStatus parses `git status --porcelain=v2 --branch -z` output.
+func parseStatus(out string) *Status {
+	status := &Status{
+		Staged:    []FileChange{},
+		Unstaged:  []FileChange{},
+		Untracked: []string{},
+		Conflicts: []string{},
+	}
+
+	entries := strings.Split(out, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if entry == "" {
+			continue
+		}
+		switch entry[0] {
+		case '#':
+			parseBranchHeader(status, entry)
+		case '1':
+			// 1 XY sub mH mI mW hH hI path
+			fields := strings.SplitN(entry, " ", 9)
+			if len(fields) == 9 {
+				addChange(status, fields[1], fields[8], "")
+			}
+		case '2':
+			// 2 XY sub mH mI mW hH hI Xscore path, followed by the original path.
+			fields := strings.SplitN(entry, " ", 10)
+			if len(fields) == 10 {
+				origPath := ""
+				if i+1 < len(entries) {
+					i++
+					origPath = entries[i]
+				}
+				addChange(status, fields[1], fields[9], origPath)
+			}
+		case 'u':
+			// u XY sub m1 m2 m3 mW h1 h2 h3 path
+			fields := strings.SplitN(entry, " ", 11)
+			if len(fields) == 11 {
+				status.Conflicts = append(status.Conflicts, fields[10])
+			}
+		case '?':
+			status.Untracked = append(status.Untracked, strings.TrimPrefix(entry, "? "))
+		}
+	}
+	return status
+}
+
+func parseBranchHeader(status *Status, entry string) {
+	key, value, _ := strings.Cut(strings.TrimPrefix(entry, "# "), " ")
+	switch key {
+	case "branch.oid":
+		if value != "(initial)" {
+			status.Head = value
+		}
+	case "branch.head":
+		if value != "(detached)" {
+			status.Branch = value
+		}
+	case "branch.upstream":
+		status.Upstream = value
+	case "branch.ab":
+		ahead, behind, _ := strings.Cut(value, " ")
+		status.Ahead, _ = strconv.Atoi(strings.TrimPrefix(ahead, "+"))
+		status.Behind, _ = strconv.Atoi(strings.TrimPrefix(behind, "-"))
+	}
+}
+
+func addChange(status *Status, xy, path, origPath string) {
+	if len(xy) != 2 {
+		return
+	}
+	if name, ok := changeNames[xy[0]]; ok {
+		status.Staged = append(status.Staged, FileChange{Path: path, OrigPath: origPath, Status: name})
+	}
+	if name, ok := changeNames[xy[1]]; ok {
+		change := FileChange{Path: path, Status: name}
+		// The rename is recorded in the index; the work tree side only modifies the new path.
+		if xy[0] != 'R' && xy[0] != 'C' {
+			change.OrigPath = origPath
+		}
+		status.Unstaged = append(status.Unstaged, change)
+	}
+}
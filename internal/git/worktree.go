@@ -0,0 +1,104 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// ErrConflict is returned when a merge or cherry-pick stops on conflicts. The operation is
+// aborted, leaving the working tree as it was.
+var ErrConflict = errors.New("the changes conflict with the working tree")
+
+// AddWorktree checks out a new branch at startPoint into a linked worktree at path.
+func (r *Repo) AddWorktree(ctx context.Context, path, branch, startPoint string) (*Repo, error) {
+	if err := r.ValidateBranchName(ctx, branch); err != nil {
+		return nil, err
+	}
+	hash, err := r.ResolveCommit(ctx, startPoint)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.run(ctx, "worktree", "add", "--quiet", "-b", branch, "--", path, hash); err != nil {
+		return nil, err
+	}
+	return Open(ctx, path)
+}
+
+// RemoveWorktree deletes a linked worktree, discarding any changes in it.
+func (r *Repo) RemoveWorktree(ctx context.Context, path string) error {
+	_, err := r.run(ctx, "worktree", "remove", "--force", "--force", "--", path)
+	return err
+}
+
+// PruneWorktrees drops the administrative data of worktrees whose directories are gone.
+func (r *Repo) PruneWorktrees(ctx context.Context) error {
+	_, err := r.run(ctx, "worktree", "prune")
+	return err
+}
+
+// MainRepo returns the main work tree of the repository that dir belongs to. For a linked
+// worktree this is the repository it was created from.
+func MainRepo(ctx context.Context, dir string) (*Repo, error) {
+	repo, err := Open(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	out, err := repo.run(ctx, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return nil, err
+	}
+	return Open(ctx, filepath.Dir(strings.TrimSpace(out)))
+}
+
+// Merge merges rev into the current branch with a merge commit. It refuses to run with
+// uncommitted changes and aborts on conflicts.
+func (r *Repo) Merge(ctx context.Context, rev, message string) error {
+	hash, err := r.ResolveCommit(ctx, rev)
+	if err != nil {
+		return err
+	}
+	if err := r.requireClean(ctx); err != nil {
+		return err
+	}
+	if _, err := r.run(ctx, "merge", "--no-ff", "--quiet", "-m", message, hash); err != nil {
+		if r.abortIfInProgress(ctx, "MERGE_HEAD", "merge") {
+			return ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+// CherryPick applies the changes of the commits in from..to onto the current branch. It
+// refuses to run with uncommitted changes and aborts on conflicts.
+func (r *Repo) CherryPick(ctx context.Context, from, to string) error {
+	fromHash, err := r.ResolveCommit(ctx, from)
+	if err != nil {
+		return err
+	}
+	toHash, err := r.ResolveCommit(ctx, to)
+	if err != nil {
+		return err
+	}
+	if err := r.requireClean(ctx); err != nil {
+		return err
+	}
+	if _, err := r.run(ctx, "cherry-pick", "--allow-empty", fromHash+".."+toHash); err != nil {
+		if r.abortIfInProgress(ctx, "CHERRY_PICK_HEAD", "cherry-pick") {
+			return ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+// abortIfInProgress aborts an interrupted merge or cherry-pick and reports whether there was one.
+func (r *Repo) abortIfInProgress(ctx context.Context, ref, command string) bool {
+	if _, err := r.run(ctx, "rev-parse", "--verify", "--quiet", ref); err != nil {
+		return false
+	}
+	r.run(ctx, command, "--abort")
+	return true
+}
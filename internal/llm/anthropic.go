@@ -21,3 +21,28 @@ func (p *AnthropicProvider) Generate(ctx context.Context, messages []ChatMessage
 	log.Printf("AnthropicProvider selected, but it is not fully implemented yet.")
 	return nil, fmt.Errorf("provider 'Anthropic' is not yet implemented")
 }
+
+// ListModels returns the models available to the config's API key.
+func (p *AnthropicProvider) ListModels(ctx context.Context, config *models.LLMProviderConfig, llmConfigStore storage.LLMConfigStore) ([]ModelInfo, error) {
+	apiKey, err := providerAPIKey(config, llmConfigStore)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Data []struct {
+			ID          string `json:"id"`
+			DisplayName string `json:"display_name"`
+		} `json:"data"`
+	}
+	headers := map[string]string{"x-api-key": apiKey, "anthropic-version": "2023-06-01"}
+	if err := getJSON(ctx, "Anthropic", anthropicBaseURL+"/models?limit=1000", headers, &resp); err != nil {
+		return nil, err
+	}
+
+	list := make([]ModelInfo, 0, len(resp.Data))
+	for _, m := range resp.Data {
+		list = append(list, ModelInfo{ID: m.ID, Name: m.DisplayName})
+	}
+	return sortModels(list), nil
+}
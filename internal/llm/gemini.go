@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"slices"
+	"strings"
 
 	"github.com/ClarionDev/clarion/internal/models"
 	"github.com/ClarionDev/clarion/internal/storage"
@@ -21,3 +23,37 @@ func (p *GeminiProvider) Generate(ctx context.Context, messages []ChatMessage, r
 	log.Printf("GeminiProvider selected, but it is not fully implemented yet.")
 	return nil, fmt.Errorf("provider 'Google Gemini' is not yet implemented")
 }
+
+// ListModels returns the models available to the config's API key that support content generation.
+func (p *GeminiProvider) ListModels(ctx context.Context, config *models.LLMProviderConfig, llmConfigStore storage.LLMConfigStore) ([]ModelInfo, error) {
+	apiKey, err := providerAPIKey(config, llmConfigStore)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Models []struct {
+			Name                       string   `json:"name"`
+			DisplayName                string   `json:"displayName"`
+			InputTokenLimit            int      `json:"inputTokenLimit"`
+			SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+		} `json:"models"`
+	}
+	headers := map[string]string{"x-goog-api-key": apiKey}
+	if err := getJSON(ctx, "Google Gemini", geminiBaseURL+"/models?pageSize=1000", headers, &resp); err != nil {
+		return nil, err
+	}
+
+	list := make([]ModelInfo, 0, len(resp.Models))
+	for _, m := range resp.Models {
+		if !slices.Contains(m.SupportedGenerationMethods, "generateContent") {
+			continue
+		}
+		list = append(list, ModelInfo{
+			ID:            strings.TrimPrefix(m.Name, "models/"),
+			Name:          m.DisplayName,
+			ContextLength: m.InputTokenLimit,
+		})
+	}
+	return sortModels(list), nil
+}
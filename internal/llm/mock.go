@@ -0,0 +1,381 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/storage"
+)
+
+// Mock modes, chosen with the model name of a Mock LLM config.
+const (
+	MockReplay = "replay" // return the response recorded for the same request
+	MockCanned = "canned" // return the first of the config's "outputs" that matches the prompt
+	MockRandom = "random" // generate an output that conforms to the request's schema
+	MockRecord = "record" // call the "upstream" config's provider and record its response
+)
+
+// mockModes lists the mock modes in the order ListModels returns them.
+var mockModes = []ModelInfo{
+	{ID: MockReplay, Name: "Replay recorded responses"},
+	{ID: MockCanned, Name: "Canned outputs"},
+	{ID: MockRandom, Name: "Random schema-conformant output"},
+	{ID: MockRecord, Name: "Record an upstream provider"},
+}
+
+var (
+	mockFixtureDir   string
+	mockFixtureDirMu sync.RWMutex
+)
+
+// SetMockFixtureDir sets the directory the Mock provider records and replays responses
+// in, unless a config's "fixtures_dir" parameter names another.
+func SetMockFixtureDir(dir string) {
+	mockFixtureDirMu.Lock()
+	defer mockFixtureDirMu.Unlock()
+	mockFixtureDir = dir
+}
+
+func init() {
+	RegisterProvider(models.ProviderMock, &MockProvider{})
+}
+
+// MockProvider answers without a model, so that agents and the backend can be tested
+// offline and without API keys. The mode is the config's model name; the config's
+// parameters hold the mode's settings:
+//   - "outputs" for canned: a list of {"match": regexp, "output": {...}}; an entry without
+//     a match answers any prompt.
+//   - "upstream" for record: the LLM config of the real provider.
+//   - "fixtures_dir" for replay and record: where responses are kept.
+//   - "seed" for random: makes the output the same across requests; by default it only
+//     stays the same for the same request.
+type MockProvider struct{}
+
+// MockFixture is a recorded response, kept as <key>.json in the fixture directory.
+type MockFixture struct {
+	Key        string         `json:"key"`
+	Provider   string         `json:"provider"`
+	Model      string         `json:"model"`
+	Messages   []ChatMessage  `json:"messages"`
+	Output     map[string]any `json:"output"`
+	RecordedAt time.Time      `json:"recorded_at"`
+}
+
+func (p *MockProvider) Generate(ctx context.Context, messages []ChatMessage, request models.AgentRunRequest, llmConfigStore storage.LLMConfigStore) (map[string]any, error) {
+	key, err := MockRequestKey(messages, request)
+	if err != nil {
+		return nil, err
+	}
+	params := request.LLMConfig.Parameters
+
+	switch request.LLMConfig.Model {
+	case MockReplay:
+		fixture, err := readMockFixture(params, key)
+		if err != nil {
+			return nil, err
+		}
+		return fixture.Output, nil
+
+	case MockCanned:
+		return cannedOutput(params, messages)
+
+	case MockRandom:
+		seed := int64(binary.BigEndian.Uint64(mustDecodeHex(key)))
+		if s, ok := params["seed"].(float64); ok {
+			seed = int64(s)
+		}
+		schema, _ := request.OutputSchema["schema"].(map[string]any)
+		if schema == nil {
+			return nil, errors.New("mock: random output needs an output schema")
+		}
+		gen := &schemaGenerator{rand: rand.New(rand.NewSource(seed))}
+		output, _ := gen.value(schema, "", 0).(map[string]any)
+		if output == nil {
+			return nil, errors.New("mock: the output schema is not an object")
+		}
+		return output, nil
+
+	case MockRecord:
+		return recordMockFixture(ctx, messages, request, llmConfigStore, key)
+	}
+	return nil, fmt.Errorf("mock: unknown mode %q; use %s, %s, %s or %s", request.LLMConfig.Model, MockReplay, MockCanned, MockRandom, MockRecord)
+}
+
+// ListModels returns the mock modes; a Mock config needs no API key.
+func (p *MockProvider) ListModels(ctx context.Context, config *models.LLMProviderConfig, llmConfigStore storage.LLMConfigStore) ([]ModelInfo, error) {
+	return append([]ModelInfo(nil), mockModes...), nil
+}
+
+// ValidateKey accepts any key, since the Mock provider does not use one.
+func (p *MockProvider) ValidateKey(ctx context.Context, config *models.LLMProviderConfig, llmConfigStore storage.LLMConfigStore) error {
+	return nil
+}
+
+// MockRequestKey identifies a request by a hash of its messages and output schema. The LLM
+// config is left out, so that a response recorded from one provider replays for the same
+// request made with another.
+func MockRequestKey(messages []ChatMessage, request models.AgentRunRequest) (string, error) {
+	data, err := json.Marshal(struct {
+		Messages     []ChatMessage  `json:"messages"`
+		OutputSchema map[string]any `json:"output_schema"`
+	}{messages, request.OutputSchema})
+	if err != nil {
+		return "", fmt.Errorf("mock: failed to hash the request: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func fixtureDir(params map[string]any) (string, error) {
+	if dir, ok := params["fixtures_dir"].(string); ok && dir != "" {
+		return dir, nil
+	}
+	mockFixtureDirMu.RLock()
+	defer mockFixtureDirMu.RUnlock()
+	if mockFixtureDir == "" {
+		return "", errors.New("mock: no fixture directory is set")
+	}
+	return mockFixtureDir, nil
+}
+
+func readMockFixture(params map[string]any, key string) (*MockFixture, error) {
+	dir, err := fixtureDir(params)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, key+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("mock: no recorded response for request %s; record it first", key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mock: failed to read fixture: %w", err)
+	}
+	var fixture MockFixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("mock: failed to read fixture %s: %w", key, err)
+	}
+	return &fixture, nil
+}
+
+// recordMockFixture runs a request against the upstream provider and saves its response
+// under the request's key.
+func recordMockFixture(ctx context.Context, messages []ChatMessage, request models.AgentRunRequest, llmConfigStore storage.LLMConfigStore, key string) (map[string]any, error) {
+	dir, err := fixtureDir(request.LLMConfig.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	var upstream models.LLMConfig
+	if raw, ok := request.LLMConfig.Parameters["upstream"]; ok {
+		data, err := json.Marshal(raw)
+		if err == nil {
+			err = json.Unmarshal(data, &upstream)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("mock: invalid upstream config: %w", err)
+		}
+	}
+	if upstream.Provider == "" || upstream.Provider == models.ProviderMock {
+		return nil, errors.New("mock: recording needs an upstream config with a real provider")
+	}
+	provider, err := GetProvider(upstream.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	upstreamRequest := request
+	upstreamRequest.LLMConfig = upstream
+	output, err := provider.Generate(ctx, messages, upstreamRequest, llmConfigStore)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(MockFixture{
+		Key:        key,
+		Provider:   upstream.Provider,
+		Model:      upstream.Model,
+		Messages:   messages,
+		Output:     output,
+		RecordedAt: time.Now().UTC(),
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("mock: failed to encode fixture: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("mock: failed to create fixture directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, key+".json"), data, 0644); err != nil {
+		return nil, fmt.Errorf("mock: failed to write fixture: %w", err)
+	}
+	return output, nil
+}
+
+// cannedOutput returns the first canned output whose pattern matches the last user
+// message.
+func cannedOutput(params map[string]any, messages []ChatMessage) (map[string]any, error) {
+	var prompt string
+	for _, m := range messages {
+		if m.Role == "user" {
+			prompt = m.Content
+		}
+	}
+	outputs, _ := params["outputs"].([]any)
+	if len(outputs) == 0 {
+		return nil, errors.New(`mock: canned mode needs an "outputs" parameter`)
+	}
+	for i, o := range outputs {
+		entry, _ := o.(map[string]any)
+		output, ok := entry["output"].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("mock: canned output %d has no \"output\" object", i)
+		}
+		if pattern, _ := entry["match"].(string); pattern != "" {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("mock: canned output %d: %w", i, err)
+			}
+			if !re.MatchString(prompt) {
+				continue
+			}
+		}
+		// The config keeps the canned output; runs get a copy they may change.
+		data, err := json.Marshal(output)
+		if err != nil {
+			return nil, fmt.Errorf("mock: canned output %d: %w", i, err)
+		}
+		var c map[string]any
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, fmt.Errorf("mock: canned output %d: %w", i, err)
+		}
+		return c, nil
+	}
+	return nil, errors.New("mock: no canned output matches the prompt")
+}
+
+func mustDecodeHex(s string) []byte {
+	b, _ := hex.DecodeString(s)
+	return b
+}
+
+// maxMockDepth stops random generation of recursive or very deep schemas.
+const maxMockDepth = 10
+
+var mockWords = strings.Fields("lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor incididunt ut labore et dolore magna aliqua")
+
+// schemaGenerator generates random values that conform to a JSON schema.
+type schemaGenerator struct {
+	rand *rand.Rand
+}
+
+func (g *schemaGenerator) value(schema map[string]any, name string, depth int) any {
+	if c, ok := schema["const"]; ok {
+		return c
+	}
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 0 {
+		return enum[g.rand.Intn(len(enum))]
+	}
+	for _, key := range []string{"anyOf", "oneOf"} {
+		if options, ok := schema[key].([]any); ok && len(options) > 0 {
+			if option, ok := options[g.rand.Intn(len(options))].(map[string]any); ok {
+				return g.value(option, name, depth)
+			}
+		}
+	}
+	if depth > maxMockDepth {
+		return nil
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		properties, _ := schema["properties"].(map[string]any)
+		keys := make([]string, 0, len(properties))
+		for k := range properties {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out := make(map[string]any, len(keys))
+		for _, k := range keys {
+			sub, _ := properties[k].(map[string]any)
+			out[k] = g.value(sub, k, depth+1)
+		}
+		return out
+	case "array":
+		minItems, maxItems := intKeyword(schema, "minItems", 1), intKeyword(schema, "maxItems", 3)
+		maxItems = max(maxItems, minItems)
+		n := minItems + g.rand.Intn(maxItems-minItems+1)
+		items, _ := schema["items"].(map[string]any)
+		out := make([]any, n)
+		for i := range out {
+			out[i] = g.value(items, name, depth+1)
+		}
+		return out
+	case "string":
+		n := 2 + g.rand.Intn(5)
+		words := make([]string, n)
+		for i := range words {
+			words[i] = mockWords[g.rand.Intn(len(mockWords))]
+		}
+		if name == "path" {
+			return strings.Join(words[:2], "/") + ".txt"
+		}
+		return strings.Join(words, " ")
+	case "integer":
+		lo, hi := intKeyword(schema, "minimum", 0), intKeyword(schema, "maximum", 100)
+		if hi < lo {
+			hi = lo
+		}
+		return lo + g.rand.Intn(hi-lo+1)
+	case "number":
+		lo, hi := floatKeyword(schema, "minimum", 0), floatKeyword(schema, "maximum", 100)
+		return math.Round((lo+g.rand.Float64()*(hi-lo))*100) / 100
+	case "boolean":
+		return g.rand.Intn(2) == 1
+	}
+	return nil
+}
+
+// schemaType returns a schema's type; of a list of types, the first that is not null.
+func schemaType(schema map[string]any) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []any:
+		for _, v := range t {
+			if s, ok := v.(string); ok && s != "null" {
+				return s
+			}
+		}
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	return ""
+}
+
+func intKeyword(schema map[string]any, key string, fallback int) int {
+	if v, ok := schema[key].(float64); ok {
+		return int(v)
+	}
+	return fallback
+}
+
+func floatKeyword(schema map[string]any, key string, fallback float64) float64 {
+	if v, ok := schema[key].(float64); ok {
+		return v
+	}
+	return fallback
+}
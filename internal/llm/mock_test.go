@@ -0,0 +1,143 @@
+package llm
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/storage"
+)
+
+// upstreamProvider answers with the model it was called with.
+type upstreamProvider struct{ calls int }
+
+func (p *upstreamProvider) Generate(ctx context.Context, messages []ChatMessage, request models.AgentRunRequest, llmConfigStore storage.LLMConfigStore) (map[string]any, error) {
+	p.calls++
+	return map[string]any{"summary": "from " + request.LLMConfig.Model}, nil
+}
+
+var testUpstream = &upstreamProvider{}
+
+func init() {
+	RegisterProvider("Mock Test Upstream", testUpstream)
+}
+
+var mockSchema = map[string]any{"schema": map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"summary": map[string]any{"type": "string"},
+		"file_changes": map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"action": map[string]any{"type": "string", "enum": []any{"create", "delete"}},
+					"path":   map[string]any{"type": "string"},
+					"lines":  map[string]any{"type": "integer", "minimum": 1.0, "maximum": 5.0},
+				},
+			},
+			"minItems": 2.0,
+			"maxItems": 2.0,
+		},
+	},
+}}
+
+func mockRequest(mode string, params map[string]any) models.AgentRunRequest {
+	return models.AgentRunRequest{OutputSchema: mockSchema, LLMConfig: models.LLMConfig{Provider: models.ProviderMock, Model: mode, Parameters: params}}
+}
+
+func TestMockRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	messages := []ChatMessage{{Role: "system", Content: "sys"}, {Role: "user", Content: "Add a file"}}
+	mock := &MockProvider{}
+
+	if _, err := mock.Generate(context.Background(), messages, mockRequest(MockReplay, map[string]any{"fixtures_dir": dir}), nil); err == nil {
+		t.Fatal("replay without a recording succeeded, want an error")
+	}
+
+	record := mockRequest(MockRecord, map[string]any{
+		"fixtures_dir": dir,
+		"upstream":     map[string]any{"provider": "Mock Test Upstream", "model": "real-model"},
+	})
+	recorded, err := mock.Generate(context.Background(), messages, record, nil)
+	if err != nil {
+		t.Fatalf("record error = %v", err)
+	}
+	if recorded["summary"] != "from real-model" || testUpstream.calls != 1 {
+		t.Fatalf("record = %v after %d calls, want the upstream output", recorded, testUpstream.calls)
+	}
+
+	replayed, err := mock.Generate(context.Background(), messages, mockRequest(MockReplay, map[string]any{"fixtures_dir": dir}), nil)
+	if err != nil {
+		t.Fatalf("replay error = %v", err)
+	}
+	if !reflect.DeepEqual(replayed, recorded) || testUpstream.calls != 1 {
+		t.Errorf("replay = %v, want %v without calling upstream", replayed, recorded)
+	}
+
+	other := []ChatMessage{{Role: "user", Content: "Something else"}}
+	if _, err := mock.Generate(context.Background(), other, mockRequest(MockReplay, map[string]any{"fixtures_dir": dir}), nil); err == nil {
+		t.Error("replay of another request succeeded, want an error")
+	}
+}
+
+func TestMockCanned(t *testing.T) {
+	params := map[string]any{"outputs": []any{
+		map[string]any{"match": "(?i)delete", "output": map[string]any{"summary": "deleted"}},
+		map[string]any{"output": map[string]any{"summary": "default"}},
+	}}
+	tests := []struct{ prompt, want string }{
+		{"Please DELETE it", "deleted"},
+		{"Add a file", "default"},
+	}
+	for _, tt := range tests {
+		output, err := (&MockProvider{}).Generate(context.Background(), []ChatMessage{{Role: "user", Content: tt.prompt}}, mockRequest(MockCanned, params), nil)
+		if err != nil || output["summary"] != tt.want {
+			t.Errorf("canned(%q) = %v, %v; want summary %q", tt.prompt, output, err, tt.want)
+		}
+		// Runs add to and remove from their output; the config must not change.
+		output["cache"] = "info"
+		delete(output, "summary")
+	}
+	for _, o := range params["outputs"].([]any) {
+		output := o.(map[string]any)["output"].(map[string]any)
+		if _, ok := output["cache"]; ok || output["summary"] == nil {
+			t.Errorf("canned output changed by a run: %v", output)
+		}
+	}
+}
+
+func TestMockRandom(t *testing.T) {
+	messages := []ChatMessage{{Role: "user", Content: "Add a file"}}
+	mock := &MockProvider{}
+	first, err := mock.Generate(context.Background(), messages, mockRequest(MockRandom, nil), nil)
+	if err != nil {
+		t.Fatalf("random error = %v", err)
+	}
+	second, _ := mock.Generate(context.Background(), messages, mockRequest(MockRandom, nil), nil)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("random output differs for the same request: %v and %v", first, second)
+	}
+
+	if _, ok := first["summary"].(string); !ok {
+		t.Errorf("summary = %#v, want a string", first["summary"])
+	}
+	changes, _ := first["file_changes"].([]any)
+	if len(changes) != 2 {
+		t.Fatalf("file_changes = %#v, want 2 items", first["file_changes"])
+	}
+	for _, c := range changes {
+		change := c.(map[string]any)
+		if a := change["action"]; a != "create" && a != "delete" {
+			t.Errorf("action = %v, want an enum value", a)
+		}
+		if p, _ := change["path"].(string); !strings.HasSuffix(p, ".txt") {
+			t.Errorf("path = %v, want a file path", change["path"])
+		}
+		if n, _ := change["lines"].(int); n < 1 || n > 5 {
+			t.Errorf("lines = %v, want 1 to 5", change["lines"])
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/storage"
+)
+
+// ErrInvalidAPIKey is returned when a provider rejects the configured API key.
+var ErrInvalidAPIKey = errors.New("the provider rejected the API key")
+
+// ModelInfo describes a model available to an API key.
+type ModelInfo struct {
+	ID            string `json:"id"`
+	Name          string `json:"name,omitempty"`
+	ContextLength int    `json:"context_length,omitempty"`
+}
+
+// ModelLister is implemented by providers that can list the models available to a config's API key.
+type ModelLister interface {
+	ListModels(ctx context.Context, config *models.LLMProviderConfig, llmConfigStore storage.LLMConfigStore) ([]ModelInfo, error)
+}
+
+// KeyValidator is implemented by providers with a dedicated endpoint for checking an API key.
+// Providers without one are checked by listing their models.
+type KeyValidator interface {
+	ValidateKey(ctx context.Context, config *models.LLMProviderConfig, llmConfigStore storage.LLMConfigStore) error
+}
+
+// CheckAPIKey makes a cheap authenticated request to verify a config's API key.
+// A rejected key is reported as ErrInvalidAPIKey.
+func CheckAPIKey(ctx context.Context, provider Provider, config *models.LLMProviderConfig, llmConfigStore storage.LLMConfigStore) error {
+	if validator, ok := provider.(KeyValidator); ok {
+		return validator.ValidateKey(ctx, config, llmConfigStore)
+	}
+	if lister, ok := provider.(ModelLister); ok {
+		_, err := lister.ListModels(ctx, config, llmConfigStore)
+		return err
+	}
+	return fmt.Errorf("provider '%s' does not support key validation", config.Provider)
+}
+
+// providerAPIKey decrypts a config's API key for an outgoing request.
+func providerAPIKey(config *models.LLMProviderConfig, llmConfigStore storage.LLMConfigStore) (string, error) {
+	apiKey, err := llmConfigStore.DecryptAPIKey(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt API key for LLM config '%s': %w", config.ID, err)
+	}
+	if apiKey == "" {
+		return "", fmt.Errorf("API key for LLM config '%s' is empty", config.ID)
+	}
+	return apiKey, nil
+}
+
+var metadataClient = &http.Client{Timeout: 15 * time.Second}
+
+// Base URLs of the provider APIs. They are variables so that tests can point them at a
+// local server.
+var (
+	openAIBaseURL     = "https://api.openai.com/v1"
+	anthropicBaseURL  = "https://api.anthropic.com/v1"
+	geminiBaseURL     = "https://generativelanguage.googleapis.com/v1beta"
+	openRouterBaseURL = "https://openrouter.ai/api/v1"
+)
+
+// getJSON performs an authenticated GET against a provider's metadata API and decodes the
+// response into out. 401 and 403 responses, and the 400 with which Gemini answers an
+// invalid key, are reported as ErrInvalidAPIKey.
+func getJSON(ctx context.Context, providerName, url string, headers map[string]string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("error creating GET request: %w", err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := metadataClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making HTTP request to %s: %w", providerName, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read %s response body: %w", providerName, err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden,
+		resp.StatusCode == http.StatusBadRequest && bytes.Contains(body, []byte("API_KEY_INVALID")):
+		return fmt.Errorf("%w (%s: %s)", ErrInvalidAPIKey, providerName, resp.Status)
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("%s API error (%s): %s", providerName, resp.Status, string(body))
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to unmarshal %s response: %w", providerName, err)
+	}
+	return nil
+}
+
+func sortModels(list []ModelInfo) []ModelInfo {
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
@@ -0,0 +1,138 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ClarionDev/clarion/internal/models"
+)
+
+// keyStore is an LLMConfigStore whose keys are stored in plain text.
+type keyStore struct{}
+
+func (keyStore) SaveLLMConfig(ctx context.Context, config *models.LLMProviderConfig) error {
+	return nil
+}
+func (keyStore) GetLLMConfig(ctx context.Context, id string) (*models.LLMProviderConfig, error) {
+	return nil, errors.New("not found")
+}
+func (keyStore) ListLLMConfigs(ctx context.Context) ([]*models.LLMProviderConfig, error) {
+	return nil, nil
+}
+func (keyStore) DeleteLLMConfig(ctx context.Context, id string) error { return nil }
+func (keyStore) DecryptAPIKey(config *models.LLMProviderConfig) (string, error) {
+	return config.APIKey, nil
+}
+
+// fakeAPI serves body for path when the request carries the header, and answers
+// status with errBody otherwise.
+func fakeAPI(t *testing.T, base *string, path, header, value, body string, status int, errBody string) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get(header) != value {
+			w.WriteHeader(status)
+			w.Write([]byte(errBody))
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	old := *base
+	*base = srv.URL
+	t.Cleanup(func() { *base = old })
+}
+
+func modelIDs(list []ModelInfo) []string {
+	ids := make([]string, len(list))
+	for i, m := range list {
+		ids[i] = m.ID
+	}
+	return ids
+}
+
+func TestListModels(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider ModelLister
+		base     *string
+		path     string
+		header   string
+		value    string
+		body     string
+		want     []string
+	}{
+		{"OpenAI", &OpenAIProvider{}, &openAIBaseURL, "/models", "Authorization", "Bearer good",
+			`{"data":[{"id":"gpt-4o"},{"id":"gpt-4.1"}]}`, []string{"gpt-4.1", "gpt-4o"}},
+		{"Anthropic", &AnthropicProvider{}, &anthropicBaseURL, "/models", "x-api-key", "good",
+			`{"data":[{"id":"claude-b","display_name":"B"},{"id":"claude-a","display_name":"A"}]}`, []string{"claude-a", "claude-b"}},
+		{"Gemini", &GeminiProvider{}, &geminiBaseURL, "/models", "x-goog-api-key", "good",
+			`{"models":[{"name":"models/gemini-pro","supportedGenerationMethods":["generateContent"]},{"name":"models/embedding","supportedGenerationMethods":["embedContent"]}]}`,
+			[]string{"gemini-pro"}},
+		{"OpenRouter", &OpenRouterProvider{}, &openRouterBaseURL, "/models", "Authorization", "Bearer good",
+			`{"data":[{"id":"x/model","name":"X","context_length":8192}]}`, []string{"x/model"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeAPI(t, tt.base, tt.path, tt.header, tt.value, tt.body, http.StatusUnauthorized, `{"error":"bad key"}`)
+			list, err := tt.provider.ListModels(context.Background(), &models.LLMProviderConfig{ID: "c", APIKey: "good"}, keyStore{})
+			if err != nil {
+				t.Fatalf("ListModels() error = %v", err)
+			}
+			if got := modelIDs(list); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListModels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckAPIKeyRejected(t *testing.T) {
+	tests := []struct {
+		name        string
+		provider    Provider
+		base        *string
+		path        string
+		header      string
+		status      int
+		errBody     string
+		wantInvalid bool
+	}{
+		{"OpenAI 401", &OpenAIProvider{}, &openAIBaseURL, "/models", "Authorization", http.StatusUnauthorized, `{}`, true},
+		{"Anthropic 403", &AnthropicProvider{}, &anthropicBaseURL, "/models", "x-api-key", http.StatusForbidden, `{}`, true},
+		{"OpenRouter key 401", &OpenRouterProvider{}, &openRouterBaseURL, "/key", "Authorization", http.StatusUnauthorized, `{}`, true},
+		{"Gemini invalid key", &GeminiProvider{}, &geminiBaseURL, "/models", "x-goog-api-key", http.StatusBadRequest,
+			`{"error":{"code":400,"message":"API key not valid.","status":"INVALID_ARGUMENT","details":[{"reason":"API_KEY_INVALID"}]}}`, true},
+		{"Gemini other 400", &GeminiProvider{}, &geminiBaseURL, "/models", "x-goog-api-key", http.StatusBadRequest,
+			`{"error":{"code":400,"message":"Invalid page size.","status":"INVALID_ARGUMENT"}}`, false},
+		{"server error", &OpenAIProvider{}, &openAIBaseURL, "/models", "Authorization", http.StatusInternalServerError, `oops`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeAPI(t, tt.base, tt.path, tt.header, "never", `{}`, tt.status, tt.errBody)
+			err := CheckAPIKey(context.Background(), tt.provider, &models.LLMProviderConfig{ID: "c", APIKey: "bad"}, keyStore{})
+			if err == nil {
+				t.Fatal("CheckAPIKey() succeeded, want an error")
+			}
+			if errors.Is(err, ErrInvalidAPIKey) != tt.wantInvalid {
+				t.Errorf("CheckAPIKey() error = %v, ErrInvalidAPIKey: %v, want %v", err, errors.Is(err, ErrInvalidAPIKey), tt.wantInvalid)
+			}
+		})
+	}
+}
+
+func TestCheckAPIKeyValid(t *testing.T) {
+	fakeAPI(t, &openRouterBaseURL, "/key", "Authorization", "Bearer good", `{"data":{"label":"k"}}`, http.StatusUnauthorized, `{}`)
+	if err := CheckAPIKey(context.Background(), &OpenRouterProvider{}, &models.LLMProviderConfig{ID: "c", APIKey: "good"}, keyStore{}); err != nil {
+		t.Errorf("CheckAPIKey() error = %v, want nil", err)
+	}
+	if err := CheckAPIKey(context.Background(), &OpenAIProvider{}, &models.LLMProviderConfig{ID: "c"}, keyStore{}); err == nil || errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("CheckAPIKey() with an empty key error = %v, want a config error", err)
+	}
+}
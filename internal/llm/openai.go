@@ -141,7 +141,7 @@ func SetRequestHeaders(req *http.Request, apiKey string) {
 }
 
 func (o *OpenAIProvider) Generate(ctx context.Context, messages []ChatMessage, request models.AgentRunRequest, llmConfigStore storage.LLMConfigStore) (map[string]any, error) {
-	url := "https://api.openai.com/v1/responses"
+	url := openAIBaseURL + "/responses"
 
 	if request.LLMConfig.ConfigID == "" {
 		return nil, errors.New("agent's LLM configuration is missing a Config ID")
@@ -151,7 +151,10 @@ func (o *OpenAIProvider) Generate(ctx context.Context, messages []ChatMessage, r
 	if err != nil {
 		return nil, fmt.Errorf("failed to load LLM config '%s': %w", request.LLMConfig.ConfigID, err)
 	}
-	apiKey := llmConfig.APIKey
+	apiKey, err := llmConfigStore.DecryptAPIKey(llmConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt API key for LLM config '%s': %w", request.LLMConfig.ConfigID, err)
+	}
 
 	if apiKey == "" {
 		return nil, fmt.Errorf("API key for LLM config '%s' is empty", request.LLMConfig.ConfigID)
@@ -185,8 +188,14 @@ func (o *OpenAIProvider) Generate(ctx context.Context, messages []ChatMessage, r
 		Type    string            `json:"type"`
 		Content []ResponseContent `json:"content,omitempty"`
 	}
+	type Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	}
 	type APIResponse struct {
 		Output []OutputItem `json:"output"`
+		Usage  *Usage       `json:"usage"`
 		Error  any          `json:"error"`
 	}
 
@@ -232,5 +241,37 @@ func (o *OpenAIProvider) Generate(ctx context.Context, messages []ChatMessage, r
 		return nil, fmt.Errorf("failed to unmarshal structured output from model response: %w. Raw content: %s", err, jsonContentString)
 	}
 
+	if apiResp.Usage != nil {
+		finalOutput["token_usage"] = map[string]int{
+			"prompt":     apiResp.Usage.InputTokens,
+			"completion": apiResp.Usage.OutputTokens,
+			"total":      apiResp.Usage.TotalTokens,
+		}
+	}
+
 	return finalOutput, nil
 }
+
+// ListModels returns the models available to the config's API key.
+func (o *OpenAIProvider) ListModels(ctx context.Context, config *models.LLMProviderConfig, llmConfigStore storage.LLMConfigStore) ([]ModelInfo, error) {
+	apiKey, err := providerAPIKey(config, llmConfigStore)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	headers := map[string]string{"Authorization": "Bearer " + apiKey}
+	if err := getJSON(ctx, "OpenAI", openAIBaseURL+"/models", headers, &resp); err != nil {
+		return nil, err
+	}
+
+	list := make([]ModelInfo, 0, len(resp.Data))
+	for _, m := range resp.Data {
+		list = append(list, ModelInfo{ID: m.ID})
+	}
+	return sortModels(list), nil
+}
@@ -10,6 +10,7 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/ClarionDev/clarion/internal/message"
 	"github.com/ClarionDev/clarion/internal/models"
 	"github.com/ClarionDev/clarion/internal/storage"
 )
@@ -70,7 +71,10 @@ func (o *OpenRouterProvider) Generate(ctx context.Context, messages []ChatMessag
 	if err != nil {
 		return nil, fmt.Errorf("failed to get LLM config for OpenRouter: %w", err)
 	}
-	apiKey := config.APIKey
+	apiKey, err := llmConfigStore.DecryptAPIKey(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt API key for OpenRouter: %w", err)
+	}
 
 	requestBody, err := createOpenRouterRequestPayload(request, messages)
 	if err != nil {
@@ -82,7 +86,7 @@ func (o *OpenRouterProvider) Generate(ctx context.Context, messages []ChatMessag
 		return nil, fmt.Errorf("failed to marshal request body: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://openrouter.ai/api/v1/chat/completions", bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", openRouterBaseURL+"/chat/completions", bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create POST request: %w", err)
 	}
@@ -148,6 +152,10 @@ func (o *OpenRouterProvider) Generate(ctx context.Context, messages []ChatMessag
 func createOpenRouterRequestPayload(request models.AgentRunRequest, messages []ChatMessage) (ChatCompletionRequest, error) {
 	var chatMessages []ChatCompletionMessage
 	for _, msg := range messages {
+		// Chat completions have no developer role; developer messages are sent as system ones.
+		if msg.Role == message.DEVELOPER_MESSAGE.String() {
+			msg.Role = message.SYSTEM_MESSAGE.String()
+		}
 		chatMessages = append(chatMessages, ChatCompletionMessage(msg))
 	}
 
@@ -188,3 +196,42 @@ func createOpenRouterRequestPayload(request models.AgentRunRequest, messages []C
 
 	return payload, nil
 }
+
+// ValidateKey checks the key against OpenRouter's key endpoint, since its model list is public.
+func (o *OpenRouterProvider) ValidateKey(ctx context.Context, config *models.LLMProviderConfig, llmConfigStore storage.LLMConfigStore) error {
+	apiKey, err := providerAPIKey(config, llmConfigStore)
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Data map[string]any `json:"data"`
+	}
+	headers := map[string]string{"Authorization": "Bearer " + apiKey}
+	return getJSON(ctx, "OpenRouter", openRouterBaseURL+"/key", headers, &resp)
+}
+
+// ListModels returns the models available through OpenRouter.
+func (o *OpenRouterProvider) ListModels(ctx context.Context, config *models.LLMProviderConfig, llmConfigStore storage.LLMConfigStore) ([]ModelInfo, error) {
+	apiKey, err := providerAPIKey(config, llmConfigStore)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Data []struct {
+			ID            string `json:"id"`
+			Name          string `json:"name"`
+			ContextLength int    `json:"context_length"`
+		} `json:"data"`
+	}
+	headers := map[string]string{"Authorization": "Bearer " + apiKey}
+	if err := getJSON(ctx, "OpenRouter", openRouterBaseURL+"/models", headers, &resp); err != nil {
+		return nil, err
+	}
+
+	list := make([]ModelInfo, 0, len(resp.Data))
+	for _, m := range resp.Data {
+		list = append(list, ModelInfo{ID: m.ID, Name: m.Name, ContextLength: m.ContextLength})
+	}
+	return sortModels(list), nil
+}
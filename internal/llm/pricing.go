@@ -0,0 +1,74 @@
+package llm
+
+import (
+	"strings"
+
+	"github.com/ClarionDev/clarion/internal/models"
+)
+
+// Price is what a model costs in US dollars per million tokens.
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+// prices are list prices of common models, keyed by model ID or the start of one; the
+// longest matching key wins, so "gpt-4o-mini" is not priced as "gpt-4o".
+var prices = map[string]Price{
+	"gpt-4o":            {Input: 2.50, Output: 10.00},
+	"gpt-4o-mini":       {Input: 0.15, Output: 0.60},
+	"gpt-4.1":           {Input: 2.00, Output: 8.00},
+	"gpt-4.1-mini":      {Input: 0.40, Output: 1.60},
+	"gpt-4.1-nano":      {Input: 0.10, Output: 0.40},
+	"o3-mini":           {Input: 1.10, Output: 4.40},
+	"claude-3-5-sonnet": {Input: 3.00, Output: 15.00},
+	"claude-3-7-sonnet": {Input: 3.00, Output: 15.00},
+	"claude-3-5-haiku":  {Input: 0.80, Output: 4.00},
+	"claude-3-opus":     {Input: 15.00, Output: 75.00},
+	"gemini-1.5-pro":    {Input: 1.25, Output: 5.00},
+	"gemini-1.5-flash":  {Input: 0.075, Output: 0.30},
+	"gemini-2.0-flash":  {Input: 0.10, Output: 0.40},
+}
+
+// ModelPrice returns the price of a model. OpenRouter model IDs are looked up without
+// their vendor prefix, e.g. "openai/gpt-4o" as "gpt-4o".
+func ModelPrice(model string) (Price, bool) {
+	if _, name, ok := strings.Cut(model, "/"); ok {
+		model = name
+	}
+	var best string
+	for key := range prices {
+		if strings.HasPrefix(model, key) && len(key) > len(best) {
+			best = key
+		}
+	}
+	if best == "" {
+		return Price{}, false
+	}
+	return prices[best], true
+}
+
+// Cost returns what a generation with the given usage cost on a model, if its price is
+// known.
+func Cost(model string, usage models.TokenUsage) (float64, bool) {
+	price, ok := ModelPrice(model)
+	if !ok {
+		return 0, false
+	}
+	return (float64(usage.Prompt)*price.Input + float64(usage.Completion)*price.Output) / 1e6, true
+}
+
+// Usage reads the token usage a provider added to its output as "token_usage".
+func Usage(output map[string]any) (*models.TokenUsage, bool) {
+	switch u := output["token_usage"].(type) {
+	case map[string]int:
+		return &models.TokenUsage{Prompt: u["prompt"], Completion: u["completion"], Total: u["total"]}, true
+	case map[string]any:
+		count := func(key string) int {
+			n, _ := u[key].(float64)
+			return int(n)
+		}
+		return &models.TokenUsage{Prompt: count("prompt"), Completion: count("completion"), Total: count("total")}, true
+	}
+	return nil, false
+}
@@ -3,9 +3,12 @@ package llm
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"slices"
 	"sort"
 	"strings"
 
+	"github.com/ClarionDev/clarion/internal/message"
 	"github.com/ClarionDev/clarion/internal/models"
 )
 
@@ -25,6 +28,7 @@ type FileContent struct {
 // PromptData is a structured representation of all components of a prompt.
 type PromptData struct {
 	CodebaseContext    []FileContent  `json:"Codebase,omitempty"`
+	Diff               string         `json:"Diff,omitempty"`
 	UserTask           string         `json:"Prompt"`
 	SystemInstructions string         `json:"Systemp Instructions"`
 	OutputSchema       map[string]any `json:"Output Schema"`
@@ -49,6 +53,7 @@ func buildPromptData(request models.AgentRunRequest, codebaseContent map[string]
 
 	return PromptData{
 		CodebaseContext:    files,
+		Diff:               request.Diff,
 		UserTask:           request.Prompt,
 		SystemInstructions: request.SystemInstruction,
 		OutputSchema:       request.OutputSchema,
@@ -61,6 +66,14 @@ func BuildPromptMarkdown(request models.AgentRunRequest, codebaseContent map[str
 	data := buildPromptData(request, codebaseContent)
 	var builder strings.Builder
 
+	if len(request.Messages) > 0 {
+		for _, m := range buildTemplateMessages(request, codebaseContent) {
+			builder.WriteString(fmt.Sprintf("## %s Message\n%s\n\n", m.Role, m.Content))
+		}
+		writeSchemaSection(&builder, data.OutputSchema)
+		return builder.String()
+	}
+
 	// Append codebase context
 	if len(data.CodebaseContext) > 0 {
 		builder.WriteString("## Codebase Context\n")
@@ -68,6 +81,7 @@ func BuildPromptMarkdown(request models.AgentRunRequest, codebaseContent map[str
 			builder.WriteString(fmt.Sprintf("File: %s\n```\n%s\n```\n\n", file.Path, file.Content))
 		}
 	}
+	writeDiffSection(&builder, data.Diff)
 
 	// Append the user's main task/prompt
 	builder.WriteString("## User's Task\n")
@@ -85,13 +99,17 @@ func BuildPromptMarkdown(request models.AgentRunRequest, codebaseContent map[str
 	builder.WriteString(data.SystemInstructions)
 	builder.WriteString("\n\n")
 
+	writeSchemaSection(&builder, data.OutputSchema)
+
+	return builder.String()
+}
+
+func writeSchemaSection(builder *strings.Builder, schema map[string]any) {
 	builder.WriteString("## Output Schema: \n")
 	builder.WriteString("```json\n")
-	schemaBytes, _ := json.MarshalIndent(data.OutputSchema, "", "  ")
+	schemaBytes, _ := json.MarshalIndent(schema, "", "  ")
 	builder.Write(schemaBytes)
 	builder.WriteString("\n```\n")
-
-	return builder.String()
 }
 
 // BuildPromptJSON constructs the final prompt as a JSON string.
@@ -123,6 +141,10 @@ func BuildPromptJSON(request models.AgentRunRequest, codebaseContent map[string]
 // }
 
 func BuildChatMessages(request models.AgentRunRequest, codebaseContent map[string]string) ([]ChatMessage, error) {
+	if len(request.Messages) > 0 {
+		return buildTemplateMessages(request, codebaseContent), nil
+	}
+
 	var messages []ChatMessage
 
 	// 1. Add the System Message (if it exists)
@@ -135,6 +157,53 @@ func BuildChatMessages(request models.AgentRunRequest, codebaseContent map[strin
 
 	// 2. Build the User Message content (Codebase + Task)
 	var userContentBuilder strings.Builder
+	writeContextSections(&userContentBuilder, codebaseContent, request.Diff)
+	userContentBuilder.WriteString("## User's Task\n")
+	userContentBuilder.WriteString(request.Prompt)
+
+	// 3. Add the fully constructed User Message
+	messages = append(messages, ChatMessage{
+		Role:    "user",
+		Content: userContentBuilder.String(),
+	})
+
+	return messages, nil
+}
+
+// contextPlaceholder marks where template messages take the codebase context.
+var contextPlaceholder = regexp.MustCompile(`\{\{\s*context\s*\}\}`)
+
+// buildTemplateMessages sends a prompt template's messages in order. The codebase context
+// replaces {{context}}; if no message uses it, it is sent as a user message after the
+// leading system and developer messages.
+func buildTemplateMessages(request models.AgentRunRequest, codebaseContent map[string]string) []ChatMessage {
+	var contextBuilder strings.Builder
+	writeContextSections(&contextBuilder, codebaseContent, request.Diff)
+	contextText := strings.TrimSpace(contextBuilder.String())
+
+	used := false
+	messages := make([]ChatMessage, 0, len(request.Messages)+1)
+	for _, m := range request.Messages {
+		content := m.Content
+		if contextPlaceholder.MatchString(content) {
+			used = true
+			content = contextPlaceholder.ReplaceAllLiteralString(content, contextText)
+		}
+		messages = append(messages, ChatMessage{Role: m.Role.String(), Content: content})
+	}
+	if used || contextText == "" {
+		return messages
+	}
+
+	i := 0
+	for i < len(messages) && (messages[i].Role == message.SYSTEM_MESSAGE.String() || messages[i].Role == message.DEVELOPER_MESSAGE.String()) {
+		i++
+	}
+	return slices.Insert(messages, i, ChatMessage{Role: message.USER_MESSAGE.String(), Content: contextText})
+}
+
+// writeContextSections appends the codebase files, in path order, and the diff.
+func writeContextSections(builder *strings.Builder, codebaseContent map[string]string, diff string) {
 	if len(codebaseContent) > 0 {
 		keys := make([]string, 0, len(codebaseContent))
 		for k := range codebaseContent {
@@ -142,20 +211,20 @@ func BuildChatMessages(request models.AgentRunRequest, codebaseContent map[strin
 		}
 		sort.Strings(keys)
 
-		userContentBuilder.WriteString("## Codebase Context\n")
+		builder.WriteString("## Codebase Context\n")
 		for _, path := range keys {
-			content := codebaseContent[path]
-			userContentBuilder.WriteString(fmt.Sprintf("File: %s\n```\n%s\n```\n\n", path, content))
+			builder.WriteString(fmt.Sprintf("File: %s\n```\n%s\n```\n\n", path, codebaseContent[path]))
 		}
 	}
-	userContentBuilder.WriteString("## User's Task\n")
-	userContentBuilder.WriteString(request.Prompt)
-
-	// 3. Add the fully constructed User Message
-	messages = append(messages, ChatMessage{
-		Role:    "user",
-		Content: userContentBuilder.String(),
-	})
+	writeDiffSection(builder, diff)
+}
 
-	return messages, nil
+// writeDiffSection appends the request's diff, if any, as a "Changes" section.
+func writeDiffSection(builder *strings.Builder, diff string) {
+	if strings.TrimSpace(diff) == "" {
+		return
+	}
+	builder.WriteString("## Changes\n```diff\n")
+	builder.WriteString(strings.TrimRight(diff, "\n"))
+	builder.WriteString("\n```\n\n")
 }
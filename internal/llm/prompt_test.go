@@ -0,0 +1,85 @@
+package llm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ClarionDev/clarion/internal/message"
+	"github.com/ClarionDev/clarion/internal/models"
+)
+
+func TestBuildTemplateMessages(t *testing.T) {
+	files := map[string]string{"b.go": "package b", "a.go": "package a"}
+	tests := []struct {
+		name      string
+		messages  []models.Message
+		files     map[string]string
+		wantRoles []string
+		// contextAt is the index of the message holding the context, or -1.
+		contextAt int
+	}{
+		{
+			name: "placeholder",
+			messages: []models.Message{
+				{Role: message.SYSTEM_MESSAGE, Content: "Be brief."},
+				{Role: message.USER_MESSAGE, Content: "Files:\n{{ context }}\nTask: fix it."},
+			},
+			files:     files,
+			wantRoles: []string{"system", "user"},
+			contextAt: 1,
+		},
+		{
+			name: "after leading system and developer messages",
+			messages: []models.Message{
+				{Role: message.SYSTEM_MESSAGE, Content: "Be brief."},
+				{Role: message.DEVELOPER_MESSAGE, Content: "Use Go."},
+				{Role: message.USER_MESSAGE, Content: "Fix it."},
+				{Role: message.SYSTEM_MESSAGE, Content: "Late system."},
+			},
+			files:     files,
+			wantRoles: []string{"system", "developer", "user", "user", "system"},
+			contextAt: 2,
+		},
+		{
+			name:      "only user messages",
+			messages:  []models.Message{{Role: message.USER_MESSAGE, Content: "Fix it."}},
+			files:     files,
+			wantRoles: []string{"user", "user"},
+			contextAt: 0,
+		},
+		{
+			name:      "no context",
+			messages:  []models.Message{{Role: message.SYSTEM_MESSAGE, Content: "Be brief."}, {Role: message.USER_MESSAGE, Content: "Fix it."}},
+			wantRoles: []string{"system", "user"},
+			contextAt: -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildTemplateMessages(models.AgentRunRequest{Messages: tt.messages}, tt.files)
+			roles := make([]string, len(got))
+			for i, m := range got {
+				roles[i] = m.Role
+			}
+			if !reflect.DeepEqual(roles, tt.wantRoles) {
+				t.Fatalf("roles = %v, want %v", roles, tt.wantRoles)
+			}
+			for i, m := range got {
+				hasContext := strings.Contains(m.Content, "File: a.go")
+				if hasContext != (i == tt.contextAt) {
+					t.Errorf("message %d = %q, context expected: %v", i, m.Content, i == tt.contextAt)
+				}
+				if strings.Contains(m.Content, "{{") {
+					t.Errorf("message %d keeps a placeholder: %q", i, m.Content)
+				}
+			}
+			if tt.contextAt >= 0 {
+				content := got[tt.contextAt].Content
+				if strings.Index(content, "File: a.go") > strings.Index(content, "File: b.go") {
+					t.Errorf("context files out of path order: %q", content)
+				}
+			}
+		})
+	}
+}
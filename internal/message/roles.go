@@ -12,3 +12,12 @@ func (r ROLE) String() string {
 	return string(r)
 }
 
+// Valid reports whether r is one of the roles above.
+func (r ROLE) Valid() bool {
+	switch r {
+	case SYSTEM_MESSAGE, USER_MESSAGE, DEVELOPER_MESSAGE:
+		return true
+	}
+	return false
+}
+
@@ -0,0 +1,92 @@
+package models
+
+import "time"
+
+// Eval assertion types.
+const (
+	AssertJSONPathEquals  = "json_path_equals"  // the value at Path equals Value
+	AssertJSONPathMatches = "json_path_matches" // the value at Path matches the Pattern regexp
+	AssertTouchesPath     = "touches_path"      // a file change's path matches the Path glob
+	AssertCommandPasses   = "command_passes"    // Command exits with 0 after the changes are applied
+	AssertLLMJudge        = "llm_judge"         // a model grades the output against Rubric
+)
+
+// AssertionTypes lists the assertion types an eval case can use.
+var AssertionTypes = []string{AssertJSONPathEquals, AssertJSONPathMatches, AssertTouchesPath, AssertCommandPasses, AssertLLMJudge}
+
+// EvalSuite is a set of golden test cases for an agent, run to tell whether a change to
+// the agent makes it better or worse.
+type EvalSuite struct {
+	ID          string     `json:"id" yaml:"id"`
+	AgentID     string     `json:"agent_id" yaml:"agent_id"`
+	Name        string     `json:"name" yaml:"name"`
+	Description string     `json:"description,omitempty" yaml:"description,omitempty"`
+	Cases       []EvalCase `json:"cases" yaml:"cases"`
+	CreatedAt   time.Time  `json:"created_at" yaml:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" yaml:"updated_at"`
+}
+
+// EvalCase is one input to an agent and what its output must satisfy.
+type EvalCase struct {
+	Name   string `json:"name" yaml:"name"`
+	Prompt string `json:"prompt" yaml:"prompt"`
+	// Files are the fixture project the case runs in, by path; all of them are in the
+	// run's context.
+	Files      map[string]string `json:"files,omitempty" yaml:"files,omitempty"`
+	Variables  map[string]string `json:"variables,omitempty" yaml:"variables,omitempty"`
+	Assertions []EvalAssertion   `json:"assertions" yaml:"assertions"`
+	// RecordedOutput is returned in place of a provider's output when the suite runs on
+	// recordings, so that assertions can be checked without calling a model.
+	RecordedOutput map[string]any `json:"recorded_output,omitempty" yaml:"recorded_output,omitempty"`
+}
+
+// EvalAssertion is a check on a case's output. Which fields apply depends on Type.
+type EvalAssertion struct {
+	Type string `json:"type" yaml:"type"`
+	// Path is a JSON path into the output such as "file_changes[0].action" for the
+	// json_path types, and a glob such as "src/**/*.go" for touches_path, where "**"
+	// matches any number of directories.
+	Path    string `json:"path,omitempty" yaml:"path,omitempty"`
+	Value   any    `json:"value,omitempty" yaml:"value,omitempty"`
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	Command string `json:"command,omitempty" yaml:"command,omitempty"`
+	Rubric  string `json:"rubric,omitempty" yaml:"rubric,omitempty"`
+}
+
+// EvalReport is the result of running a suite against one revision of its agent.
+type EvalReport struct {
+	ID            string    `json:"id"`
+	SuiteID       string    `json:"suite_id"`
+	AgentID       string    `json:"agent_id"`
+	AgentRevision int       `json:"agent_revision"`
+	LLMConfig     LLMConfig `json:"llm_config"`
+	// Recorded is set when the cases' recorded outputs were used instead of a provider.
+	Recorded bool             `json:"recorded"`
+	Cases    []EvalCaseResult `json:"cases"`
+	// Score is the share of assertions that passed, from 0 to 1; a judge counts with its
+	// own score. Skipped assertions do not count.
+	Score       float64   `json:"score"`
+	PassedCases int       `json:"passed_cases"`
+	TotalCases  int       `json:"total_cases"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// EvalCaseResult is the outcome of one case. A case passes when the agent ran and every
+// assertion that was not skipped passed.
+type EvalCaseResult struct {
+	Name       string                `json:"name"`
+	Passed     bool                  `json:"passed"`
+	Output     map[string]any        `json:"output,omitempty"`
+	Error      string                `json:"error,omitempty"`
+	LatencyMS  int64                 `json:"latency_ms"`
+	Assertions []EvalAssertionResult `json:"assertions"`
+}
+
+// EvalAssertionResult is the outcome of one assertion. Score is 1 or 0 except for judges.
+type EvalAssertionResult struct {
+	Type    string  `json:"type"`
+	Passed  bool    `json:"passed"`
+	Skipped bool    `json:"skipped,omitempty"`
+	Score   float64 `json:"score"`
+	Message string  `json:"message,omitempty"`
+}
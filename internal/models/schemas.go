@@ -1,6 +1,9 @@
 package models
 
 import (
+	"encoding/json"
+	"time"
+
 	"github.com/ClarionDev/clarion/internal/agent"
 	"github.com/ClarionDev/clarion/internal/message"
 )
@@ -10,6 +13,8 @@ const (
 	ProviderOpenAI    = "OpenAI"
 	ProviderAnthropic = "Anthropic"
 	ProviderOpenRouter = "OpenRouter"
+	// ProviderMock answers without calling a model; see llm.MockProvider.
+	ProviderMock = "Mock"
 )
 
 type LLMConfig struct {
@@ -26,6 +31,21 @@ type Agent struct {
 	OutputSchema    OutputSchema      `json:"output_schema" yaml:"output_schema"`
 	UserVariables   []UserVariableDef `json:"user_variables" yaml:"user_variables"`
 	LLMConfig       LLMConfig         `json:"llm_config" yaml:"llm_config"`
+	// ProjectPath is set on agents loaded from a project's .clarion/agents directory. They
+	// are listed only for that project and replaced whenever the directory is loaded.
+	ProjectPath string `json:"project_path,omitempty" yaml:"-"`
+	// Revision is the number of the agent's latest saved revision.
+	Revision int `json:"revision,omitempty" yaml:"-"`
+}
+
+// AgentRevision is an immutable snapshot of an agent, appended whenever a changed agent is
+// saved. Revisions of an agent are numbered from 1.
+type AgentRevision struct {
+	AgentID   string    `json:"agent_id"`
+	Revision  int       `json:"revision"`
+	Changelog string    `json:"changelog,omitempty"`
+	Agent     Agent     `json:"agent"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type FilterSet struct {
@@ -41,10 +61,17 @@ type UserVariableDef struct {
 	Required    bool   `json:"required" yaml:"required"`
 }
 
+// PromptTemplate is a reusable message sequence for agent runs. Message contents may use
+// the run's variables and {{system_instruction}}, {{prompt}} and {{context}}, which hold the
+// agent's system prompt, the task and the codebase context.
 type PromptTemplate struct {
+	ID          string    `json:"id" yaml:"id"`
 	Name        string    `json:"name" yaml:"name"`
 	Description string    `json:"description" yaml:"description"`
+	Tags        []string  `json:"tags" yaml:"tags"`
 	Messages    []Message `json:"messages" yaml:"messages"`
+	CreatedAt   time.Time `json:"created_at" yaml:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" yaml:"updated_at"`
 }
 
 type Message struct {
@@ -61,13 +88,21 @@ type AgentRunRequest struct {
 	Prompt            string
 	OutputSchema      map[string]any
 	LLMConfig         LLMConfig
+	// Diff is an optional unified diff added to the prompt as its own context section.
+	Diff string
+	// Messages, when set, are sent in place of the system and user message pair. They are
+	// rendered except for {{context}}, which is replaced with the codebase context.
+	Messages []Message
 }
 
 type LLMProviderConfig struct {
 	ID       string `json:"id" yaml:"id"`
 	Name     string `json:"name" yaml:"name"`
 	Provider string `json:"provider" yaml:"provider"`
-	APIKey   string `json:"apiKey" yaml:"apiKey"`
+	// APIKey is sealed by the store; use LLMConfigStore.DecryptAPIKey to read it.
+	APIKey string `json:"apiKey" yaml:"apiKey"`
+	// APIKeyHint is the masked form of the key, e.g. "sk-…abcd", kept for display.
+	APIKeyHint string `json:"apiKeyHint,omitempty" yaml:"apiKeyHint,omitempty"`
 }
 
 type Project struct {
@@ -76,6 +111,27 @@ type Project struct {
 	Path         string `json:"path" yaml:"path"`
 	LastOpenedAt string `json:"lastOpenedAt" yaml:"lastOpenedAt"`
 	ActiveAgentID string `json:"activeAgentId" yaml:"activeAgentId"`
+	// Settings are the settings saved in the app; .clarion/project.yml can override them.
+	Settings ProjectSettings `json:"settings" yaml:"settings"`
+}
+
+// ProjectSettings apply to every run in a project.
+type ProjectSettings struct {
+	// IncludeGlobs are used for agents without include globs of their own; ExcludeGlobs are
+	// added to every agent's.
+	IncludeGlobs []string `json:"include_globs,omitempty" yaml:"include_globs,omitempty"`
+	ExcludeGlobs []string `json:"exclude_globs,omitempty" yaml:"exclude_globs,omitempty"`
+	// IgnoreDirs are skipped when listing the project's files, next to the built-in ones
+	// such as node_modules. A name matches directories anywhere; a path with a slash
+	// matches one directory relative to the project root.
+	IgnoreDirs []string `json:"ignore_dirs,omitempty" yaml:"ignore_dirs,omitempty"`
+	// LLMConfigID is the provider config for agents that do not name one.
+	LLMConfigID string `json:"llm_config_id,omitempty" yaml:"llm_config_id,omitempty"`
+	// VerifyCommands run after changes are applied in a run's worktree, unless the request
+	// lists its own.
+	VerifyCommands []string `json:"verify_commands,omitempty" yaml:"verify_commands,omitempty"`
+	// PinnedFiles are added to the context of every run.
+	PinnedFiles []string `json:"pinned_files,omitempty" yaml:"pinned_files,omitempty"`
 }
 
 type Run struct {
@@ -83,3 +139,60 @@ type Run struct {
 	ProjectID string `json:"project_id"`
 	RunData   string `json:"run_data"`
 }
+
+// RunSummary is the part of a saved run shown in run lists. Prompt and Summary are cut
+// to their first few hundred characters.
+type RunSummary struct {
+	ID        string    `json:"id"`
+	ProjectID string    `json:"project_id"`
+	AgentID   string    `json:"agent_id,omitempty"`
+	AgentName string    `json:"agent_name"`
+	Status    string    `json:"status"`
+	Prompt    string    `json:"prompt"`
+	Summary   string    `json:"summary"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TokenUsage is the number of tokens a provider reports for one generation.
+type TokenUsage struct {
+	Prompt     int `json:"prompt"`
+	Completion int `json:"completion"`
+	Total      int `json:"total"`
+}
+
+// Comparison is one prompt and context run against several LLM configs at once, to choose
+// between the models.
+type Comparison struct {
+	ID        string `json:"id"`
+	ProjectID string `json:"project_id,omitempty"`
+	// Request is the run every candidate was given, apart from its LLM config.
+	Request    json.RawMessage       `json:"request"`
+	Candidates []ComparisonCandidate `json:"candidates"`
+	CreatedAt  time.Time             `json:"created_at"`
+}
+
+// ComparisonCandidate is the result of one LLM config in a comparison. Cost is in US
+// dollars and only set when the provider reports token usage and the model's price is
+// known.
+type ComparisonCandidate struct {
+	LLMConfig  LLMConfig      `json:"llm_config"`
+	Output     map[string]any `json:"output,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	LatencyMS  int64          `json:"latency_ms"`
+	TokenUsage *TokenUsage    `json:"token_usage,omitempty"`
+	Cost       *float64       `json:"cost,omitempty"`
+}
+
+// CachedResponse is a provider output kept in the response cache under a hash of the
+// request that produced it. Lists leave Output out.
+type CachedResponse struct {
+	Key      string         `json:"key"`
+	Provider string         `json:"provider"`
+	Model    string         `json:"model"`
+	Output   map[string]any `json:"output,omitempty"`
+	// Size is the size of the output in bytes.
+	Size      int       `json:"size"`
+	Hits      int       `json:"hits"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,330 @@
+package models
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ClarionDev/clarion/internal/templating"
+)
+
+// Providers lists the provider names an LLMConfig can use.
+var Providers = []string{ProviderOpenAI, ProviderAnthropic, ProviderGoogle, ProviderOpenRouter, ProviderMock}
+
+var (
+	idPattern       = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:-]*$`)
+	variablePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+	schemaTypes     = []string{"string", "number", "integer", "boolean", "object", "array", "null"}
+)
+
+// FieldError is a problem with one field of a model. Field is the field's JSON path, such
+// as "llm_config.provider" or "output_schema.schema.properties.summary.type".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every problem found in a model. Subject names the model, such as
+// "agent".
+type ValidationError struct {
+	Subject string
+	Fields  []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	problems := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		problems[i] = f.Field + ": " + f.Message
+	}
+	return "invalid " + e.Subject + ": " + strings.Join(problems, "; ")
+}
+
+type fieldErrors []FieldError
+
+func (f *fieldErrors) add(field, format string, args ...any) {
+	*f = append(*f, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks that an agent can be run: it has an ID and a name, its system prompt is
+// a valid template, its filters are valid globs and regular expressions, its output schema
+// is a JSON schema with an object at the root, its user variables have unique valid names,
+// and its LLM config names a known provider and model. If lookupConfig is not nil, a
+// config ID must name a saved provider config for the same provider; lookupConfig returns
+// that config's provider and whether it exists. The error is a *ValidationError.
+func (a *Agent) Validate(lookupConfig func(id string) (provider string, ok bool)) error {
+	var errs fieldErrors
+
+	switch {
+	case a.Profile.ID == "":
+		errs.add("Profile.ID", "is required")
+	case !idPattern.MatchString(a.Profile.ID):
+		errs.add("Profile.ID", "may only contain letters, digits, '_', '.', ':' and '-'")
+	}
+	if strings.TrimSpace(a.Profile.Name) == "" {
+		errs.add("Profile.Name", "is required")
+	}
+	if _, err := templating.Parse(a.SystemPrompt); err != nil {
+		errs.add("system_prompt", "%v", err)
+	}
+
+	for i, glob := range a.CodebaseFilters.IncludeGlobs {
+		if _, err := filepath.Match(glob, ""); err != nil {
+			errs.add(fmt.Sprintf("codebase_filters.include_globs[%d]", i), "%q is not a valid glob", glob)
+		}
+	}
+	for i, glob := range a.CodebaseFilters.ExcludeGlobs {
+		if _, err := filepath.Match(glob, ""); err != nil {
+			errs.add(fmt.Sprintf("codebase_filters.exclude_globs[%d]", i), "%q is not a valid glob", glob)
+		}
+	}
+	if a.CodebaseFilters.ContentRegexInclude != "" {
+		if _, err := regexp.Compile(a.CodebaseFilters.ContentRegexInclude); err != nil {
+			errs.add("codebase_filters.content_regex_include", "%v", err)
+		}
+	}
+	if a.CodebaseFilters.MaxTotalFiles < 0 {
+		errs.add("codebase_filters.max_total_files", "must not be negative")
+	}
+
+	if len(a.OutputSchema.Schema) == 0 {
+		errs.add("output_schema.schema", "is required")
+	} else {
+		if t, _ := a.OutputSchema.Schema["type"].(string); t != "object" {
+			errs.add("output_schema.schema.type", "must be \"object\"")
+		}
+		validateSchema(&errs, "output_schema.schema", a.OutputSchema.Schema)
+	}
+
+	seen := make(map[string]bool)
+	for i, v := range a.UserVariables {
+		field := fmt.Sprintf("user_variables[%d].name", i)
+		switch {
+		case !variablePattern.MatchString(v.Name):
+			errs.add(field, "%q is not a valid variable name", v.Name)
+		case seen[v.Name]:
+			errs.add(field, "%q is declared more than once", v.Name)
+		}
+		seen[v.Name] = true
+	}
+
+	if !slices.Contains(Providers, a.LLMConfig.Provider) {
+		errs.add("llm_config.provider", "unknown provider %q", a.LLMConfig.Provider)
+	}
+	if strings.TrimSpace(a.LLMConfig.Model) == "" {
+		errs.add("llm_config.model", "is required")
+	}
+	if a.LLMConfig.ConfigID != "" && lookupConfig != nil {
+		provider, ok := lookupConfig(a.LLMConfig.ConfigID)
+		switch {
+		case !ok:
+			errs.add("llm_config.configId", "no LLM config with id %q", a.LLMConfig.ConfigID)
+		case provider != a.LLMConfig.Provider:
+			errs.add("llm_config.configId", "config %q is for %s, not %s", a.LLMConfig.ConfigID, provider, a.LLMConfig.Provider)
+		}
+	}
+
+	if len(errs) > 0 {
+		return &ValidationError{Subject: "agent", Fields: errs}
+	}
+	return nil
+}
+
+// Validate checks that the settings' globs are valid, that their paths stay inside the
+// project, and, if lookupConfig is not nil, that the LLM config exists. The error is a
+// *ValidationError.
+func (s *ProjectSettings) Validate(lookupConfig func(id string) (provider string, ok bool)) error {
+	var errs fieldErrors
+	globs := map[string][]string{"include_globs": s.IncludeGlobs, "exclude_globs": s.ExcludeGlobs}
+	for _, name := range []string{"include_globs", "exclude_globs"} {
+		for i, glob := range globs[name] {
+			if _, err := filepath.Match(glob, ""); err != nil {
+				errs.add(fmt.Sprintf("%s[%d]", name, i), "%q is not a valid glob", glob)
+			}
+		}
+	}
+	paths := map[string][]string{"ignore_dirs": s.IgnoreDirs, "pinned_files": s.PinnedFiles}
+	for _, name := range []string{"ignore_dirs", "pinned_files"} {
+		for i, path := range paths[name] {
+			if !filepath.IsLocal(filepath.FromSlash(path)) {
+				errs.add(fmt.Sprintf("%s[%d]", name, i), "%q is not a path inside the project", path)
+			}
+		}
+	}
+	for i, command := range s.VerifyCommands {
+		if strings.TrimSpace(command) == "" {
+			errs.add(fmt.Sprintf("verify_commands[%d]", i), "is empty")
+		}
+	}
+	if s.LLMConfigID != "" && lookupConfig != nil {
+		if _, ok := lookupConfig(s.LLMConfigID); !ok {
+			errs.add("llm_config_id", "no LLM config with id %q", s.LLMConfigID)
+		}
+	}
+
+	if len(errs) > 0 {
+		return &ValidationError{Subject: "project settings", Fields: errs}
+	}
+	return nil
+}
+
+// Validate checks that a suite names its agent and has cases with unique names and
+// prompts, fixture paths inside the project, and assertions of a known type with the
+// fields that type needs. The error is a *ValidationError.
+func (s *EvalSuite) Validate() error {
+	var errs fieldErrors
+	if s.AgentID == "" {
+		errs.add("agent_id", "is required")
+	}
+	if strings.TrimSpace(s.Name) == "" {
+		errs.add("name", "is required")
+	}
+	if len(s.Cases) == 0 {
+		errs.add("cases", "must have at least one case")
+	}
+
+	seen := make(map[string]bool)
+	for i, c := range s.Cases {
+		field := fmt.Sprintf("cases[%d]", i)
+		switch {
+		case strings.TrimSpace(c.Name) == "":
+			errs.add(field+".name", "is required")
+		case seen[c.Name]:
+			errs.add(field+".name", "%q is used by another case", c.Name)
+		}
+		seen[c.Name] = true
+		if strings.TrimSpace(c.Prompt) == "" {
+			errs.add(field+".prompt", "is required")
+		}
+		for path := range c.Files {
+			if !filepath.IsLocal(filepath.FromSlash(path)) {
+				errs.add(field+".files", "%q is not a path inside the project", path)
+			}
+		}
+		if len(c.Assertions) == 0 {
+			errs.add(field+".assertions", "must have at least one assertion")
+		}
+		for j, a := range c.Assertions {
+			validateAssertion(&errs, fmt.Sprintf("%s.assertions[%d]", field, j), a)
+		}
+	}
+
+	if len(errs) > 0 {
+		return &ValidationError{Subject: "eval suite", Fields: errs}
+	}
+	return nil
+}
+
+func validateAssertion(errs *fieldErrors, field string, a EvalAssertion) {
+	switch a.Type {
+	case AssertJSONPathEquals:
+		if a.Path == "" {
+			errs.add(field+".path", "is required")
+		}
+	case AssertJSONPathMatches:
+		if a.Path == "" {
+			errs.add(field+".path", "is required")
+		}
+		if _, err := regexp.Compile(a.Pattern); err != nil {
+			errs.add(field+".pattern", "%v", err)
+		}
+	case AssertTouchesPath:
+		if _, err := filepath.Match(a.Path, ""); a.Path == "" || err != nil {
+			errs.add(field+".path", "%q is not a valid glob", a.Path)
+		}
+	case AssertCommandPasses:
+		if strings.TrimSpace(a.Command) == "" {
+			errs.add(field+".command", "is required")
+		}
+	case AssertLLMJudge:
+		if strings.TrimSpace(a.Rubric) == "" {
+			errs.add(field+".rubric", "is required")
+		}
+	default:
+		errs.add(field+".type", "unknown assertion type %q, expected one of %s", a.Type, strings.Join(AssertionTypes, ", "))
+	}
+}
+
+// validateSchema checks the parts of a JSON schema that structured output relies on: types,
+// object properties, required property names, array items and enums.
+func validateSchema(errs *fieldErrors, path string, schema map[string]any) {
+	if t, ok := schema["type"]; ok {
+		var types []any
+		switch t := t.(type) {
+		case string:
+			types = []any{t}
+		case []any, []string:
+			types, _ = asList(t)
+		default:
+			errs.add(path+".type", "must be a string or a list of strings")
+		}
+		for _, t := range types {
+			if name, ok := t.(string); !ok || !slices.Contains(schemaTypes, name) {
+				errs.add(path+".type", "unknown type %v", t)
+			}
+		}
+	}
+
+	var properties map[string]any
+	if p, ok := schema["properties"]; ok {
+		if properties, ok = p.(map[string]any); !ok {
+			errs.add(path+".properties", "must be an object")
+		}
+	}
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		field := path + ".properties." + name
+		if sub, ok := properties[name].(map[string]any); ok {
+			validateSchema(errs, field, sub)
+		} else {
+			errs.add(field, "must be a schema object")
+		}
+	}
+
+	if r, ok := schema["required"]; ok {
+		required, ok := asList(r)
+		if !ok {
+			errs.add(path+".required", "must be a list of property names")
+		}
+		for _, name := range required {
+			if s, ok := name.(string); !ok || properties[s] == nil {
+				errs.add(path+".required", "%v is not a property", name)
+			}
+		}
+	}
+
+	if items, ok := schema["items"]; ok {
+		if sub, ok := items.(map[string]any); ok {
+			validateSchema(errs, path+".items", sub)
+		} else {
+			errs.add(path+".items", "must be a schema object")
+		}
+	}
+
+	if enum, ok := schema["enum"]; ok {
+		if values, ok := asList(enum); !ok || len(values) == 0 {
+			errs.add(path+".enum", "must be a non-empty list")
+		}
+	}
+}
+
+// asList returns v as a list. Schemas decoded from JSON or YAML hold []any, while schemas
+// built in Go often hold []string.
+func asList(v any) ([]any, bool) {
+	switch v := v.(type) {
+	case []any:
+		return v, true
+	case []string:
+		list := make([]any, len(v))
+		for i, s := range v {
+			list[i] = s
+		}
+		return list, true
+	}
+	return nil, false
+}
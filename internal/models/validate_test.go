@@ -0,0 +1,122 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ClarionDev/clarion/internal/agent"
+)
+
+func validAgent() Agent {
+	return Agent{
+		Profile:      agent.AgentProfile{ID: "reviewer", Name: "Reviewer"},
+		SystemPrompt: "Review {{project.name}}.",
+		OutputSchema: OutputSchema{Schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"summary": map[string]any{"type": "string"},
+				"files":   map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			},
+			"required": []string{"summary"},
+		}},
+		CodebaseFilters: FilterSet{ExcludeGlobs: []string{"node_modules/**"}},
+		LLMConfig:       LLMConfig{Provider: ProviderOpenAI, Model: "gpt-4o", ConfigID: "cfg"},
+	}
+}
+
+func fields(t *testing.T, err error) map[string]bool {
+	t.Helper()
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	got := make(map[string]bool)
+	for _, f := range invalid.Fields {
+		got[f.Field] = true
+	}
+	return got
+}
+
+func TestValidateAcceptsValidAgent(t *testing.T) {
+	a := validAgent()
+	lookup := func(id string) (string, bool) { return ProviderOpenAI, id == "cfg" }
+	if err := a.Validate(lookup); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+}
+
+func TestValidateReportsFieldErrors(t *testing.T) {
+	a := validAgent()
+	a.Profile.ID = ""
+	a.SystemPrompt = "{{#if x}}never closed"
+	a.CodebaseFilters.IncludeGlobs = []string{"src/[a"}
+	a.CodebaseFilters.ContentRegexInclude = "("
+	a.OutputSchema.Schema = map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"n": map[string]any{"type": "float"}},
+		"required":   []any{"missing"},
+	}
+	a.UserVariables = []UserVariableDef{{Name: "lang"}, {Name: "lang"}}
+	a.LLMConfig.Provider = "Acme"
+
+	got := fields(t, a.Validate(nil))
+	for _, field := range []string{
+		"Profile.ID",
+		"system_prompt",
+		"codebase_filters.include_globs[0]",
+		"codebase_filters.content_regex_include",
+		"output_schema.schema.properties.n.type",
+		"output_schema.schema.required",
+		"user_variables[1].name",
+		"llm_config.provider",
+	} {
+		if !got[field] {
+			t.Errorf("missing error for %s (got %v)", field, got)
+		}
+	}
+}
+
+func TestValidateChecksConfigID(t *testing.T) {
+	a := validAgent()
+	if got := fields(t, a.Validate(func(string) (string, bool) { return "", false })); !got["llm_config.configId"] {
+		t.Errorf("unknown config not reported: %v", got)
+	}
+	if got := fields(t, a.Validate(func(string) (string, bool) { return ProviderGoogle, true })); !got["llm_config.configId"] {
+		t.Errorf("provider mismatch not reported: %v", got)
+	}
+}
+
+func TestValidateEvalSuite(t *testing.T) {
+	s := &EvalSuite{AgentID: "a", Name: "Smoke", Cases: []EvalCase{{
+		Name:       "adds a file",
+		Prompt:     "Add main.go",
+		Files:      map[string]string{"go.mod": "module x\n"},
+		Assertions: []EvalAssertion{{Type: AssertTouchesPath, Path: "*.go"}},
+	}}}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("Validate() = %v, want nil", err)
+	}
+
+	s.Cases = append(s.Cases, EvalCase{
+		Name:  "adds a file",
+		Files: map[string]string{"../escape": ""},
+		Assertions: []EvalAssertion{
+			{Type: AssertJSONPathMatches, Path: "summary", Pattern: "("},
+			{Type: AssertCommandPasses},
+			{Type: "contains"},
+		},
+	})
+	got := fields(t, s.Validate())
+	for _, field := range []string{
+		"cases[1].name",
+		"cases[1].prompt",
+		"cases[1].files",
+		"cases[1].assertions[0].pattern",
+		"cases[1].assertions[1].command",
+		"cases[1].assertions[2].type",
+	} {
+		if !got[field] {
+			t.Errorf("missing error for %s (got %v)", field, got)
+		}
+	}
+}
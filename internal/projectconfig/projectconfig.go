@@ -0,0 +1,156 @@
+// Package projectconfig resolves a project's settings from the ones saved in the app and
+// the optional .clarion/project.yml file in the project, and applies them to runs.
+package projectconfig
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/storage"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the project settings file, relative to the project root.
+const FileName = ".clarion/project.yml"
+
+// ErrInvalid is wrapped by errors for settings files that cannot be read.
+var ErrInvalid = errors.New("invalid project settings file")
+
+// LoadFile reads a project's settings file. It returns nil and no error when the project has
+// none. Unknown fields are errors.
+func LoadFile(projectRoot string) (*models.ProjectSettings, error) {
+	data, err := os.ReadFile(filepath.Join(projectRoot, FileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var settings models.ProjectSettings
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&settings); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if err := settings.Validate(nil); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return &settings, nil
+}
+
+// WriteFile writes settings to a project's settings file, creating .clarion if needed.
+func WriteFile(projectRoot string, settings *models.ProjectSettings) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(settings); err != nil {
+		return fmt.Errorf("failed to marshal project settings: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	path := filepath.Join(projectRoot, FileName)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// Merge returns the settings in effect when a project has both saved settings and a
+// settings file: each field set in the file replaces the saved one.
+func Merge(saved models.ProjectSettings, file *models.ProjectSettings) models.ProjectSettings {
+	if file == nil {
+		return saved
+	}
+	merged := saved
+	if file.IncludeGlobs != nil {
+		merged.IncludeGlobs = file.IncludeGlobs
+	}
+	if file.ExcludeGlobs != nil {
+		merged.ExcludeGlobs = file.ExcludeGlobs
+	}
+	if file.IgnoreDirs != nil {
+		merged.IgnoreDirs = file.IgnoreDirs
+	}
+	if file.LLMConfigID != "" {
+		merged.LLMConfigID = file.LLMConfigID
+	}
+	if file.VerifyCommands != nil {
+		merged.VerifyCommands = file.VerifyCommands
+	}
+	if file.PinnedFiles != nil {
+		merged.PinnedFiles = file.PinnedFiles
+	}
+	return merged
+}
+
+// Resolve returns the settings in effect for the project at projectRoot. A project that was
+// never opened in the app has only its settings file. An unreadable settings file is
+// reported in the error together with the saved settings, which still apply.
+func Resolve(ctx context.Context, store storage.ProjectStore, projectRoot string) (models.ProjectSettings, error) {
+	var saved models.ProjectSettings
+	project, err := store.GetProjectByPath(ctx, projectRoot)
+	switch {
+	case err == nil:
+		saved = project.Settings
+	case !errors.Is(err, storage.ErrNotFound):
+		return saved, err
+	}
+	file, err := LoadFile(projectRoot)
+	return Merge(saved, file), err
+}
+
+// Filters merges the project's default globs into an agent's filters: the project's
+// include globs apply when the agent has none, and its exclude globs are always added.
+func Filters(settings models.ProjectSettings, filters models.FilterSet) models.FilterSet {
+	if len(filters.IncludeGlobs) == 0 {
+		filters.IncludeGlobs = settings.IncludeGlobs
+	}
+	exclude := slices.Clone(filters.ExcludeGlobs)
+	for _, glob := range settings.ExcludeGlobs {
+		if !slices.Contains(exclude, glob) {
+			exclude = append(exclude, glob)
+		}
+	}
+	filters.ExcludeGlobs = exclude
+	return filters
+}
+
+// LLMConfigID returns the project's default LLM config for a run with the given provider,
+// or "" when the project has none, or the config is missing or belongs to another
+// provider.
+func LLMConfigID(ctx context.Context, store storage.LLMConfigStore, settings models.ProjectSettings, provider string) string {
+	if settings.LLMConfigID == "" || store == nil {
+		return ""
+	}
+	config, err := store.GetLLMConfig(ctx, settings.LLMConfigID)
+	if err != nil || config.Provider != provider {
+		return ""
+	}
+	return config.ID
+}
+
+// ContextPaths returns the project's pinned files followed by paths, without duplicates.
+func ContextPaths(settings models.ProjectSettings, paths []string) []string {
+	if len(settings.PinnedFiles) == 0 {
+		return paths
+	}
+	merged := make([]string, 0, len(settings.PinnedFiles)+len(paths))
+	for _, list := range [][]string{settings.PinnedFiles, paths} {
+		for _, p := range list {
+			p = filepath.ToSlash(filepath.Clean(filepath.FromSlash(p)))
+			if !slices.Contains(merged, p) {
+				merged = append(merged, p)
+			}
+		}
+	}
+	return merged
+}
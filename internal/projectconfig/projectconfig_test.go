@@ -0,0 +1,79 @@
+package projectconfig
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ClarionDev/clarion/internal/models"
+)
+
+func TestFileRoundTripAndMerge(t *testing.T) {
+	root := t.TempDir()
+	if file, err := LoadFile(root); file != nil || err != nil {
+		t.Fatalf("LoadFile without a file = %v, %v", file, err)
+	}
+
+	file := &models.ProjectSettings{ExcludeGlobs: []string{"*.lock"}, VerifyCommands: []string{"go test ./..."}}
+	if err := WriteFile(root, file); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	loaded, err := LoadFile(root)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, file) {
+		t.Errorf("LoadFile = %+v, want %+v", loaded, file)
+	}
+
+	saved := models.ProjectSettings{ExcludeGlobs: []string{"dist/*"}, LLMConfigID: "cfg", PinnedFiles: []string{"README.md"}}
+	got := Merge(saved, loaded)
+	want := models.ProjectSettings{ExcludeGlobs: []string{"*.lock"}, LLMConfigID: "cfg", VerifyCommands: []string{"go test ./..."}, PinnedFiles: []string{"README.md"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadFileRejectsInvalidSettings(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown field": "include_glob: ['*.go']\n",
+		"bad glob":      "exclude_globs: ['[a']\n",
+		"outside path":  "pinned_files: ['../secret']\n",
+	} {
+		root := t.TempDir()
+		path := filepath.Join(root, FileName)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadFile(root); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: LoadFile error = %v, want ErrInvalid", name, err)
+		}
+	}
+}
+
+func TestFiltersAndContextPaths(t *testing.T) {
+	settings := models.ProjectSettings{
+		IncludeGlobs: []string{"src/*"},
+		ExcludeGlobs: []string{"*.lock", "dist/*"},
+		PinnedFiles:  []string{"README.md", "./docs/arch.md"},
+	}
+
+	got := Filters(settings, models.FilterSet{ExcludeGlobs: []string{"dist/*"}})
+	if !reflect.DeepEqual(got.IncludeGlobs, []string{"src/*"}) || !reflect.DeepEqual(got.ExcludeGlobs, []string{"dist/*", "*.lock"}) {
+		t.Errorf("Filters without agent includes = %+v", got)
+	}
+	got = Filters(settings, models.FilterSet{IncludeGlobs: []string{"*.go"}})
+	if !reflect.DeepEqual(got.IncludeGlobs, []string{"*.go"}) {
+		t.Errorf("Filters replaced the agent's include globs: %+v", got)
+	}
+
+	paths := ContextPaths(settings, []string{"main.go", "README.md"})
+	if want := []string{"README.md", "docs/arch.md", "main.go"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("ContextPaths = %v, want %v", paths, want)
+	}
+}
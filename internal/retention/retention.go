@@ -0,0 +1,87 @@
+// Package retention keeps the run history within the configured limits, deleting old runs
+// and compacting the ones that are kept in the background.
+package retention
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ClarionDev/clarion/internal/storage"
+)
+
+const interval = time.Hour
+
+// Config is what the janitor enforces.
+type Config struct {
+	Policy storage.RetentionPolicy
+	// Compact moves large file contents of the runs that are kept into shared blobs.
+	Compact bool
+	// Pruned, if set, is called with the IDs of the deleted runs, e.g. to remove their
+	// worktrees.
+	Pruned func(ctx context.Context, runIDs []string)
+}
+
+// Enabled reports whether the config asks for any work.
+func (c Config) Enabled() bool {
+	return c.Policy.MaxAge > 0 || c.Policy.MaxRunsPerProject > 0 || c.Compact
+}
+
+// Janitor applies a Config to a run store once when it starts and then every hour.
+type Janitor struct {
+	store  storage.RunStore
+	config Config
+	done   chan struct{}
+}
+
+// Start returns a running janitor. It does nothing when the config asks for no work.
+func Start(store storage.RunStore, config Config) *Janitor {
+	j := &Janitor{store: store, config: config, done: make(chan struct{})}
+	if config.Enabled() {
+		go j.run()
+	}
+	return j
+}
+
+// Close stops the janitor.
+func (j *Janitor) Close() {
+	close(j.done)
+}
+
+func (j *Janitor) run() {
+	j.RunOnce(context.Background())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-j.done:
+			return
+		case <-ticker.C:
+			j.RunOnce(context.Background())
+		}
+	}
+}
+
+// RunOnce deletes the runs outside the policy and, when enabled, compacts the rest. Errors
+// are logged, since the next pass retries.
+func (j *Janitor) RunOnce(ctx context.Context) {
+	deleted, err := j.store.PruneRuns(ctx, j.config.Policy)
+	if len(deleted) > 0 && j.config.Pruned != nil {
+		j.config.Pruned(ctx, deleted)
+	}
+	if err != nil {
+		log.Printf("Failed to delete runs outside the retention policy: %v", err)
+	} else if len(deleted) > 0 {
+		log.Printf("Deleted %d runs outside the retention policy", len(deleted))
+	}
+
+	if !j.config.Compact {
+		return
+	}
+	result, err := j.store.CompactRuns(ctx, storage.DefaultCompactMinSize)
+	if err != nil {
+		log.Printf("Failed to compact runs: %v", err)
+	} else if result.Runs > 0 {
+		log.Printf("Compacted %d runs, saving %d bytes", result.Runs, result.BytesSaved)
+	}
+}
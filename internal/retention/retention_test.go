@@ -0,0 +1,183 @@
+package retention_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ClarionDev/clarion/internal/database"
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/retention"
+	"github.com/ClarionDev/clarion/internal/storage"
+)
+
+func newRunStore(t *testing.T) (*storage.SQLiteRunStore, *sql.DB) {
+	t.Helper()
+	ctx := context.Background()
+	db, err := database.New(ctx, "sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("database.New: %v", err)
+	}
+	t.Cleanup(db.Close)
+	if err := db.RunMigrations(ctx); err != nil {
+		t.Fatalf("RunMigrations: %v", err)
+	}
+	sqlDB := db.Handle().(*sql.DB)
+	projects := storage.NewSQLiteProjectStore(sqlDB)
+	for _, id := range []string{"p1", "p2"} {
+		if err := projects.SaveProject(ctx, &models.Project{ID: id, Path: "/" + id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return storage.NewSQLiteRunStore(sqlDB), sqlDB
+}
+
+// saveRun saves a run with a large file change, created the given number of days ago.
+func saveRun(t *testing.T, store *storage.SQLiteRunStore, db *sql.DB, id, projectID string, daysAgo int) {
+	t.Helper()
+	data, _ := json.Marshal(map[string]any{
+		"id": id, "status": "success", "prompt": "Prompt " + id,
+		"output": map[string]any{"summary": "Done", "fileChanges": []any{
+			map[string]any{"path": "main.go", "content": strings.Repeat("package main\n", 500)},
+		}},
+	})
+	if err := store.SaveRun(context.Background(), &models.Run{ID: id, ProjectID: projectID, RunData: string(data)}); err != nil {
+		t.Fatal(err)
+	}
+	created := time.Now().AddDate(0, 0, -daysAgo).UTC().Format("2006-01-02 15:04:05")
+	if _, err := db.Exec(`UPDATE runs SET created_at = ? WHERE id = ?`, created, id); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func runIDs(t *testing.T, store *storage.SQLiteRunStore, projectID string) []string {
+	t.Helper()
+	page, err := store.ListRuns(context.Background(), storage.RunFilter{ProjectID: projectID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]string, len(page.Runs))
+	for i, r := range page.Runs {
+		ids[i] = r.ID
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestRunOnceMaxAge(t *testing.T) {
+	store, db := newRunStore(t)
+	saveRun(t, store, db, "old", "p1", 10)
+	saveRun(t, store, db, "edge", "p1", 6)
+	saveRun(t, store, db, "new", "p2", 1)
+
+	// Start runs a pass of its own, so the deleted runs may be reported by either pass.
+	var mu sync.Mutex
+	var pruned []string
+	j := retention.Start(store, retention.Config{
+		Policy: storage.RetentionPolicy{MaxAge: 7 * 24 * time.Hour},
+		Pruned: func(ctx context.Context, runIDs []string) {
+			mu.Lock()
+			defer mu.Unlock()
+			pruned = append(pruned, runIDs...)
+		},
+	})
+	defer j.Close()
+	j.RunOnce(context.Background())
+
+	if got := runIDs(t, store, "p1"); !slices.Equal(got, []string{"edge"}) {
+		t.Errorf("p1 runs = %v, want [edge]", got)
+	}
+	if got := runIDs(t, store, "p2"); !slices.Equal(got, []string{"new"}) {
+		t.Errorf("p2 runs = %v, want [new]", got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(pruned, []string{"old"}) {
+		t.Errorf("pruned runs = %v, want [old]", pruned)
+	}
+}
+
+func TestRunOnceMaxCount(t *testing.T) {
+	store, db := newRunStore(t)
+	for i, id := range []string{"a1", "a2", "a3", "a4"} {
+		saveRun(t, store, db, id, "p1", 4-i)
+	}
+	saveRun(t, store, db, "b1", "p2", 30)
+
+	retention.Start(store, retention.Config{}).RunOnce(context.Background())
+	if got := runIDs(t, store, "p1"); len(got) != 4 {
+		t.Fatalf("runs after a pass without limits = %v, want all 4", got)
+	}
+
+	j := retention.Start(store, retention.Config{Policy: storage.RetentionPolicy{MaxRunsPerProject: 2}})
+	defer j.Close()
+	j.RunOnce(context.Background())
+	if got := runIDs(t, store, "p1"); !slices.Equal(got, []string{"a3", "a4"}) {
+		t.Errorf("p1 runs = %v, want the newest two [a3 a4]", got)
+	}
+	// The limit is per project.
+	if got := runIDs(t, store, "p2"); !slices.Equal(got, []string{"b1"}) {
+		t.Errorf("p2 runs = %v, want [b1]", got)
+	}
+}
+
+func TestRunOnceCompacts(t *testing.T) {
+	ctx := context.Background()
+	store, db := newRunStore(t)
+	saveRun(t, store, db, "r1", "p1", 1)
+	saveRun(t, store, db, "r2", "p1", 2)
+	before, err := store.GetRun(ctx, "r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var size int
+	if err := db.QueryRow(`SELECT SUM(length(run_data)) FROM runs`).Scan(&size); err != nil {
+		t.Fatal(err)
+	}
+
+	j := retention.Start(store, retention.Config{Compact: true})
+	defer j.Close()
+	j.RunOnce(ctx)
+
+	var compacted int
+	if err := db.QueryRow(`SELECT SUM(length(run_data)) FROM runs`).Scan(&compacted); err != nil {
+		t.Fatal(err)
+	}
+	if compacted >= size {
+		t.Errorf("run data after compaction = %d bytes, want less than %d", compacted, size)
+	}
+	after, err := store.GetRun(ctx, "r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want, got any
+	json.Unmarshal([]byte(before.RunData), &want)
+	json.Unmarshal([]byte(after.RunData), &got)
+	if !reflect.DeepEqual(got, want) {
+		t.Error("GetRun() after compaction differs from the original run")
+	}
+}
+
+func TestConfigEnabled(t *testing.T) {
+	tests := []struct {
+		config retention.Config
+		want   bool
+	}{
+		{retention.Config{}, false},
+		{retention.Config{Policy: storage.RetentionPolicy{MaxAge: time.Hour}}, true},
+		{retention.Config{Policy: storage.RetentionPolicy{MaxRunsPerProject: 1}}, true},
+		{retention.Config{Compact: true}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.config.Enabled(); got != tt.want {
+			t.Errorf("%+v.Enabled() = %v, want %v", tt.config, got, tt.want)
+		}
+	}
+}
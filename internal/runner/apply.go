@@ -0,0 +1,63 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/ClarionDev/clarion/internal/fs"
+)
+
+// fileChange is one entry of the file_changes list in an agent's output.
+type fileChange struct {
+	Action     string `json:"action"`
+	Path       string `json:"path"`
+	NewContent string `json:"new_content"`
+}
+
+// ApplyOutput applies the file_changes of an agent's output to the project and returns how
+// many changes it applied. Every change is checked before any is applied, so a change with
+// an unknown action or a path outside the project leaves the project untouched.
+func ApplyOutput(projectRoot string, output map[string]any) (int, error) {
+	raw, ok := output["file_changes"]
+	if !ok {
+		return 0, nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return 0, err
+	}
+	var changes []fileChange
+	if err := json.Unmarshal(data, &changes); err != nil {
+		return 0, fmt.Errorf("file_changes is not a list of changes: %w", err)
+	}
+
+	paths := make([]string, len(changes))
+	for i, change := range changes {
+		switch change.Action {
+		case "create", "modify", "delete":
+		default:
+			return 0, fmt.Errorf("unknown action: %s for path: %s", change.Action, change.Path)
+		}
+		if filepath.IsAbs(change.Path) || !filepath.IsLocal(filepath.FromSlash(change.Path)) {
+			return 0, fmt.Errorf("path %q is outside the project", change.Path)
+		}
+		paths[i] = filepath.Join(projectRoot, filepath.FromSlash(change.Path))
+	}
+
+	for i, change := range changes {
+		var err error
+		switch change.Action {
+		case "create":
+			err = fs.CreateFile(paths[i], change.NewContent)
+		case "modify":
+			err = fs.ModifyFile(paths[i], change.NewContent)
+		case "delete":
+			err = fs.DeleteFile(paths[i])
+		}
+		if err != nil {
+			return i, fmt.Errorf("%s: %w", change.Path, err)
+		}
+	}
+	return len(changes), nil
+}
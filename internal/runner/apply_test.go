@@ -0,0 +1,45 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyOutput(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "old.txt"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := map[string]any{"file_changes": []any{
+		map[string]any{"action": "create", "path": "src/new.go", "new_content": "package src\n"},
+		map[string]any{"action": "delete", "path": "old.txt"},
+	}}
+
+	n, err := ApplyOutput(root, output)
+	if err != nil || n != 2 {
+		t.Fatalf("ApplyOutput = %d, %v", n, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "src", "new.go")); err != nil || string(data) != "package src\n" {
+		t.Errorf("src/new.go = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "old.txt")); !os.IsNotExist(err) {
+		t.Errorf("old.txt was not deleted: %v", err)
+	}
+}
+
+func TestApplyOutputRejectsPathsOutsideProject(t *testing.T) {
+	root := t.TempDir()
+	for _, path := range []string{"../escape.txt", "/etc/passwd"} {
+		output := map[string]any{"file_changes": []any{
+			map[string]any{"action": "create", "path": "ok.txt", "new_content": "x"},
+			map[string]any{"action": "create", "path": path, "new_content": "x"},
+		}}
+		if _, err := ApplyOutput(root, output); err == nil {
+			t.Errorf("ApplyOutput accepted %s", path)
+		}
+		if _, err := os.Stat(filepath.Join(root, "ok.txt")); !os.IsNotExist(err) {
+			t.Errorf("changes were applied before %s was rejected", path)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package runner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ClarionDev/clarion/internal/llm"
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/storage"
+)
+
+// Cache policies of a request.
+const (
+	CacheDefault = ""      // use the cache when it is enabled and the temperature is set to 0
+	CacheOff     = "off"   // neither read nor write the cache
+	CacheOn      = "on"    // use the cache even when it is not enabled by default
+	CacheForce   = "force" // use the cache even with a temperature above 0
+)
+
+// ErrInvalidCachePolicy is wrapped by errors from requests with an unknown cache policy.
+var ErrInvalidCachePolicy = errors.New("invalid cache policy")
+
+// ResponseCache reuses provider outputs for identical requests.
+type ResponseCache struct {
+	Store storage.ResponseCacheStore
+	TTL   time.Duration
+	// Enabled makes requests with CacheDefault use the cache.
+	Enabled bool
+}
+
+// CacheInfo tells how a run used the response cache. It is added to the output as "cache"
+// whenever the cache was consulted.
+type CacheInfo struct {
+	Hit bool   `json:"hit"`
+	Key string `json:"key"`
+	// CachedAt is when the reused response was generated.
+	CachedAt *time.Time `json:"cached_at,omitempty"`
+}
+
+func checkCachePolicy(policy string) error {
+	switch policy {
+	case CacheDefault, CacheOff, CacheOn, CacheForce:
+		return nil
+	}
+	return fmt.Errorf("%w %q, expected %s, %s or %s", ErrInvalidCachePolicy, policy, CacheOff, CacheOn, CacheForce)
+}
+
+// uses reports whether a request with the given policy and LLM config reads and writes
+// the cache. Outputs of configs with a temperature above 0 vary between calls, so they
+// are only cached when forced. A config without a temperature runs at the provider's
+// default, which is usually above 0, so it is not cached by default either.
+func (c *ResponseCache) uses(policy string, config models.LLMConfig) bool {
+	if c == nil || c.Store == nil {
+		return false
+	}
+	switch policy {
+	case CacheOff:
+		return false
+	case CacheForce:
+		return true
+	case CacheDefault:
+		if !c.Enabled {
+			return false
+		}
+	}
+	return zeroTemperature(config.Parameters)
+}
+
+// zeroTemperature reports whether params set the temperature to exactly 0. A missing or
+// unreadable temperature is not 0.
+func zeroTemperature(params map[string]any) bool {
+	var t float64
+	switch v := params["temperature"].(type) {
+	case float64:
+		t = v
+	case float32:
+		t = float64(v)
+	case int:
+		t = float64(v)
+	case int64:
+		t = float64(v)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return false
+		}
+		t = f
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return false
+		}
+		t = f
+	default:
+		return false
+	}
+	return t == 0
+}
+
+// CacheKey hashes the final provider payload: the provider, model and parameters, the
+// output schema and the chat messages. The saved config, and so the API key, is left out.
+func CacheKey(messages []llm.ChatMessage, req models.AgentRunRequest) (string, error) {
+	data, err := json.Marshal(struct {
+		Provider     string            `json:"provider"`
+		Model        string            `json:"model"`
+		Parameters   map[string]any    `json:"parameters"`
+		OutputSchema map[string]any    `json:"output_schema"`
+		Messages     []llm.ChatMessage `json:"messages"`
+	}{req.LLMConfig.Provider, req.LLMConfig.Model, req.LLMConfig.Parameters, req.OutputSchema, messages})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash the request: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// lookup returns the cached output for a key, or nil on a miss. A hit uses no tokens, so
+// the token usage of the original response is left out.
+func (c *ResponseCache) lookup(ctx context.Context, key string) map[string]any {
+	entry, err := c.Store.GetCachedResponse(ctx, key)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Failed to read the response cache: %v", err)
+		}
+		return nil
+	}
+	output := entry.Output
+	delete(output, "token_usage")
+	output["cache"] = CacheInfo{Hit: true, Key: key, CachedAt: &entry.CreatedAt}
+	return output
+}
+
+// store caches an output. A failure only costs the next run a provider call, so it is
+// logged rather than failing this one.
+func (c *ResponseCache) store(ctx context.Context, key string, config models.LLMConfig, output map[string]any) {
+	now := time.Now().UTC()
+	entry := &models.CachedResponse{
+		Key:       key,
+		Provider:  config.Provider,
+		Model:     config.Model,
+		Output:    output,
+		CreatedAt: now,
+		ExpiresAt: now.Add(c.TTL),
+	}
+	if err := c.Store.SaveCachedResponse(ctx, entry); err != nil {
+		log.Printf("Failed to cache the response: %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ClarionDev/clarion/internal/llm"
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/storage"
+)
+
+// countingProvider answers with the number of calls it has had.
+type countingProvider struct{ calls int }
+
+func (p *countingProvider) Generate(ctx context.Context, messages []llm.ChatMessage, request models.AgentRunRequest, store storage.LLMConfigStore) (map[string]any, error) {
+	p.calls++
+	return map[string]any{"summary": fmt.Sprintf("call %d", p.calls), "token_usage": map[string]any{"total": 10}}, nil
+}
+
+var counting = &countingProvider{}
+
+func init() {
+	llm.RegisterProvider("Cache Test", counting)
+}
+
+// memoryCache is a ResponseCacheStore in memory.
+type memoryCache map[string]*models.CachedResponse
+
+func (m memoryCache) GetCachedResponse(ctx context.Context, key string) (*models.CachedResponse, error) {
+	entry, ok := m[key]
+	if !ok || !entry.ExpiresAt.After(time.Now()) {
+		return nil, storage.ErrNotFound
+	}
+	entry.Hits++
+	c := *entry
+	c.Output = map[string]any{}
+	for k, v := range entry.Output {
+		c.Output[k] = v
+	}
+	return &c, nil
+}
+
+func (m memoryCache) SaveCachedResponse(ctx context.Context, entry *models.CachedResponse) error {
+	saved := *entry
+	saved.Output = map[string]any{}
+	for k, v := range entry.Output {
+		saved.Output[k] = v
+	}
+	m[entry.Key] = &saved
+	return nil
+}
+
+func (m memoryCache) ListCachedResponses(ctx context.Context) ([]*models.CachedResponse, error) {
+	return nil, nil
+}
+func (m memoryCache) DeleteCachedResponse(ctx context.Context, key string) error { return nil }
+func (m memoryCache) ClearResponseCache(ctx context.Context, expiredOnly bool) (int, error) {
+	return 0, nil
+}
+
+func TestRunResponseCache(t *testing.T) {
+	store := memoryCache{}
+	r := New(nil)
+	r.Cache = &ResponseCache{Store: store, TTL: time.Hour, Enabled: true}
+	run := func(prompt, policy string, temperature float64) map[string]any {
+		t.Helper()
+		output, err := r.Run(context.Background(), Request{
+			Prompt:      prompt,
+			ProjectRoot: t.TempDir(),
+			LLMConfig:   models.LLMConfig{Provider: "Cache Test", Model: "m", Parameters: map[string]any{"temperature": temperature}},
+			Cache:       policy,
+		}, nil)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		return output
+	}
+	start := counting.calls
+
+	first := run("hello", CacheDefault, 0)
+	if info, _ := first["cache"].(CacheInfo); info.Hit || info.Key == "" {
+		t.Errorf("first run cache = %#v, want a miss with a key", first["cache"])
+	}
+	second := run("hello", CacheDefault, 0)
+	if info, _ := second["cache"].(CacheInfo); !info.Hit || second["summary"] != first["summary"] {
+		t.Errorf("second run = %v, want a hit with the first output", second)
+	}
+	if _, ok := second["token_usage"]; ok {
+		t.Error("cache hit reports token usage, want none")
+	}
+	if _, ok := store[first["cache"].(CacheInfo).Key].Output["cache"]; ok {
+		t.Error("cached output contains the cache info")
+	}
+	if counting.calls != start+1 {
+		t.Errorf("provider called %d times, want 1", counting.calls-start)
+	}
+
+	if out := run("hello", CacheOff, 0); out["cache"] != nil {
+		t.Errorf("run with cache off = %v, want no cache info", out)
+	}
+	if out := run("warm", CacheDefault, 0.7); out["cache"] != nil {
+		t.Errorf("run with temperature 0.7 = %v, want the cache bypassed", out)
+	}
+	run("warm", CacheForce, 0.7)
+	if out := run("warm", CacheForce, 0.7); !out["cache"].(CacheInfo).Hit {
+		t.Errorf("forced run = %v, want a hit", out)
+	}
+
+	r.Cache.Enabled = false
+	if out := run("hello", CacheDefault, 0); out["cache"] != nil {
+		t.Errorf("run with the cache disabled = %v, want it bypassed", out)
+	}
+	if out := run("hello", CacheOn, 0); !out["cache"].(CacheInfo).Hit {
+		t.Errorf("opted-in run = %v, want a hit", out)
+	}
+
+	_, err := r.Run(context.Background(), Request{ProjectRoot: t.TempDir(), LLMConfig: models.LLMConfig{Provider: "Cache Test"}, Cache: "always"}, nil)
+	if !errors.Is(err, ErrInvalidCachePolicy) {
+		t.Errorf("Run() with an unknown policy error = %v, want ErrInvalidCachePolicy", err)
+	}
+}
+
+func TestCacheUsesTemperature(t *testing.T) {
+	c := &ResponseCache{Store: memoryCache{}, Enabled: true}
+	tests := []struct {
+		name   string
+		params map[string]any
+		want   bool
+	}{
+		{"zero", map[string]any{"temperature": 0.0}, true},
+		{"zero int", map[string]any{"temperature": 0}, true},
+		{"zero number", map[string]any{"temperature": json.Number("0")}, true},
+		{"zero string", map[string]any{"temperature": "0.0"}, true},
+		{"above zero", map[string]any{"temperature": 0.2}, false},
+		{"above zero number", map[string]any{"temperature": json.Number("1")}, false},
+		{"above zero string", map[string]any{"temperature": "0.7"}, false},
+		{"missing", map[string]any{"max_tokens": 100}, false},
+		{"no parameters", nil, false},
+		{"unparseable", map[string]any{"temperature": "cold"}, false},
+		{"wrong type", map[string]any{"temperature": true}, false},
+	}
+	for _, tt := range tests {
+		config := models.LLMConfig{Provider: "Cache Test", Parameters: tt.params}
+		if got := c.uses(CacheDefault, config); got != tt.want {
+			t.Errorf("%s: uses() = %v, want %v", tt.name, got, tt.want)
+		}
+		if !c.uses(CacheForce, config) {
+			t.Errorf("%s: uses(force) = false, want true", tt.name)
+		}
+	}
+}
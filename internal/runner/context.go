@@ -0,0 +1,209 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ClarionDev/clarion/internal/git"
+)
+
+// Git context selection modes.
+const (
+	GitContextModified    = "modified"     // tracked files changed since HEAD, staged or not
+	GitContextStaged      = "staged"       // files in the index
+	GitContextUntracked   = "untracked"    // new files git does not track yet
+	GitContextCommitRange = "commit_range" // files touched by a range such as HEAD~3..HEAD
+)
+
+// maxContextDiffBytes caps the diff added to a prompt so a large change cannot crowd out
+// everything else in the model's context window.
+const maxContextDiffBytes = 256 << 10
+
+// GitContext selects codebase paths from git in addition to the explicitly listed ones.
+type GitContext struct {
+	Mode string `json:"mode"`
+	// Range is the commit range for the commit_range mode, e.g. "HEAD~3..HEAD" or a single commit.
+	Range string `json:"range,omitempty"`
+	// IncludeDiff adds the unified diff of the selection to the prompt as its own section.
+	IncludeDiff bool `json:"include_diff,omitempty"`
+}
+
+// ErrInvalidContextSelection is wrapped by errors caused by the request's git selection
+// rather than by the repository or the file system.
+var ErrInvalidContextSelection = errors.New("invalid context selection")
+
+// ContextSnapshot is the codebase context of a run as it was read: the file contents by
+// path relative to the project root, and the diff if one was requested.
+type ContextSnapshot struct {
+	Files map[string]string `json:"files"`
+	Diff  string            `json:"diff,omitempty"`
+}
+
+// Snapshot reads the context of a run, like BuildContext, for keeping with the run.
+func Snapshot(ctx context.Context, projectRoot string, codebasePaths []string, gitContext *GitContext) (*ContextSnapshot, error) {
+	files, diff, err := BuildContext(ctx, projectRoot, codebasePaths, gitContext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read codebase files: %w", err)
+	}
+	return &ContextSnapshot{Files: files, Diff: diff}, nil
+}
+
+// BuildContext reads the files for a run: the explicit codebase paths plus the files
+// selected by gitContext. It also returns the selection's diff when one was requested.
+func BuildContext(ctx context.Context, projectRoot string, codebasePaths []string, gitContext *GitContext) (map[string]string, string, error) {
+	paths := codebasePaths
+	diff := ""
+	if gitContext != nil && gitContext.Mode != "" {
+		selected, selectionDiff, err := selectGitContext(ctx, projectRoot, gitContext)
+		if err != nil {
+			return nil, "", err
+		}
+		paths = mergePaths(codebasePaths, selected)
+		diff = truncateDiff(selectionDiff)
+	}
+
+	contents, err := ReadCodebaseFiles(projectRoot, paths)
+	if err != nil {
+		return nil, "", err
+	}
+	return contents, diff, nil
+}
+
+// selectGitContext resolves a git selection to paths relative to projectRoot and, if
+// requested, the matching diff. Files are read from the working tree, so for a commit
+// range the content reflects the current state rather than the end of the range.
+func selectGitContext(ctx context.Context, projectRoot string, gc *GitContext) ([]string, string, error) {
+	repo, err := git.Open(ctx, projectRoot)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidContextSelection, err)
+	}
+	// The project may be a subdirectory of the repository. Git reports paths relative to
+	// the repository root, so limit everything to the project and strip the prefix again.
+	prefix, err := repo.Prefix(projectRoot)
+	if err != nil {
+		return nil, "", err
+	}
+	var pathspec []string
+	if prefix != "" {
+		pathspec = []string{prefix}
+	}
+
+	var paths []string
+	var diff string
+	switch gc.Mode {
+	case GitContextModified, GitContextStaged, GitContextUntracked:
+		status, err := repo.Status(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		switch gc.Mode {
+		case GitContextModified:
+			paths = append(existingPaths(status.Staged), existingPaths(status.Unstaged)...)
+			if gc.IncludeDiff {
+				diff, err = repo.DiffHead(ctx, pathspec...)
+			}
+		case GitContextStaged:
+			paths = existingPaths(status.Staged)
+			if gc.IncludeDiff {
+				diff, err = repo.Diff(ctx, true, pathspec...)
+			}
+		case GitContextUntracked:
+			// Untracked files have no diff; their full content is the change.
+			paths = status.Untracked
+		}
+		if err != nil {
+			return nil, "", err
+		}
+	case GitContextCommitRange:
+		if gc.Range == "" {
+			return nil, "", fmt.Errorf("%w: range is required for the %s mode", ErrInvalidContextSelection, gc.Mode)
+		}
+		if paths, err = repo.RangeFiles(ctx, gc.Range, pathspec...); err != nil {
+			return nil, "", wrapRangeError(err)
+		}
+		if gc.IncludeDiff {
+			if diff, err = repo.RangeDiff(ctx, gc.Range, pathspec...); err != nil {
+				return nil, "", wrapRangeError(err)
+			}
+		}
+	default:
+		return nil, "", fmt.Errorf("%w: unknown mode %q, expected %s, %s, %s or %s", ErrInvalidContextSelection, gc.Mode,
+			GitContextModified, GitContextStaged, GitContextUntracked, GitContextCommitRange)
+	}
+	return relativeTo(prefix, paths), diff, nil
+}
+
+// relativeTo keeps the paths under prefix and makes them relative to it.
+func relativeTo(prefix string, paths []string) []string {
+	if prefix == "" {
+		return paths
+	}
+	var rel []string
+	for _, path := range paths {
+		if trimmed, ok := strings.CutPrefix(path, prefix); ok {
+			rel = append(rel, trimmed)
+		}
+	}
+	return rel
+}
+
+func wrapRangeError(err error) error {
+	if errors.Is(err, git.ErrInvalidRef) {
+		return fmt.Errorf("%w: %v", ErrInvalidContextSelection, err)
+	}
+	return err
+}
+
+// existingPaths returns the paths of changes that still have content to read.
+func existingPaths(changes []git.FileChange) []string {
+	var paths []string
+	for _, change := range changes {
+		if change.Status != "deleted" {
+			paths = append(paths, change.Path)
+		}
+	}
+	return paths
+}
+
+func mergePaths(lists ...[]string) []string {
+	seen := make(map[string]bool)
+	var merged []string
+	for _, list := range lists {
+		for _, path := range list {
+			if !seen[path] {
+				seen[path] = true
+				merged = append(merged, path)
+			}
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
+
+func truncateDiff(diff string) string {
+	if len(diff) <= maxContextDiffBytes {
+		return diff
+	}
+	return diff[:maxContextDiffBytes] + fmt.Sprintf("\n... diff truncated, %d more bytes ...\n", len(diff)-maxContextDiffBytes)
+}
+
+// ReadCodebaseFiles reads files relative to projectRoot, skipping those that cannot be read.
+func ReadCodebaseFiles(projectRoot string, codebasePaths []string) (map[string]string, error) {
+	contentMap := make(map[string]string)
+	for _, relPath := range codebasePaths {
+		absPath := filepath.Join(projectRoot, relPath)
+		fileContent, err := os.ReadFile(absPath)
+		if err != nil {
+			log.Printf("Skipping file %s due to read error: %v", relPath, err)
+			continue
+		}
+		contentMap[relPath] = string(fileContent)
+	}
+	return contentMap, nil
+}
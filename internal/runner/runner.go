@@ -0,0 +1,112 @@
+// Package runner executes single agent runs: it reads the run's context, builds the
+// prompt and asks the configured provider for the structured output. The HTTP API, the
+// canvas executor and the command line all run agents through it.
+package runner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ClarionDev/clarion/internal/llm"
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/storage"
+)
+
+// Progress receives status updates while a run executes.
+type Progress func(status, message string, err error)
+
+// Request describes one agent run.
+type Request struct {
+	SystemInstruction string
+	Prompt            string
+	OutputSchema      map[string]any
+	LLMConfig         models.LLMConfig
+	ProjectRoot       string
+	CodebasePaths     []string
+	GitContext        *GitContext
+
+	// Variables fill the {{name}} placeholders of SystemInstruction and Prompt, next to
+	// the built-in variables. UserVariables declares the agent's variables, and
+	// ProjectName overrides the project.name built-in, which defaults to the directory name.
+	Variables     map[string]string
+	UserVariables []models.UserVariableDef
+	ProjectName   string
+	// Messages, usually from a prompt template, replace the system and user message pair.
+	Messages []models.Message
+	// Context, when set, is used as the codebase context instead of reading CodebasePaths
+	// and GitContext, e.g. to replay a run on the files it saw.
+	Context *ContextSnapshot
+	// Cache is the request's cache policy, CacheDefault unless set.
+	Cache string
+}
+
+type Runner struct {
+	llmConfigStore storage.LLMConfigStore
+	// Cache, when set, reuses provider outputs for identical requests.
+	Cache *ResponseCache
+}
+
+func New(llmConfigStore storage.LLMConfigStore) *Runner {
+	return &Runner{llmConfigStore: llmConfigStore}
+}
+
+// Run executes an agent run and returns the provider's output. Errors wrap
+// ErrInvalidContextSelection, ErrInvalidTemplate or ErrInvalidCachePolicy, or are a
+// *MissingVariablesError, when the request is at fault.
+func (r *Runner) Run(ctx context.Context, req Request, progress Progress) (map[string]any, error) {
+	if progress == nil {
+		progress = func(string, string, error) {}
+	}
+
+	if err := checkCachePolicy(req.Cache); err != nil {
+		return nil, err
+	}
+	internalReq, err := PrepareRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := llm.GetProvider(internalReq.LLMConfig.Provider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get LLM provider: %w", err)
+	}
+
+	snapshot := req.Context
+	if snapshot == nil {
+		progress("reading_context", fmt.Sprintf("Reading %d files", len(req.CodebasePaths)), nil)
+		if snapshot, err = Snapshot(ctx, req.ProjectRoot, req.CodebasePaths, req.GitContext); err != nil {
+			return nil, err
+		}
+	}
+	codebaseContent := snapshot.Files
+	internalReq.Diff = snapshot.Diff
+
+	messages, err := llm.BuildChatMessages(internalReq, codebaseContent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build chat messages: %w", err)
+	}
+
+	// The key is taken before generating, since providers may adjust the schema in place.
+	var cacheKey string
+	useCache := r.Cache.uses(req.Cache, internalReq.LLMConfig)
+	if useCache {
+		if cacheKey, err = CacheKey(messages, internalReq); err != nil {
+			return nil, err
+		}
+		if output := r.Cache.lookup(ctx, cacheKey); output != nil {
+			progress("generating", "Using a cached response", nil)
+			return output, nil
+		}
+	}
+
+	progress("generating", fmt.Sprintf("Waiting for %s (%s)", internalReq.LLMConfig.Provider, internalReq.LLMConfig.Model), nil)
+	output, err := provider.Generate(ctx, messages, internalReq, r.llmConfigStore)
+	if err != nil {
+		return nil, fmt.Errorf("LLM generation failed: %w", err)
+	}
+	if useCache {
+		r.Cache.store(ctx, cacheKey, internalReq.LLMConfig, output)
+		output["cache"] = CacheInfo{Key: cacheKey}
+	}
+	return output, nil
+}
@@ -0,0 +1,121 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ClarionDev/clarion/internal/git"
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/templating"
+)
+
+// ErrInvalidTemplate is wrapped by errors from prompts that cannot be parsed.
+var ErrInvalidTemplate = errors.New("invalid prompt template")
+
+// MissingVariablesError lists required user variables that were not given a value.
+type MissingVariablesError struct {
+	Names []string
+}
+
+func (e *MissingVariablesError) Error() string {
+	return fmt.Sprintf("missing required variables: %s", strings.Join(e.Names, ", "))
+}
+
+// BuiltinVariables returns the variables every prompt can use: project.name, project.path,
+// git.branch, git.commit, date, time and datetime. The git variables are empty outside a
+// repository.
+func BuiltinVariables(ctx context.Context, projectRoot, projectName string) map[string]string {
+	now := time.Now()
+	if projectName == "" && projectRoot != "" {
+		projectName = filepath.Base(projectRoot)
+	}
+	vars := map[string]string{
+		"project.name": projectName,
+		"project.path": projectRoot,
+		"git.branch":   "",
+		"git.commit":   "",
+		"date":         now.Format("2006-01-02"),
+		"time":         now.Format("15:04"),
+		"datetime":     now.Format(time.RFC3339),
+	}
+	if projectRoot == "" {
+		return vars
+	}
+	if repo, err := git.Open(ctx, projectRoot); err == nil {
+		if branch, err := repo.CurrentBranch(ctx); err == nil {
+			vars["git.branch"] = branch
+		}
+		if commit, err := repo.ResolveCommit(ctx, "HEAD"); err == nil {
+			vars["git.commit"] = commit[:12]
+		}
+	}
+	return vars
+}
+
+// PrepareRequest fills the run's variables into its system instruction, prompt and template
+// messages and returns the request for the provider. Declared user variables without a
+// value render as empty text, or fail the run with a *MissingVariablesError when they are
+// required. Template messages can also use {{system_instruction}} and {{prompt}}, which hold
+// the rendered system instruction and prompt; {{context}} is left for the provider request.
+func PrepareRequest(ctx context.Context, req Request) (models.AgentRunRequest, error) {
+	if err := checkRequired(req.UserVariables, req.Variables); err != nil {
+		return models.AgentRunRequest{}, err
+	}
+
+	data := BuiltinVariables(ctx, req.ProjectRoot, req.ProjectName)
+	for _, v := range req.UserVariables {
+		data[v.Name] = ""
+	}
+	for name, value := range req.Variables {
+		data[name] = value
+	}
+
+	system, err := templating.Render(req.SystemInstruction, data)
+	if err != nil {
+		return models.AgentRunRequest{}, fmt.Errorf("%w: system prompt: %v", ErrInvalidTemplate, err)
+	}
+	prompt, err := templating.Render(req.Prompt, data)
+	if err != nil {
+		return models.AgentRunRequest{}, fmt.Errorf("%w: prompt: %v", ErrInvalidTemplate, err)
+	}
+
+	var messages []models.Message
+	if len(req.Messages) > 0 {
+		data["system_instruction"] = system
+		data["prompt"] = prompt
+		delete(data, "context")
+		messages = make([]models.Message, len(req.Messages))
+		for i, m := range req.Messages {
+			content, err := templating.Render(m.Content, data)
+			if err != nil {
+				return models.AgentRunRequest{}, fmt.Errorf("%w: message %d: %v", ErrInvalidTemplate, i+1, err)
+			}
+			messages[i] = models.Message{Role: m.Role, Content: content}
+		}
+	}
+
+	return models.AgentRunRequest{
+		SystemInstruction: system,
+		Prompt:            prompt,
+		OutputSchema:      req.OutputSchema,
+		LLMConfig:         req.LLMConfig,
+		Messages:          messages,
+	}, nil
+}
+
+func checkRequired(defs []models.UserVariableDef, vars map[string]string) error {
+	var missing []string
+	for _, v := range defs {
+		if v.Required && strings.TrimSpace(vars[v.Name]) == "" {
+			missing = append(missing, v.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return &MissingVariablesError{Names: missing}
+	}
+	return nil
+}
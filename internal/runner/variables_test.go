@@ -0,0 +1,172 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ClarionDev/clarion/internal/models"
+)
+
+func TestPrepareRequest(t *testing.T) {
+	defs := []models.UserVariableDef{{Name: "ticket", Required: true}, {Name: "scope"}}
+	tests := []struct {
+		name       string
+		req        Request
+		wantSystem string
+		wantPrompt string
+		wantErr    error
+	}{
+		{
+			name:       "placeholders",
+			req:        Request{SystemInstruction: "You work on {{project.name}}.", Prompt: "Fix {{ticket}} in {{scope}}.", UserVariables: defs, Variables: map[string]string{"ticket": "T-1", "scope": "api"}},
+			wantSystem: "You work on demo.",
+			wantPrompt: "Fix T-1 in api.",
+		},
+		{
+			name:       "optional variable left empty",
+			req:        Request{Prompt: "Fix {{ticket}}{{#if scope}} in {{scope}}{{/if}}.", UserVariables: defs, Variables: map[string]string{"ticket": "T-1"}},
+			wantPrompt: "Fix T-1.",
+		},
+		{
+			name:       "defaults",
+			req:        Request{Prompt: `Use {{scope | default "everything"}} and {{lang | default "Go"}}.`, Variables: map[string]string{"lang": "Rust"}},
+			wantPrompt: "Use everything and Rust.",
+		},
+		{
+			name:       "unknown placeholders are kept",
+			req:        Request{Prompt: "Return {{json}} as is."},
+			wantPrompt: "Return {{json}} as is.",
+		},
+		{
+			name:    "missing required variable",
+			req:     Request{Prompt: "Fix {{ticket}}.", UserVariables: defs, Variables: map[string]string{"ticket": "  "}},
+			wantErr: &MissingVariablesError{Names: []string{"ticket"}},
+		},
+		{
+			name:    "invalid template",
+			req:     Request{Prompt: "{{#if ticket}}unclosed"},
+			wantErr: ErrInvalidTemplate,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.ProjectName = "demo"
+			got, err := PrepareRequest(context.Background(), tt.req)
+			if tt.wantErr != nil {
+				var missing *MissingVariablesError
+				if want, ok := tt.wantErr.(*MissingVariablesError); ok {
+					if !errors.As(err, &missing) || !reflect.DeepEqual(missing.Names, want.Names) {
+						t.Fatalf("PrepareRequest() error = %v, want missing %v", err, want.Names)
+					}
+					return
+				}
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("PrepareRequest() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PrepareRequest() error = %v", err)
+			}
+			if got.SystemInstruction != tt.wantSystem || got.Prompt != tt.wantPrompt {
+				t.Errorf("PrepareRequest() = %q, %q; want %q, %q", got.SystemInstruction, got.Prompt, tt.wantSystem, tt.wantPrompt)
+			}
+		})
+	}
+}
+
+func TestPrepareRequestTemplateMessages(t *testing.T) {
+	req := Request{
+		SystemInstruction: "Be brief about {{ticket}}.",
+		Prompt:            "Fix {{ticket}}.",
+		Variables:         map[string]string{"ticket": "T-1", "context": "overridden"},
+		Messages: []models.Message{
+			{Role: "system", Content: "{{system_instruction}}"},
+			{Role: "user", Content: "{{prompt}}\n{{context}}"},
+		},
+	}
+	got, err := PrepareRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("PrepareRequest() error = %v", err)
+	}
+	want := []models.Message{
+		{Role: "system", Content: "Be brief about T-1."},
+		{Role: "user", Content: "Fix T-1.\n{{context}}"},
+	}
+	if !reflect.DeepEqual(got.Messages, want) {
+		t.Errorf("Messages = %+v, want %+v", got.Messages, want)
+	}
+}
+
+func TestCheckRequired(t *testing.T) {
+	defs := []models.UserVariableDef{{Name: "a", Required: true}, {Name: "b"}, {Name: "c", Required: true}}
+	tests := []struct {
+		vars map[string]string
+		want []string
+	}{
+		{map[string]string{"a": "1", "c": "3"}, nil},
+		{map[string]string{"a": "1"}, []string{"c"}},
+		{map[string]string{"b": "2", "c": " "}, []string{"a", "c"}},
+		{nil, []string{"a", "c"}},
+	}
+	for _, tt := range tests {
+		err := checkRequired(defs, tt.vars)
+		var missing *MissingVariablesError
+		switch {
+		case tt.want == nil && err != nil:
+			t.Errorf("checkRequired(%v) = %v, want nil", tt.vars, err)
+		case tt.want != nil && (!errors.As(err, &missing) || !reflect.DeepEqual(missing.Names, tt.want)):
+			t.Errorf("checkRequired(%v) = %v, want missing %v", tt.vars, err, tt.want)
+		}
+	}
+}
+
+func TestBuiltinVariables(t *testing.T) {
+	dir := t.TempDir()
+	vars := BuiltinVariables(context.Background(), dir, "")
+	if vars["project.name"] != filepath.Base(dir) || vars["project.path"] != dir {
+		t.Errorf("project variables = %q, %q; want the directory", vars["project.name"], vars["project.path"])
+	}
+	if vars["git.branch"] != "" || vars["git.commit"] != "" {
+		t.Errorf("git variables outside a repository = %q, %q; want empty", vars["git.branch"], vars["git.commit"])
+	}
+	if _, err := time.Parse("2006-01-02", vars["date"]); err != nil {
+		t.Errorf("date = %q: %v", vars["date"], err)
+	}
+	if _, err := time.Parse(time.RFC3339, vars["datetime"]); err != nil {
+		t.Errorf("datetime = %q: %v", vars["datetime"], err)
+	}
+
+	if named := BuiltinVariables(context.Background(), dir, "Named"); named["project.name"] != "Named" {
+		t.Errorf("project.name = %q, want the given name", named["project.name"])
+	}
+	if empty := BuiltinVariables(context.Background(), "", ""); empty["project.name"] != "" || len(empty) != 7 {
+		t.Errorf("BuiltinVariables without a project = %v, want 7 empty project variables", empty)
+	}
+}
+
+func TestBuiltinVariablesGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch=feature"},
+		{"-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "--quiet", "--allow-empty", "-m", "Initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	vars := BuiltinVariables(context.Background(), dir, "")
+	if vars["git.branch"] != "feature" || len(vars["git.commit"]) != 12 {
+		t.Errorf("git variables = %q, %q; want the branch and a short commit", vars["git.branch"], vars["git.commit"])
+	}
+}
@@ -0,0 +1,11 @@
+package secrets
+
+import "errors"
+
+const (
+	keyringService = "clarion"
+	keyringAccount = "master-key"
+)
+
+// errKeyringNotFound means the keyring works but holds no master key yet.
+var errKeyringNotFound = errors.New("master key not found in OS keyring")
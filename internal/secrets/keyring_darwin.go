@@ -0,0 +1,41 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// On macOS the master key is kept in the login keychain through the security command.
+
+// errItemNotFound is the exit status of security when no matching item exists.
+const errItemNotFound = 44
+
+func keyringGet() (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("security", "find-generic-password", "-s", keyringService, "-a", keyringAccount, "-w")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if cmd.ProcessState != nil && cmd.ProcessState.ExitCode() == errItemNotFound {
+			return "", errKeyringNotFound
+		}
+		return "", fmt.Errorf("security find-generic-password failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// keyringSet stores the secret. It is written to the standard input of security, which
+// prompts for it, and then for its confirmation, when -w is the last argument; as an
+// argument it would be visible to other processes.
+func keyringSet(secret string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("security", "add-generic-password", "-U", "-s", keyringService, "-a", keyringAccount, "-l", "Clarion master key", "-w")
+	cmd.Stdin = strings.NewReader(secret + "\n" + secret + "\n")
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("security add-generic-password failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
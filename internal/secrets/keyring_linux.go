@@ -0,0 +1,41 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// On Linux the master key is kept in the Secret Service (GNOME Keyring, KWallet)
+// through the secret-tool command from libsecret.
+
+func keyringGet() (string, error) {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return "", errors.New("secret-tool not found")
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", "lookup", "service", keyringService, "account", keyringAccount)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// secret-tool exits 1 with no output when the item does not exist.
+		if stderr.Len() == 0 && stdout.Len() == 0 {
+			return "", errKeyringNotFound
+		}
+		return "", fmt.Errorf("secret-tool lookup failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func keyringSet(secret string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", "store", "--label=Clarion master key", "service", keyringService, "account", keyringAccount)
+	cmd.Stdin = strings.NewReader(secret)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("secret-tool store failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
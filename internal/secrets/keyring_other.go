@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package secrets
+
+import "errors"
+
+func keyringGet() (string, error) {
+	return "", errors.New("OS keyring is not supported on this platform")
+}
+
+func keyringSet(secret string) error {
+	return errors.New("OS keyring is not supported on this platform")
+}
@@ -0,0 +1,196 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// KeyStoreAuto uses an existing key file, then the OS keyring, then a new key file.
+	KeyStoreAuto = "auto"
+	// KeyStoreKeyring requires the OS keyring.
+	KeyStoreKeyring = "keyring"
+	// KeyStoreFile uses the passphrase-protected key file in the data directory.
+	KeyStoreFile = "file"
+
+	// PassphraseEnv holds the passphrase protecting the master key file.
+	PassphraseEnv = "CLARION_MASTER_PASSPHRASE"
+
+	keyFileName      = "master.key"
+	pbkdf2Iterations = 600000
+)
+
+// keyFile is the on-disk format of the passphrase-protected master key.
+type keyFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// LoadMasterKey returns the master key, creating and storing a new one on first use.
+// mode is one of KeyStoreAuto, KeyStoreKeyring or KeyStoreFile.
+func LoadMasterKey(dataDir, mode string) ([]byte, error) {
+	keyPath := filepath.Join(dataDir, keyFileName)
+
+	switch mode {
+	case KeyStoreFile:
+		return loadOrCreateKeyFile(keyPath)
+	case KeyStoreKeyring:
+		return loadOrCreateKeyringKey()
+	case KeyStoreAuto, "":
+		// A key file that already exists wins, otherwise switching back from a fallback
+		// would silently generate a second key and orphan the encrypted data.
+		if _, err := os.Stat(keyPath); err == nil {
+			return loadOrCreateKeyFile(keyPath)
+		}
+		key, err := loadOrCreateKeyringKey()
+		if err == nil {
+			return key, nil
+		}
+		log.Printf("OS keyring unavailable (%v), using key file %s", err, keyPath)
+		return loadOrCreateKeyFile(keyPath)
+	default:
+		return nil, fmt.Errorf("unknown key store %q, expected auto, keyring or file", mode)
+	}
+}
+
+func newMasterKey() ([]byte, error) {
+	key := make([]byte, masterKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate master key: %w", err)
+	}
+	return key, nil
+}
+
+func loadOrCreateKeyringKey() ([]byte, error) {
+	encoded, err := keyringGet()
+	if err == nil {
+		key, err := hex.DecodeString(encoded)
+		if err != nil || len(key) != masterKeySize {
+			return nil, errors.New("master key in OS keyring is malformed")
+		}
+		return key, nil
+	}
+	if !errors.Is(err, errKeyringNotFound) {
+		return nil, err
+	}
+
+	key, err := newMasterKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := keyringSet(hex.EncodeToString(key)); err != nil {
+		return nil, err
+	}
+	log.Println("Generated a new master key and stored it in the OS keyring.")
+	return key, nil
+}
+
+func loadOrCreateKeyFile(path string) ([]byte, error) {
+	passphrase := os.Getenv(PassphraseEnv)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		key, err := newMasterKey()
+		if err != nil {
+			return nil, err
+		}
+		if err := writeKeyFile(path, key, passphrase); err != nil {
+			return nil, err
+		}
+		log.Printf("Generated a new master key in %s", path)
+		if passphrase == "" {
+			log.Printf("Warning: %s is not set, so the master key file is only protected by its file permissions.", PassphraseEnv)
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read master key file: %w", err)
+	}
+
+	var kf keyFile
+	if err := json.Unmarshal(data, &kf); err != nil {
+		return nil, fmt.Errorf("malformed master key file %s: %w", path, err)
+	}
+	if kf.Version != 1 || kf.KDF != "pbkdf2-sha256" {
+		return nil, fmt.Errorf("unsupported master key file format in %s", path)
+	}
+
+	aead, err := passphraseAEAD(passphrase, kf.Salt, kf.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	key, err := aead.Open(nil, kf.Nonce, kf.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unlock master key file %s: check %s", path, PassphraseEnv)
+	}
+	return key, nil
+}
+
+func writeKeyFile(path string, key []byte, passphrase string) error {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	aead, err := passphraseAEAD(passphrase, salt, pbkdf2Iterations)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(keyFile{
+		Version:    1,
+		KDF:        "pbkdf2-sha256",
+		Iterations: pbkdf2Iterations,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, key, nil),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create key directory: %w", err)
+	}
+	// O_EXCL so two processes starting at once cannot overwrite each other's key.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create master key file: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func passphraseAEAD(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(deriveKey(passphrase, salt, iterations))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// deriveKey derives the key that encrypts the master key file from the passphrase.
+func deriveKey(passphrase string, salt []byte, iterations int) []byte {
+	return pbkdf2.Key([]byte(passphrase), salt, iterations, masterKeySize, sha256.New)
+}
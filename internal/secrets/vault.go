@@ -0,0 +1,93 @@
+// Package secrets encrypts sensitive values, such as provider API keys, with a locally
+// generated master key before they are written to the database.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// sealedPrefix marks values encrypted by a Vault. Values without it are legacy plaintext.
+const sealedPrefix = "enc:v1:"
+
+const masterKeySize = 32
+
+// Vault encrypts and decrypts values with AES-256-GCM under the master key.
+type Vault struct {
+	aead cipher.AEAD
+}
+
+// NewVault creates a vault from a 32-byte master key.
+func NewVault(masterKey []byte) (*Vault, error) {
+	if len(masterKey) != masterKeySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", masterKeySize, len(masterKey))
+	}
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Vault{aead: aead}, nil
+}
+
+// IsSealed reports whether value was produced by Seal.
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix)
+}
+
+// Seal encrypts plaintext. Empty and already sealed values are returned unchanged.
+func (v *Vault) Seal(plaintext string) (string, error) {
+	if plaintext == "" || IsSealed(plaintext) {
+		return plaintext, nil
+	}
+	nonce := make([]byte, v.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := v.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a sealed value. Values that are not sealed are returned as-is so that
+// rows written before encryption was introduced keep working until they are re-saved.
+func (v *Vault) Open(value string) (string, error) {
+	if !IsSealed(value) {
+		return value, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, sealedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("malformed sealed value: %w", err)
+	}
+	nonceSize := v.aead.NonceSize()
+	if len(raw) < nonceSize {
+		return "", errors.New("malformed sealed value: too short")
+	}
+	plaintext, err := v.aead.Open(nil, raw[:nonceSize], raw[nonceSize:], nil)
+	if err != nil {
+		return "", errors.New("failed to decrypt value: the master key does not match the one it was encrypted with")
+	}
+	return string(plaintext), nil
+}
+
+// MaskKey returns a display form of an API key such as "sk-…abcd". Short keys are fully hidden.
+func MaskKey(key string) string {
+	if key == "" {
+		return ""
+	}
+	if len(key) < 12 {
+		return "…"
+	}
+	prefix := key[:3]
+	if i := strings.IndexAny(key, "-_"); i > 0 && i < 8 {
+		prefix = key[:i+1]
+	}
+	return prefix + "…" + key[len(key)-4:]
+}
@@ -0,0 +1,93 @@
+package secrets
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeriveKey(t *testing.T) {
+	// Test vector from RFC 7914, section 11, truncated to the key size.
+	want := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc"
+	got := hex.EncodeToString(deriveKey("passwd", []byte("salt"), 1))
+	if got != want {
+		t.Fatalf("deriveKey = %s, want %s", got, want)
+	}
+}
+
+func TestVaultSealOpen(t *testing.T) {
+	key := make([]byte, masterKeySize)
+	vault, err := NewVault(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := vault.Seal("sk-test-1234567890abcd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSealed(sealed) {
+		t.Fatalf("Seal returned %q without the sealed prefix", sealed)
+	}
+	if again, _ := vault.Seal(sealed); again != sealed {
+		t.Fatal("sealing a sealed value should return it unchanged")
+	}
+
+	opened, err := vault.Open(sealed)
+	if err != nil || opened != "sk-test-1234567890abcd" {
+		t.Fatalf("Open = %q, %v", opened, err)
+	}
+	if plain, err := vault.Open("legacy-plaintext"); err != nil || plain != "legacy-plaintext" {
+		t.Fatalf("Open of plaintext = %q, %v", plain, err)
+	}
+
+	other, _ := NewVault(append(make([]byte, masterKeySize-1), 1))
+	if _, err := other.Open(sealed); err == nil {
+		t.Fatal("expected an error opening with a different master key")
+	}
+}
+
+func TestMaskKey(t *testing.T) {
+	cases := map[string]string{
+		"":                          "",
+		"short":                     "…",
+		"sk-proj-abcdefghijklmnop":  "sk-…mnop",
+		"AIzaSyabcdefghijklmnopqrs": "AIz…pqrs",
+	}
+	for key, want := range cases {
+		if got := MaskKey(key); got != want {
+			t.Errorf("MaskKey(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestKeyFileRoundTrip(t *testing.T) {
+	t.Setenv(PassphraseEnv, "correct horse")
+	dir := t.TempDir()
+
+	key, err := LoadMasterKey(dir, KeyStoreFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(dir, keyFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Fatalf("key file permissions = %o, want 600", perm)
+	}
+
+	again, err := LoadMasterKey(dir, KeyStoreFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(again) != hex.EncodeToString(key) {
+		t.Fatal("reloading the key file returned a different key")
+	}
+
+	t.Setenv(PassphraseEnv, "wrong")
+	if _, err := LoadMasterKey(dir, KeyStoreFile); err == nil {
+		t.Fatal("expected an error unlocking the key file with the wrong passphrase")
+	}
+}
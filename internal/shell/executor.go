@@ -2,39 +2,29 @@ package shell
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os/exec"
 	"strings"
 	"sync"
-
-	"github.com/gorilla/websocket"
 )
 
-type WsResponseMessage struct {
-	Type string `json:"type"` // "stdout", "stderr", "exit", "error"
-	Data string `json:"data"`
-}
-
-func writeJSONMessage(conn *websocket.Conn, msgType string, data string, mu *sync.Mutex) {
-	mu.Lock()
-	defer mu.Unlock()
-	msg := WsResponseMessage{Type: msgType, Data: data}
-	if err := conn.WriteJSON(msg); err != nil {
-		log.Printf("WebSocket write error: %v", err)
-	}
-}
+// OutputFunc receives the output of a running command. msgType is one of
+// "stdout", "stderr", "error" or "exit".
+type OutputFunc func(msgType string, data string)
 
-func streamPipe(conn *websocket.Conn, pipe io.Reader, msgType string, wg *sync.WaitGroup, mu *sync.Mutex) {
+func streamPipe(pipe io.Reader, msgType string, emit OutputFunc, wg *sync.WaitGroup) {
 	defer wg.Done()
 	scanner := bufio.NewScanner(pipe)
 	for scanner.Scan() {
-		writeJSONMessage(conn, msgType, scanner.Text()+"\n", mu)
+		emit(msgType, scanner.Text()+"\n")
 	}
 	if err := scanner.Err(); err != nil {
 		log.Printf("Error reading from pipe (%s): %v", msgType, err)
-		writeJSONMessage(conn, "error", "Error reading pipe: "+err.Error(), mu)
+		emit("error", "Error reading pipe: "+err.Error())
 	}
 }
 
@@ -76,55 +66,101 @@ func splitCommand(command string) ([]string, error) {
 	return parts, nil
 }
 
-func StreamCommand(conn *websocket.Conn, command string, workingDir string) {
+// StreamCommand runs a command in workingDir and streams its output to emit until it
+// exits or ctx is cancelled. emit is never called concurrently.
+func StreamCommand(ctx context.Context, command string, workingDir string, emit OutputFunc) {
+	var mu sync.Mutex
+	safeEmit := func(msgType string, data string) {
+		mu.Lock()
+		defer mu.Unlock()
+		emit(msgType, data)
+	}
+
 	parts, err := splitCommand(command)
 	if err != nil {
 		log.Printf("Error parsing command: %v", err)
-		writeJSONMessage(conn, "error", "Could not parse command: "+err.Error(), &sync.Mutex{})
-		writeJSONMessage(conn, "exit", "Command failed due to parsing error.", &sync.Mutex{})
+		safeEmit("error", "Could not parse command: "+err.Error())
+		safeEmit("exit", "Command failed due to parsing error.")
 		return
 	}
 
-	cmd := exec.Command(parts[0], parts[1:]...)
+	cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
 	cmd.Dir = workingDir
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		log.Printf("Error creating stdout pipe: %v", err)
-		writeJSONMessage(conn, "error", "Could not create stdout pipe: "+err.Error(), &sync.Mutex{})
+		safeEmit("error", "Could not create stdout pipe: "+err.Error())
 		return
 	}
 
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
 		log.Printf("Error creating stderr pipe: %v", err)
-		writeJSONMessage(conn, "error", "Could not create stderr pipe: "+err.Error(), &sync.Mutex{})
+		safeEmit("error", "Could not create stderr pipe: "+err.Error())
 		return
 	}
 
 	var wg sync.WaitGroup
-	var mu sync.Mutex
 
 	if err := cmd.Start(); err != nil {
 		log.Printf("Error starting command: %v", err)
 		fullErrorMsg := fmt.Sprintf("Could not start command '%s': %v", parts[0], err)
-		writeJSONMessage(conn, "error", fullErrorMsg, &mu)
-		writeJSONMessage(conn, "exit", "Command failed to start.", &mu)
+		safeEmit("error", fullErrorMsg)
+		safeEmit("exit", "Command failed to start.")
 		return
 	}
 
 	wg.Add(2)
-	go streamPipe(conn, stdout, "stdout", &wg, &mu)
-	go streamPipe(conn, stderr, "stderr", &wg, &mu)
+	go streamPipe(stdout, "stdout", safeEmit, &wg)
+	go streamPipe(stderr, "stderr", safeEmit, &wg)
 
 	wg.Wait()
 
 	err = cmd.Wait()
 	if err != nil {
 		log.Printf("Command finished with error: %v", err)
-		writeJSONMessage(conn, "exit", "Command finished with error: "+err.Error(), &mu)
+		safeEmit("exit", "Command finished with error: "+err.Error())
 	} else {
 		log.Printf("Command finished successfully")
-		writeJSONMessage(conn, "exit", "Command finished successfully.", &mu)
+		safeEmit("exit", "Command finished successfully.")
+	}
+}
+
+// CommandResult is the outcome of a command run to completion with RunCommand.
+type CommandResult struct {
+	Command  string `json:"command"`
+	ExitCode int    `json:"exit_code"`
+	Output   string `json:"output"`
+}
+
+// maxCommandOutput bounds the output kept by RunCommand; the tail is the useful part of a
+// failing build or test run.
+const maxCommandOutput = 64 << 10
+
+// RunCommand runs a command in workingDir and returns its combined output and exit code.
+// The error is only set when the command could not be run at all.
+func RunCommand(ctx context.Context, command string, workingDir string) (*CommandResult, error) {
+	parts, err := splitCommand(command)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
+	cmd.Dir = workingDir
+	out, err := cmd.CombinedOutput()
+
+	result := &CommandResult{Command: command}
+	if len(out) > maxCommandOutput {
+		out = append([]byte("...\n"), out[len(out)-maxCommandOutput:]...)
+	}
+	result.Output = string(out)
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitCode()
+	} else if err != nil {
+		return nil, fmt.Errorf("could not run '%s': %w", parts[0], err)
 	}
+	return result, nil
 }
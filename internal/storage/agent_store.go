@@ -7,7 +7,14 @@ import (
 )
 
 type AgentStore interface {
+	// SaveAgent saves an agent with no changelog; see SaveAgentRevision.
 	SaveAgent(ctx context.Context, agent *models.Agent) error
+	// SaveAgentRevision saves an agent and, if it differs from its latest revision, appends
+	// a new revision. It sets agent.Revision and returns the agent's latest revision.
+	SaveAgentRevision(ctx context.Context, agent *models.Agent, changelog string) (*models.AgentRevision, error)
+	// ListAgentRevisions returns the revisions of an agent, newest first.
+	ListAgentRevisions(ctx context.Context, agentID string) ([]*models.AgentRevision, error)
+	GetAgentRevision(ctx context.Context, agentID string, revision int) (*models.AgentRevision, error)
 	GetAgent(ctx context.Context, id string) (*models.Agent, error)
 	ListAgents(ctx context.Context) ([]*models.Agent, error)
 	DeleteAgent(ctx context.Context, id string) error
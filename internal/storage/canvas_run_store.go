@@ -0,0 +1,13 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/ClarionDev/clarion/internal/canvas"
+)
+
+type CanvasRunStore interface {
+	SaveCanvasRun(ctx context.Context, run *canvas.Run) error
+	GetCanvasRun(ctx context.Context, id string) (*canvas.Run, error)
+	ListCanvasRuns(ctx context.Context, canvasID string) ([]*canvas.Run, error)
+}
@@ -0,0 +1,14 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/ClarionDev/clarion/internal/canvas"
+)
+
+type CanvasStore interface {
+	SaveCanvas(ctx context.Context, c *canvas.Canvas) error
+	GetCanvas(ctx context.Context, id string) (*canvas.Canvas, error)
+	ListCanvases(ctx context.Context) ([]*canvas.Canvas, error)
+	DeleteCanvas(ctx context.Context, id string) error
+}
@@ -0,0 +1,14 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/ClarionDev/clarion/internal/models"
+)
+
+type ComparisonStore interface {
+	SaveComparison(ctx context.Context, comparison *models.Comparison) error
+	GetComparison(ctx context.Context, id string) (*models.Comparison, error)
+	// ListComparisons returns the comparisons of a project, newest first.
+	ListComparisons(ctx context.Context, projectID string) ([]*models.Comparison, error)
+}
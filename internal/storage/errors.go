@@ -0,0 +1,6 @@
+package storage
+
+import "errors"
+
+// ErrNotFound is wrapped by the stores' Get and lookup methods when no record matches.
+var ErrNotFound = errors.New("record not found")
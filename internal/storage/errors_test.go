@@ -0,0 +1,39 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ClarionDev/clarion/internal/storage"
+)
+
+func TestLookupsWrapErrNotFound(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	projects := storage.NewSQLiteProjectStore(db)
+
+	lookups := map[string]func() error{
+		"GetAgent": func() error {
+			_, err := storage.NewSQLiteAgentStore(db).GetAgent(ctx, "missing")
+			return err
+		},
+		"GetProject": func() error {
+			_, err := projects.GetProject(ctx, "missing")
+			return err
+		},
+		"GetProjectByPath": func() error {
+			_, err := projects.GetProjectByPath(ctx, "/missing")
+			return err
+		},
+		"GetLLMConfig": func() error {
+			_, err := storage.NewSQLiteLLMConfigStore(db, nil).GetLLMConfig(ctx, "missing")
+			return err
+		},
+	}
+	for name, lookup := range lookups {
+		if err := lookup(); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("%s error = %v, want ErrNotFound", name, err)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/ClarionDev/clarion/internal/models"
+)
+
+type EvalStore interface {
+	SaveEvalSuite(ctx context.Context, suite *models.EvalSuite) error
+	GetEvalSuite(ctx context.Context, id string) (*models.EvalSuite, error)
+	// ListEvalSuites returns the suites of an agent, oldest first.
+	ListEvalSuites(ctx context.Context, agentID string) ([]*models.EvalSuite, error)
+	// DeleteEvalSuite deletes a suite and its reports.
+	DeleteEvalSuite(ctx context.Context, id string) error
+
+	SaveEvalReport(ctx context.Context, report *models.EvalReport) error
+	GetEvalReport(ctx context.Context, id string) (*models.EvalReport, error)
+	// ListEvalReports returns the reports of a suite, newest first.
+	ListEvalReports(ctx context.Context, suiteID string) ([]*models.EvalReport, error)
+}
@@ -11,4 +11,7 @@ type LLMConfigStore interface {
 	GetLLMConfig(ctx context.Context, id string) (*models.LLMProviderConfig, error)
 	ListLLMConfigs(ctx context.Context) ([]*models.LLMProviderConfig, error)
 	DeleteLLMConfig(ctx context.Context, id string) error
+	// DecryptAPIKey returns the plaintext API key of a config loaded from the store.
+	// Providers call it when building a request; the key is not kept decrypted elsewhere.
+	DecryptAPIKey(config *models.LLMProviderConfig) (string, error)
 }
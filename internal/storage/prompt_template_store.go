@@ -0,0 +1,16 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/ClarionDev/clarion/internal/models"
+)
+
+type PromptTemplateStore interface {
+	SavePromptTemplate(ctx context.Context, t *models.PromptTemplate) error
+	GetPromptTemplate(ctx context.Context, id string) (*models.PromptTemplate, error)
+	// ListPromptTemplates returns every template, or only those tagged with tag if it is
+	// not empty.
+	ListPromptTemplates(ctx context.Context, tag string) ([]*models.PromptTemplate, error)
+	DeletePromptTemplate(ctx context.Context, id string) error
+}
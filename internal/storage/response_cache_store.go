@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/ClarionDev/clarion/internal/models"
+)
+
+type ResponseCacheStore interface {
+	// GetCachedResponse returns an entry that has not expired and counts the hit. A missing
+	// or expired entry is ErrNotFound.
+	GetCachedResponse(ctx context.Context, key string) (*models.CachedResponse, error)
+	SaveCachedResponse(ctx context.Context, entry *models.CachedResponse) error
+	// ListCachedResponses returns the entries without their outputs, newest first.
+	ListCachedResponses(ctx context.Context) ([]*models.CachedResponse, error)
+	DeleteCachedResponse(ctx context.Context, key string) error
+	// ClearResponseCache deletes every entry, or only the expired ones, and returns how
+	// many it deleted.
+	ClearResponseCache(ctx context.Context, expiredOnly bool) (int, error)
+}
@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DefaultCompactMinSize is the size in bytes from which CompactRuns moves a string out of a
+// run record.
+const DefaultCompactMinSize = 4096
+
+// blobKey marks a string moved into the blobs table: {"$blob": "<sha256>"}.
+const blobKey = "$blob"
+
+// keepInline are the run fields that stay in the record when it is compacted, because the
+// runs table indexes and searches them.
+var keepInline = map[string]bool{
+	"prompt":         true,
+	"agentName":      true,
+	"output.summary": true,
+}
+
+// compactRunData replaces every string of at least minSize bytes in a run record with a
+// blob marker. It returns the new record and the moved strings by hash, or nil when there
+// was nothing to move.
+func compactRunData(data string, minSize int) (string, map[string]string, error) {
+	doc, err := decodeRunData(data)
+	if err != nil {
+		return "", nil, err
+	}
+	blobs := map[string]string{}
+	doc = extractBlobs(doc, "", minSize, blobs)
+	if len(blobs) == 0 {
+		return data, nil, nil
+	}
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return "", nil, err
+	}
+	return string(out), blobs, nil
+}
+
+// decodeRunData decodes a run record, keeping numbers as written so that re-encoding the
+// record does not round large integers through float64.
+func decodeRunData(data string) (any, error) {
+	dec := json.NewDecoder(strings.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func extractBlobs(v any, path string, minSize int, blobs map[string]string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, child := range v {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			v[key] = extractBlobs(child, childPath, minSize, blobs)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = extractBlobs(child, path, minSize, blobs)
+		}
+	case string:
+		if len(v) >= minSize && !keepInline[path] {
+			sum := sha256.Sum256([]byte(v))
+			hash := hex.EncodeToString(sum[:])
+			blobs[hash] = v
+			return map[string]any{blobKey: hash}
+		}
+	}
+	return v
+}
+
+// inlineBlobs puts the contents of the blobs a compacted run record refers to back in place.
+func inlineBlobs(ctx context.Context, db *sql.DB, data string) (string, error) {
+	if !strings.Contains(data, `"`+blobKey+`"`) {
+		return data, nil
+	}
+	doc, err := decodeRunData(data)
+	if err != nil {
+		return "", err
+	}
+	doc = replaceBlobs(doc, func(hash string) (string, error) {
+		var content string
+		if err := db.QueryRowContext(ctx, `SELECT content FROM blobs WHERE hash = ?;`, hash).Scan(&content); err != nil {
+			return "", fmt.Errorf("failed to read blob %s: %w", hash, err)
+		}
+		return content, nil
+	}, &err)
+	if err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(doc)
+	return string(out), err
+}
+
+func replaceBlobs(v any, load func(hash string) (string, error), errp *error) any {
+	switch v := v.(type) {
+	case map[string]any:
+		if hash, ok := v[blobKey].(string); ok && len(v) == 1 {
+			content, err := load(hash)
+			if err != nil && *errp == nil {
+				*errp = err
+			}
+			return content
+		}
+		for key, child := range v {
+			v[key] = replaceBlobs(child, load, errp)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = replaceBlobs(child, load, errp)
+		}
+	}
+	return v
+}
+
+// deleteOrphanBlobs deletes the blobs no run refers to any more.
+func deleteOrphanBlobs(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM blobs WHERE hash NOT IN (SELECT hash FROM run_blobs);`)
+	return err
+}
@@ -2,11 +2,82 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/ClarionDev/clarion/internal/models"
 )
 
 type RunStore interface {
 	SaveRun(ctx context.Context, run *models.Run) error
-	ListRunsByProject(ctx context.Context, projectID string) ([]*models.Run, error)
+	GetRun(ctx context.Context, id string) (*models.Run, error)
+	// ListRuns returns a page of the runs matching filter, newest first.
+	ListRuns(ctx context.Context, filter RunFilter) (*RunPage, error)
+	// DeleteRun deletes a run, returning an error wrapping ErrNotFound when there is none.
+	DeleteRun(ctx context.Context, id string) error
+	// DeleteRuns deletes the runs with the given IDs and returns how many there were.
+	DeleteRuns(ctx context.Context, ids []string) (int, error)
+	// PruneRuns deletes the runs the policy does not keep and returns their IDs.
+	PruneRuns(ctx context.Context, policy RetentionPolicy) ([]string, error)
+	// CompactRuns moves strings of at least minSize bytes, such as file contents, out of run
+	// records into blobs shared by every run with the same content. GetRun puts them back.
+	CompactRuns(ctx context.Context, minSize int) (*CompactResult, error)
+	// Vacuum rebuilds the database file, giving the space freed by deleted records back to
+	// the file system.
+	Vacuum(ctx context.Context) (*VacuumResult, error)
+}
+
+// RetentionPolicy limits which runs are kept. Zero fields keep every run.
+type RetentionPolicy struct {
+	// MaxAge is how long a run is kept after it was first saved.
+	MaxAge time.Duration
+	// MaxRunsPerProject is how many of each project's newest runs are kept.
+	MaxRunsPerProject int
+}
+
+// CompactResult reports a CompactRuns pass.
+type CompactResult struct {
+	// Runs is the number of run records that were compacted.
+	Runs int `json:"runs"`
+	// Blobs is the number of blobs added; content already stored is not added again.
+	Blobs int `json:"blobs"`
+	// BytesSaved is how much smaller the compacted run records and the added blobs are
+	// than the records were.
+	BytesSaved int64 `json:"bytes_saved"`
+}
+
+// VacuumResult reports the size of the database file before and after Vacuum.
+type VacuumResult struct {
+	SizeBefore int64 `json:"size_before"`
+	SizeAfter  int64 `json:"size_after"`
+}
+
+// DefaultRunPageSize and MaxRunPageSize bound RunFilter.Limit.
+const (
+	DefaultRunPageSize = 50
+	MaxRunPageSize     = 200
+)
+
+// RunFilter selects runs. Empty fields match every run.
+type RunFilter struct {
+	ProjectID string
+	AgentID   string
+	Status    string
+	// Since and Until bound the time the run was first saved; Until is exclusive.
+	Since time.Time
+	Until time.Time
+	// Query is free text matched against the prompt and summary. Every word must appear,
+	// as a whole word or as the start of one.
+	Query string
+	// Limit is the page size, DefaultRunPageSize when zero; Offset is the number of
+	// matching runs to skip.
+	Limit  int
+	Offset int
+}
+
+// RunPage is one page of runs. Total counts every run that matches the filter.
+type RunPage struct {
+	Runs   []*models.RunSummary `json:"runs"`
+	Total  int                  `json:"total"`
+	Limit  int                  `json:"limit"`
+	Offset int                  `json:"offset"`
 }
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"time"
+
 	"github.com/ClarionDev/clarion/internal/models"
 )
 
@@ -17,16 +19,118 @@ func NewSQLiteAgentStore(db *sql.DB) *SQLiteAgentStore {
 }
 
 func (s *SQLiteAgentStore) SaveAgent(ctx context.Context, agent *models.Agent) error {
-	agentData, err := json.Marshal(agent)
+	_, err := s.SaveAgentRevision(ctx, agent, "")
+	return err
+}
+
+func (s *SQLiteAgentStore) SaveAgentRevision(ctx context.Context, agent *models.Agent, changelog string) (*models.AgentRevision, error) {
+	agent.Revision = 0
+	revisionData, err := json.Marshal(agent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal agent: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to marshal agent: %w", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	latest, err := scanAgentRevision(tx.QueryRowContext(ctx, `SELECT agent_id, revision, agent_data, changelog, created_at FROM agent_revisions
+			  WHERE agent_id = ? ORDER BY revision DESC LIMIT 1;`, agent.Profile.ID))
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	if latest == nil || string(revisionData) != latest.data {
+		next := 1
+		if latest != nil {
+			next = latest.Revision + 1
+		}
+		latest = &agentRevisionRow{AgentRevision: models.AgentRevision{
+			AgentID:   agent.Profile.ID,
+			Revision:  next,
+			Changelog: changelog,
+			CreatedAt: time.Now().UTC(),
+		}, data: string(revisionData)}
+		query := `INSERT INTO agent_revisions (agent_id, revision, agent_data, changelog, created_at) VALUES (?, ?, ?, ?, ?);`
+		if _, err := tx.ExecContext(ctx, query, agent.Profile.ID, next, latest.data, changelog, latest.CreatedAt); err != nil {
+			return nil, err
+		}
 	}
 
+	agent.Revision = latest.Revision
+	agentData, err := json.Marshal(agent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal agent: %w", err)
+	}
 	query := `INSERT INTO agents (id, agent_data) VALUES (?, ?)
 			  ON CONFLICT(id) DO UPDATE SET agent_data = excluded.agent_data, updated_at = CURRENT_TIMESTAMP;`
+	if _, err := tx.ExecContext(ctx, query, agent.Profile.ID, string(agentData)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return latest.revision()
+}
 
-	_, err = s.db.ExecContext(ctx, query, agent.Profile.ID, string(agentData))
-	return err
+func (s *SQLiteAgentStore) ListAgentRevisions(ctx context.Context, agentID string) ([]*models.AgentRevision, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT agent_id, revision, agent_data, changelog, created_at FROM agent_revisions
+			  WHERE agent_id = ? ORDER BY revision DESC;`, agentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*models.AgentRevision{}
+	for rows.Next() {
+		row, err := scanAgentRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revision, err := row.revision()
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+func (s *SQLiteAgentStore) GetAgentRevision(ctx context.Context, agentID string, revision int) (*models.AgentRevision, error) {
+	row, err := scanAgentRevision(s.db.QueryRowContext(ctx, `SELECT agent_id, revision, agent_data, changelog, created_at FROM agent_revisions
+			  WHERE agent_id = ? AND revision = ?;`, agentID, revision))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("revision %d of agent '%s': %w", revision, agentID, ErrNotFound)
+		}
+		return nil, err
+	}
+	return row.revision()
+}
+
+// agentRevisionRow is a revision as stored, with the agent still encoded.
+type agentRevisionRow struct {
+	models.AgentRevision
+	data string
+}
+
+func scanAgentRevision(row interface{ Scan(...any) error }) (*agentRevisionRow, error) {
+	var r agentRevisionRow
+	if err := row.Scan(&r.AgentID, &r.Revision, &r.data, &r.Changelog, &r.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (r *agentRevisionRow) revision() (*models.AgentRevision, error) {
+	revision := r.AgentRevision
+	if err := json.Unmarshal([]byte(r.data), &revision.Agent); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal agent revision: %w", err)
+	}
+	revision.Agent.Revision = revision.Revision
+	return &revision, nil
 }
 
 func (s *SQLiteAgentStore) GetAgent(ctx context.Context, id string) (*models.Agent, error) {
@@ -34,6 +138,9 @@ func (s *SQLiteAgentStore) GetAgent(ctx context.Context, id string) (*models.Age
 	query := `SELECT agent_data FROM agents WHERE id = ?;`
 	err := s.db.QueryRowContext(ctx, query, id).Scan(&agentData)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("agent with id '%s': %w", id, ErrNotFound)
+		}
 		return nil, err
 	}
 
@@ -0,0 +1,59 @@
+package storage_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ClarionDev/clarion/internal/agent"
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/storage"
+)
+
+func TestSaveAgentRevision(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewSQLiteAgentStore(newTestDB(t))
+	a := &models.Agent{Profile: agent.AgentProfile{ID: "a1", Name: "Agent"}, SystemPrompt: "one"}
+
+	save := func(changelog string, want int) {
+		t.Helper()
+		rev, err := store.SaveAgentRevision(ctx, a, changelog)
+		if err != nil {
+			t.Fatalf("SaveAgentRevision(%q) error = %v", changelog, err)
+		}
+		if rev.Revision != want || a.Revision != want {
+			t.Fatalf("SaveAgentRevision(%q) = revision %d, agent at %d; want %d", changelog, rev.Revision, a.Revision, want)
+		}
+	}
+	save("First", 1)
+	// An unchanged agent adds no revision.
+	save("Same", 1)
+	a.SystemPrompt = "two"
+	save("Second", 2)
+
+	got, err := store.GetAgent(ctx, "a1")
+	if err != nil || got.Revision != 2 || got.SystemPrompt != "two" {
+		t.Fatalf("GetAgent() = %+v, %v; want revision 2", got, err)
+	}
+
+	revisions, err := store.ListAgentRevisions(ctx, "a1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 2 || revisions[0].Revision != 2 || revisions[1].Revision != 1 {
+		t.Fatalf("ListAgentRevisions() = %d revisions, want 2 newest first", len(revisions))
+	}
+	if revisions[0].Changelog != "Second" || revisions[1].Agent.SystemPrompt != "one" {
+		t.Errorf("revisions = %+v, %+v; want the saved changelog and agent", revisions[0], revisions[1])
+	}
+
+	first, err := store.GetAgentRevision(ctx, "a1", 1)
+	if err != nil || first.Agent.SystemPrompt != "one" || first.Changelog != "First" {
+		t.Errorf("GetAgentRevision(1) = %+v, %v; want the first revision", first, err)
+	}
+	if _, err := store.GetAgentRevision(ctx, "a1", 3); err == nil {
+		t.Error("GetAgentRevision(3) succeeded, want an error")
+	}
+	if revisions, err := store.ListAgentRevisions(ctx, "other"); err != nil || len(revisions) != 0 {
+		t.Errorf("ListAgentRevisions(other) = %v, %v; want none", revisions, err)
+	}
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ClarionDev/clarion/internal/canvas"
+)
+
+type SQLiteCanvasRunStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteCanvasRunStore(db *sql.DB) *SQLiteCanvasRunStore {
+	return &SQLiteCanvasRunStore{db: db}
+}
+
+func (s *SQLiteCanvasRunStore) SaveCanvasRun(ctx context.Context, run *canvas.Run) error {
+	runData, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to marshal canvas run: %w", err)
+	}
+
+	query := `INSERT INTO canvas_runs (id, canvas_id, run_data) VALUES (?, ?, ?)
+			  ON CONFLICT(id) DO UPDATE SET run_data = excluded.run_data, updated_at = CURRENT_TIMESTAMP;`
+
+	_, err = s.db.ExecContext(ctx, query, run.ID, run.CanvasID, string(runData))
+	return err
+}
+
+func (s *SQLiteCanvasRunStore) GetCanvasRun(ctx context.Context, id string) (*canvas.Run, error) {
+	var runData string
+	query := `SELECT run_data FROM canvas_runs WHERE id = ?;`
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&runData)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("canvas run with id '%s': %w", id, ErrNotFound)
+		}
+		return nil, err
+	}
+
+	var run canvas.Run
+	if err := json.Unmarshal([]byte(runData), &run); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal canvas run: %w", err)
+	}
+	return &run, nil
+}
+
+// ListCanvasRuns returns the runs of a canvas, newest first.
+func (s *SQLiteCanvasRunStore) ListCanvasRuns(ctx context.Context, canvasID string) ([]*canvas.Run, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT run_data FROM canvas_runs WHERE canvas_id = ? ORDER BY created_at DESC;`, canvasID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []*canvas.Run{}
+	for rows.Next() {
+		var runData string
+		if err := rows.Scan(&runData); err != nil {
+			return nil, err
+		}
+
+		var run canvas.Run
+		if err := json.Unmarshal([]byte(runData), &run); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal canvas run: %w", err)
+		}
+		runs = append(runs, &run)
+	}
+	return runs, rows.Err()
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ClarionDev/clarion/internal/canvas"
+)
+
+type SQLiteCanvasStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteCanvasStore(db *sql.DB) *SQLiteCanvasStore {
+	return &SQLiteCanvasStore{db: db}
+}
+
+// SaveCanvas inserts or replaces a canvas. CreatedAt is kept from the stored copy and
+// UpdatedAt is set to the current time.
+func (s *SQLiteCanvasStore) SaveCanvas(ctx context.Context, c *canvas.Canvas) error {
+	now := time.Now().UTC()
+	c.UpdatedAt = now
+	if existing, err := s.GetCanvas(ctx, c.ID); err == nil {
+		c.CreatedAt = existing.CreatedAt
+	} else if !errors.Is(err, ErrNotFound) {
+		return err
+	} else if c.CreatedAt.IsZero() {
+		c.CreatedAt = now
+	}
+
+	canvasData, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to marshal canvas: %w", err)
+	}
+
+	query := `INSERT INTO canvases (id, canvas_data) VALUES (?, ?)
+			  ON CONFLICT(id) DO UPDATE SET canvas_data = excluded.canvas_data, updated_at = CURRENT_TIMESTAMP;`
+
+	_, err = s.db.ExecContext(ctx, query, c.ID, string(canvasData))
+	return err
+}
+
+func (s *SQLiteCanvasStore) GetCanvas(ctx context.Context, id string) (*canvas.Canvas, error) {
+	var canvasData string
+	query := `SELECT canvas_data FROM canvases WHERE id = ?;`
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&canvasData)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("canvas with id '%s': %w", id, ErrNotFound)
+		}
+		return nil, err
+	}
+
+	var c canvas.Canvas
+	if err := json.Unmarshal([]byte(canvasData), &c); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal canvas: %w", err)
+	}
+	return &c, nil
+}
+
+func (s *SQLiteCanvasStore) ListCanvases(ctx context.Context) ([]*canvas.Canvas, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT canvas_data FROM canvases ORDER BY created_at;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	canvases := []*canvas.Canvas{}
+	for rows.Next() {
+		var canvasData string
+		if err := rows.Scan(&canvasData); err != nil {
+			return nil, err
+		}
+
+		var c canvas.Canvas
+		if err := json.Unmarshal([]byte(canvasData), &c); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal canvas: %w", err)
+		}
+		canvases = append(canvases, &c)
+	}
+	return canvases, rows.Err()
+}
+
+func (s *SQLiteCanvasStore) DeleteCanvas(ctx context.Context, id string) error {
+	query := `DELETE FROM canvases WHERE id = ?;`
+	_, err := s.db.ExecContext(ctx, query, id)
+	return err
+}
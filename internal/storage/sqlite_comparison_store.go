@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ClarionDev/clarion/internal/models"
+)
+
+type SQLiteComparisonStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteComparisonStore(db *sql.DB) *SQLiteComparisonStore {
+	return &SQLiteComparisonStore{db: db}
+}
+
+func (s *SQLiteComparisonStore) SaveComparison(ctx context.Context, comparison *models.Comparison) error {
+	data, err := json.Marshal(comparison)
+	if err != nil {
+		return fmt.Errorf("failed to marshal comparison: %w", err)
+	}
+
+	var projectID any
+	if comparison.ProjectID != "" {
+		projectID = comparison.ProjectID
+	}
+	query := `INSERT INTO comparisons (id, project_id, comparison_data) VALUES (?, ?, ?)
+			  ON CONFLICT(id) DO UPDATE SET comparison_data = excluded.comparison_data;`
+	_, err = s.db.ExecContext(ctx, query, comparison.ID, projectID, string(data))
+	return err
+}
+
+func (s *SQLiteComparisonStore) GetComparison(ctx context.Context, id string) (*models.Comparison, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, `SELECT comparison_data FROM comparisons WHERE id = ?;`, id).Scan(&data)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("comparison with id '%s': %w", id, ErrNotFound)
+		}
+		return nil, err
+	}
+
+	var comparison models.Comparison
+	if err := json.Unmarshal([]byte(data), &comparison); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal comparison: %w", err)
+	}
+	return &comparison, nil
+}
+
+func (s *SQLiteComparisonStore) ListComparisons(ctx context.Context, projectID string) ([]*models.Comparison, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT comparison_data FROM comparisons WHERE project_id = ? ORDER BY created_at DESC, rowid DESC;`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comparisons := []*models.Comparison{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var comparison models.Comparison
+		if err := json.Unmarshal([]byte(data), &comparison); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal comparison: %w", err)
+		}
+		comparisons = append(comparisons, &comparison)
+	}
+	return comparisons, rows.Err()
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ClarionDev/clarion/internal/models"
+)
+
+type SQLiteEvalStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteEvalStore(db *sql.DB) *SQLiteEvalStore {
+	return &SQLiteEvalStore{db: db}
+}
+
+// SaveEvalSuite inserts or replaces a suite. CreatedAt is kept from the stored copy and
+// UpdatedAt is set to the current time.
+func (s *SQLiteEvalStore) SaveEvalSuite(ctx context.Context, suite *models.EvalSuite) error {
+	now := time.Now().UTC()
+	suite.UpdatedAt = now
+	if existing, err := s.GetEvalSuite(ctx, suite.ID); err == nil {
+		suite.CreatedAt = existing.CreatedAt
+	} else if !errors.Is(err, ErrNotFound) {
+		return err
+	} else if suite.CreatedAt.IsZero() {
+		suite.CreatedAt = now
+	}
+
+	suiteData, err := json.Marshal(suite)
+	if err != nil {
+		return fmt.Errorf("failed to marshal eval suite: %w", err)
+	}
+
+	query := `INSERT INTO eval_suites (id, agent_id, suite_data) VALUES (?, ?, ?)
+			  ON CONFLICT(id) DO UPDATE SET agent_id = excluded.agent_id, suite_data = excluded.suite_data, updated_at = CURRENT_TIMESTAMP;`
+	_, err = s.db.ExecContext(ctx, query, suite.ID, suite.AgentID, string(suiteData))
+	return err
+}
+
+func (s *SQLiteEvalStore) GetEvalSuite(ctx context.Context, id string) (*models.EvalSuite, error) {
+	var suiteData string
+	err := s.db.QueryRowContext(ctx, `SELECT suite_data FROM eval_suites WHERE id = ?;`, id).Scan(&suiteData)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("eval suite with id '%s': %w", id, ErrNotFound)
+		}
+		return nil, err
+	}
+
+	var suite models.EvalSuite
+	if err := json.Unmarshal([]byte(suiteData), &suite); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal eval suite: %w", err)
+	}
+	return &suite, nil
+}
+
+func (s *SQLiteEvalStore) ListEvalSuites(ctx context.Context, agentID string) ([]*models.EvalSuite, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT suite_data FROM eval_suites WHERE agent_id = ? ORDER BY created_at, rowid;`, agentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suites := []*models.EvalSuite{}
+	for rows.Next() {
+		var suiteData string
+		if err := rows.Scan(&suiteData); err != nil {
+			return nil, err
+		}
+		var suite models.EvalSuite
+		if err := json.Unmarshal([]byte(suiteData), &suite); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal eval suite: %w", err)
+		}
+		suites = append(suites, &suite)
+	}
+	return suites, rows.Err()
+}
+
+func (s *SQLiteEvalStore) DeleteEvalSuite(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM eval_suites WHERE id = ?;`, id)
+	return err
+}
+
+func (s *SQLiteEvalStore) SaveEvalReport(ctx context.Context, report *models.EvalReport) error {
+	if report.CreatedAt.IsZero() {
+		report.CreatedAt = time.Now().UTC()
+	}
+	reportData, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to marshal eval report: %w", err)
+	}
+
+	query := `INSERT INTO eval_reports (id, suite_id, agent_revision, report_data) VALUES (?, ?, ?, ?)
+			  ON CONFLICT(id) DO UPDATE SET report_data = excluded.report_data;`
+	_, err = s.db.ExecContext(ctx, query, report.ID, report.SuiteID, report.AgentRevision, string(reportData))
+	return err
+}
+
+func (s *SQLiteEvalStore) GetEvalReport(ctx context.Context, id string) (*models.EvalReport, error) {
+	var reportData string
+	err := s.db.QueryRowContext(ctx, `SELECT report_data FROM eval_reports WHERE id = ?;`, id).Scan(&reportData)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("eval report with id '%s': %w", id, ErrNotFound)
+		}
+		return nil, err
+	}
+
+	var report models.EvalReport
+	if err := json.Unmarshal([]byte(reportData), &report); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal eval report: %w", err)
+	}
+	return &report, nil
+}
+
+func (s *SQLiteEvalStore) ListEvalReports(ctx context.Context, suiteID string) ([]*models.EvalReport, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT report_data FROM eval_reports WHERE suite_id = ? ORDER BY created_at DESC, rowid DESC;`, suiteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []*models.EvalReport{}
+	for rows.Next() {
+		var reportData string
+		if err := rows.Scan(&reportData); err != nil {
+			return nil, err
+		}
+		var report models.EvalReport
+		if err := json.Unmarshal([]byte(reportData), &report); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal eval report: %w", err)
+		}
+		reports = append(reports, &report)
+	}
+	return reports, rows.Err()
+}
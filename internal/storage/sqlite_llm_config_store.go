@@ -5,19 +5,35 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log"
+
 	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/secrets"
 )
 
 type SQLiteLLMConfigStore struct {
-	db *sql.DB
+	db    *sql.DB
+	vault *secrets.Vault
 }
 
-func NewSQLiteLLMConfigStore(db *sql.DB) *SQLiteLLMConfigStore {
-	return &SQLiteLLMConfigStore{db: db}
+func NewSQLiteLLMConfigStore(db *sql.DB, vault *secrets.Vault) *SQLiteLLMConfigStore {
+	return &SQLiteLLMConfigStore{db: db, vault: vault}
 }
 
 func (s *SQLiteLLMConfigStore) SaveLLMConfig(ctx context.Context, config *models.LLMProviderConfig) error {
-	configData, err := json.Marshal(config)
+	toStore := *config
+
+	// Clients only ever see the masked key, so saving a config back unchanged sends the
+	// hint. Keep the stored key in that case instead of overwriting it with the mask.
+	existing, err := s.GetLLMConfig(ctx, config.ID)
+	if err == nil && existing.APIKeyHint != "" && config.APIKey == existing.APIKeyHint {
+		toStore.APIKey = existing.APIKey
+		toStore.APIKeyHint = existing.APIKeyHint
+	} else if err := s.sealAPIKey(&toStore); err != nil {
+		return err
+	}
+
+	configData, err := json.Marshal(toStore)
 	if err != nil {
 		return fmt.Errorf("failed to marshal llm config: %w", err)
 	}
@@ -29,13 +45,27 @@ func (s *SQLiteLLMConfigStore) SaveLLMConfig(ctx context.Context, config *models
 	return err
 }
 
+// sealAPIKey encrypts a plaintext key in place and records its masked hint.
+func (s *SQLiteLLMConfigStore) sealAPIKey(config *models.LLMProviderConfig) error {
+	if config.APIKey == "" || secrets.IsSealed(config.APIKey) {
+		return nil
+	}
+	config.APIKeyHint = secrets.MaskKey(config.APIKey)
+	sealed, err := s.vault.Seal(config.APIKey)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt API key: %w", err)
+	}
+	config.APIKey = sealed
+	return nil
+}
+
 func (s *SQLiteLLMConfigStore) GetLLMConfig(ctx context.Context, id string) (*models.LLMProviderConfig, error) {
 	var configData string
 	query := `SELECT config_data FROM llm_configs WHERE id = ?;`
 	err := s.db.QueryRowContext(ctx, query, id).Scan(&configData)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("llm config with id '%s' not found", id)
+			return nil, fmt.Errorf("llm config with id '%s': %w", id, ErrNotFound)
 		}
 		return nil, err
 	}
@@ -79,3 +109,25 @@ func (s *SQLiteLLMConfigStore) DeleteLLMConfig(ctx context.Context, id string) e
 	_, err := s.db.ExecContext(ctx, query, id)
 	return err
 }
+
+func (s *SQLiteLLMConfigStore) DecryptAPIKey(config *models.LLMProviderConfig) (string, error) {
+	return s.vault.Open(config.APIKey)
+}
+
+// SealPlaintextKeys encrypts API keys that were stored before encryption at rest existed.
+func (s *SQLiteLLMConfigStore) SealPlaintextKeys(ctx context.Context) error {
+	configs, err := s.ListLLMConfigs(ctx)
+	if err != nil {
+		return err
+	}
+	for _, config := range configs {
+		if config.APIKey == "" || secrets.IsSealed(config.APIKey) {
+			continue
+		}
+		if err := s.SaveLLMConfig(ctx, config); err != nil {
+			return fmt.Errorf("failed to encrypt API key of llm config '%s': %w", config.ID, err)
+		}
+		log.Printf("Encrypted the stored API key of LLM config '%s'.", config.Name)
+	}
+	return nil
+}
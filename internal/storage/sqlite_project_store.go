@@ -34,6 +34,9 @@ func (s *SQLiteProjectStore) GetProject(ctx context.Context, id string) (*models
 	query := `SELECT project_data FROM projects WHERE id = ?;`
 	err := s.db.QueryRowContext(ctx, query, id).Scan(&projectData)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("project with id '%s': %w", id, ErrNotFound)
+		}
 		return nil, err
 	}
 
@@ -56,7 +59,7 @@ func (s *SQLiteProjectStore) GetProjectByPath(ctx context.Context, path string)
 			return p, nil
 		}
 	}
-	return nil, sql.ErrNoRows
+	return nil, fmt.Errorf("project with path '%s': %w", path, ErrNotFound)
 }
 
 func (s *SQLiteProjectStore) ListProjects(ctx context.Context) ([]*models.Project, error) {
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ClarionDev/clarion/internal/models"
+)
+
+type SQLitePromptTemplateStore struct {
+	db *sql.DB
+}
+
+func NewSQLitePromptTemplateStore(db *sql.DB) *SQLitePromptTemplateStore {
+	return &SQLitePromptTemplateStore{db: db}
+}
+
+// SavePromptTemplate inserts or replaces a template. CreatedAt is kept from the stored copy
+// and UpdatedAt is set to the current time.
+func (s *SQLitePromptTemplateStore) SavePromptTemplate(ctx context.Context, t *models.PromptTemplate) error {
+	now := time.Now().UTC()
+	t.UpdatedAt = now
+	if existing, err := s.GetPromptTemplate(ctx, t.ID); err == nil {
+		t.CreatedAt = existing.CreatedAt
+	} else if !errors.Is(err, ErrNotFound) {
+		return err
+	} else if t.CreatedAt.IsZero() {
+		t.CreatedAt = now
+	}
+
+	templateData, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("failed to marshal prompt template: %w", err)
+	}
+
+	query := `INSERT INTO prompt_templates (id, template_data) VALUES (?, ?)
+			  ON CONFLICT(id) DO UPDATE SET template_data = excluded.template_data, updated_at = CURRENT_TIMESTAMP;`
+
+	_, err = s.db.ExecContext(ctx, query, t.ID, string(templateData))
+	return err
+}
+
+func (s *SQLitePromptTemplateStore) GetPromptTemplate(ctx context.Context, id string) (*models.PromptTemplate, error) {
+	var templateData string
+	query := `SELECT template_data FROM prompt_templates WHERE id = ?;`
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&templateData)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("prompt template with id '%s': %w", id, ErrNotFound)
+		}
+		return nil, err
+	}
+
+	var t models.PromptTemplate
+	if err := json.Unmarshal([]byte(templateData), &t); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal prompt template: %w", err)
+	}
+	return &t, nil
+}
+
+func (s *SQLitePromptTemplateStore) ListPromptTemplates(ctx context.Context, tag string) ([]*models.PromptTemplate, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT template_data FROM prompt_templates ORDER BY created_at;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []*models.PromptTemplate{}
+	for rows.Next() {
+		var templateData string
+		if err := rows.Scan(&templateData); err != nil {
+			return nil, err
+		}
+
+		var t models.PromptTemplate
+		if err := json.Unmarshal([]byte(templateData), &t); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal prompt template: %w", err)
+		}
+		if tag != "" && !slices.Contains(t.Tags, tag) {
+			continue
+		}
+		templates = append(templates, &t)
+	}
+	return templates, rows.Err()
+}
+
+func (s *SQLitePromptTemplateStore) DeletePromptTemplate(ctx context.Context, id string) error {
+	query := `DELETE FROM prompt_templates WHERE id = ?;`
+	_, err := s.db.ExecContext(ctx, query, id)
+	return err
+}
@@ -0,0 +1,71 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ClarionDev/clarion/internal/message"
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/storage"
+)
+
+func TestPromptTemplateStore(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewSQLitePromptTemplateStore(newTestDB(t))
+
+	if _, err := store.GetPromptTemplate(ctx, "missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("GetPromptTemplate(missing) error = %v, want ErrNotFound", err)
+	}
+
+	review := &models.PromptTemplate{
+		ID:   "review",
+		Name: "Review",
+		Tags: []string{"review"},
+		Messages: []models.Message{
+			{Role: message.SYSTEM_MESSAGE, Content: "{{system_instruction}}"},
+			{Role: message.USER_MESSAGE, Content: "{{context}}\n{{prompt}}"},
+		},
+	}
+	if err := store.SavePromptTemplate(ctx, review); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SavePromptTemplate(ctx, &models.PromptTemplate{ID: "plain", Name: "Plain", Tags: []string{"other"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.GetPromptTemplate(ctx, "review")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Review" || len(got.Messages) != 2 || got.Messages[1].Role != message.USER_MESSAGE || got.CreatedAt.IsZero() {
+		t.Fatalf("GetPromptTemplate() = %+v, want the saved template", got)
+	}
+	created := got.CreatedAt
+
+	review.Name = "Code review"
+	review.CreatedAt = created.AddDate(1, 0, 0)
+	if err := store.SavePromptTemplate(ctx, review); err != nil {
+		t.Fatal(err)
+	}
+	got, err = store.GetPromptTemplate(ctx, "review")
+	if err != nil || got.Name != "Code review" || !got.CreatedAt.Equal(created) || got.UpdatedAt.Before(created) {
+		t.Errorf("updated template = %+v, %v; want the new name and the original creation time", got, err)
+	}
+
+	all, err := store.ListPromptTemplates(ctx, "")
+	if err != nil || len(all) != 2 {
+		t.Fatalf("ListPromptTemplates() = %d templates, %v; want 2", len(all), err)
+	}
+	tagged, err := store.ListPromptTemplates(ctx, "review")
+	if err != nil || len(tagged) != 1 || tagged[0].ID != "review" {
+		t.Errorf("ListPromptTemplates(review) = %v, %v; want the review template", tagged, err)
+	}
+
+	if err := store.DeletePromptTemplate(ctx, "review"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetPromptTemplate(ctx, "review"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetPromptTemplate() after delete error = %v, want ErrNotFound", err)
+	}
+}
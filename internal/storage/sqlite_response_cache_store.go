@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ClarionDev/clarion/internal/models"
+)
+
+type SQLiteResponseCacheStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteResponseCacheStore(db *sql.DB) *SQLiteResponseCacheStore {
+	return &SQLiteResponseCacheStore{db: db}
+}
+
+func (s *SQLiteResponseCacheStore) GetCachedResponse(ctx context.Context, key string) (*models.CachedResponse, error) {
+	now := time.Now().UTC().Format(runTimeLayout)
+	res, err := s.db.ExecContext(ctx, `UPDATE response_cache SET hits = hits + 1 WHERE key = ? AND expires_at > ?;`, key, now)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, fmt.Errorf("cached response with key '%s': %w", key, ErrNotFound)
+	}
+
+	var entry models.CachedResponse
+	var output, createdAt, expiresAt string
+	err = s.db.QueryRowContext(ctx, `SELECT key, provider, model, output, hits, created_at, expires_at FROM response_cache WHERE key = ?;`, key).
+		Scan(&entry.Key, &entry.Provider, &entry.Model, &output, &entry.Hits, &createdAt, &expiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("cached response with key '%s': %w", key, ErrNotFound)
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(output), &entry.Output); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cached response: %w", err)
+	}
+	entry.Size = len(output)
+	if err := scanCacheTimes(&entry, createdAt, expiresAt); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// SaveCachedResponse inserts or replaces an entry. A replaced entry starts again with no
+// hits.
+func (s *SQLiteResponseCacheStore) SaveCachedResponse(ctx context.Context, entry *models.CachedResponse) error {
+	output, err := json.Marshal(entry.Output)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cached response: %w", err)
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now().UTC()
+	}
+	entry.Size = len(output)
+
+	query := `INSERT INTO response_cache (key, provider, model, output, hits, created_at, expires_at) VALUES (?, ?, ?, ?, 0, ?, ?)
+			  ON CONFLICT(key) DO UPDATE SET provider = excluded.provider, model = excluded.model, output = excluded.output,
+			  hits = 0, created_at = excluded.created_at, expires_at = excluded.expires_at;`
+	_, err = s.db.ExecContext(ctx, query, entry.Key, entry.Provider, entry.Model, string(output),
+		entry.CreatedAt.UTC().Format(runTimeLayout), entry.ExpiresAt.UTC().Format(runTimeLayout))
+	return err
+}
+
+func (s *SQLiteResponseCacheStore) ListCachedResponses(ctx context.Context) ([]*models.CachedResponse, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT key, provider, model, length(output), hits, created_at, expires_at FROM response_cache ORDER BY created_at DESC, rowid DESC;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*models.CachedResponse{}
+	for rows.Next() {
+		var entry models.CachedResponse
+		var createdAt, expiresAt string
+		if err := rows.Scan(&entry.Key, &entry.Provider, &entry.Model, &entry.Size, &entry.Hits, &createdAt, &expiresAt); err != nil {
+			return nil, err
+		}
+		if err := scanCacheTimes(&entry, createdAt, expiresAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, rows.Err()
+}
+
+func (s *SQLiteResponseCacheStore) DeleteCachedResponse(ctx context.Context, key string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM response_cache WHERE key = ?;`, key)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("cached response with key '%s': %w", key, ErrNotFound)
+	}
+	return nil
+}
+
+func (s *SQLiteResponseCacheStore) ClearResponseCache(ctx context.Context, expiredOnly bool) (int, error) {
+	query, args := `DELETE FROM response_cache;`, []any{}
+	if expiredOnly {
+		query, args = `DELETE FROM response_cache WHERE expires_at <= ?;`, []any{time.Now().UTC().Format(runTimeLayout)}
+	}
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func scanCacheTimes(entry *models.CachedResponse, createdAt, expiresAt string) error {
+	var err error
+	if entry.CreatedAt, err = parseRunTime(createdAt); err != nil {
+		return err
+	}
+	entry.ExpiresAt, err = parseRunTime(expiresAt)
+	return err
+}
@@ -3,6 +3,10 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/ClarionDev/clarion/internal/models"
 )
@@ -15,28 +19,312 @@ func NewSQLiteRunStore(db *sql.DB) *SQLiteRunStore {
 	return &SQLiteRunStore{db: db}
 }
 
+// SaveRun stores a run. A saved record holds its full content again, so the blobs a
+// compacted version of it referred to are released.
 func (s *SQLiteRunStore) SaveRun(ctx context.Context, run *models.Run) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `INSERT INTO runs (id, project_id, run_data) VALUES (?, ?, ?)
 			  ON CONFLICT(id) DO UPDATE SET run_data = excluded.run_data;`
+	if _, err := tx.ExecContext(ctx, query, run.ID, run.ProjectID, run.RunData); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM run_blobs WHERE run_id = ?;`, run.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteRunStore) GetRun(ctx context.Context, id string) (*models.Run, error) {
+	var run models.Run
+	query := `SELECT id, project_id, run_data FROM runs WHERE id = ?;`
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&run.ID, &run.ProjectID, &run.RunData)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("run with id '%s': %w", id, ErrNotFound)
+		}
+		return nil, err
+	}
+	if run.RunData, err = inlineBlobs(ctx, s.db, run.RunData); err != nil {
+		return nil, fmt.Errorf("failed to read run '%s': %w", id, err)
+	}
+	return &run, nil
+}
+
+func (s *SQLiteRunStore) DeleteRun(ctx context.Context, id string) error {
+	n, err := s.DeleteRuns(ctx, []string{id})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("run with id '%s': %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+func (s *SQLiteRunStore) DeleteRuns(ctx context.Context, ids []string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	deleted, err := s.deleteWhere(ctx, `id IN (`+placeholders+`)`, args...)
+	return len(deleted), err
+}
+
+func (s *SQLiteRunStore) PruneRuns(ctx context.Context, policy RetentionPolicy) ([]string, error) {
+	var deleted []string
+	if policy.MaxAge > 0 {
+		cutoff := time.Now().Add(-policy.MaxAge).UTC().Format(runTimeLayout)
+		ids, err := s.deleteWhere(ctx, `created_at < ?`, cutoff)
+		deleted = append(deleted, ids...)
+		if err != nil {
+			return deleted, err
+		}
+	}
+	if policy.MaxRunsPerProject > 0 {
+		ids, err := s.deleteWhere(ctx, `rowid IN (SELECT rowid FROM (
+			  SELECT rowid, ROW_NUMBER() OVER (PARTITION BY project_id ORDER BY created_at DESC, rowid DESC) AS position
+			  FROM runs) WHERE position > ?)`, policy.MaxRunsPerProject)
+		deleted = append(deleted, ids...)
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// deleteWhere deletes the runs matching cond, then the blobs only they referred to, and
+// returns the IDs of the deleted runs.
+func (s *SQLiteRunStore) deleteWhere(ctx context.Context, cond string, args ...any) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `DELETE FROM runs WHERE `+cond+` RETURNING id;`, args...)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) > 0 {
+		if err := deleteOrphanBlobs(ctx, s.db); err != nil {
+			return ids, err
+		}
+	}
+	return ids, nil
+}
+
+func (s *SQLiteRunStore) CompactRuns(ctx context.Context, minSize int) (*CompactResult, error) {
+	if minSize <= 0 {
+		minSize = DefaultCompactMinSize
+	}
+	// Only records big enough to hold such a string are candidates. The IDs are read
+	// first so that no query is open while the records are rewritten.
+	rows, err := s.db.QueryContext(ctx, `SELECT id FROM runs WHERE length(run_data) >= ?;`, minSize)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	_, err := s.db.ExecContext(ctx, query, run.ID, run.ProjectID, run.RunData)
-	return err
+	result := &CompactResult{}
+	for _, id := range ids {
+		if err := s.compactRun(ctx, id, minSize, result); err != nil {
+			return result, fmt.Errorf("failed to compact run '%s': %w", id, err)
+		}
+	}
+	return result, deleteOrphanBlobs(ctx, s.db)
+}
+
+func (s *SQLiteRunStore) compactRun(ctx context.Context, id string, minSize int, result *CompactResult) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var data string
+	if err := tx.QueryRowContext(ctx, `SELECT run_data FROM runs WHERE id = ?;`, id).Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	compacted, blobs, err := compactRunData(data, minSize)
+	if err != nil || len(blobs) == 0 {
+		return err
+	}
+
+	saved := int64(len(data) - len(compacted))
+	added := 0
+	for hash, content := range blobs {
+		res, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO blobs (hash, content, size) VALUES (?, ?, ?);`, hash, content, len(content))
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			added++
+			saved -= int64(len(content))
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO run_blobs (run_id, hash) VALUES (?, ?);`, id, hash); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE runs SET run_data = ? WHERE id = ?;`, compacted, id); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	result.Runs++
+	result.Blobs += added
+	result.BytesSaved += saved
+	return nil
+}
+
+func (s *SQLiteRunStore) Vacuum(ctx context.Context) (*VacuumResult, error) {
+	var result VacuumResult
+	var err error
+	if result.SizeBefore, err = s.databaseSize(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := s.db.ExecContext(ctx, `VACUUM;`); err != nil {
+		return nil, err
+	}
+	if result.SizeAfter, err = s.databaseSize(ctx); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *SQLiteRunStore) databaseSize(ctx context.Context) (int64, error) {
+	var size int64
+	err := s.db.QueryRowContext(ctx, `SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size();`).Scan(&size)
+	return size, err
 }
 
-func (s *SQLiteRunStore) ListRunsByProject(ctx context.Context, projectID string) ([]*models.Run, error) {
-	rows, err := s.db.QueryContext(ctx, `SELECT id, project_id, run_data FROM runs WHERE project_id = ? ORDER BY created_at ASC;`, projectID)
+// runPreviewLength is the number of characters of the prompt and summary kept in run
+// summaries.
+const runPreviewLength = 300
+
+// runTimeLayout is how SQLite's CURRENT_TIMESTAMP formats created_at.
+const runTimeLayout = "2006-01-02 15:04:05"
+
+func (s *SQLiteRunStore) ListRuns(ctx context.Context, filter RunFilter) (*RunPage, error) {
+	limit := filter.Limit
+	switch {
+	case limit <= 0:
+		limit = DefaultRunPageSize
+	case limit > MaxRunPageSize:
+		limit = MaxRunPageSize
+	}
+	offset := max(filter.Offset, 0)
+
+	var where []string
+	var args []any
+	if filter.ProjectID != "" {
+		where = append(where, "project_id = ?")
+		args = append(args, filter.ProjectID)
+	}
+	if filter.AgentID != "" {
+		where = append(where, "agent_id = ?")
+		args = append(args, filter.AgentID)
+	}
+	if filter.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, filter.Since.UTC().Format(runTimeLayout))
+	}
+	if !filter.Until.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, filter.Until.UTC().Format(runTimeLayout))
+	}
+	if match := ftsQuery(filter.Query); match != "" {
+		where = append(where, "rowid IN (SELECT rowid FROM runs_fts WHERE runs_fts MATCH ?)")
+		args = append(args, match)
+	}
+	cond := ""
+	if len(where) > 0 {
+		cond = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	page := &RunPage{Runs: []*models.RunSummary{}, Limit: limit, Offset: offset}
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM runs`+cond, args...).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, project_id, COALESCE(agent_id, ''), COALESCE(json_extract(run_data, '$.agentName'), ''),
+			  COALESCE(status, ''), substr(COALESCE(prompt, ''), 1, ?), substr(COALESCE(summary, ''), 1, ?), created_at
+			  FROM runs` + cond + ` ORDER BY created_at DESC, rowid DESC LIMIT ? OFFSET ?;`
+	args = append([]any{runPreviewLength, runPreviewLength}, args...)
+	rows, err := s.db.QueryContext(ctx, query, append(args, limit, offset)...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	runs := make([]*models.Run, 0)
 	for rows.Next() {
-		var run models.Run
-		if err := rows.Scan(&run.ID, &run.ProjectID, &run.RunData); err != nil {
+		var run models.RunSummary
+		var createdAt string
+		if err := rows.Scan(&run.ID, &run.ProjectID, &run.AgentID, &run.AgentName, &run.Status, &run.Prompt, &run.Summary, &createdAt); err != nil {
 			return nil, err
 		}
-		runs = append(runs, &run)
+		if run.CreatedAt, err = parseRunTime(createdAt); err != nil {
+			return nil, err
+		}
+		page.Runs = append(page.Runs, &run)
+	}
+	return page, rows.Err()
+}
+
+// parseRunTime parses created_at, which the SQLite driver returns in RFC 3339 or in
+// SQLite's own format.
+func parseRunTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(runTimeLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid run time %q: %w", value, err)
+	}
+	return t, nil
+}
+
+// ftsQuery turns free text into an FTS5 query that requires every word, matching words
+// that start with it. Words are quoted, so FTS5 operators in the text are taken literally.
+func ftsQuery(text string) string {
+	var terms []string
+	for _, word := range strings.Fields(text) {
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"*`)
 	}
-	return runs, nil
+	return strings.Join(terms, " ")
 }
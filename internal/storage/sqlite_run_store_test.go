@@ -0,0 +1,228 @@
+package storage_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ClarionDev/clarion/internal/database"
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/storage"
+)
+
+// newTestDB returns a migrated database in a temporary directory.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	ctx := context.Background()
+	db, err := database.New(ctx, "sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("database.New: %v", err)
+	}
+	t.Cleanup(db.Close)
+	if err := db.RunMigrations(ctx); err != nil {
+		t.Fatalf("RunMigrations: %v", err)
+	}
+	return db.Handle().(*sql.DB)
+}
+
+func newRunStore(t *testing.T) (*storage.SQLiteRunStore, *sql.DB) {
+	t.Helper()
+	ctx := context.Background()
+	sqlDB := newTestDB(t)
+	if err := storage.NewSQLiteProjectStore(sqlDB).SaveProject(ctx, &models.Project{ID: "p1", Path: "/p1"}); err != nil {
+		t.Fatal(err)
+	}
+	return storage.NewSQLiteRunStore(sqlDB), sqlDB
+}
+
+func saveRun(t *testing.T, store *storage.SQLiteRunStore, id, agentID, status, prompt, summary string) {
+	t.Helper()
+	data, _ := json.Marshal(map[string]any{
+		"id": id, "agentId": agentID, "agentName": "Agent " + agentID, "status": status,
+		"prompt": prompt, "output": map[string]any{"summary": summary},
+	})
+	if err := store.SaveRun(context.Background(), &models.Run{ID: id, ProjectID: "p1", RunData: string(data)}); err != nil {
+		t.Fatalf("SaveRun(%s): %v", id, err)
+	}
+}
+
+func runIDs(page *storage.RunPage) []string {
+	ids := make([]string, len(page.Runs))
+	for i, r := range page.Runs {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+func TestListRuns(t *testing.T) {
+	ctx := context.Background()
+	store, db := newRunStore(t)
+	saveRun(t, store, "r1", "a", "success", "Refactor the parser", "Split the tokenizer")
+	saveRun(t, store, "r2", "b", "error", "Add logging", "")
+	saveRun(t, store, "r3", "a", "success", "Fix the login bug", "Parser now handles quotes")
+	// Spread the runs over three days, r1 first.
+	for i, id := range []string{"r1", "r2", "r3"} {
+		day := time.Date(2024, 5, 1+i, 12, 0, 0, 0, time.UTC).Format("2006-01-02 15:04:05")
+		if _, err := db.Exec(`UPDATE runs SET created_at = ? WHERE id = ?`, day, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter storage.RunFilter
+		want   []string
+	}{
+		{"newest first", storage.RunFilter{ProjectID: "p1"}, []string{"r3", "r2", "r1"}},
+		{"agent", storage.RunFilter{ProjectID: "p1", AgentID: "a"}, []string{"r3", "r1"}},
+		{"status", storage.RunFilter{ProjectID: "p1", Status: "error"}, []string{"r2"}},
+		{"date range", storage.RunFilter{ProjectID: "p1", Since: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), Until: time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC)}, []string{"r2"}},
+		{"text in prompt or summary", storage.RunFilter{ProjectID: "p1", Query: "parser"}, []string{"r3", "r1"}},
+		{"every word, as a prefix", storage.RunFilter{ProjectID: "p1", Query: "pars tok"}, []string{"r1"}},
+		{"operators are literal", storage.RunFilter{ProjectID: "p1", Query: `"login" OR`}, []string{}},
+		{"page", storage.RunFilter{ProjectID: "p1", Limit: 1, Offset: 1}, []string{"r2"}},
+		{"other project", storage.RunFilter{ProjectID: "p2"}, []string{}},
+	}
+	for _, tt := range tests {
+		page, err := store.ListRuns(ctx, tt.filter)
+		if err != nil {
+			t.Fatalf("%s: ListRuns: %v", tt.name, err)
+		}
+		if got := runIDs(page); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	page, err := store.ListRuns(ctx, storage.RunFilter{ProjectID: "p1", Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 3 {
+		t.Errorf("Total = %d, want 3", page.Total)
+	}
+	if r := page.Runs[0]; r.AgentName != "Agent a" || r.Summary != "Parser now handles quotes" || !r.CreatedAt.Equal(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("summary = %+v", r)
+	}
+
+	// Updating a run updates the search index.
+	saveRun(t, store, "r2", "b", "success", "Add structured logging", "Done")
+	if page, err = store.ListRuns(ctx, storage.RunFilter{ProjectID: "p1", Query: "structured"}); err != nil || len(page.Runs) != 1 {
+		t.Errorf("search after update = %v, %v", page, err)
+	}
+}
+
+func TestGetRunNotFound(t *testing.T) {
+	store, _ := newRunStore(t)
+	if _, err := store.GetRun(context.Background(), "missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetRun error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDeleteAndPruneRuns(t *testing.T) {
+	ctx := context.Background()
+	store, db := newRunStore(t)
+	for i, id := range []string{"r1", "r2", "r3", "r4"} {
+		saveRun(t, store, id, "a", "success", "Prompt "+id, "")
+		day := time.Now().AddDate(0, 0, -10+2*i).UTC().Format("2006-01-02 15:04:05")
+		if _, err := db.Exec(`UPDATE runs SET created_at = ? WHERE id = ?`, day, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := store.DeleteRun(ctx, "missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("DeleteRun(missing) error = %v, want ErrNotFound", err)
+	}
+	if n, err := store.DeleteRuns(ctx, []string{"r4", "missing"}); err != nil || n != 1 {
+		t.Errorf("DeleteRuns() = %d, %v, want 1", n, err)
+	}
+
+	// r1 is ten days old, r2 eight; r3 is the newest left.
+	pruned, err := store.PruneRuns(ctx, storage.RetentionPolicy{MaxAge: 9 * 24 * time.Hour})
+	if err != nil || !slices.Equal(pruned, []string{"r1"}) {
+		t.Errorf("PruneRuns(max age) = %v, %v, want [r1]", pruned, err)
+	}
+	pruned, err = store.PruneRuns(ctx, storage.RetentionPolicy{MaxRunsPerProject: 1})
+	if err != nil || !slices.Equal(pruned, []string{"r2"}) {
+		t.Errorf("PruneRuns(max count) = %v, %v, want [r2]", pruned, err)
+	}
+	page, err := store.ListRuns(ctx, storage.RunFilter{ProjectID: "p1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := runIDs(page); !slices.Equal(got, []string{"r3"}) {
+		t.Errorf("runs left = %v, want [r3]", got)
+	}
+	// Deleted runs are gone from the search index too.
+	if page, _ := store.ListRuns(ctx, storage.RunFilter{Query: "r1"}); len(page.Runs) != 0 {
+		t.Errorf("search found deleted runs: %v", runIDs(page))
+	}
+}
+
+func TestCompactRuns(t *testing.T) {
+	ctx := context.Background()
+	store, db := newRunStore(t)
+	content := strings.Repeat("package main\n", 50)
+	for _, id := range []string{"r1", "r2"} {
+		data, _ := json.Marshal(map[string]any{
+			"id": id, "status": "success", "prompt": strings.Repeat("long prompt ", 100),
+			// Above 2^53, so a float64 round trip would change it.
+			"startedAtNanos": json.Number("9007199254740993"),
+			"output":         map[string]any{"summary": "Done", "fileChanges": []any{map[string]any{"path": "main.go", "content": content}}},
+		})
+		if err := store.SaveRun(ctx, &models.Run{ID: id, ProjectID: "p1", RunData: string(data)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before, _ := store.GetRun(ctx, "r1")
+
+	result, err := store.CompactRuns(ctx, 256)
+	if err != nil {
+		t.Fatalf("CompactRuns() error = %v", err)
+	}
+	if result.Runs != 2 || result.Blobs != 1 || result.BytesSaved <= 0 {
+		t.Errorf("CompactRuns() = %+v, want 2 runs sharing 1 blob", result)
+	}
+
+	after, err := store.GetRun(ctx, "r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want, got any
+	json.Unmarshal([]byte(before.RunData), &want)
+	json.Unmarshal([]byte(after.RunData), &got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetRun() after compaction = %s, want %s", after.RunData, before.RunData)
+	}
+	if !strings.Contains(after.RunData, `"startedAtNanos":9007199254740993`) {
+		t.Errorf("GetRun() after compaction changed a large integer: %s", after.RunData)
+	}
+	// The prompt stays in the record, so it can still be searched.
+	if page, _ := store.ListRuns(ctx, storage.RunFilter{Query: "long prompt"}); len(page.Runs) != 2 {
+		t.Errorf("search after compaction found %v", runIDs(page))
+	}
+
+	countBlobs := func() int {
+		var n int
+		db.QueryRow(`SELECT COUNT(*) FROM blobs`).Scan(&n)
+		return n
+	}
+	store.DeleteRun(ctx, "r1")
+	if n := countBlobs(); n != 1 {
+		t.Errorf("blobs after deleting one run = %d, want 1", n)
+	}
+	store.DeleteRun(ctx, "r2")
+	if n := countBlobs(); n != 0 {
+		t.Errorf("blobs after deleting both runs = %d, want 0", n)
+	}
+
+	if _, err := store.Vacuum(ctx); err != nil {
+		t.Errorf("Vacuum() error = %v", err)
+	}
+}
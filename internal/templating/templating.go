@@ -0,0 +1,238 @@
+// Package templating fills variables into agent prompts.
+//
+// The syntax is deliberately small:
+//
+//	{{name}}                      the value of name
+//	{{name | default "text"}}     the value of name, or text when it is empty or unset
+//	{{#if name}}...{{else}}...{{/if}}
+//	{{#unless name}}...{{/unless}}
+//	\{{                           a literal "{{"
+//
+// A value is true for #if when it is set and is not "", "0" or "false". A {{name}} whose
+// name is not in the data and has no default is left as written, so prompts that contain
+// braces for other reasons, such as code samples, pass through unchanged.
+package templating
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	namePattern = `[A-Za-z_][A-Za-z0-9_.-]*`
+	varTag      = regexp.MustCompile(`^(` + namePattern + `)(?:\s*\|\s*default\s+(?:"([^"]*)"|'([^']*)'))?$`)
+	blockTag    = regexp.MustCompile(`^#(if|unless)\s+(` + namePattern + `)$`)
+)
+
+// Template is a parsed template.
+type Template struct {
+	nodes []node
+}
+
+type node interface {
+	render(b *strings.Builder, data map[string]string)
+}
+
+type textNode string
+
+type varNode struct {
+	raw        string
+	name       string
+	def        string
+	hasDefault bool
+}
+
+type ifNode struct {
+	name            string
+	negate          bool
+	then, otherwise []node
+}
+
+func (n textNode) render(b *strings.Builder, data map[string]string) {
+	b.WriteString(string(n))
+}
+
+func (n *varNode) render(b *strings.Builder, data map[string]string) {
+	value, ok := data[n.name]
+	switch {
+	case value != "":
+		b.WriteString(value)
+	case n.hasDefault:
+		b.WriteString(n.def)
+	case !ok:
+		b.WriteString(n.raw)
+	}
+}
+
+func (n *ifNode) render(b *strings.Builder, data map[string]string) {
+	value := data[n.name]
+	cond := value != "" && value != "0" && value != "false"
+	branch := n.then
+	if cond == n.negate {
+		branch = n.otherwise
+	}
+	for _, child := range branch {
+		child.render(b, data)
+	}
+}
+
+// Parse parses a template. It fails only on unbalanced #if, #unless, else and /if tags.
+func Parse(src string) (*Template, error) {
+	p := &parser{src: src}
+	nodes, end, err := p.parse("")
+	if err != nil {
+		return nil, err
+	}
+	if end != "" {
+		return nil, fmt.Errorf("unexpected {{%s}} at offset %d", end, p.pos)
+	}
+	return &Template{nodes: nodes}, nil
+}
+
+// Execute renders the template with data.
+func (t *Template) Execute(data map[string]string) string {
+	var b strings.Builder
+	for _, n := range t.nodes {
+		n.render(&b, data)
+	}
+	return b.String()
+}
+
+// Names returns the variable names the template refers to, in order of first use.
+func (t *Template) Names() []string {
+	seen := make(map[string]bool)
+	var names []string
+	var walk func(nodes []node)
+	walk = func(nodes []node) {
+		for _, n := range nodes {
+			switch n := n.(type) {
+			case *varNode:
+				if !seen[n.name] {
+					seen[n.name] = true
+					names = append(names, n.name)
+				}
+			case *ifNode:
+				if !seen[n.name] {
+					seen[n.name] = true
+					names = append(names, n.name)
+				}
+				walk(n.then)
+				walk(n.otherwise)
+			}
+		}
+	}
+	walk(t.nodes)
+	return names
+}
+
+// Render parses and executes src in one step.
+func Render(src string, data map[string]string) (string, error) {
+	t, err := Parse(src)
+	if err != nil {
+		return "", err
+	}
+	return t.Execute(data), nil
+}
+
+type parser struct {
+	src string
+	pos int
+}
+
+// parse reads nodes until the end of the input or until a closing tag for block, which it
+// returns ("else", "/if" or "/unless").
+func (p *parser) parse(block string) ([]node, string, error) {
+	var nodes []node
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, textNode(text.String()))
+			text.Reset()
+		}
+	}
+
+	for p.pos < len(p.src) {
+		rest := p.src[p.pos:]
+		if strings.HasPrefix(rest, `\{{`) {
+			text.WriteString("{{")
+			p.pos += 3
+			continue
+		}
+		if !strings.HasPrefix(rest, "{{") {
+			next := strings.IndexAny(rest[1:], `{\`)
+			if next < 0 {
+				text.WriteString(rest)
+				p.pos = len(p.src)
+			} else {
+				text.WriteString(rest[:next+1])
+				p.pos += next + 1
+			}
+			continue
+		}
+
+		end := strings.Index(rest, "}}")
+		if end < 0 {
+			text.WriteString(rest)
+			p.pos = len(p.src)
+			continue
+		}
+		raw := rest[:end+2]
+		tag := strings.TrimSpace(rest[2:end])
+		start := p.pos
+		p.pos += end + 2
+
+		switch {
+		case tag == "else" || tag == "/if" || tag == "/unless":
+			if block == "" || (tag == "/if" && block != "if") || (tag == "/unless" && block != "unless") {
+				return nil, "", fmt.Errorf("unexpected {{%s}} at offset %d", tag, start)
+			}
+			flush()
+			return nodes, tag, nil
+		case blockTag.MatchString(tag):
+			m := blockTag.FindStringSubmatch(tag)
+			flush()
+			n, err := p.parseBlock(m[1], m[2], start)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, n)
+		case varTag.MatchString(tag):
+			m := varTag.FindStringSubmatch(tag)
+			flush()
+			v := &varNode{raw: raw, name: m[1]}
+			if strings.Contains(tag, "|") {
+				v.hasDefault = true
+				v.def = m[2] + m[3]
+			}
+			nodes = append(nodes, v)
+		default:
+			// Not template syntax; keep it as text.
+			text.WriteString(raw)
+		}
+	}
+
+	if block != "" {
+		return nil, "", fmt.Errorf("{{#%s}} is never closed", block)
+	}
+	flush()
+	return nodes, "", nil
+}
+
+func (p *parser) parseBlock(kind, name string, start int) (node, error) {
+	n := &ifNode{name: name, negate: kind == "unless"}
+	then, end, err := p.parse(kind)
+	if err != nil {
+		return nil, err
+	}
+	n.then = then
+	if end == "else" {
+		if n.otherwise, end, err = p.parse(kind); err != nil {
+			return nil, err
+		}
+		if end == "else" {
+			return nil, fmt.Errorf("{{#%s}} at offset %d has more than one {{else}}", kind, start)
+		}
+	}
+	return n, nil
+}
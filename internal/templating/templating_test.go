@@ -0,0 +1,70 @@
+package templating
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	data := map[string]string{
+		"name":         "Ada",
+		"empty":        "",
+		"off":          "false",
+		"project.name": "clarion",
+	}
+	tests := []struct {
+		src, want string
+	}{
+		{"Hello {{name}}!", "Hello Ada!"},
+		{"{{ name }} works on {{project.name}}", "Ada works on clarion"},
+		{`{{empty | default "none"}}`, "none"},
+		{`{{missing | default 'n/a'}}`, "n/a"},
+		{`{{name | default "none"}}`, "Ada"},
+		{"[{{empty}}]", "[]"},
+		{"{{missing}} stays", "{{missing}} stays"},
+		{"{{#if name}}yes{{else}}no{{/if}}", "yes"},
+		{"{{#if off}}yes{{else}}no{{/if}}", "no"},
+		{"{{#if missing}}yes{{/if}}", ""},
+		{"{{#unless empty}}unset{{else}}set{{/unless}}", "unset"},
+		{"{{#unless name}}unset{{else}}set{{/unless}}", "set"},
+		{"{{#if name}}a{{#if off}}b{{else}}c{{/if}}d{{/if}}", "acd"},
+		{`\{{name}} is {{name}}`, "{{name}} is Ada"},
+		{"func() { return map[string]int{} }", "func() { return map[string]int{} }"},
+		{"{{ not a variable }}", "{{ not a variable }}"},
+		{"unterminated {{name", "unterminated {{name"},
+	}
+	for _, tt := range tests {
+		got, err := Render(tt.src, data)
+		if err != nil {
+			t.Errorf("Render(%q): %v", tt.src, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Render(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, src := range []string{
+		"{{#if a}}never closed",
+		"{{/if}}",
+		"{{else}}",
+		"{{#if a}}x{{/unless}}",
+		"{{#if a}}x{{else}}y{{else}}z{{/if}}",
+	} {
+		if _, err := Parse(src); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", src)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	tmpl, err := Parse(`{{a}} {{#if b}}{{c | default "x"}}{{/if}} {{a}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tmpl.Names(), []string{"a", "b", "c"}; !slices.Equal(got, want) {
+		t.Fatalf("Names() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,36 @@
+package textdiff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	b := "one\ntwo\nTHREE\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\n"
+	want := `--- a
++++ b
+@@ -1,6 +1,6 @@
+ one
+ two
+-three
++THREE
+ four
+ five
+ six
+@@ -8,3 +8,4 @@
+ eight
+ nine
+ ten
++eleven
+`
+	if got := Unified("a", "b", a, b); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	if got := Unified("a", "b", "same", "same"); got != "" {
+		t.Errorf("equal texts: got %q", got)
+	}
+
+	want = "--- a\n+++ b\n@@ -0,0 +1 @@\n+new\n"
+	if got := Unified("a", "b", "", "new"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,508 @@
+// Package workflow executes canvas workflows. Nodes run in dependency order, independent
+// branches concurrently up to a limit, and every node's result is stored as soon as it is
+// known so that a failed or paused run can be resumed where it stopped.
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ClarionDev/clarion/internal/canvas"
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/projectconfig"
+	"github.com/ClarionDev/clarion/internal/runner"
+	"github.com/ClarionDev/clarion/internal/shell"
+	"github.com/ClarionDev/clarion/internal/storage"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrRunActive is returned when resuming or approving a run that is executing.
+	ErrRunActive = errors.New("the workflow run is already executing")
+	// ErrRunFinished is returned when resuming a run that has completed.
+	ErrRunFinished = errors.New("the workflow run has already completed")
+	// ErrNotWaiting is returned when approving a node that is not waiting for approval.
+	ErrNotWaiting = errors.New("the node is not waiting for approval")
+	// ErrNoChanges is returned when a decision edits the file changes of an approval node
+	// that was not given any.
+	ErrNoChanges = errors.New("the node was not given file changes to edit")
+)
+
+// DefaultConcurrency is the number of nodes run at the same time when no limit is given.
+const DefaultConcurrency = 4
+
+// Notify is called whenever a run changes state. nodeID names the node that changed, or is
+// empty when the run as a whole did. The run must not be modified or kept.
+type Notify func(run *canvas.Run, nodeID string)
+
+type Executor struct {
+	// ProjectSettings, if set, returns the settings of the project a run works on, whose
+	// pinned files and default LLM config apply to agent nodes.
+	ProjectSettings func(ctx context.Context, projectRoot string) models.ProjectSettings
+	// LLMConfigStore, if set, is used to check that a project's default LLM config is for
+	// the provider of the agent it would apply to.
+	LLMConfigStore storage.LLMConfigStore
+
+	agentStore  storage.AgentStore
+	runStore    storage.CanvasRunStore
+	runner      *runner.Runner
+	concurrency int
+	notify      Notify
+
+	mu     sync.Mutex
+	active map[string]bool
+}
+
+// NewExecutor returns an executor that runs up to concurrency nodes of a run at once.
+// notify may be nil.
+func NewExecutor(agentStore storage.AgentStore, runStore storage.CanvasRunStore, r *runner.Runner, concurrency int, notify Notify) *Executor {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	if notify == nil {
+		notify = func(*canvas.Run, string) {}
+	}
+	return &Executor{
+		agentStore:  agentStore,
+		runStore:    runStore,
+		runner:      r,
+		concurrency: concurrency,
+		notify:      notify,
+		active:      make(map[string]bool),
+	}
+}
+
+// Start validates a canvas, stores a new run of it and executes the run in the background.
+func (e *Executor) Start(ctx context.Context, c *canvas.Canvas, projectRoot string, inputs map[string]string) (*canvas.Run, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	run := &canvas.Run{
+		ID:          uuid.New().String(),
+		CanvasID:    c.ID,
+		Canvas:      *c,
+		ProjectRoot: projectRoot,
+		Inputs:      inputs,
+		Status:      canvas.StatusPending,
+		Nodes:       make(map[string]*canvas.NodeResult, len(c.Nodes)),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	for _, n := range c.Nodes {
+		run.Nodes[n.ID] = &canvas.NodeResult{Status: canvas.StatusPending}
+	}
+	if err := e.runStore.SaveCanvasRun(ctx, run); err != nil {
+		return nil, fmt.Errorf("failed to save workflow run: %w", err)
+	}
+	return e.launch(run)
+}
+
+// Resume executes a stored run again. Nodes that completed keep their results; failed and
+// interrupted nodes run again.
+func (e *Executor) Resume(ctx context.Context, runID string) (*canvas.Run, error) {
+	if !e.claim(runID) {
+		return nil, ErrRunActive
+	}
+	run, err := e.runStore.GetCanvasRun(ctx, runID)
+	if err != nil {
+		e.release(runID)
+		return nil, err
+	}
+	if run.Status == canvas.StatusCompleted {
+		e.release(runID)
+		return nil, ErrRunFinished
+	}
+	return e.start(run), nil
+}
+
+// Decision is a person's answer to an approval node.
+type Decision struct {
+	Approved bool
+	Comment  string
+	// Changes, if set, replace the file changes given to the node, so the nodes after it
+	// use the changes as edited by the approver.
+	Changes []map[string]any
+}
+
+// Approve records a decision on an approval node and resumes the run. A rejection keeps
+// the nodes that depend on the approval from running.
+func (e *Executor) Approve(ctx context.Context, runID, nodeID string, d Decision) (*canvas.Run, error) {
+	// The run is claimed before it is read, so no other call can start it between the
+	// check and the save below.
+	if !e.claim(runID) {
+		return nil, ErrRunActive
+	}
+	run, err := e.runStore.GetCanvasRun(ctx, runID)
+	if err != nil {
+		e.release(runID)
+		return nil, err
+	}
+	result, ok := run.Nodes[nodeID]
+	if !ok || result.Status != canvas.StatusWaiting {
+		e.release(runID)
+		return nil, ErrNotWaiting
+	}
+	changes, given := result.Inputs["changes"]
+	if d.Changes != nil {
+		if !given {
+			e.release(runID)
+			return nil, ErrNoChanges
+		}
+		changes = d.Changes
+	}
+
+	now := time.Now().UTC()
+	result.Status = canvas.StatusRejected
+	if d.Approved {
+		result.Status = canvas.StatusCompleted
+	}
+	result.Outputs = map[string]any{"approved": strconv.FormatBool(d.Approved), "comment": d.Comment}
+	if given {
+		result.Outputs["changes"] = changes
+	}
+	result.FinishedAt = &now
+	if err := e.runStore.SaveCanvasRun(ctx, run); err != nil {
+		e.release(runID)
+		return nil, fmt.Errorf("failed to save workflow run: %w", err)
+	}
+	return e.start(run), nil
+}
+
+func (e *Executor) isActive(runID string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.active[runID]
+}
+
+// claim marks a run as executing, reporting false if it already is.
+func (e *Executor) claim(runID string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.active[runID] {
+		return false
+	}
+	e.active[runID] = true
+	return true
+}
+
+func (e *Executor) release(runID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.active, runID)
+}
+
+// launch marks a run as executing and starts it.
+func (e *Executor) launch(run *canvas.Run) (*canvas.Run, error) {
+	if !e.claim(run.ID) {
+		return nil, ErrRunActive
+	}
+	return e.start(run), nil
+}
+
+// start executes a claimed run in the background and releases it when done. The returned
+// run is a snapshot taken before any node has started.
+func (e *Executor) start(run *canvas.Run) *canvas.Run {
+	for _, result := range run.Nodes {
+		if result.Status == canvas.StatusFailed || result.Status == canvas.StatusRunning {
+			result.Status = canvas.StatusPending
+			result.Error = ""
+		}
+	}
+	run.Status = canvas.StatusRunning
+	run.Error = ""
+	snapshot := copyRun(run)
+
+	go func() {
+		defer e.release(run.ID)
+		// The run outlives the request that started it.
+		e.execute(context.Background(), run)
+	}()
+	return snapshot
+}
+
+// copyRun copies a run deeply enough to read it while the original is being executed.
+func copyRun(run *canvas.Run) *canvas.Run {
+	c := *run
+	c.Nodes = make(map[string]*canvas.NodeResult, len(run.Nodes))
+	for id, result := range run.Nodes {
+		r := *result
+		c.Nodes[id] = &r
+	}
+	return &c
+}
+
+// execution is the state of one pass over a run. All fields are guarded by mu.
+type execution struct {
+	e   *Executor
+	mu  sync.Mutex
+	run *canvas.Run
+}
+
+// update applies fn to the run, stores it and notifies observers.
+func (x *execution) update(nodeID string, fn func()) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	fn()
+	x.run.UpdatedAt = time.Now().UTC()
+	if err := x.e.runStore.SaveCanvasRun(context.Background(), x.run); err != nil {
+		log.Printf("Failed to save workflow run %s: %v", x.run.ID, err)
+	}
+	x.e.notify(x.run, nodeID)
+}
+
+type nodeDone struct {
+	id      string
+	outputs map[string]any
+	waiting bool
+	err     error
+}
+
+// execute runs the pending nodes of a run until every node is done or waiting, or a node
+// fails. After a failure no new nodes start, but the ones already running finish.
+func (e *Executor) execute(ctx context.Context, run *canvas.Run) {
+	x := &execution{e: e, run: run}
+	x.update("", func() {})
+
+	c := &run.Canvas
+	order, err := c.Order()
+	if err != nil {
+		x.update("", func() { run.Status, run.Error = canvas.StatusFailed, err.Error() })
+		return
+	}
+	nodes := make(map[string]*canvas.Node, len(c.Nodes))
+	for i := range c.Nodes {
+		nodes[c.Nodes[i].ID] = &c.Nodes[i]
+	}
+	incoming := make(map[string][]canvas.Edge)
+	for _, edge := range c.Edges {
+		incoming[edge.Target] = append(incoming[edge.Target], edge)
+	}
+
+	done := make(chan nodeDone)
+	running := 0
+	failed := ""
+	for {
+		if failed == "" {
+			for _, id := range order {
+				if running >= e.concurrency {
+					break
+				}
+				x.mu.Lock()
+				status := run.Nodes[id].Status
+				x.mu.Unlock()
+				if status != canvas.StatusPending {
+					continue
+				}
+				vars, ready, skip := x.inputsFor(id, incoming[id])
+				if !ready {
+					continue
+				}
+				if skip {
+					x.update(id, func() { run.Nodes[id].Status = canvas.StatusSkipped })
+					// Skipping may have made later nodes ready or skippable.
+					continue
+				}
+
+				now := time.Now().UTC()
+				x.update(id, func() {
+					result := run.Nodes[id]
+					result.Status = canvas.StatusRunning
+					result.Inputs = vars
+					result.Attempts++
+					result.StartedAt = &now
+					result.FinishedAt = nil
+				})
+				running++
+				go func(node *canvas.Node) {
+					outputs, waiting, err := e.runNode(ctx, x, node, vars)
+					done <- nodeDone{id: node.ID, outputs: outputs, waiting: waiting, err: err}
+				}(nodes[id])
+			}
+		}
+		if running == 0 {
+			break
+		}
+
+		d := <-done
+		running--
+		now := time.Now().UTC()
+		x.update(d.id, func() {
+			result := run.Nodes[d.id]
+			switch {
+			case d.err != nil:
+				result.Status = canvas.StatusFailed
+				result.Error = d.err.Error()
+				result.FinishedAt = &now
+			case d.waiting:
+				result.Status = canvas.StatusWaiting
+			default:
+				result.Status = canvas.StatusCompleted
+				result.Outputs = d.outputs
+				result.FinishedAt = &now
+			}
+		})
+		if d.err != nil && failed == "" {
+			failed = d.id
+		}
+	}
+
+	x.update("", func() {
+		run.Status = canvas.StatusCompleted
+		for _, id := range order {
+			switch run.Nodes[id].Status {
+			case canvas.StatusWaiting:
+				if run.Status != canvas.StatusFailed {
+					run.Status = canvas.StatusWaiting
+				}
+			case canvas.StatusFailed:
+				run.Status = canvas.StatusFailed
+				if run.Error == "" {
+					run.Error = fmt.Sprintf("node %q failed: %s", id, run.Nodes[id].Error)
+				}
+			}
+		}
+	})
+}
+
+// inputsFor decides whether a pending node can run. It is ready once all of its sources are
+// done; it is skipped when it has incoming edges but none of them is followed, because their
+// sources were skipped or rejected or their conditions do not hold.
+func (x *execution) inputsFor(id string, edges []canvas.Edge) (vars map[string]any, ready, skip bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	vars = make(map[string]any)
+	followed := 0
+	for _, edge := range edges {
+		source := x.run.Nodes[edge.Source]
+		if !source.Status.Done() || source.Status == canvas.StatusFailed {
+			return nil, false, false
+		}
+		if source.Status != canvas.StatusCompleted {
+			continue
+		}
+		if edge.Condition != nil && !edge.Condition.Holds(source.Outputs) {
+			continue
+		}
+		vars[edge.TargetInput] = source.Outputs[edge.SourceOutput]
+		followed++
+	}
+	return vars, true, len(edges) > 0 && followed == 0
+}
+
+// runNode executes one node. Approval nodes do no work; they report that they are waiting.
+func (e *Executor) runNode(ctx context.Context, x *execution, node *canvas.Node, vars map[string]any) (map[string]any, bool, error) {
+	text := textVars(vars)
+	switch node.Type {
+	case canvas.NodeInput:
+		value, ok := x.run.Inputs[node.Input.Name]
+		if !ok {
+			if node.Input.Default == "" {
+				return nil, false, fmt.Errorf("no value was given for input %q", node.Input.Name)
+			}
+			value = node.Input.Default
+		}
+		return map[string]any{"value": value}, false, nil
+
+	case canvas.NodePrompt:
+		rendered, err := render(ctx, x.run.ProjectRoot, node.Prompt.Template, text)
+		if err != nil {
+			return nil, false, err
+		}
+		return map[string]any{"text": rendered}, false, nil
+
+	case canvas.NodeFileSelection:
+		return map[string]any{"files": node.FileSelection.Paths}, false, nil
+
+	case canvas.NodeAgent:
+		output, err := e.runAgent(ctx, x, node, vars, text)
+		if err != nil {
+			return nil, false, err
+		}
+		summary, _ := output["summary"].(string)
+		return map[string]any{"output": output, "summary": summary, "changes": output["file_changes"]}, false, nil
+
+	case canvas.NodeCommand:
+		command, err := render(ctx, x.run.ProjectRoot, node.Command.Command, text)
+		if err != nil {
+			return nil, false, err
+		}
+		result, err := shell.RunCommand(ctx, command, x.run.ProjectRoot)
+		if err != nil {
+			return nil, false, err
+		}
+		return map[string]any{"output": result.Output, "exit_code": strconv.Itoa(result.ExitCode)}, false, nil
+
+	case canvas.NodeApproval:
+		return nil, true, nil
+	}
+	return nil, false, fmt.Errorf("unknown node type %q", node.Type)
+}
+
+// runAgent runs a saved agent through the same runner as the HTTP API and records the
+// agent revision it used. The node's variables fill the agent's user variables in its
+// system prompt and in the task prompt; a "files" variable adds to the files given as
+// context.
+func (e *Executor) runAgent(ctx context.Context, x *execution, node *canvas.Node, vars map[string]any, text map[string]string) (map[string]any, error) {
+	agent, err := e.agentStore.GetAgent(ctx, node.Agent.AgentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load agent %q: %w", node.Agent.AgentID, err)
+	}
+	x.update(node.ID, func() { x.run.Nodes[node.ID].AgentRevision = agent.Revision })
+
+	prompt := node.Agent.Prompt
+	if prompt == "" {
+		prompt = text["prompt"]
+	}
+
+	req := runner.Request{
+		SystemInstruction: agent.SystemPrompt,
+		Prompt:            prompt,
+		OutputSchema:      map[string]any{"schema": agent.OutputSchema.Schema},
+		LLMConfig:         agent.LLMConfig,
+		ProjectRoot:       x.run.ProjectRoot,
+		CodebasePaths:     stringList(vars["files"]),
+		Variables:         text,
+		UserVariables:     agent.UserVariables,
+	}
+	if e.ProjectSettings != nil {
+		settings := e.ProjectSettings(ctx, x.run.ProjectRoot)
+		req.CodebasePaths = projectconfig.ContextPaths(settings, req.CodebasePaths)
+		if req.LLMConfig.ConfigID == "" {
+			req.LLMConfig.ConfigID = projectconfig.LLMConfigID(ctx, e.LLMConfigStore, settings, req.LLMConfig.Provider)
+		}
+	}
+	return e.runner.Run(ctx, req, nil)
+}
+
+// textVars renders variables as text for templates.
+func textVars(vars map[string]any) map[string]string {
+	text := make(map[string]string, len(vars))
+	for name, value := range vars {
+		text[name] = canvas.ValueString(value)
+	}
+	return text
+}
+
+// stringList converts a files value, which is []any once it has been stored and loaded.
+func stringList(value any) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []any:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
@@ -0,0 +1,432 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ClarionDev/clarion/internal/agent"
+	"github.com/ClarionDev/clarion/internal/canvas"
+	"github.com/ClarionDev/clarion/internal/llm"
+	"github.com/ClarionDev/clarion/internal/models"
+	"github.com/ClarionDev/clarion/internal/runner"
+	"github.com/ClarionDev/clarion/internal/storage"
+)
+
+// memoryRunStore keeps runs as JSON, like the SQLite store, so outputs are round-tripped.
+type memoryRunStore struct {
+	mu   sync.Mutex
+	runs map[string][]byte
+	// beforeGet, if set, is called before each GetCanvasRun.
+	beforeGet func(id string)
+}
+
+func (s *memoryRunStore) SaveCanvasRun(ctx context.Context, run *canvas.Run) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runs[run.ID] = data
+	return nil
+}
+
+func (s *memoryRunStore) GetCanvasRun(ctx context.Context, id string) (*canvas.Run, error) {
+	if s.beforeGet != nil {
+		s.beforeGet(id)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.runs[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	var run canvas.Run
+	return &run, json.Unmarshal(data, &run)
+}
+
+func (s *memoryRunStore) ListCanvasRuns(ctx context.Context, canvasID string) ([]*canvas.Run, error) {
+	return nil, nil
+}
+
+// newTestExecutor returns an executor and a function that waits for a run to stop.
+func newTestExecutor(t *testing.T) (*Executor, *memoryRunStore, func(runID string) *canvas.Run) {
+	t.Helper()
+	store := &memoryRunStore{runs: make(map[string][]byte)}
+	stopped := make(chan string, 10)
+	notify := func(run *canvas.Run, nodeID string) {
+		if nodeID == "" && run.Status != canvas.StatusRunning {
+			stopped <- run.ID
+		}
+	}
+	e := NewExecutor(nil, store, nil, 2, notify)
+	wait := func(runID string) *canvas.Run {
+		t.Helper()
+		select {
+		case id := <-stopped:
+			if id != runID {
+				t.Fatalf("run %s stopped, want %s", id, runID)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("run did not stop")
+		}
+		// The executor releases the run right after its last update.
+		for e.isActive(runID) {
+			time.Sleep(time.Millisecond)
+		}
+		run, err := store.GetCanvasRun(context.Background(), runID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return run
+	}
+	return e, store, wait
+}
+
+func node(id string, typ canvas.NodeType) canvas.Node {
+	n := canvas.Node{ID: id, Type: typ}
+	switch typ {
+	case canvas.NodeInput:
+		n.Input = &canvas.InputConfig{Name: id}
+	case canvas.NodeApproval:
+		n.Approval = &canvas.ApprovalConfig{}
+	}
+	return n
+}
+
+func command(id, cmd string) canvas.Node {
+	return canvas.Node{ID: id, Type: canvas.NodeCommand, Command: &canvas.CommandConfig{Command: cmd}}
+}
+
+func edge(source, output, target, input string) canvas.Edge {
+	return canvas.Edge{Source: source, SourceOutput: output, Target: target, TargetInput: input}
+}
+
+func TestConditionalEdgesAndApproval(t *testing.T) {
+	e, _, wait := newTestExecutor(t)
+	ctx := context.Background()
+
+	onExit := func(edge canvas.Edge, op, value string) canvas.Edge {
+		edge.Condition = &canvas.Condition{Field: "exit_code", Op: op, Value: value}
+		return edge
+	}
+	c := &canvas.Canvas{
+		ID:   "c1",
+		Name: "branching",
+		Nodes: []canvas.Node{
+			node("name", canvas.NodeInput),
+			{ID: "greeting", Type: canvas.NodePrompt, Prompt: &canvas.PromptConfig{Template: "hello {{name}}"}},
+			command("check", "echo {{greeting}}"),
+			command("passed", "echo ok {{out}}"),
+			command("broken", "echo broken"),
+			node("gate", canvas.NodeApproval),
+			command("after", "echo approved: {{comment}}"),
+		},
+		Edges: []canvas.Edge{
+			edge("name", "value", "greeting", "name"),
+			edge("greeting", "text", "check", "greeting"),
+			onExit(edge("check", "output", "passed", "out"), canvas.OpEquals, "0"),
+			onExit(edge("check", "output", "broken", "out"), canvas.OpNotEquals, "0"),
+			edge("passed", "output", "gate", "result"),
+			edge("gate", "comment", "after", "comment"),
+		},
+	}
+
+	run, err := e.Start(ctx, c, t.TempDir(), map[string]string{"name": "world"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run = wait(run.ID)
+	if run.Status != canvas.StatusWaiting {
+		t.Fatalf("run status = %s (%s), want waiting for approval", run.Status, run.Error)
+	}
+	if got := run.Nodes["check"].Outputs["output"]; got != "hello world\n" {
+		t.Fatalf("check output = %q", got)
+	}
+	if run.Nodes["broken"].Status != canvas.StatusSkipped {
+		t.Fatalf("broken = %s, want skipped", run.Nodes["broken"].Status)
+	}
+	if run.Nodes["gate"].Status != canvas.StatusWaiting || run.Nodes["after"].Status != canvas.StatusPending {
+		t.Fatalf("gate = %s, after = %s", run.Nodes["gate"].Status, run.Nodes["after"].Status)
+	}
+
+	if _, err := e.Approve(ctx, run.ID, "gate", Decision{Approved: true, Comment: "looks good"}); err != nil {
+		t.Fatal(err)
+	}
+	run = wait(run.ID)
+	if run.Status != canvas.StatusCompleted {
+		t.Fatalf("run status = %s (%s), want completed", run.Status, run.Error)
+	}
+	if got := run.Nodes["after"].Outputs["output"]; got != "approved: looks good\n" {
+		t.Fatalf("after output = %q", got)
+	}
+	if _, err := e.Approve(ctx, run.ID, "gate", Decision{Approved: true}); err != ErrNotWaiting {
+		t.Fatalf("second Approve = %v, want ErrNotWaiting", err)
+	}
+}
+
+func TestResumeFromFailedNode(t *testing.T) {
+	e, _, wait := newTestExecutor(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	c := &canvas.Canvas{
+		ID:   "c2",
+		Name: "resume",
+		Nodes: []canvas.Node{
+			command("first", "echo first"),
+			command("tool", "./tool.sh {{in}}"),
+			command("last", "echo {{in}}"),
+		},
+		Edges: []canvas.Edge{
+			edge("first", "output", "tool", "in"),
+			edge("tool", "output", "last", "in"),
+		},
+	}
+
+	run, err := e.Start(ctx, c, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run = wait(run.ID)
+	if run.Status != canvas.StatusFailed || run.Nodes["tool"].Status != canvas.StatusFailed {
+		t.Fatalf("run = %s, tool = %s, want both failed", run.Status, run.Nodes["tool"].Status)
+	}
+	if !strings.Contains(run.Error, `node "tool" failed`) {
+		t.Fatalf("run error = %q", run.Error)
+	}
+
+	script := filepath.Join(dir, "tool.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho tool $1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Resume(ctx, run.ID); err != nil {
+		t.Fatal(err)
+	}
+	run = wait(run.ID)
+	if run.Status != canvas.StatusCompleted {
+		t.Fatalf("run status = %s (%s), want completed", run.Status, run.Error)
+	}
+	if run.Nodes["first"].Attempts != 1 || run.Nodes["tool"].Attempts != 2 {
+		t.Fatalf("attempts: first = %d, tool = %d", run.Nodes["first"].Attempts, run.Nodes["tool"].Attempts)
+	}
+	if got := run.Nodes["last"].Outputs["output"]; got != "tool first\n\n" {
+		t.Fatalf("last output = %q", got)
+	}
+	if _, err := e.Resume(ctx, run.ID); err != ErrRunFinished {
+		t.Fatalf("Resume of a completed run = %v, want ErrRunFinished", err)
+	}
+}
+
+func TestApproveClaimsRun(t *testing.T) {
+	e, store, wait := newTestExecutor(t)
+	ctx := context.Background()
+
+	c := &canvas.Canvas{
+		ID:   "c3",
+		Name: "approvals",
+		Nodes: []canvas.Node{
+			node("gate", canvas.NodeApproval),
+			command("after", "echo {{comment}}"),
+		},
+		Edges: []canvas.Edge{edge("gate", "comment", "after", "comment")},
+	}
+	run, err := e.Start(ctx, c, t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run = wait(run.ID)
+
+	// A second decision arriving while the first one reads the run must not start it.
+	var nestedErrs []error
+	store.beforeGet = func(id string) {
+		store.beforeGet = nil
+		_, err := e.Approve(ctx, id, "gate", Decision{Approved: false, Comment: "second"})
+		nestedErrs = append(nestedErrs, err)
+		_, err = e.Resume(ctx, id)
+		nestedErrs = append(nestedErrs, err)
+	}
+	if _, err := e.Approve(ctx, run.ID, "gate", Decision{Approved: true, Comment: "first"}); err != nil {
+		t.Fatalf("Approve() error = %v", err)
+	}
+	for i, err := range nestedErrs {
+		if err != ErrRunActive {
+			t.Errorf("call %d during an approval = %v, want ErrRunActive", i, err)
+		}
+	}
+
+	run = wait(run.ID)
+	if run.Status != canvas.StatusCompleted || run.Nodes["after"].Attempts != 1 {
+		t.Fatalf("run = %s (%s), after attempts = %d", run.Status, run.Error, run.Nodes["after"].Attempts)
+	}
+	if got := run.Nodes["after"].Outputs["output"]; got != "first\n" {
+		t.Errorf("after output = %q, want the first decision", got)
+	}
+}
+
+func TestConcurrentApprovals(t *testing.T) {
+	e, _, wait := newTestExecutor(t)
+	ctx := context.Background()
+
+	c := &canvas.Canvas{
+		ID:   "c4",
+		Name: "approvals",
+		Nodes: []canvas.Node{
+			node("gate", canvas.NodeApproval),
+			command("after", "echo {{comment}}"),
+		},
+		Edges: []canvas.Edge{edge("gate", "comment", "after", "comment")},
+	}
+	run, err := e.Start(ctx, c, t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run = wait(run.ID)
+
+	// Only one decision is recorded; the others find the run executing or already decided.
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = e.Approve(ctx, run.ID, "gate", Decision{Approved: true, Comment: strconv.Itoa(i)})
+		}()
+	}
+	wg.Wait()
+	winner := -1
+	for i, err := range errs {
+		switch {
+		case err == nil && winner >= 0:
+			t.Fatalf("approvals %d and %d both succeeded", winner, i)
+		case err == nil:
+			winner = i
+		case err != ErrRunActive && err != ErrNotWaiting:
+			t.Fatalf("approval %d error = %v", i, err)
+		}
+	}
+	if winner < 0 {
+		t.Fatal("no approval succeeded")
+	}
+
+	run = wait(run.ID)
+	if run.Status != canvas.StatusCompleted || run.Nodes["after"].Attempts != 1 {
+		t.Fatalf("run = %s (%s), after attempts = %d", run.Status, run.Error, run.Nodes["after"].Attempts)
+	}
+	if got, want := run.Nodes["after"].Outputs["output"], strconv.Itoa(winner)+"\n"; got != want {
+		t.Errorf("after output = %q, want the winning comment %q", got, want)
+	}
+}
+
+// memoryAgentStore holds agents for agent nodes; only GetAgent is implemented.
+type memoryAgentStore struct {
+	storage.AgentStore
+	agents map[string]*models.Agent
+}
+
+func (s *memoryAgentStore) GetAgent(ctx context.Context, id string) (*models.Agent, error) {
+	if a, ok := s.agents[id]; ok {
+		return a, nil
+	}
+	return nil, storage.ErrNotFound
+}
+
+// changesProvider proposes a change to main.go, or reports the task prompt it was given.
+type changesProvider struct{}
+
+func (changesProvider) Generate(ctx context.Context, messages []llm.ChatMessage, request models.AgentRunRequest, store storage.LLMConfigStore) (map[string]any, error) {
+	if request.LLMConfig.Model == "writer" {
+		return map[string]any{"summary": "Edited main.go", "file_changes": []any{
+			map[string]any{"action": "modify", "path": "main.go", "new_content": "package main\n"},
+		}}, nil
+	}
+	return map[string]any{"summary": messages[len(messages)-1].Content, "file_changes": []any{}}, nil
+}
+
+func init() {
+	llm.RegisterProvider("Workflow Test", changesProvider{})
+}
+
+func TestApproveEditsChanges(t *testing.T) {
+	e, _, wait := newTestExecutor(t)
+	ctx := context.Background()
+	testAgent := func(model string) *models.Agent {
+		return &models.Agent{
+			Profile:      agent.AgentProfile{ID: model, Name: model},
+			OutputSchema: models.OutputSchema{Schema: map[string]any{"type": "object"}},
+			LLMConfig:    models.LLMConfig{Provider: "Workflow Test", Model: model},
+		}
+	}
+	e.agentStore = &memoryAgentStore{agents: map[string]*models.Agent{"writer": testAgent("writer"), "reviewer": testAgent("reviewer")}}
+	e.runner = runner.New(nil)
+
+	c := &canvas.Canvas{
+		ID:   "c1",
+		Name: "edited changes",
+		Nodes: []canvas.Node{
+			{ID: "write", Type: canvas.NodeAgent, Agent: &canvas.AgentConfig{AgentID: "writer", Prompt: "Write main.go"}},
+			node("gate", canvas.NodeApproval),
+			{ID: "review", Type: canvas.NodeAgent, Agent: &canvas.AgentConfig{AgentID: "reviewer", Prompt: "Review {{changes}}"}},
+		},
+		Edges: []canvas.Edge{
+			edge("write", "changes", "gate", "changes"),
+			edge("gate", "changes", "review", "changes"),
+		},
+	}
+	run, err := e.Start(ctx, c, t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run = wait(run.ID)
+	if run.Nodes["gate"].Status != canvas.StatusWaiting {
+		t.Fatalf("gate = %s (%s), want waiting for approval", run.Nodes["gate"].Status, run.Error)
+	}
+
+	edited := []map[string]any{{"action": "modify", "path": "main.go", "new_content": "package edited\n"}}
+	if _, err := e.Approve(ctx, run.ID, "gate", Decision{Approved: true, Changes: edited}); err != nil {
+		t.Fatal(err)
+	}
+	run = wait(run.ID)
+	if run.Status != canvas.StatusCompleted {
+		t.Fatalf("run status = %s (%s), want completed", run.Status, run.Error)
+	}
+	summary, _ := run.Nodes["review"].Outputs["summary"].(string)
+	if !strings.Contains(summary, `package edited\n`) || strings.Contains(summary, `package main\n`) {
+		t.Errorf("review prompt = %q, want the edited changes", summary)
+	}
+}
+
+func TestApproveChangesWithoutInput(t *testing.T) {
+	e, _, wait := newTestExecutor(t)
+	ctx := context.Background()
+	c := &canvas.Canvas{
+		ID:    "c1",
+		Name:  "no changes",
+		Nodes: []canvas.Node{command("build", "echo built"), node("gate", canvas.NodeApproval)},
+		Edges: []canvas.Edge{edge("build", "output", "gate", "result")},
+	}
+	run, err := e.Start(ctx, c, t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait(run.ID)
+
+	changes := []map[string]any{{"action": "create", "path": "new.go"}}
+	if _, err := e.Approve(ctx, run.ID, "gate", Decision{Approved: true, Changes: changes}); err != ErrNoChanges {
+		t.Fatalf("Approve() with changes = %v, want ErrNoChanges", err)
+	}
+	// The refused decision leaves the node waiting for another one.
+	if _, err := e.Approve(ctx, run.ID, "gate", Decision{Approved: true}); err != nil {
+		t.Fatalf("Approve() error = %v", err)
+	}
+	if run = wait(run.ID); run.Status != canvas.StatusCompleted {
+		t.Errorf("run status = %s (%s), want completed", run.Status, run.Error)
+	}
+}
@@ -0,0 +1,17 @@
+package workflow
+
+import (
+	"context"
+	"maps"
+
+	"github.com/ClarionDev/clarion/internal/runner"
+	"github.com/ClarionDev/clarion/internal/templating"
+)
+
+// render fills a prompt or command template with the built-in variables and the node's
+// variables, which take precedence over them.
+func render(ctx context.Context, projectRoot, template string, vars map[string]string) (string, error) {
+	data := runner.BuiltinVariables(ctx, projectRoot, "")
+	maps.Copy(data, vars)
+	return templating.Render(template, data)
+}
@@ -0,0 +1,343 @@
+// Package worktree isolates agent runs in temporary git worktrees, so that concurrent runs
+// on the same project do not write over each other or over the user's working tree.
+package worktree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/ClarionDev/clarion/internal/git"
+	"github.com/ClarionDev/clarion/internal/shell"
+)
+
+// ErrNotFound is returned for runs without a worktree.
+var ErrNotFound = errors.New("no worktree for this run")
+
+// ErrNoChanges is returned when integrating a worktree that has no commits on top of its base.
+var ErrNoChanges = errors.New("the worktree has no committed changes")
+
+const janitorInterval = 10 * time.Minute
+
+// Integration modes.
+const (
+	ModeMerge      = "merge"
+	ModeCherryPick = "cherry-pick"
+)
+
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// Worktree is a temporary checkout of a project for one run.
+type Worktree struct {
+	RunID  string `json:"run_id"`
+	Path   string `json:"path"`
+	Branch string `json:"branch"`
+	// ProjectDir is the project root inside the worktree. It differs from Path when the
+	// project is a subdirectory of its repository.
+	ProjectDir string `json:"project_dir"`
+	// RepoDir is the main working tree the worktree was created from.
+	RepoDir    string    `json:"repo_dir"`
+	BaseBranch string    `json:"base_branch,omitempty"`
+	BaseCommit string    `json:"base_commit"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	mu       sync.Mutex
+	lastUsed time.Time
+}
+
+// Manager creates worktrees under a directory it owns and removes them once they are
+// integrated, discarded, or left idle for longer than the TTL.
+type Manager struct {
+	dir string
+	ttl time.Duration
+
+	mu    sync.Mutex
+	trees map[string]*Worktree
+
+	done chan struct{}
+}
+
+// NewManager returns a manager that keeps worktrees in dir. Worktrees left behind by a
+// previous process are removed, since their runs cannot be resumed.
+func NewManager(dir string, ttl time.Duration) (*Manager, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create worktree directory: %w", err)
+	}
+	m := &Manager{
+		dir:   dir,
+		ttl:   ttl,
+		trees: make(map[string]*Worktree),
+		done:  make(chan struct{}),
+	}
+	m.removeLeftovers()
+	go m.janitor()
+	return m, nil
+}
+
+// Close stops the background cleanup.
+func (m *Manager) Close() {
+	close(m.done)
+}
+
+// Create makes a worktree for a run on a new branch at the HEAD of the repository that
+// contains projectRoot. Uncommitted changes in projectRoot are not carried over.
+func (m *Manager) Create(ctx context.Context, projectRoot, runID, branch string) (*Worktree, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.trees[runID]; exists {
+		return nil, fmt.Errorf("run %s already has a worktree", runID)
+	}
+	return m.create(ctx, projectRoot, runID, branch)
+}
+
+// GetOrCreate returns the worktree of a run, creating it as Create does if the run has
+// none. Concurrent calls for one run share a single worktree.
+func (m *Manager) GetOrCreate(ctx context.Context, projectRoot, runID, branch string) (*Worktree, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if wt, ok := m.trees[runID]; ok {
+		return wt, nil
+	}
+	return m.create(ctx, projectRoot, runID, branch)
+}
+
+// create makes a run's worktree; m.mu must be held.
+func (m *Manager) create(ctx context.Context, projectRoot, runID, branch string) (*Worktree, error) {
+	repo, err := git.Open(ctx, projectRoot)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := repo.Prefix(projectRoot)
+	if err != nil {
+		return nil, err
+	}
+	baseCommit, err := repo.ResolveCommit(ctx, "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("cannot isolate a run in a repository without commits: %w", err)
+	}
+	baseBranch, err := repo.CurrentBranch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(m.dir, unsafeName.ReplaceAllString(runID, "_"))
+	if _, err := repo.AddWorktree(ctx, path, branch, baseCommit); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	wt := &Worktree{
+		RunID:      runID,
+		Path:       path,
+		Branch:     branch,
+		ProjectDir: filepath.Join(path, filepath.FromSlash(prefix)),
+		RepoDir:    repo.Dir,
+		BaseBranch: baseBranch,
+		BaseCommit: baseCommit,
+		CreatedAt:  now,
+		lastUsed:   now,
+	}
+	m.trees[runID] = wt
+	log.Printf("Created worktree for run %s at %s", runID, path)
+	return wt, nil
+}
+
+// Get returns the worktree of a run.
+func (m *Manager) Get(runID string) (*Worktree, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wt, ok := m.trees[runID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return wt, nil
+}
+
+// Use runs fn with exclusive access to a run's worktree and its repository.
+func (m *Manager) Use(ctx context.Context, runID string, fn func(wt *Worktree, repo *git.Repo) error) error {
+	wt, err := m.Get(runID)
+	if err != nil {
+		return err
+	}
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+	wt.lastUsed = time.Now()
+
+	repo, err := git.Open(ctx, wt.Path)
+	if err != nil {
+		return err
+	}
+	return fn(wt, repo)
+}
+
+// Verify runs commands one after another in the worktree's project directory and returns
+// their results. It stops at the first command that fails.
+func (m *Manager) Verify(ctx context.Context, runID string, commands []string) ([]*shell.CommandResult, error) {
+	var results []*shell.CommandResult
+	err := m.Use(ctx, runID, func(wt *Worktree, repo *git.Repo) error {
+		for _, command := range commands {
+			result, err := shell.RunCommand(ctx, command, wt.ProjectDir)
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+			if result.ExitCode != 0 {
+				break
+			}
+		}
+		return nil
+	})
+	return results, err
+}
+
+// Integrate brings the worktree's commits into the main working tree, as a merge commit or
+// by cherry-picking them, and then removes the worktree. The main working tree must be on
+// the branch the worktree was created from and must have no uncommitted changes.
+func (m *Manager) Integrate(ctx context.Context, runID, mode, message string) error {
+	err := m.Use(ctx, runID, func(wt *Worktree, repo *git.Repo) error {
+		head, err := repo.ResolveCommit(ctx, "HEAD")
+		if err != nil {
+			return err
+		}
+		if head == wt.BaseCommit {
+			return ErrNoChanges
+		}
+
+		main, err := git.Open(ctx, wt.RepoDir)
+		if err != nil {
+			return err
+		}
+		current, err := main.CurrentBranch(ctx)
+		if err != nil {
+			return err
+		}
+		if current != wt.BaseBranch {
+			return fmt.Errorf("the worktree was created from %q but %q is checked out", wt.BaseBranch, current)
+		}
+
+		switch mode {
+		case ModeMerge:
+			return main.Merge(ctx, head, message)
+		case ModeCherryPick:
+			return main.CherryPick(ctx, wt.BaseCommit, head)
+		default:
+			return fmt.Errorf("unknown integration mode %q, expected %s or %s", mode, ModeMerge, ModeCherryPick)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return m.Remove(ctx, runID, true)
+}
+
+// Remove deletes a run's worktree. The branch is deleted too when deleteBranch is set or
+// when it holds no commits beyond the base; otherwise it is kept for review with git tools.
+func (m *Manager) Remove(ctx context.Context, runID string, deleteBranch bool) error {
+	m.mu.Lock()
+	wt, ok := m.trees[runID]
+	if ok {
+		delete(m.trees, runID)
+	}
+	m.mu.Unlock()
+	if !ok {
+		return ErrNotFound
+	}
+
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+
+	main, err := git.Open(ctx, wt.RepoDir)
+	if err != nil {
+		os.RemoveAll(wt.Path)
+		return err
+	}
+	if err := main.RemoveWorktree(ctx, wt.Path); err != nil {
+		os.RemoveAll(wt.Path)
+		main.PruneWorktrees(ctx)
+	}
+
+	if !deleteBranch {
+		head, err := main.ResolveCommit(ctx, "refs/heads/"+wt.Branch)
+		deleteBranch = err == nil && head == wt.BaseCommit
+	}
+	if deleteBranch {
+		if err := main.DeleteBranch(ctx, wt.Branch); err != nil {
+			log.Printf("Failed to delete branch %s of run %s: %v", wt.Branch, runID, err)
+		}
+	}
+	log.Printf("Removed worktree of run %s", runID)
+	return nil
+}
+
+// RemoveRuns removes the worktrees of deleted runs, as Remove without deleteBranch does.
+// Runs without a worktree are skipped and failures are logged.
+func (m *Manager) RemoveRuns(ctx context.Context, runIDs []string) {
+	for _, runID := range runIDs {
+		if err := m.Remove(ctx, runID, false); err != nil && !errors.Is(err, ErrNotFound) {
+			log.Printf("Failed to remove the worktree of deleted run %s: %v", runID, err)
+		}
+	}
+}
+
+func (m *Manager) janitor() {
+	ticker := time.NewTicker(janitorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			m.removeIdle()
+		}
+	}
+}
+
+func (m *Manager) removeIdle() {
+	m.mu.Lock()
+	var idle []string
+	for runID, wt := range m.trees {
+		if wt.mu.TryLock() {
+			if time.Since(wt.lastUsed) > m.ttl {
+				idle = append(idle, runID)
+			}
+			wt.mu.Unlock()
+		}
+	}
+	m.mu.Unlock()
+
+	for _, runID := range idle {
+		if err := m.Remove(context.Background(), runID, false); err != nil && !errors.Is(err, ErrNotFound) {
+			log.Printf("Failed to remove idle worktree of run %s: %v", runID, err)
+		}
+	}
+}
+
+// removeLeftovers removes worktrees in the manager's directory that no run owns.
+func (m *Manager) removeLeftovers() {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		log.Printf("Failed to read worktree directory: %v", err)
+		return
+	}
+	ctx := context.Background()
+	for _, entry := range entries {
+		path := filepath.Join(m.dir, entry.Name())
+		if main, err := git.MainRepo(ctx, path); err == nil {
+			if main.Dir == path {
+				log.Printf("Skipping %s in the worktree directory: it is a repository, not a worktree", path)
+				continue
+			}
+			if err := main.RemoveWorktree(ctx, path); err == nil {
+				log.Printf("Removed leftover worktree %s", path)
+				continue
+			}
+		}
+		os.RemoveAll(path)
+	}
+}
@@ -0,0 +1,193 @@
+package worktree
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ClarionDev/clarion/internal/git"
+)
+
+// newTestRepo creates a repository with one commit and a "sub" project directory.
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "a.txt"), []byte("a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch=main"},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "commit.gpgsign", "false"},
+		{"add", "."},
+		{"commit", "--quiet", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	return dir
+}
+
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	m, err := NewManager(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Close)
+	return m
+}
+
+// commitInWorktree writes a file, named relative to the top of the worktree, and commits it.
+func commitInWorktree(t *testing.T, m *Manager, runID, name, content string) {
+	t.Helper()
+	ctx := context.Background()
+	err := m.Use(ctx, runID, func(wt *Worktree, repo *git.Repo) error {
+		if err := os.WriteFile(filepath.Join(wt.Path, filepath.FromSlash(name)), []byte(content), 0644); err != nil {
+			return err
+		}
+		if err := repo.Stage(ctx, name); err != nil {
+			return err
+		}
+		_, err := repo.Commit(ctx, "add "+name)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateVerifyIntegrate(t *testing.T) {
+	ctx := context.Background()
+	dir := newTestRepo(t)
+	m := newTestManager(t)
+
+	wt, err := m.Create(ctx, filepath.Join(dir, "sub"), "run-1", "clarion/run-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wt.BaseBranch != "main" || filepath.Base(wt.ProjectDir) != "sub" {
+		t.Fatalf("unexpected worktree %+v", wt)
+	}
+	if _, err := os.Stat(filepath.Join(wt.ProjectDir, "a.txt")); err != nil {
+		t.Fatalf("worktree is missing the project files: %v", err)
+	}
+
+	if err := m.Integrate(ctx, "run-1", ModeMerge, "merge"); !errors.Is(err, ErrNoChanges) {
+		t.Fatalf("Integrate without commits = %v, want ErrNoChanges", err)
+	}
+
+	commitInWorktree(t, m, "run-1", "sub/b.txt", "b\n")
+	if _, err := os.Stat(filepath.Join(dir, "sub", "b.txt")); !os.IsNotExist(err) {
+		t.Fatal("a change in the worktree reached the main working tree before integration")
+	}
+
+	results, err := m.Verify(ctx, "run-1", []string{"cat b.txt", "false", "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].ExitCode != 0 || results[0].Output != "b\n" || results[1].ExitCode == 0 {
+		t.Fatalf("unexpected verification results %+v", results)
+	}
+
+	if err := m.Integrate(ctx, "run-1", ModeMerge, "merge run-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub", "b.txt")); err != nil {
+		t.Fatalf("merged file missing from the main working tree: %v", err)
+	}
+	if _, err := os.Stat(wt.Path); !os.IsNotExist(err) {
+		t.Fatal("worktree was not removed after integration")
+	}
+	if _, err := m.Get("run-1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after integration = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCherryPickConflict(t *testing.T) {
+	ctx := context.Background()
+	dir := newTestRepo(t)
+	m := newTestManager(t)
+
+	if _, err := m.Create(ctx, dir, "run-2", "clarion/run-2"); err != nil {
+		t.Fatal(err)
+	}
+	commitInWorktree(t, m, "run-2", "sub/a.txt", "from the run\n")
+
+	// Change the same file on the main branch so the cherry-pick conflicts.
+	if err := os.WriteFile(filepath.Join(dir, "sub", "a.txt"), []byte("from the user\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	main, err := git.Open(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := main.Stage(ctx, "sub/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := main.Commit(ctx, "user change"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Integrate(ctx, "run-2", ModeCherryPick, ""); !errors.Is(err, git.ErrConflict) {
+		t.Fatalf("Integrate = %v, want ErrConflict", err)
+	}
+	status, err := main.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Clean() {
+		t.Fatalf("conflicting cherry-pick was not aborted: %+v", status)
+	}
+	// The worktree survives a failed integration so the user can decide what to do.
+	if _, err := m.Get("run-2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Remove(ctx, "run-2", true); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetOrCreateConcurrent(t *testing.T) {
+	ctx := context.Background()
+	dir := newTestRepo(t)
+	m := newTestManager(t)
+
+	trees := make([]*Worktree, 8)
+	errs := make([]error, len(trees))
+	var wg sync.WaitGroup
+	for i := range trees {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			trees[i], errs[i] = m.GetOrCreate(ctx, dir, "run-3", "clarion/wt/run-3")
+		}()
+	}
+	wg.Wait()
+	for i := range trees {
+		if errs[i] != nil {
+			t.Fatalf("GetOrCreate() call %d error = %v", i, errs[i])
+		}
+		if trees[i] != trees[0] {
+			t.Errorf("GetOrCreate() call %d returned another worktree", i)
+		}
+	}
+	if _, err := m.Create(ctx, dir, "run-3", "clarion/wt/run-3"); err == nil {
+		t.Error("Create() for a run with a worktree succeeded, want an error")
+	}
+}
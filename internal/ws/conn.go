@@ -0,0 +1,136 @@
+package ws
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// heartbeatInterval is how often the server sends a heartbeat envelope and a ping frame.
+	heartbeatInterval = 25 * time.Second
+	// pongWait is how long the server waits for any client traffic before dropping the connection.
+	pongWait = 60 * time.Second
+	// writeWait bounds a single write to the client.
+	writeWait = 10 * time.Second
+	// connSendBuffer is the outbound queue size of a connection, large enough for a full replay.
+	connSendBuffer = bufferSize * 4
+)
+
+// client is one multiplexed websocket connection and its channel subscriptions.
+type client struct {
+	hub  *Hub
+	conn *websocket.Conn
+	sink *sink
+	subs map[string]struct{}
+}
+
+// ServeConn runs the multiplexed protocol on an upgraded connection until the client disconnects.
+func (h *Hub) ServeConn(ctx context.Context, conn *websocket.Conn) {
+	c := &client{
+		hub:  h,
+		conn: conn,
+		sink: newSink(connSendBuffer),
+		subs: make(map[string]struct{}),
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go c.writeLoop()
+	c.readLoop(ctx)
+
+	for name := range c.subs {
+		h.detach(name, c.sink)
+	}
+	c.sink.close()
+}
+
+func (c *client) readLoop(ctx context.Context) {
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		var env Envelope
+		if err := c.conn.ReadJSON(&env); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("Workspace socket read error: %v", err)
+			}
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+
+		select {
+		case <-c.sink.done:
+			return
+		default:
+		}
+
+		switch env.Type {
+		case TypeSubscribe:
+			if _, ok := c.subs[env.Channel]; ok {
+				c.hub.detach(env.Channel, c.sink)
+			}
+			if err := c.hub.attach(env.Channel, env.Since, c.sink); err != nil {
+				c.sendError(env.Channel, err)
+				continue
+			}
+			c.subs[env.Channel] = struct{}{}
+		case TypeUnsubscribe:
+			c.hub.detach(env.Channel, c.sink)
+			delete(c.subs, env.Channel)
+			c.sink.send(Envelope{Type: TypeUnsubscribed, Channel: env.Channel})
+		case TypeCommand:
+			if err := c.hub.Command(ctx, env.Channel, env.Event, env.Data); err != nil {
+				c.sendError(env.Channel, err)
+			}
+		case TypePing:
+			c.sink.send(Envelope{Type: TypePong})
+		default:
+			c.sink.send(Envelope{Type: TypeError, Error: "unknown message type: " + string(env.Type)})
+		}
+	}
+}
+
+func (c *client) sendError(channel string, err error) {
+	c.sink.send(Envelope{Type: TypeError, Channel: channel, Error: err.Error()})
+}
+
+// writeLoop is the only goroutine writing to the connection.
+func (c *client) writeLoop() {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case env := <-c.sink.ch:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteJSON(env); err != nil {
+				log.Printf("Workspace socket write error: %v", err)
+				c.sink.close()
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteJSON(Envelope{Type: TypeHeartbeat}); err != nil {
+				c.sink.close()
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.sink.close()
+				return
+			}
+		case <-c.sink.done:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package ws
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// MessageType identifies the kind of frame carried by an Envelope.
+type MessageType string
+
+const (
+	// Client -> server
+	TypeSubscribe   MessageType = "subscribe"
+	TypeUnsubscribe MessageType = "unsubscribe"
+	TypeCommand     MessageType = "command"
+	TypePing        MessageType = "ping"
+
+	// Server -> client
+	TypeSubscribed   MessageType = "subscribed"
+	TypeUnsubscribed MessageType = "unsubscribed"
+	TypeEvent        MessageType = "event"
+	TypePong         MessageType = "pong"
+	TypeHeartbeat    MessageType = "heartbeat"
+	TypeError        MessageType = "error"
+)
+
+// Envelope is the single frame format exchanged over the multiplexed workspace socket.
+// Every event published on a channel carries a per-channel, monotonically increasing Seq
+// so that a client can resume a subscription after a reconnect by sending the last Seq it saw.
+type Envelope struct {
+	Type    MessageType     `json:"type"`
+	Channel string          `json:"channel,omitempty"`
+	Event   string          `json:"event,omitempty"`
+	Seq     uint64          `json:"seq,omitempty"`
+	Since   uint64          `json:"since,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// SubscribedData is the payload of a "subscribed" envelope.
+type SubscribedData struct {
+	// Head is the sequence number of the latest event on the channel at subscription time.
+	Head uint64 `json:"head"`
+	// Replayed is the number of buffered events re-sent because of a resume request.
+	Replayed int `json:"replayed"`
+	// Gap is true when the requested Since is older than the oldest buffered event,
+	// meaning some events were lost and the client should refresh its state.
+	Gap bool `json:"gap"`
+}
+
+// SplitChannel splits a channel name like "fs:<project>" into its topic and key.
+func SplitChannel(channel string) (topic, key string, ok bool) {
+	topic, key, ok = strings.Cut(channel, ":")
+	if !ok || topic == "" || key == "" {
+		return "", "", false
+	}
+	return topic, key, true
+}
+
+// Channel builds a channel name from a topic and key.
+func Channel(topic, key string) string {
+	return topic + ":" + key
+}
+
+func marshalData(data any) (json.RawMessage, error) {
+	if data == nil {
+		return nil, nil
+	}
+	if raw, ok := data.(json.RawMessage); ok {
+		return raw, nil
+	}
+	return json.Marshal(data)
+}
@@ -0,0 +1,361 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// bufferSize is the number of recent events kept per channel for resume-from-sequence.
+	bufferSize = 512
+	// lingerPeriod is how long a channel stays active after its last subscriber leaves,
+	// so a client reconnecting quickly does not miss events.
+	lingerPeriod = 30 * time.Second
+	// retentionPeriod is how long an idle channel's buffer is kept before it is dropped.
+	retentionPeriod = 10 * time.Minute
+)
+
+// PublishFunc publishes an event on the channel it was created for.
+type PublishFunc func(event string, data any)
+
+// Topic produces events for every channel sharing a prefix, e.g. "fs" for "fs:<project>".
+type Topic interface {
+	// Activate is called when a channel of this topic gets its first subscriber.
+	// Any background work must stop when ctx is cancelled, which happens once the
+	// channel has had no subscribers for the linger period.
+	Activate(ctx context.Context, key string, publish PublishFunc) error
+}
+
+// CommandHandler is implemented by topics that accept commands from clients,
+// e.g. "exec" on a terminal channel.
+type CommandHandler interface {
+	HandleCommand(ctx context.Context, key, command string, data json.RawMessage, publish PublishFunc) error
+}
+
+type channel struct {
+	name        string
+	topic       Topic
+	key         string
+	seq         uint64
+	buffer      []Envelope
+	subscribers map[*sink]struct{}
+	idleSince   time.Time
+
+	// activation state
+	ready       chan struct{}
+	activateErr error
+	cancel      context.CancelFunc
+}
+
+// Hub fans out channel events to subscribers and keeps a short history per channel.
+type Hub struct {
+	mu       sync.Mutex
+	topics   map[string]Topic
+	channels map[string]*channel
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// NewHub creates a hub and starts its background janitor.
+func NewHub() *Hub {
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &Hub{
+		topics:   make(map[string]Topic),
+		channels: make(map[string]*channel),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	go h.janitor()
+	return h
+}
+
+// RegisterTopic registers the producer for all channels named "<name>:<key>".
+func (h *Hub) RegisterTopic(name string, topic Topic) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.topics[name] = topic
+}
+
+// Close stops all active channels.
+func (h *Hub) Close() {
+	h.cancel()
+}
+
+// getChannelLocked returns the channel with the given name, creating it if its topic is registered.
+func (h *Hub) getChannelLocked(name string) (*channel, error) {
+	if ch, ok := h.channels[name]; ok {
+		return ch, nil
+	}
+	topicName, key, ok := SplitChannel(name)
+	if !ok {
+		return nil, fmt.Errorf("invalid channel name %q, expected <topic>:<key>", name)
+	}
+	topic, ok := h.topics[topicName]
+	if !ok {
+		return nil, fmt.Errorf("unknown channel topic %q", topicName)
+	}
+	ch := &channel{
+		name:        name,
+		topic:       topic,
+		key:         key,
+		subscribers: make(map[*sink]struct{}),
+		idleSince:   time.Now(),
+	}
+	h.channels[name] = ch
+	return ch, nil
+}
+
+// Publish sends an event to every subscriber of a channel and records it in the channel's history.
+func (h *Hub) Publish(channelName, event string, data any) error {
+	payload, err := marshalData(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event data: %w", err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch, err := h.getChannelLocked(channelName)
+	if err != nil {
+		return err
+	}
+
+	ch.seq++
+	env := Envelope{Type: TypeEvent, Channel: ch.name, Event: event, Seq: ch.seq, Data: payload}
+	if len(ch.buffer) >= bufferSize {
+		ch.buffer = append(ch.buffer[1:], env)
+	} else {
+		ch.buffer = append(ch.buffer, env)
+	}
+
+	for s := range ch.subscribers {
+		if !s.send(env) {
+			delete(ch.subscribers, s)
+		}
+	}
+	if len(ch.subscribers) == 0 && ch.idleSince.IsZero() {
+		ch.idleSince = time.Now()
+	}
+	return nil
+}
+
+// Publisher returns a PublishFunc bound to a channel. Errors are logged.
+func (h *Hub) Publisher(channelName string) PublishFunc {
+	return func(event string, data any) {
+		if err := h.Publish(channelName, event, data); err != nil {
+			log.Printf("Failed to publish %s event on %s: %v", event, channelName, err)
+		}
+	}
+}
+
+// attach registers s on a channel, activating the channel's topic if needed. A "subscribed"
+// envelope followed by any events newer than since are queued on s before live events, so
+// the subscriber sees a gapless, ordered stream.
+func (h *Hub) attach(name string, since uint64, s *sink) error {
+	h.mu.Lock()
+	ch, err := h.getChannelLocked(name)
+	if err != nil {
+		h.mu.Unlock()
+		return err
+	}
+
+	if ch.ready == nil {
+		ch.ready = make(chan struct{})
+		ctx, cancel := context.WithCancel(h.ctx)
+		ch.cancel = cancel
+		h.mu.Unlock()
+
+		err := ch.topic.Activate(ctx, ch.key, h.Publisher(name))
+
+		h.mu.Lock()
+		ch.activateErr = err
+		close(ch.ready)
+		if err != nil {
+			cancel()
+			delete(h.channels, name)
+			h.mu.Unlock()
+			return err
+		}
+	} else {
+		ready := ch.ready
+		h.mu.Unlock()
+		<-ready
+		h.mu.Lock()
+		if ch.activateErr != nil {
+			h.mu.Unlock()
+			return ch.activateErr
+		}
+	}
+	defer h.mu.Unlock()
+
+	info := SubscribedData{Head: ch.seq}
+	var backlog []Envelope
+	if since > 0 {
+		switch {
+		case since > ch.seq:
+			// The channel was recreated (e.g. server restart); the client's state is stale.
+			info.Gap = true
+		case len(ch.buffer) > 0 && since+1 < ch.buffer[0].Seq:
+			info.Gap = true
+			backlog = ch.buffer
+		case len(ch.buffer) == 0 && since < ch.seq:
+			info.Gap = true
+		default:
+			for i, env := range ch.buffer {
+				if env.Seq > since {
+					backlog = ch.buffer[i:]
+					break
+				}
+			}
+		}
+	}
+	info.Replayed = len(backlog)
+
+	ack, _ := marshalData(info)
+	if !s.send(Envelope{Type: TypeSubscribed, Channel: name, Seq: ch.seq, Data: ack}) {
+		return fmt.Errorf("subscriber closed")
+	}
+	for _, env := range backlog {
+		if !s.send(env) {
+			return fmt.Errorf("subscriber closed")
+		}
+	}
+
+	ch.subscribers[s] = struct{}{}
+	ch.idleSince = time.Time{}
+	return nil
+}
+
+// detach removes s from a channel.
+func (h *Hub) detach(name string, s *sink) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch, ok := h.channels[name]
+	if !ok {
+		return
+	}
+	delete(ch.subscribers, s)
+	if len(ch.subscribers) == 0 && ch.idleSince.IsZero() {
+		ch.idleSince = time.Now()
+	}
+}
+
+// Command dispatches a client command to the topic owning the channel.
+func (h *Hub) Command(ctx context.Context, channelName, command string, data json.RawMessage) error {
+	h.mu.Lock()
+	ch, err := h.getChannelLocked(channelName)
+	h.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	handler, ok := ch.topic.(CommandHandler)
+	if !ok {
+		return fmt.Errorf("channel %q does not accept commands", channelName)
+	}
+	return handler.HandleCommand(ctx, ch.key, command, data, h.Publisher(channelName))
+}
+
+// janitor deactivates channels without subscribers and eventually drops their history.
+func (h *Hub) janitor() {
+	ticker := time.NewTicker(lingerPeriod / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.ctx.Done():
+			return
+		case now := <-ticker.C:
+			h.mu.Lock()
+			for name, ch := range h.channels {
+				if len(ch.subscribers) > 0 || ch.idleSince.IsZero() {
+					continue
+				}
+				idle := now.Sub(ch.idleSince)
+				if ch.cancel != nil && idle > lingerPeriod {
+					ch.cancel()
+					ch.cancel = nil
+					ch.ready = nil
+				}
+				if idle > retentionPeriod {
+					delete(h.channels, name)
+				}
+			}
+			h.mu.Unlock()
+		}
+	}
+}
+
+// Subscription is an in-process subscription to a single channel.
+type Subscription struct {
+	// C delivers the "subscribed" acknowledgement followed by channel events.
+	C       <-chan Envelope
+	hub     *Hub
+	channel string
+	sink    *sink
+}
+
+// Subscribe attaches an in-process subscriber to a channel, replaying events newer than since.
+func (h *Hub) Subscribe(channelName string, since uint64) (*Subscription, error) {
+	s := newSink(bufferSize * 2)
+	if err := h.attach(channelName, since, s); err != nil {
+		return nil, err
+	}
+	return &Subscription{C: s.ch, hub: h, channel: channelName, sink: s}, nil
+}
+
+// Done is closed when the subscription is closed or was dropped for falling behind.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.sink.done
+}
+
+// Close detaches the subscription from its channel.
+func (s *Subscription) Close() {
+	s.hub.detach(s.channel, s.sink)
+	s.sink.close()
+}
+
+// sink is a bounded outbound queue. A sink that falls behind is closed rather than
+// blocking publishers; its owner can resubscribe with the last seen Seq.
+type sink struct {
+	mu     sync.Mutex
+	ch     chan Envelope
+	done   chan struct{}
+	closed bool
+}
+
+func newSink(size int) *sink {
+	return &sink{ch: make(chan Envelope, size), done: make(chan struct{})}
+}
+
+func (s *sink) send(env Envelope) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	select {
+	case s.ch <- env:
+		return true
+	default:
+		s.closeLocked()
+		return false
+	}
+}
+
+func (s *sink) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeLocked()
+}
+
+func (s *sink) closeLocked() {
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+}
@@ -0,0 +1,212 @@
+package ws_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ClarionDev/clarion/internal/ws"
+	"github.com/gorilla/websocket"
+)
+
+// nopTopic is a topic whose events are only published by the tests.
+type nopTopic struct{}
+
+func (nopTopic) Activate(ctx context.Context, key string, publish ws.PublishFunc) error {
+	return nil
+}
+
+func newHub(t *testing.T) *ws.Hub {
+	t.Helper()
+	h := ws.NewHub()
+	t.Cleanup(h.Close)
+	h.RegisterTopic("test", nopTopic{})
+	return h
+}
+
+func publishN(t *testing.T, h *ws.Hub, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := h.Publish("test:a", "tick", i); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+}
+
+func receive(t *testing.T, c <-chan ws.Envelope) ws.Envelope {
+	t.Helper()
+	select {
+	case env := <-c:
+		return env
+	case <-time.After(5 * time.Second):
+		t.Fatal("no envelope received")
+		return ws.Envelope{}
+	}
+}
+
+func subscribed(t *testing.T, env ws.Envelope) ws.SubscribedData {
+	t.Helper()
+	if env.Type != ws.TypeSubscribed {
+		t.Fatalf("first envelope = %+v, want subscribed", env)
+	}
+	var info ws.SubscribedData
+	if err := json.Unmarshal(env.Data, &info); err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func TestSubscribeResume(t *testing.T) {
+	h := newHub(t)
+	sub, err := h.Subscribe("test:a", 0)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	subscribed(t, receive(t, sub.C))
+	publishN(t, h, 3)
+	var last uint64
+	for i := 0; i < 3; i++ {
+		last = receive(t, sub.C).Seq
+	}
+	sub.Close()
+
+	// Events published while disconnected are replayed in order, then live events follow.
+	publishN(t, h, 2)
+	sub, err = h.Subscribe("test:a", last)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	defer sub.Close()
+	if info := subscribed(t, receive(t, sub.C)); info.Gap || info.Replayed != 2 || info.Head != 5 {
+		t.Errorf("resume = %+v, want 2 replayed without a gap", info)
+	}
+	publishN(t, h, 1)
+	for want := uint64(4); want <= 6; want++ {
+		if env := receive(t, sub.C); env.Type != ws.TypeEvent || env.Seq != want {
+			t.Errorf("envelope = %+v, want event %d", env, want)
+		}
+	}
+}
+
+func TestSubscribeGap(t *testing.T) {
+	h := newHub(t)
+	publishN(t, h, 600)
+
+	tests := []struct {
+		name         string
+		since        uint64
+		wantGap      bool
+		wantReplayed int
+	}{
+		{"in buffer", 590, false, 10},
+		{"older than buffer", 10, true, 512},
+		{"newer than head", 1000, true, 0},
+	}
+	for _, tt := range tests {
+		sub, err := h.Subscribe("test:a", tt.since)
+		if err != nil {
+			t.Fatalf("%s: Subscribe() error = %v", tt.name, err)
+		}
+		info := subscribed(t, receive(t, sub.C))
+		if info.Gap != tt.wantGap || info.Replayed != tt.wantReplayed || info.Head != 600 {
+			t.Errorf("%s: subscribed = %+v, want gap %v and %d replayed", tt.name, info, tt.wantGap, tt.wantReplayed)
+		}
+		sub.Close()
+	}
+}
+
+func TestSlowSubscriberDropped(t *testing.T) {
+	h := newHub(t)
+	slow, err := h.Subscribe("test:a", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slow.Close()
+	fast, err := h.Subscribe("test:a", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fast.Close()
+
+	// The fast subscriber keeps up; the slow one never reads and overflows its queue.
+	subscribed(t, receive(t, fast.C))
+	for i := 0; i < 2000; i++ {
+		publishN(t, h, 1)
+		if env := receive(t, fast.C); env.Seq != uint64(i+1) {
+			t.Fatalf("fast subscriber envelope = %+v, want event %d", env, i+1)
+		}
+	}
+
+	select {
+	case <-slow.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow subscriber was not dropped")
+	}
+	select {
+	case <-fast.Done():
+		t.Error("fast subscriber was dropped")
+	default:
+	}
+}
+
+func TestServeConnResume(t *testing.T) {
+	h := newHub(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		h.ServeConn(context.Background(), conn)
+	}))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	dial := func(since uint64) *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("Dial() error = %v", err)
+		}
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if err := conn.WriteJSON(ws.Envelope{Type: ws.TypeSubscribe, Channel: "test:a", Since: since}); err != nil {
+			t.Fatal(err)
+		}
+		return conn
+	}
+	read := func(conn *websocket.Conn) ws.Envelope {
+		var env ws.Envelope
+		if err := conn.ReadJSON(&env); err != nil {
+			t.Fatalf("ReadJSON() error = %v", err)
+		}
+		return env
+	}
+
+	conn := dial(0)
+	subscribed(t, read(conn))
+	publishN(t, h, 2)
+	read(conn)
+	last := read(conn).Seq
+	conn.Close()
+
+	publishN(t, h, 3)
+	conn = dial(last)
+	defer conn.Close()
+	if info := subscribed(t, read(conn)); info.Gap || info.Replayed != 3 {
+		t.Errorf("resume = %+v, want 3 replayed without a gap", info)
+	}
+	for want := uint64(3); want <= 5; want++ {
+		if env := read(conn); env.Seq != want {
+			t.Errorf("envelope = %+v, want event %d", env, want)
+		}
+	}
+
+	if err := conn.WriteJSON(ws.Envelope{Type: ws.TypeSubscribe, Channel: "nope:a"}); err != nil {
+		t.Fatal(err)
+	}
+	if env := read(conn); env.Type != ws.TypeError || env.Channel != "nope:a" {
+		t.Errorf("subscribe to an unknown topic = %+v, want an error", env)
+	}
+}
@@ -4,31 +4,104 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/ClarionDev/clarion/internal/api"
+	"github.com/ClarionDev/clarion/internal/cli"
+	"github.com/ClarionDev/clarion/internal/config"
 	"github.com/ClarionDev/clarion/internal/database"
 	"github.com/ClarionDev/clarion/internal/llm"
+	"github.com/ClarionDev/clarion/internal/retention"
+	"github.com/ClarionDev/clarion/internal/secrets"
 	"github.com/ClarionDev/clarion/internal/storage"
 	"github.com/ClarionDev/clarion/internal/tokencounter"
+	"github.com/ClarionDev/clarion/internal/worktree"
 	"github.com/joho/godotenv"
 )
 
 func main() {
-	if err := godotenv.Load(); err != nil {
+	// The headless commands take their own flags, so they are dispatched before the
+	// server's flags are parsed.
+	headless := len(os.Args) > 1 && cli.IsCommand(os.Args[1])
+
+	if err := godotenv.Load(); err != nil && !headless {
 		log.Println("No .env file found, using default or environment-set variables.")
 	}
 
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	if headless {
+		log.SetFlags(0)
+		os.Exit(cli.Main(context.Background(), os.Args[1:], os.Stdout, os.Stderr))
+	}
+
+	settings, args, err := config.LoadSettings(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		printUsage()
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n\n", err)
+		printUsage()
+		os.Exit(2)
+	}
+
+	switch strings.Join(args, " ") {
+	case "", "serve":
+		serve(settings)
+	case "config show":
+		if err := settings.Print(os.Stdout); err != nil {
+			log.Fatalf("Failed to print settings: %v", err)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", strings.Join(args, " "))
+		printUsage()
+		os.Exit(2)
+	}
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: clarion [flags] [command]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	fmt.Fprintln(os.Stderr, "  serve        start the API server (default)")
+	fmt.Fprintln(os.Stderr, "  config show  print the effective settings and where they come from")
+	cli.Usage(os.Stderr)
+	fmt.Fprintln(os.Stderr, "\nRun clarion <command> -h for the flags of run, prompt and agents list.")
+	fmt.Fprintln(os.Stderr, "\nFlags:")
+	config.Usage(os.Stderr)
+}
+
+func serve(settings *config.Settings) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: settings.SlogLevel()}))
+	slog.SetDefault(logger)
+	// SetDefault routes the standard logger through the handler at the info level; keep it
+	// on stderr so fatal and startup errors are printed whatever the configured level.
+	log.SetOutput(os.Stderr)
+	log.SetFlags(log.LstdFlags)
 
 	llm.RegisterProviders()
 	tokencounter.SetupProviders(context.Background(), logger)
 
+	if err := settings.EnsureDataDir(); err != nil {
+		log.Fatalf("Failed to prepare data directory: %v", err)
+	}
+	llm.SetMockFixtureDir(filepath.Join(settings.DataDir, "mock-fixtures"))
+
+	if _, err := os.Stat(settings.DBPath); os.IsNotExist(err) {
+		if _, err := os.Stat("clarion.db"); err == nil {
+			log.Printf("Found a database in the current directory but not at %s. Start with --db clarion.db to keep using it.", settings.DBPath)
+		}
+	}
+
 	ctx := context.Background()
-	db, err := database.New(ctx, "sqlite", "clarion.db")
+	log.Printf("Using database at %s", settings.DBPath)
+	db, err := database.New(ctx, "sqlite", settings.DBPath)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -38,24 +111,51 @@ func main() {
 		log.Fatalf("Failed to run database migrations: %v", err)
 	}
 
+	masterKey, err := secrets.LoadMasterKey(settings.DataDir, settings.KeyStore)
+	if err != nil {
+		log.Fatalf("Failed to load master key: %v", err)
+	}
+	vault, err := secrets.NewVault(masterKey)
+	if err != nil {
+		log.Fatalf("Failed to initialize secrets vault: %v", err)
+	}
+
 	sqlDB := db.Handle().(*sql.DB)
 	agentStore := storage.NewSQLiteAgentStore(sqlDB)
-	llmConfigStore := storage.NewSQLiteLLMConfigStore(sqlDB)
+	llmConfigStore := storage.NewSQLiteLLMConfigStore(sqlDB, vault)
+	if err := llmConfigStore.SealPlaintextKeys(ctx); err != nil {
+		log.Fatalf("Failed to encrypt stored API keys: %v", err)
+	}
 	projectStore := storage.NewSQLiteProjectStore(sqlDB)
 	runStore := storage.NewSQLiteRunStore(sqlDB)
+	canvasStore := storage.NewSQLiteCanvasStore(sqlDB)
+	canvasRunStore := storage.NewSQLiteCanvasRunStore(sqlDB)
+	promptTemplateStore := storage.NewSQLitePromptTemplateStore(sqlDB)
+	comparisonStore := storage.NewSQLiteComparisonStore(sqlDB)
+	evalStore := storage.NewSQLiteEvalStore(sqlDB)
+	responseCacheStore := storage.NewSQLiteResponseCacheStore(sqlDB)
 
 	database.SeedData(ctx, agentStore, llmConfigStore, projectStore, runStore)
 
-	server := api.NewServer(agentStore, llmConfigStore, projectStore, runStore)
-
-	port := os.Getenv("BACKEND_PORT")
-	if port == "" {
-		port = "2077"
+	// Worktrees of isolated runs are removed after two hours without use.
+	worktrees, err := worktree.NewManager(filepath.Join(settings.DataDir, "worktrees"), 2*time.Hour)
+	if err != nil {
+		log.Fatalf("Failed to set up worktrees: %v", err)
 	}
-	addr := fmt.Sprintf(":%s", port)
+	defer worktrees.Close()
+
+	maxAge, maxCount := settings.RunRetention()
+	janitor := retention.Start(runStore, retention.Config{
+		Policy:  storage.RetentionPolicy{MaxAge: maxAge, MaxRunsPerProject: maxCount},
+		Compact: settings.CompactRunsEnabled(),
+		Pruned:  worktrees.RemoveRuns,
+	})
+	defer janitor.Close()
+
+	server := api.NewServer(settings, agentStore, llmConfigStore, projectStore, runStore, canvasStore, canvasRunStore, promptTemplateStore, comparisonStore, evalStore, responseCacheStore, worktrees)
 
-	log.Printf("Starting server on %s", addr)
-	if err := server.Start(addr); err != nil {
+	log.Printf("Starting server on %s", settings.Addr())
+	if err := server.Start(settings.Addr()); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }